@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// TableAccess is the access counts of a table
+type TableAccess struct {
+	Table     string
+	Reads     uint64
+	Writes    uint64
+	LastRead  time.Time
+	LastWrite time.Time
+}
+
+// AccessStats counts the statements that read or write each table, as seen by
+// the authorizer of each connection when the statement is prepared.
+// Columns read or updated by the same statement are counted once, so the counts are
+// close to, but not exactly, the number of statements
+type AccessStats struct {
+	mu     sync.Mutex
+	since  time.Time
+	tables map[string]*TableAccess
+}
+
+// NewAccessStats returns an empty AccessStats
+func NewAccessStats() *AccessStats {
+	return &AccessStats{since: time.Now(), tables: make(map[string]*TableAccess)}
+}
+
+// WithAccessStats counts table access in stats for each connection
+func WithAccessStats(stats *AccessStats) Optional {
+	return func(c *Config) {
+		c.access = stats
+	}
+}
+
+func (a *AccessStats) record(table string, write bool) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.tables[table]
+	if !ok {
+		t = &TableAccess{Table: table}
+		a.tables[table] = t
+	}
+	if write {
+		t.Writes++
+		t.LastWrite = now
+	} else {
+		t.Reads++
+		t.LastRead = now
+	}
+}
+
+// authorizer returns the authorizer callback for a connection
+func (a *AccessStats) authorizer() func(int, string, string, string) int {
+	// seen is what the current statement has accessed, stmt is its kind
+	seen := make(map[string]bool)
+	stmt := 0
+	return func(op int, arg1, arg2, dbName string) int {
+		switch op {
+		case sqlite3.SQLITE_SELECT:
+			seen, stmt = make(map[string]bool), op
+			return sqlite3.SQLITE_OK
+		case sqlite3.SQLITE_INSERT, sqlite3.SQLITE_DELETE:
+			seen, stmt = make(map[string]bool), op
+		case sqlite3.SQLITE_UPDATE:
+			// there's no signal for the start of an update, but
+			// a column can only be updated once per statement
+			column := "update:" + dbName + "." + arg1 + "." + arg2
+			if stmt != op || seen[column] {
+				seen, stmt = make(map[string]bool), op
+			}
+			seen[column] = true
+		case sqlite3.SQLITE_READ:
+		default:
+			return sqlite3.SQLITE_OK
+		}
+		if strings.HasPrefix(arg1, "sqlite_") {
+			return sqlite3.SQLITE_OK
+		}
+		table := arg1
+		if dbName != "" && dbName != "main" {
+			table = dbName + "." + arg1
+		}
+		write := op != sqlite3.SQLITE_READ
+		key := table
+		if write {
+			key = "write:" + table
+		}
+		if !seen[key] {
+			seen[key] = true
+			a.record(table, write)
+		}
+		return sqlite3.SQLITE_OK
+	}
+}
+
+// Since returns when counting started
+func (a *AccessStats) Since() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.since
+}
+
+// Reset clears the counts
+func (a *AccessStats) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.since = time.Now()
+	a.tables = make(map[string]*TableAccess)
+}
+
+// Report returns the counts of each table accessed, busiest first
+func (a *AccessStats) Report() []TableAccess {
+	a.mu.Lock()
+	report := make([]TableAccess, 0, len(a.tables))
+	for _, t := range a.tables {
+		report = append(report, *t)
+	}
+	a.mu.Unlock()
+	sort.Slice(report, func(i, j int) bool {
+		ti, tj := report[i].Reads+report[i].Writes, report[j].Reads+report[j].Writes
+		if ti != tj {
+			return ti > tj
+		}
+		return report[i].Table < report[j].Table
+	})
+	return report
+}
+
+// Hot returns the n busiest tables
+func (a *AccessStats) Hot(n int) []TableAccess {
+	report := a.Report()
+	if n < len(report) {
+		report = report[:n]
+	}
+	return report
+}
+
+// Dead returns the tables of db that have not been accessed in the given period,
+// including those never accessed since counting started
+func (a *AccessStats) Dead(db *sql.DB, period time.Duration) ([]string, error) {
+	cutoff := time.Now().Add(-period)
+	a.mu.Lock()
+	active := make(map[string]bool)
+	for name, t := range a.tables {
+		if t.LastRead.After(cutoff) || t.LastWrite.After(cutoff) {
+			active[name] = true
+		}
+	}
+	a.mu.Unlock()
+
+	rows, err := db.Query("select name from sqlite_master where type='table' and name not like 'sqlite_%' order by name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var dead []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if !active[name] {
+			dead = append(dead, name)
+		}
+	}
+	return dead, rows.Err()
+}
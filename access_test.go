@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccessStats(t *testing.T) {
+	stats := NewAccessStats()
+	db, err := Open(":memory:", WithDriver("access_stats"), WithAccessStats(stats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const setup = `
+	create table hot (id integer primary key, name text, n integer);
+	create table warm (id integer primary key, name text);
+	create table dead (id integer primary key);
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := db.Exec("insert into hot (name, n) values(?, ?)", "x", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("update hot set name = 'y', n = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("update hot set name = 'z' where n = 1"); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from hot h join warm w on h.name = w.name where h.n > 0"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := stats.Report()
+	if len(report) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if hot := report[0]; hot.Table != "hot" || hot.Writes != 5 || hot.Reads != 2 || hot.LastWrite.IsZero() {
+		t.Fatalf("unexpected hot access: %+v", hot)
+	}
+	if warm := report[1]; warm.Table != "warm" || warm.Writes != 0 || warm.Reads != 1 {
+		t.Fatalf("unexpected warm access: %+v", warm)
+	}
+	if hot := stats.Hot(1); len(hot) != 1 || hot[0].Table != "hot" {
+		t.Fatalf("unexpected hot tables: %+v", hot)
+	}
+
+	dead, err := stats.Dead(db, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0] != "dead" {
+		t.Fatalf("expected: [dead] but got: %q", dead)
+	}
+
+	stats.Reset()
+	if report := stats.Report(); len(report) != 0 {
+		t.Fatalf("unexpected report after reset: %+v", report)
+	}
+}
@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// LogRecord is a record of an AppendLog
+type LogRecord struct {
+	Seq     int64
+	Created time.Time
+	Data    []byte
+}
+
+// ChecksumError is returned when a record of an AppendLog has been altered or corrupted
+type ChecksumError struct {
+	Table string
+	Seq   int64
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("append log %s: checksum mismatch for record %d", e.Table, e.Seq)
+}
+
+// AppendLog is append-only record storage in a table, such as for the events of
+// an event-sourced application. Records are numbered in order and never reused,
+// and each has a checksum of its number and data that is verified when read
+type AppendLog struct {
+	db    *sql.DB
+	table string
+}
+
+// NewAppendLog returns the log stored in table, creating it if necessary
+func NewAppendLog(db *sql.DB, table string) (*AppendLog, error) {
+	// an INTEGER PRIMARY KEY appends at the end of the b-tree,
+	// and AUTOINCREMENT keeps truncated numbers from being reused
+	const create = `CREATE TABLE IF NOT EXISTS %s (
+	seq      INTEGER PRIMARY KEY AUTOINCREMENT,
+	created  INTEGER NOT NULL,
+	data     BLOB NOT NULL,
+	checksum INTEGER NOT NULL
+)`
+	if _, err := db.Exec(fmt.Sprintf(create, quoteIdent(table))); err != nil {
+		return nil, fmt.Errorf("append log %s: %w", table, err)
+	}
+	return &AppendLog{db: db, table: table}, nil
+}
+
+// recordChecksum is the checksum of a record
+func recordChecksum(seq int64, data []byte) int64 {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(seq))
+	sum := crc32.Update(crc32.Checksum(b[:], castagnoli), castagnoli, data)
+	return int64(sum)
+}
+
+// Append adds records to the log in a single transaction and returns the number of the last
+func (l *AppendLog) Append(records ...[]byte) (int64, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	name := quoteIdent(l.table)
+	insert := fmt.Sprintf("INSERT INTO %s (created, data, checksum) VALUES(?, ?, 0)", name)
+	update := fmt.Sprintf("UPDATE %s SET checksum=? WHERE seq=?", name)
+	now := time.Now().UnixNano()
+	var seq int64
+	for _, data := range records {
+		if data == nil {
+			data = []byte{}
+		}
+		result, err := tx.Exec(insert, now, data)
+		if err != nil {
+			return 0, fmt.Errorf("append log %s: %w", l.table, err)
+		}
+		if seq, err = result.LastInsertId(); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(update, recordChecksum(seq, data), seq); err != nil {
+			return 0, fmt.Errorf("append log %s: %w", l.table, err)
+		}
+	}
+	return seq, tx.Commit()
+}
+
+// Last returns the number of the last record, or 0 if the log is empty
+func (l *AppendLog) Last() (int64, error) {
+	var seq sql.NullInt64
+	err := row(l.db, []interface{}{&seq}, fmt.Sprintf("SELECT max(seq) FROM %s", quoteIdent(l.table)))
+	return seq.Int64, err
+}
+
+// ReadFrom calls fn for each record from seq onward, in order, stopping at the first error.
+// A record that fails its checksum returns a *ChecksumError
+func (l *AppendLog) ReadFrom(seq int64, fn func(LogRecord) error) error {
+	return l.read(seq, -1, fn)
+}
+
+// read calls fn for up to limit records from seq onward, or every record if limit is negative
+func (l *AppendLog) read(seq int64, limit int, fn func(LogRecord) error) error {
+	q := fmt.Sprintf("SELECT seq, created, data, checksum FROM %s WHERE seq >= ? ORDER BY seq LIMIT ?", quoteIdent(l.table))
+	rows, err := l.db.Query(q, seq, limit)
+	if err != nil {
+		return fmt.Errorf("append log %s: %w", l.table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec LogRecord
+		var created, checksum int64
+		if err := rows.Scan(&rec.Seq, &created, &rec.Data, &checksum); err != nil {
+			return err
+		}
+		if recordChecksum(rec.Seq, rec.Data) != checksum {
+			return &ChecksumError{Table: l.table, Seq: rec.Seq}
+		}
+		rec.Created = time.Unix(0, created)
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Truncate removes the records before seq, returning how many were removed
+func (l *AppendLog) Truncate(seq int64) (int64, error) {
+	result, err := l.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE seq < ?", quoteIdent(l.table)), seq)
+	if err != nil {
+		return 0, fmt.Errorf("append log %s: %w", l.table, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAppendLog(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	log, err := NewAppendLog(db, "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last, err := log.Last(); err != nil || last != 0 {
+		t.Fatalf("expected empty log but got: %d (%v)", last, err)
+	}
+	for i := 0; i < 3; i++ {
+		records := [][]byte{[]byte(fmt.Sprintf("event %d", i*2)), []byte(fmt.Sprintf("event %d", i*2+1))}
+		if _, err := log.Append(records...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last, err := log.Append(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 7 {
+		t.Fatalf("expected: %d but got: %d", 7, last)
+	}
+
+	var seen []string
+	err = log.ReadFrom(3, func(rec LogRecord) error {
+		if rec.Created.IsZero() {
+			return fmt.Errorf("record %d has no time", rec.Seq)
+		}
+		seen = append(seen, fmt.Sprintf("%d:%s", rec.Seq, rec.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(seen) != "[3:event 2 4:event 3 5:event 4 6:event 5 7:]" {
+		t.Fatalf("unexpected records: %q", seen)
+	}
+
+	if n, err := log.Truncate(6); err != nil || n != 5 {
+		t.Fatalf("expected 5 truncated but got: %d (%v)", n, err)
+	}
+	if _, err := log.Truncate(100); err != nil {
+		t.Fatal(err)
+	}
+	// numbers are not reused after truncation
+	if last, err := log.Append([]byte("after")); err != nil || last != 8 {
+		t.Fatalf("expected: 8 but got: %d (%v)", last, err)
+	}
+
+	if _, err := db.Exec("update events set data = 'tampered' where seq = 8"); err != nil {
+		t.Fatal(err)
+	}
+	var cerr *ChecksumError
+	if err := log.ReadFrom(0, func(LogRecord) error { return nil }); !errors.As(err, &cerr) || cerr.Seq != 8 {
+		t.Fatalf("expected checksum error but got: %v", err)
+	}
+}
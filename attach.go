@@ -0,0 +1,253 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// attachment is a database attached to the connections of a pool
+type attachment struct {
+	file  string
+	alias string
+}
+
+// attachConn replays the attachments of the database on a new connection
+func (c *Config) attachConn(conn *sqlite3.SQLiteConn) error {
+	c.attachMu.Lock()
+	defer c.attachMu.Unlock()
+	if len(c.attached) == 0 {
+		return nil
+	}
+	main, err := connFilename(conn)
+	if err != nil {
+		return err
+	}
+	for _, a := range c.attached[main] {
+		if _, err := conn.Exec(fmt.Sprintf("ATTACH DATABASE %s AS %s", quoteString(a.file), quoteIdent(a.alias)), nil); err != nil {
+			return fmt.Errorf("attach %s: %w", a.alias, err)
+		}
+	}
+	return nil
+}
+
+// connFilename returns the file of the main database of the connection
+func connFilename(conn *sqlite3.SQLiteConn) (string, error) {
+	var main string
+	err := connQuery(conn, func(_ []string, _ int, values []driver.Value) error {
+		if fmt.Sprint(values[1]) == "main" {
+			main = fmt.Sprint(values[2])
+		}
+		return nil
+	}, "PRAGMA database_list")
+	return main, err
+}
+
+// idleConns calls fn with each idle connection of the pool, holding them all,
+// so it sees every connection that isn't in use
+func idleConns(ctx context.Context, db *sql.DB, fn func(*sql.Conn) error) error {
+	n := db.Stats().Idle
+	if n == 0 {
+		n = 1
+	}
+	var conns []*sql.Conn
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		if err := fn(conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isAttached reports whether alias is a database of the connection
+func isAttached(ctx context.Context, conn *sql.Conn, alias string) (bool, error) {
+	var n int
+	err := conn.QueryRowContext(ctx, "SELECT count(*) FROM pragma_database_list WHERE name = ? COLLATE NOCASE", alias).Scan(&n)
+	return n > 0, err
+}
+
+// Attach attaches the database file to every connection of the pool as alias,
+// including the connections opened later, until it is detached. Connections in use
+// while it's called are attached when they are next opened, so it should be called
+// before the pool is busy. The database must have been opened by this package.
+// Connections are told apart by their main database file, so the connections of
+// an in-memory database, which has none, are attached only while they're idle
+func Attach(db *sql.DB, file, alias string) error {
+	config := configFor(db)
+	if config == nil {
+		return fmt.Errorf("attach %s: database was not opened by this package", alias)
+	}
+	main, err := FilenameE(db)
+	if err != nil {
+		return err
+	}
+	if main != "" {
+		if err := config.attach(main, file, alias); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	err = idleConns(ctx, db, func(conn *sql.Conn) error {
+		if ok, err := isAttached(ctx, conn, alias); ok || err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS ?", file, alias)
+		return err
+	})
+	if err != nil {
+		config.detach(main, alias)
+		return fmt.Errorf("attach %s: %w", alias, err)
+	}
+	return nil
+}
+
+// attach adds an attachment to the connections to the main database file
+func (c *Config) attach(main, file, alias string) error {
+	c.attachMu.Lock()
+	defer c.attachMu.Unlock()
+	for _, a := range c.attached[main] {
+		if strings.EqualFold(a.alias, alias) {
+			return fmt.Errorf("attach %s: already attached", alias)
+		}
+	}
+	if c.attached == nil {
+		c.attached = make(map[string][]attachment)
+	}
+	c.attached[main] = append(c.attached[main], attachment{file: file, alias: alias})
+	return nil
+}
+
+// detach removes an attachment from the connections to the main database file,
+// reporting whether there was one
+func (c *Config) detach(main, alias string) bool {
+	c.attachMu.Lock()
+	defer c.attachMu.Unlock()
+	list := c.attached[main]
+	for i, a := range list {
+		if strings.EqualFold(a.alias, alias) {
+			c.attached[main] = append(list[:i:i], list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Detach detaches the database attached as alias from the connections of the pool
+func Detach(db *sql.DB, alias string) error {
+	config := configFor(db)
+	if config == nil {
+		return fmt.Errorf("detach %s: database was not opened by this package", alias)
+	}
+	main, err := FilenameE(db)
+	if err != nil {
+		return err
+	}
+	if !config.detach(main, alias) && main != "" {
+		return fmt.Errorf("detach %s: not attached", alias)
+	}
+	ctx := context.Background()
+	return idleConns(ctx, db, func(conn *sql.Conn) error {
+		if ok, err := isAttached(ctx, conn, alias); !ok || err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, "DETACH DATABASE ?", alias)
+		return err
+	})
+}
+
+// CopyTable copies the rows of table in the database attached as srcAlias to the
+// one attached as dstAlias, either of which may be "main", and returns how many
+// were copied. If the table doesn't exist in the destination it's created as it
+// is in the source, with its indexes. The copy is made in one transaction
+func CopyTable(db *sql.DB, srcAlias, dstAlias, table string) (int64, error) {
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	src, dst := quoteIdent(srcAlias), quoteIdent(dstAlias)
+	var exists int
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s.sqlite_master WHERE type = 'table' AND name = ?", dst), table).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists == 0 {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT sql FROM %s.sqlite_master WHERE tbl_name = ? AND sql IS NOT NULL ORDER BY type = 'index'", src), table)
+		if err != nil {
+			return 0, err
+		}
+		var creates []string
+		for rows.Next() {
+			var stmt string
+			if err := rows.Scan(&stmt); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			if strings.HasPrefix(strings.ToUpper(stmt), "CREATE TRIGGER") {
+				continue
+			}
+			if stmt, err = qualifyCreate(stmt, dstAlias); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			creates = append(creates, stmt)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		if len(creates) == 0 {
+			return 0, fmt.Errorf("copy table: no table %s in %s", table, srcAlias)
+		}
+		for _, stmt := range creates {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return 0, fmt.Errorf("copy table %s: %w", table, err)
+			}
+		}
+	}
+
+	name := quoteIdent(table)
+	res, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s.%s SELECT * FROM %s.%s", dst, name, src, name))
+	if err != nil {
+		return 0, fmt.Errorf("copy table %s: %w", table, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// qualifyCreate puts the object created by a CREATE TABLE or CREATE INDEX statement in schema
+func qualifyCreate(stmt, schema string) (string, error) {
+	for start, end := sqlToken(stmt, 0); start < len(stmt); start, end = sqlToken(stmt, end) {
+		word := strings.ToUpper(stmt[start:end])
+		if word != "TABLE" && word != "INDEX" {
+			continue
+		}
+		start, end = sqlToken(stmt, end)
+		if strings.EqualFold(stmt[start:end], "IF") {
+			_, end = sqlToken(stmt, end) // NOT
+			_, end = sqlToken(stmt, end) // EXISTS
+			start, _ = sqlToken(stmt, end)
+		}
+		return stmt[:start] + quoteIdent(schema) + "." + stmt[start:], nil
+	}
+	return "", fmt.Errorf("cannot qualify: %s", stmt)
+}
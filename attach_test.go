@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestAttach(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "main.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	const setup = `
+create table item (id integer primary key, name text not null);
+create index item_name on item(name);
+insert into item (name) values ('a'), ('b'), ('c');
+`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	if err := Attach(db, filepath.Join(dir, "archive.db"), "archive"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Attach(db, filepath.Join(dir, "other.db"), "archive"); err == nil {
+		t.Fatal("expected error attaching an alias twice")
+	}
+
+	n, err := CopyTable(db, "main", "archive", "item")
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 rows copied, got %d (%v)", n, err)
+	}
+	if n, err = CopyTable(db, "main", "archive", "item"); err == nil {
+		t.Fatalf("expected the copied keys to conflict, got %d rows", n)
+	}
+
+	// every connection of the pool sees the attached database, including new ones
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+		var count int
+		if err := conn.QueryRowContext(ctx, "select count(*) from archive.item").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 {
+			t.Fatalf("connection %d: expected 3 rows, got %d", i, count)
+		}
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+	var index string
+	if err := db.QueryRow("select name from archive.sqlite_master where type = 'index'").Scan(&index); err != nil || index != "item_name" {
+		t.Fatalf("expected the index to be copied, got %q (%v)", index, err)
+	}
+
+	if err := Detach(db, "archive"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := db.Exec("select count(*) from archive.item"); err == nil {
+			t.Fatal("expected error reading a detached database")
+		}
+	}
+	if err := Detach(db, "archive"); err == nil {
+		t.Fatal("expected error detaching twice")
+	}
+}
+
+func TestQualifyCreate(t *testing.T) {
+	stmt, err := qualifyCreate(`CREATE TABLE IF NOT EXISTS "t" (id int)`, "aux")
+	if err != nil || stmt != `CREATE TABLE IF NOT EXISTS "aux"."t" (id int)` {
+		t.Fatalf("unexpected statement: %s (%v)", stmt, err)
+	}
+	stmt, err = qualifyCreate("CREATE UNIQUE INDEX i ON t(id)", "aux")
+	if err != nil || stmt != `CREATE UNIQUE INDEX "aux".i ON t(id)` {
+		t.Fatalf("unexpected statement: %s (%v)", stmt, err)
+	}
+}
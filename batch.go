@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"runtime"
+)
+
+// BatchedExec repeatedly executes a statement with batchSize as its last argument, until it affects no more rows
+//
+// Unless SQLite is built from source with SQLITE_ENABLE_UPDATE_DELETE_LIMIT, a batch is limited using
+// a subquery, e.g. "DELETE FROM log WHERE rowid IN (SELECT rowid FROM log WHERE ts < ? LIMIT ?)"
+//
+// Each batch runs in its own transaction, so the write lock is released between batches,
+// allowing other writers to proceed. If progress is not nil it's called after every batch
+// with the rows affected by the batch and in total, and returning an error stops execution.
+// It returns the total number of rows affected
+func BatchedExec(db *sql.DB, stmt string, batchSize int, progress func(affected, total int64) error, args ...interface{}) (int64, error) {
+	if batchSize < 1 {
+		return 0, fmt.Errorf("invalid batch size: %d", batchSize)
+	}
+	args = append(Args(args...), batchSize)
+	var total int64
+	for {
+		res, err := db.Exec(stmt, args...)
+		if err != nil {
+			return total, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += affected
+		if progress != nil {
+			if err := progress(affected, total); err != nil {
+				return total, err
+			}
+		}
+		if affected == 0 {
+			return total, nil
+		}
+		runtime.Gosched()
+	}
+}
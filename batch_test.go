@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBatchedExec(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	const setup = `
+	create table events (id integer primary key, kind text);
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 1050)
+	insert into events (kind) select case when n % 2 then 'odd' else 'even' end from seq;
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	var batches int
+	progress := func(affected, total int64) error {
+		batches++
+		return nil
+	}
+	total, err := BatchedExec(db, "delete from events where id in (select id from events where kind = ? limit ?)", 100, progress, "odd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 525 || batches != 7 {
+		t.Fatalf("expected 525 rows in 7 batches but got %d rows in %d batches", total, batches)
+	}
+
+	stop := errors.New("stop")
+	total, err = BatchedExec(db, "delete from events where id in (select id from events limit ?)", 100, func(affected, total int64) error {
+		return stop
+	})
+	if err != stop || total != 100 {
+		t.Fatalf("expected to stop after one batch but got %d rows, error: %v", total, err)
+	}
+}
@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// bulkConfig is how BulkInsert loads its rows
+type bulkConfig struct {
+	batch       int
+	unsafe      bool
+	journalMode string
+	conflict    string
+	report      Progress
+}
+
+// BulkOption configures BulkInsert
+type BulkOption func(*bulkConfig)
+
+// BulkBatchSize sets the rows inserted per transaction, ImportBatchSize if not set
+func BulkBatchSize(n int) BulkOption {
+	return func(c *bulkConfig) {
+		c.batch = n
+	}
+}
+
+// BulkSynchronousOff turns off syncing to disk while the rows are loaded, which is
+// much faster, but a crash of the machine during the load can corrupt the database
+func BulkSynchronousOff() BulkOption {
+	return func(c *bulkConfig) {
+		c.unsafe = true
+	}
+}
+
+// BulkJournalMode sets the journal mode while the rows are loaded, e.g. "MEMORY" or
+// "OFF", restoring the previous mode afterwards. A database can only leave WAL mode
+// when no other connection has it open
+func BulkJournalMode(mode string) BulkOption {
+	return func(c *bulkConfig) {
+		c.journalMode = mode
+	}
+}
+
+// BulkOnConflict sets how rows that break a constraint are handled, e.g. "IGNORE"
+// or "REPLACE", rather than failing the load
+func BulkOnConflict(resolution string) BulkOption {
+	return func(c *bulkConfig) {
+		c.conflict = resolution
+	}
+}
+
+// BulkReporter reports on the load, in rows, after each batch
+func BulkReporter(p Progress) BulkOption {
+	return func(c *bulkConfig) {
+		c.report = p
+	}
+}
+
+// BulkInsert inserts the rows received from rows, each holding a value for every
+// column, until the channel is closed, and returns the number of rows inserted.
+// The rows are inserted in batches, each in a transaction, on one connection with
+// one prepared statement. If it fails the rows of the current batch are rolled back,
+// the count is of the rows committed before, and no more are received, so the
+// sender should stop on the error as well
+func BulkInsert(db *sql.DB, table string, columns []string, rows <-chan []interface{}, opts ...BulkOption) (int64, error) {
+	return BulkInsertContext(context.Background(), db, table, columns, rows, opts...)
+}
+
+// BulkInsertContext is BulkInsert, stopping if ctx is done
+func BulkInsertContext(ctx context.Context, db *sql.DB, table string, columns []string, rows <-chan []interface{}, opts ...BulkOption) (n int64, err error) {
+	config := bulkConfig{batch: ImportBatchSize}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	report := reporting(config.report)
+	report.OnStart("bulk insert")
+	defer func() { report.OnFinish(err) }()
+
+	n, err = bulkInsert(ctx, db, table, columns, rows, config, report)
+	if err != nil {
+		err = fmt.Errorf("bulk insert %s: %w", table, err)
+	}
+	return n, err
+}
+
+func bulkInsert(ctx context.Context, db *sql.DB, table string, columns []string, rows <-chan []interface{}, config bulkConfig, report Progress) (int64, error) {
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("no columns")
+	}
+	config.batch = batchSize(config.batch)
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, ContextError(ctx, err)
+	}
+	defer conn.Close()
+
+	// the pragmas are restored when done, whether or not the load succeeds
+	var pragmas []string
+	if config.unsafe {
+		pragmas = append(pragmas, "synchronous = OFF")
+	}
+	if config.journalMode != "" {
+		pragmas = append(pragmas, "journal_mode = "+config.journalMode)
+	}
+	for _, pragma := range pragmas {
+		name := strings.TrimSpace(strings.SplitN(pragma, "=", 2)[0])
+		var previous string
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+name).Scan(&previous); err != nil {
+			return 0, err
+		}
+		if _, err := conn.ExecContext(ctx, "PRAGMA "+pragma); err != nil {
+			return 0, err
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), fmt.Sprintf("PRAGMA %s = %s", name, previous)); err != nil {
+				logf("bulk insert: can't restore %s to %s -- %v\n", name, previous, err)
+			}
+		}()
+	}
+
+	names := make([]string, len(columns))
+	marks := make([]string, len(columns))
+	for i, name := range columns {
+		names[i] = quoteIdent(name)
+		marks[i] = "?"
+	}
+	verb := "INSERT"
+	if config.conflict != "" {
+		verb += " OR " + strings.ToUpper(config.conflict)
+	}
+	insert := fmt.Sprintf("%s INTO %s (%s) VALUES(%s)", verb, quoteIdent(table), strings.Join(names, ", "), strings.Join(marks, ", "))
+	prepared, err := conn.PrepareContext(ctx, insert)
+	if err != nil {
+		return 0, err
+	}
+	defer prepared.Close()
+
+	// count is the rows inserted, and committed those in committed batches
+	var count, committed int64
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	for {
+		var row []interface{}
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return committed, ctx.Err()
+		case row, ok = <-rows:
+		}
+		if !ok {
+			break
+		}
+		if len(row) != len(columns) {
+			return committed, fmt.Errorf("row %d: %d values for %d columns", count+1, len(row), len(columns))
+		}
+		if tx == nil {
+			if tx, err = conn.BeginTx(ctx, nil); err != nil {
+				return committed, ContextError(ctx, err)
+			}
+			stmt = tx.StmtContext(ctx, prepared)
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return committed, fmt.Errorf("row %d: %w", count+1, err)
+		}
+		count++
+		if count%int64(config.batch) == 0 {
+			err, tx = tx.Commit(), nil
+			if err != nil {
+				return committed, err
+			}
+			committed = count
+			report.OnProgress(count, -1)
+		}
+	}
+	if tx != nil {
+		err, tx = tx.Commit(), nil
+		if err != nil {
+			return committed, err
+		}
+	}
+	report.OnProgress(count, count)
+	return count, nil
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBulkInsert(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "bulk.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("pragma journal_mode=wal; create table item (id integer primary key, name text not null)"); err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(n int, from int) <-chan []interface{} {
+		rows := make(chan []interface{})
+		go func() {
+			defer close(rows)
+			for i := from; i < from+n; i++ {
+				rows <- []interface{}{i, "item"}
+			}
+		}()
+		return rows
+	}
+	var batches int
+	report := ProgressFunc(func(done, total int64) { batches++ })
+	n, err := BulkInsert(db, "item", []string{"id", "name"}, send(2500, 1),
+		BulkBatchSize(1000), BulkSynchronousOff(), BulkJournalMode("memory"), BulkReporter(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2500 || batches != 3 {
+		t.Fatalf("expected 2500 rows in 3 reports, got %d in %d", n, batches)
+	}
+	var mode string
+	var count int
+	if err := db.QueryRow("pragma journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow("select count(*) from item").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" || count != 2500 {
+		t.Fatalf("expected the journal mode restored and 2500 rows, got %s and %d", mode, count)
+	}
+
+	// the rows repeated from 2001 are ignored
+	n, err = BulkInsert(db, "item", []string{"id", "name"}, send(1000, 2001), BulkOnConflict("ignore"))
+	if err != nil || n != 1000 {
+		t.Fatalf("expected 1000 rows, got %d (%v)", n, err)
+	}
+	if err := db.QueryRow("select count(*) from item").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3000 {
+		t.Fatalf("expected 3000 rows, got %d", count)
+	}
+
+	// a failed row rolls back its batch
+	rows := make(chan []interface{}, 5)
+	rows <- []interface{}{5001, "a"}
+	rows <- []interface{}{5002, "b"}
+	rows <- []interface{}{5003, nil}
+	close(rows)
+	n, err = BulkInsertContext(context.Background(), db, "item", []string{"id", "name"}, rows, BulkBatchSize(2))
+	if err == nil || !strings.Contains(err.Error(), "row 3") || n != 2 {
+		t.Fatalf("expected an error for row 3 after 2 rows, got %d (%v)", n, err)
+	}
+	if err := db.QueryRow("select count(*) from item").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3002 {
+		t.Fatalf("expected 3002 rows, got %d", count)
+	}
+
+	rows = make(chan []interface{}, 1)
+	rows <- []interface{}{1}
+	close(rows)
+	if _, err := BulkInsert(db, "item", []string{"id", "name"}, rows); err == nil {
+		t.Fatal("expected error for a short row")
+	}
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+/*
+extern int sqlite3_busy_handler(void *db, int (*handler)(void *, int), void *arg);
+extern int goBusyHandler(void *arg, int count);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// BusyHandler decides whether to retry when a connection finds the database locked.
+// It is called with the attempt, counting from 1, and the time since the first
+// attempt. Returning true retries at once, so a handler should wait before it does;
+// returning false gives up and the statement fails with sqlite3.ErrBusy
+type BusyHandler func(attempt int, elapsed time.Duration) bool
+
+// busyState is the handler of a connection, and when it began waiting
+type busyState struct {
+	handler BusyHandler
+	start   time.Time
+}
+
+// busyHandlers holds the state of each connection, by its sqlite3 handle.
+// The handles of closed connections are reused by new ones, which replace the state
+var busyHandlers sync.Map
+
+//export goBusyHandler
+func goBusyHandler(arg unsafe.Pointer, count C.int) C.int {
+	v, ok := busyHandlers.Load(uintptr(arg))
+	if !ok {
+		return 0
+	}
+	state := v.(*busyState)
+	if count == 0 {
+		state.start = time.Now()
+	}
+	if state.handler(int(count)+1, time.Since(state.start)) {
+		return 1
+	}
+	return 0
+}
+
+// setBusyHandler installs the handler on the connection, replacing its busy timeout
+func setBusyHandler(conn *sqlite3.SQLiteConn, handler BusyHandler) error {
+	db, err := connHandle(conn)
+	if err != nil {
+		return fmt.Errorf("busy handler: %w", err)
+	}
+	busyHandlers.Store(uintptr(db), &busyState{handler: handler})
+	if rc := C.sqlite3_busy_handler(db, (*[0]byte)(C.goBusyHandler), db); rc != 0 {
+		return fmt.Errorf("busy handler: %w", sqlite3.ErrNo(rc))
+	}
+	return nil
+}
+
+// connHandle returns the sqlite3 handle of the connection, which the driver keeps
+// in an unexported field. TestDriverFields fails if a new version of the driver
+// moves it
+func connHandle(conn *sqlite3.SQLiteConn) (unsafe.Pointer, error) {
+	field := reflect.ValueOf(conn).Elem().FieldByName("db")
+	if !field.IsValid() || field.Kind() != reflect.Ptr {
+		return nil, errors.New("this version of the driver doesn't expose its handle")
+	}
+	if field.IsNil() {
+		return nil, errors.New("connection is closed")
+	}
+	return unsafe.Pointer(field.Pointer()), nil
+}
+
+// WithBusyHandler calls handler when a connection finds the database locked, in
+// place of the busy timeout, for policies such as logging contention, backing off,
+// or giving up sooner on some operations. The busy_timeout pragma, if set with
+// WithPragmas or WithQuery, replaces the handler
+func WithBusyHandler(handler BusyHandler) Optional {
+	return func(c *Config) {
+		c.busy = handler
+	}
+}
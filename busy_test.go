@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+func TestWithBusyHandler(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "busy.db")
+	holder, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	if _, err := holder.Exec("create table counter (n int)"); err != nil {
+		t.Fatal(err)
+	}
+
+	var attempts, waiting int32
+	db, err := Open(file, WithDriver("busy_handler"), WithBusyHandler(func(attempt int, elapsed time.Duration) bool {
+		atomic.StoreInt32(&attempts, int32(attempt))
+		if atomic.LoadInt32(&waiting) == 0 && attempt >= 3 {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+		return true
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	conn, err := holder.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the handler gives up on its third attempt
+	_, err = db.Exec("insert into counter values (1)")
+	var serr sqlite3.Error
+	if !errors.As(err, &serr) || serr.Code != sqlite3.ErrBusy {
+		t.Fatalf("expected a busy error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Fatalf("expected 3 attempts, got %d", n)
+	}
+
+	// and waits while the lock is released
+	atomic.StoreInt32(&waiting, 1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		conn.ExecContext(ctx, "COMMIT")
+	}()
+	if _, err := db.Exec("insert into counter values (2)"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&attempts); n < 3 {
+		t.Fatalf("expected the handler to wait for the lock, got %d attempts", n)
+	}
+}
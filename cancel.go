@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// interrupted is an error caused by the context being done
+type interrupted struct {
+	ctxErr error
+	err    error
+}
+
+func (e *interrupted) Error() string {
+	return e.ctxErr.Error() + ": " + e.err.Error()
+}
+
+// Is matches the context error, so errors.Is(err, context.Canceled) works as expected
+func (e *interrupted) Is(target error) bool {
+	return target == e.ctxErr
+}
+
+func (e *interrupted) Unwrap() error {
+	return e.err
+}
+
+// ContextError returns err so that errors.Is reports context.Canceled or context.DeadlineExceeded
+// when ctx is done and err is the resulting interrupt, however the driver reported it
+func ContextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if errors.Is(err, ctx.Err()) {
+		return err
+	}
+	var serr sqlite3.Error
+	if errors.As(err, &serr) && serr.Code == sqlite3.ErrInterrupt {
+		return &interrupted{ctxErr: ctx.Err(), err: err}
+	}
+	if err.Error() == ctx.Err().Error() {
+		// the driver sometimes returns a copy of the context error
+		return &interrupted{ctxErr: ctx.Err(), err: err}
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+const endless = "with recursive c(x) as (select 1 union all select x+1 from c) select count(*) from c"
+
+func TestContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := sqlite3.Error{Code: sqlite3.ErrInterrupt}
+	if err := ContextError(ctx, interrupt); errors.Is(err, context.Canceled) {
+		t.Fatal("interrupt mapped before the context was done")
+	}
+	cancel()
+	for _, err := range []error{
+		interrupt,
+		fmt.Errorf("wrapped: %w", interrupt),
+		errors.New(context.Canceled.Error()),
+	} {
+		mapped := ContextError(ctx, err)
+		if !errors.Is(mapped, context.Canceled) || !errors.Is(mapped, err) {
+			t.Errorf("%v not mapped: %v", err, mapped)
+		}
+	}
+	other := errors.New("no such table")
+	if err := ContextError(ctx, other); err != other {
+		t.Fatalf("expected: %v but got: %v", other, err)
+	}
+}
+
+func TestCancelPropagation(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ExecScriptContext(ctx, db, "create table t (n integer);\n"+endless); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded but got: %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	rows, errc := QueryChan[int64](ctx, db, endless)
+	for range rows {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled but got: %v", err)
+	}
+
+	m := NewTxMonitor(0, nil)
+	if _, err := m.Begin(ctx, db, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled but got: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNoChangeCapture is returned by ChangeStream for a database opened without WithChangeCapture
+var ErrNoChangeCapture = errors.New("database was not opened with change capture")
+
+// ChangeEvent is a row changed by a transaction as it commits
+type ChangeEvent struct {
+	TxID     uint64 // the transaction, in order of commit; events of a transaction are sent together
+	Op       int    // OpInsert, OpUpdate, or OpDelete
+	Database string
+	Table    string
+	RowID    int64
+}
+
+// changeKey identifies a row
+type changeKey struct {
+	database, table string
+	rowid           int64
+}
+
+// changeBuffer holds the changes of a connection's transaction until it ends,
+// keeping one change for each row
+type changeBuffer struct {
+	changes []ChangeEvent
+	index   map[changeKey]int
+}
+
+func (b *changeBuffer) add(e UpdateEvent) {
+	if b.index == nil {
+		b.index = make(map[changeKey]int)
+	}
+	key := changeKey{e.Database, e.Table, e.RowID}
+	i, ok := b.index[key]
+	if !ok {
+		b.index[key] = len(b.changes)
+		b.changes = append(b.changes, ChangeEvent{Op: e.Op, Database: e.Database, Table: e.Table, RowID: e.RowID})
+		return
+	}
+	prior := &b.changes[i]
+	switch {
+	case prior.Op == OpInsert && e.Op == OpDelete:
+		// the row never existed outside the transaction
+		prior.Op = 0
+	case prior.Op == 0 && e.Op == OpInsert:
+		prior.Op = OpInsert
+	case prior.Op == OpDelete && e.Op == OpInsert:
+		prior.Op = OpUpdate
+	case prior.Op == OpInsert:
+		// an inserted row that was then updated is still new
+	default:
+		prior.Op = e.Op
+	}
+}
+
+// flush returns the changes of the transaction and empties the buffer
+func (b *changeBuffer) flush() []ChangeEvent {
+	var changes []ChangeEvent
+	for _, c := range b.changes {
+		if c.Op != 0 {
+			changes = append(changes, c)
+		}
+	}
+	b.discard()
+	return changes
+}
+
+func (b *changeBuffer) discard() {
+	b.changes, b.index = nil, nil
+}
+
+// changeCapture sends committed changes to the subscribers of a database
+type changeCapture struct {
+	mu   sync.Mutex
+	txID uint64
+	subs map[*changeSub]struct{}
+}
+
+// changeSub queues events for a subscriber, so a slow reader never holds up a writer
+type changeSub struct {
+	mu     sync.Mutex
+	queue  []ChangeEvent
+	wake   chan struct{}
+	done   chan struct{}
+	events chan ChangeEvent
+}
+
+func (c *changeCapture) publish(changes []ChangeEvent) {
+	if len(changes) == 0 {
+		return
+	}
+	txID := atomic.AddUint64(&c.txID, 1)
+	for i := range changes {
+		changes[i].TxID = txID
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for sub := range c.subs {
+		sub.mu.Lock()
+		sub.queue = append(sub.queue, changes...)
+		sub.mu.Unlock()
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run delivers the queued events until the subscriber stops
+func (sub *changeSub) run() {
+	defer close(sub.events)
+	for {
+		sub.mu.Lock()
+		queue := sub.queue
+		sub.queue = nil
+		sub.mu.Unlock()
+		for _, e := range queue {
+			select {
+			case sub.events <- e:
+			case <-sub.done:
+				return
+			}
+		}
+		select {
+		case <-sub.wake:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// WithChangeCapture records the rows changed by each transaction, for ChangeStream
+func WithChangeCapture() Optional {
+	return func(c *Config) {
+		c.hooks.capture = &changeCapture{subs: make(map[*changeSub]struct{})}
+	}
+}
+
+// ChangeStream returns the rows changed by transactions committed after it is called,
+// on any connection of db, which must be opened with WithChangeCapture. Changes are
+// coalesced within a transaction, e.g. a row inserted and then updated is one insert,
+// and one inserted and then deleted is dropped; those of a rolled back transaction are discarded.
+// Events are queued without limit, so writers never wait for the reader.
+// The stop function ends the stream and closes the channel
+//
+// The events of a transaction are sent from SQLite's commit hook, which runs just
+// before the commit is made durable, as the driver has no hook for after it.
+// If the commit then fails, such as with an I/O error, or with SQLITE_BUSY, which
+// leaves the transaction open to be rolled back, subscribers will have seen changes
+// that were never committed. Where that matters, an event should be taken as a hint
+// to read the row, rather than as proof of the change
+func ChangeStream(db *sql.DB) (<-chan ChangeEvent, func() error) {
+	config := configFor(db)
+	if config == nil || config.hooks.capture == nil {
+		events := make(chan ChangeEvent)
+		close(events)
+		return events, func() error { return ErrNoChangeCapture }
+	}
+	capture := config.hooks.capture
+	sub := &changeSub{
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		events: make(chan ChangeEvent),
+	}
+	capture.mu.Lock()
+	capture.subs[sub] = struct{}{}
+	capture.mu.Unlock()
+	go sub.run()
+
+	var once sync.Once
+	return sub.events, func() error {
+		once.Do(func() {
+			capture.mu.Lock()
+			delete(capture.subs, sub)
+			capture.mu.Unlock()
+			close(sub.done)
+		})
+		return nil
+	}
+}
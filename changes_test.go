@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChangeStream(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "changes.db"), WithDriver("changes"), WithChangeCapture())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("create table t (id integer primary key, n int)"); err != nil {
+		t.Fatal(err)
+	}
+	events, stop := ChangeStream(db)
+
+	const script = `
+	begin;
+	insert into t values (1, 1), (2, 2), (3, 3);
+	update t set n = 20 where id = 2;
+	delete from t where id = 3;
+	commit;
+	`
+	if _, err := db.Exec(script); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("delete from t where id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("update t set n = 10 where id = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ChangeEvent{
+		{TxID: 1, Op: OpInsert, Database: "main", Table: "t", RowID: 1},
+		{TxID: 1, Op: OpInsert, Database: "main", Table: "t", RowID: 2},
+		{TxID: 2, Op: OpUpdate, Database: "main", Table: "t", RowID: 1},
+	}
+	for i, w := range want {
+		select {
+		case e := <-events:
+			if e != w {
+				t.Fatalf("event %d: got %+v, want %+v", i, e, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("expected stream to be closed")
+	}
+}
+
+func TestChangeStreamNotCaptured(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	events, stop := ChangeStream(db)
+	if _, ok := <-events; ok {
+		t.Fatal("expected closed stream")
+	}
+	if err := stop(); !errors.Is(err, ErrNoChangeCapture) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TableSum is the row count and checksum of a table's contents
+type TableSum struct {
+	Table string
+	Rows  int64
+	Sum   string // hex SHA-256 of the rows in primary key (or rowid) order
+}
+
+// checksumLine matches the comment written by WriteChecksums
+var checksumLine = regexp.MustCompile(`^-- checksum: ("(?:[^"]|"")*"|\S+) rows=(\d+) sha256=([0-9a-f]{64})$`)
+
+// String returns the comment line recording the checksum in a script
+func (s TableSum) String() string {
+	return fmt.Sprintf("-- checksum: %s rows=%d sha256=%s", quoteIdent(s.Table), s.Rows, s.Sum)
+}
+
+// hashSQLValue writes a value to the hash with its type, so 1, 1.0, and '1' differ
+func hashSQLValue(w io.Writer, v interface{}) {
+	var b [8]byte
+	switch x := v.(type) {
+	case nil:
+		w.Write([]byte{'n'})
+	case int64:
+		binary.BigEndian.PutUint64(b[:], uint64(x))
+		w.Write([]byte{'i'})
+		w.Write(b[:])
+	case float64:
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(x))
+		w.Write([]byte{'f'})
+		w.Write(b[:])
+	case string:
+		binary.BigEndian.PutUint64(b[:], uint64(len(x)))
+		w.Write([]byte{'t'})
+		w.Write(b[:])
+		io.WriteString(w, x)
+	case []byte:
+		binary.BigEndian.PutUint64(b[:], uint64(len(x)))
+		w.Write([]byte{'b'})
+		w.Write(b[:])
+		w.Write(x)
+	default:
+		fmt.Fprintf(w, "?%v", x)
+	}
+}
+
+// TableChecksum returns the row count and checksum of a table
+func TableChecksum(db *sql.DB, table string) (TableSum, error) {
+	sum := TableSum{Table: table}
+	columns, err := exportColumns(db, table)
+	if err != nil {
+		return sum, err
+	}
+	if len(columns) == 0 {
+		return sum, fmt.Errorf("checksum: no such table: %s", table)
+	}
+	// an expression has no declared type, so the driver doesn't convert
+	// values such as a timestamp stored as text
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		exprs[i] = fmt.Sprintf("coalesce(%s, NULL)", quoteIdent(c.name))
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", strings.Join(exprs, ", "), quoteIdent(table), stableOrder(columns))
+	h := sha256.New()
+	err = query(db, func(_ []string, row []interface{}) {
+		sum.Rows++
+		for _, v := range row {
+			hashSQLValue(h, v)
+		}
+	}, q)
+	if err != nil {
+		return sum, fmt.Errorf("checksum %s: %w", table, err)
+	}
+	sum.Sum = hex.EncodeToString(h.Sum(nil))
+	return sum, nil
+}
+
+// WriteChecksums writes a checksum comment for each table, or for every table if none
+// are given, which the shell verifies after running a script that includes them
+func WriteChecksums(db *sql.DB, w io.Writer, tables ...string) error {
+	if len(tables) == 0 {
+		var err error
+		if tables, err = userTables(db); err != nil {
+			return err
+		}
+	}
+	for _, table := range tables {
+		sum, err := TableChecksum(db, table)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChecksumMismatch is returned when a table doesn't match its recorded checksum
+type ChecksumMismatch struct {
+	Expected TableSum
+	Actual   TableSum
+}
+
+func (e *ChecksumMismatch) Error() string {
+	return fmt.Sprintf("table %s has %d rows (sha256 %.12s), but %d rows (sha256 %.12s) were recorded",
+		e.Expected.Table, e.Actual.Rows, e.Actual.Sum, e.Expected.Rows, e.Expected.Sum)
+}
+
+// ScriptChecksums returns the checksums recorded in a script
+func ScriptChecksums(script string) ([]TableSum, error) {
+	var sums []TableSum
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(nil, len(script)+1)
+	for scanner.Scan() {
+		m := checksumLine.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		rows, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		table := m[1]
+		if unquoted, err := unquoteIdent(table); err == nil {
+			table = unquoted
+		}
+		sums = append(sums, TableSum{Table: table, Rows: rows, Sum: m[3]})
+	}
+	return sums, scanner.Err()
+}
+
+// unquoteIdent reverses quoteIdent
+func unquoteIdent(name string) (string, error) {
+	if len(name) < 2 || name[0] != '"' || name[len(name)-1] != '"' {
+		return "", fmt.Errorf("not a quoted identifier: %s", name)
+	}
+	return strings.ReplaceAll(name[1:len(name)-1], `""`, `"`), nil
+}
+
+// VerifyChecksums compares the tables of db with the checksums recorded in a script,
+// returning a *ChecksumMismatch for the first that differs
+func VerifyChecksums(db *sql.DB, script string) error {
+	sums, err := ScriptChecksums(script)
+	if err != nil {
+		return err
+	}
+	for _, expected := range sums {
+		actual, err := TableChecksum(db, expected.Table)
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return &ChecksumMismatch{Expected: expected, Actual: actual}
+		}
+	}
+	return nil
+}
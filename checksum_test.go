@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const setup = `
+create table "odd name" (id integer primary key, v);
+insert into "odd name" (v) values (1), (1.0), ('1'), (x'01'), (null);
+create table stamps (created timestamp);
+insert into stamps values ('2020-01-02 03:04:05');
+`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteChecksums(db, &buf); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := ScriptChecksums(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 2 || sums[0].Table != "odd name" || sums[0].Rows != 5 || sums[1].Rows != 1 {
+		t.Fatalf("unexpected checksums: %+v", sums)
+	}
+
+	// reload the script into a new database
+	script := setup + buf.String()
+	db2 := memDB(t)
+	defer db2.Close()
+	db2.SetMaxOpenConns(1)
+	if err := Commands(db2, script, false, testout); err != nil {
+		t.Fatal(err)
+	}
+
+	// a truncated script fails verification
+	truncated := strings.Replace(script, "(x'01'), ", "", 1)
+	db3 := memDB(t)
+	defer db3.Close()
+	db3.SetMaxOpenConns(1)
+	var mismatch *ChecksumMismatch
+	if err := Commands(db3, truncated, false, testout); !errors.As(err, &mismatch) {
+		t.Fatalf("expected checksum mismatch but got: %v", err)
+	}
+	if mismatch.Expected.Rows != 5 || mismatch.Actual.Rows != 4 {
+		t.Fatalf("unexpected mismatch: %v", mismatch)
+	}
+}
@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// DefaultChunkRows is the number of statements committed together when no limit is given
+const DefaultChunkRows = 1000
+
+// ChunkOptions bound the transactions a large script, such as a dump, is applied in
+type ChunkOptions struct {
+	Rows   int               // statements per commit, DefaultChunkRows if neither limit is set
+	Bytes  int               // statement text per commit
+	Resume int               // index of the first statement to apply, as returned by an earlier failed run
+	Commit func(applied int) // called after each commit with the number of statements applied so far
+}
+
+// ExecChunked applies a script in a series of size-bounded transactions instead of one,
+// which bounds the memory and WAL growth of restoring a large dump. The script's own
+// BEGIN, COMMIT, END and ROLLBACK statements are skipped.
+//
+// It returns the number of statements applied and committed (including any skipped by Resume).
+// On failure the uncommitted chunk is rolled back and the count can be passed as
+// ChunkOptions.Resume to carry on from there once the problem is fixed
+func ExecChunked(db *sql.DB, script string, opts ChunkOptions) (int, error) {
+	return ExecChunkedContext(context.Background(), db, script, opts)
+}
+
+// ExecChunkedContext is ExecChunked with a context
+func ExecChunkedContext(ctx context.Context, db *sql.DB, script string, opts ChunkOptions) (int, error) {
+	defer observe(MetricScript, time.Now())
+	stmts, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+	if opts.Resume > len(stmts) {
+		return 0, fmt.Errorf("resume at %d is beyond the %d statements of the script", opts.Resume, len(stmts))
+	}
+	if opts.Rows <= 0 && opts.Bytes <= 0 {
+		opts.Rows = DefaultChunkRows
+	}
+	applied := opts.Resume
+	err = rawConn(ctx, db, func(conn *sqlite3.SQLiteConn) error {
+		if !conn.AutoCommit() {
+			return fmt.Errorf("connection has a transaction open")
+		}
+		var rows, size int
+		commit := func(next int) error {
+			if conn.AutoCommit() {
+				return nil
+			}
+			if _, err := conn.Exec("COMMIT", nil); err != nil {
+				return err
+			}
+			applied, rows, size = next, 0, 0
+			if opts.Commit != nil {
+				opts.Commit(applied)
+			}
+			return nil
+		}
+		err := func() error {
+			for i := opts.Resume; i < len(stmts); i++ {
+				stmt := stmts[i]
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if stmt.Command {
+					return &ScriptError{Line: stmt.Line, Index: i, Statement: stmt.Text, Err: fmt.Errorf("dot commands are not supported")}
+				}
+				if transactionControl(stmt.Text) {
+					continue
+				}
+				if conn.AutoCommit() {
+					if _, err := conn.Exec("BEGIN", nil); err != nil {
+						return err
+					}
+				}
+				if _, err := conn.ExecContext(ctx, stmt.Text, nil); err != nil {
+					return &ScriptError{Line: stmt.Line, Index: i, Statement: stmt.Text, Err: err}
+				}
+				rows++
+				size += len(stmt.Text)
+				if (opts.Rows > 0 && rows >= opts.Rows) || (opts.Bytes > 0 && size >= opts.Bytes) {
+					if err := commit(i + 1); err != nil {
+						return err
+					}
+				}
+			}
+			if err := commit(len(stmts)); err != nil {
+				return err
+			}
+			applied = len(stmts)
+			return nil
+		}()
+		if err != nil && !conn.AutoCommit() {
+			if _, rerr := conn.Exec("ROLLBACK", nil); rerr != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+			}
+		}
+		return err
+	})
+	return applied, err
+}
+
+// transactionControl reports whether the statement begins or ends a transaction
+func transactionControl(stmt string) bool {
+	fields := strings.Fields(strings.ToUpper(stmt))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "BEGIN", "COMMIT", "END":
+		return true
+	case "ROLLBACK":
+		// ROLLBACK TO a savepoint doesn't end the transaction
+		return len(fields) == 1 || fields[1] == "TRANSACTION"
+	}
+	return false
+}
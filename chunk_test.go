@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecChunked(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chunked.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var sb strings.Builder
+	sb.WriteString("BEGIN TRANSACTION;\ncreate table t (n integer primary key);\n")
+	for i := 1; i <= 25; i++ {
+		fmt.Fprintf(&sb, "insert into t values(%d);\n", i)
+	}
+	sb.WriteString("COMMIT;\n")
+	script := sb.String()
+
+	// statement 12 is the insert of 11, which fails on a duplicate
+	if _, err := db.Exec("create table t (n integer primary key); insert into t values(11)"); err != nil {
+		t.Fatal(err)
+	}
+	var commits []int
+	opts := ChunkOptions{Rows: 5, Commit: func(applied int) { commits = append(commits, applied) }}
+	applied, err := ExecChunked(db, script, opts)
+	var serr *ScriptError
+	if !errors.As(err, &serr) || serr.Index != 1 {
+		t.Fatalf("expected create table to fail but got: %v", err)
+	}
+	if applied != 0 || len(commits) != 0 {
+		t.Fatalf("unexpected progress: %d applied, commits %v", applied, commits)
+	}
+
+	opts.Resume = 2
+	applied, err = ExecChunked(db, script, opts)
+	if !errors.As(err, &serr) || serr.Index != 12 {
+		t.Fatalf("expected duplicate insert to fail but got: %v", err)
+	}
+	if applied != 12 || fmt.Sprint(commits) != "[7 12]" {
+		t.Fatalf("unexpected progress: %d applied, commits %v", applied, commits)
+	}
+	count := func() int {
+		var n int
+		if err := row(db, []interface{}{&n}, "select count(*) from t"); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(); n != 11 {
+		t.Fatalf("expected the failed chunk to be rolled back, got %d rows", n)
+	}
+
+	if _, err := db.Exec("delete from t where n = 11"); err != nil {
+		t.Fatal(err)
+	}
+	opts.Resume, opts.Rows, opts.Bytes = applied, 0, 100
+	applied, err = ExecChunked(db, script, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 28 {
+		t.Fatalf("expected: %d but got: %d", 28, applied)
+	}
+	if n := count(); n != 25 {
+		t.Fatalf("expected: %d but got: %d", 25, n)
+	}
+	if len(commits) < 4 {
+		t.Fatalf("expected several commits but got: %v", commits)
+	}
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CLIPath is the sqlite3 shell binary used by CompareCLI
+var CLIPath = "sqlite3"
+
+// ErrNoCLI is returned by CompareCLI when the sqlite3 shell is not available
+var ErrNoCLI = errors.New("sqlite3 shell not found")
+
+// CLIDiff describes the first line of output that differs between Commands and the sqlite3 shell
+type CLIDiff struct {
+	Line   int
+	Ours   string
+	Theirs string
+}
+
+func (d *CLIDiff) Error() string {
+	return fmt.Sprintf("output differs at line %d: ours: %q sqlite3: %q", d.Line, d.Ours, d.Theirs)
+}
+
+// CompareCLI runs the script through Commands and through the sqlite3 shell,
+// each against a fresh in-memory database, and compares their output
+//
+// The shell is run with headers on and a tab separator to match the output of Commands.
+// It returns ErrNoCLI if the shell can't be found, or a *CLIDiff if the outputs differ
+func CompareCLI(script string) error {
+	bin, err := exec.LookPath(CLIPath)
+	if err != nil {
+		return ErrNoCLI
+	}
+
+	db, err := Open(":memory:")
+	if err != nil {
+		return err
+	}
+	defer Close(db)
+
+	var ours bytes.Buffer
+	if err := Commands(db, script, false, &ours); err != nil {
+		return fmt.Errorf("commands failed: %w", err)
+	}
+
+	var theirs, stderr bytes.Buffer
+	cmd := exec.Command(bin, "-batch", "-bail", "-header", "-separator", "\t", ":memory:")
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Stdout = &theirs
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sqlite3 failed: %w -- %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return compareOutput(ours.String(), theirs.String())
+}
+
+// compareOutput returns a *CLIDiff for the first line that differs
+func compareOutput(ours, theirs string) error {
+	a := strings.Split(strings.TrimRight(ours, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(theirs, "\n"), "\n")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return &CLIDiff{Line: i + 1, Ours: x, Theirs: y}
+		}
+	}
+	return nil
+}
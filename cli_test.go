@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const cliScript = `
+create table cli (id integer primary key, name text);
+insert into cli (name) values('alpha');
+insert into cli (name) values('beta');
+select count(*) as total from cli;
+select id, name from cli order by id;
+`
+
+func TestCommandsOutput(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	if err := Commands(db, cliScript, false, &buf); err != nil {
+		t.Fatal(err)
+	}
+	const expected = "total\n2\nid\tname\n1\talpha\n2\tbeta\n"
+	if buf.String() != expected {
+		t.Fatalf("expected: %q but got: %q", expected, buf.String())
+	}
+}
+
+func TestCompareOutput(t *testing.T) {
+	if err := compareOutput("a\nb\n", "a\nb"); err != nil {
+		t.Fatal(err)
+	}
+	err := compareOutput("a\nb\n", "a\nc\nd\n")
+	var diff *CLIDiff
+	if !errors.As(err, &diff) {
+		t.Fatalf("expected diff but got: %v", err)
+	}
+	if diff.Line != 2 {
+		t.Fatalf("expected diff at line 2 but got: %d", diff.Line)
+	}
+}
+
+func TestCompareCLI(t *testing.T) {
+	err := CompareCLI(cliScript)
+	if errors.Is(err, ErrNoCLI) {
+		t.Skip("sqlite3 shell not installed")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
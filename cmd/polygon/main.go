@@ -13,7 +13,7 @@ func main() {
 		fmt.Printf("Usage: %s <db-file> <sql-file>\n", os.Args[0])
 		os.Exit(1)
 	}
-	polygon := sqlite.FuncReg{"polygon", sqlite.ToPolygon, true}
+	polygon := sqlite.FuncReg{Name: "polygon", Impl: sqlite.ToPolygon, Pure: true}
 	db, err := sqlite.Open(os.Args[1], sqlite.WithFunctions(polygon))
 	if err != nil {
 		log.Fatal(err)
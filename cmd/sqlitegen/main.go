@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/paulstuart/sqlite"
+)
+
+func main() {
+	pkg := flag.String("pkg", "", "package of the generated file (default: $GOPACKAGE)")
+	out := flag.String("o", "", "file to write (default: stdout)")
+	tables := flag.String("tables", "", "comma separated tables and views to generate code for (default: all)")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <db-file | schema.sql>\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	opts := sqlite.GenerateOptions{Package: *pkg}
+	if opts.Package == "" {
+		opts.Package = os.Getenv("GOPACKAGE")
+	}
+	if *tables != "" {
+		opts.Tables = strings.Split(*tables, ",")
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	source := flag.Arg(0)
+	var err error
+	if strings.HasSuffix(source, ".sql") {
+		var schema []byte
+		if schema, err = os.ReadFile(source); err != nil {
+			log.Fatal(err)
+		}
+		err = sqlite.GenerateFromSchema(string(schema), w, opts)
+	} else {
+		db, oerr := sqlite.Open(source, sqlite.WithExists(true))
+		if oerr != nil {
+			log.Fatal(oerr)
+		}
+		defer db.Close()
+		err = sqlite.Generate(db, w, opts)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
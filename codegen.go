@@ -0,0 +1,407 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// GenerateOptions control the code written by Generate
+type GenerateOptions struct {
+	Package string   // the package of the generated file
+	Tables  []string // the tables and views to generate code for, all of them if empty
+}
+
+// genTable is a table or view as seen by the code template
+type genTable struct {
+	Name     string // in the database
+	Type     string // Go struct name
+	View     bool
+	Columns  []genColumn
+	Keys     []genColumn // primary key columns, in key order
+	RowID    *genColumn  // an INTEGER PRIMARY KEY, which is an alias for the rowid
+	Settable []genColumn // columns that are not part of the primary key
+}
+
+type genColumn struct {
+	Name   string // in the database
+	Field  string // Go field name
+	Const  string // Go constant holding the column name
+	GoType string
+}
+
+// initialisms are written in upper case in Go names
+var initialisms = map[string]bool{
+	"id": true, "ip": true, "url": true, "uri": true, "uuid": true, "json": true,
+	"api": true, "http": true, "sql": true, "html": true, "xml": true, "cpu": true,
+}
+
+// goName converts a database name such as "user_id" to an exported Go name such as "UserID"
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToLower(w)] {
+			sb.WriteString(strings.ToUpper(w))
+			continue
+		}
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		sb.WriteString(string(runes))
+	}
+	s := sb.String()
+	if s == "" || !unicode.IsLetter([]rune(s)[0]) {
+		s = "X" + s
+	}
+	return s
+}
+
+// goType returns the Go type for a column declared with ctype, following
+// SQLite's rules of type affinity and the driver's conversion of dates and booleans
+func goType(ctype string, notNull bool) string {
+	t := strings.ToUpper(ctype)
+	var base, null string
+	switch {
+	case t == "":
+		return "interface{}"
+	case strings.Contains(t, "INT"):
+		base, null = "int64", "sql.NullInt64"
+	case strings.HasPrefix(t, "BOOL"):
+		base, null = "bool", "sql.NullBool"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		base, null = "string", "sql.NullString"
+	case strings.Contains(t, "BLOB"):
+		return "[]byte"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		base, null = "float64", "sql.NullFloat64"
+	case t == "DATE", t == "DATETIME", t == "TIMESTAMP":
+		base, null = "time.Time", "sql.NullTime"
+	default:
+		base, null = "float64", "sql.NullFloat64"
+	}
+	if notNull {
+		return base
+	}
+	return null
+}
+
+// Generate writes Go source with a struct, column name constants, and functions to
+// create, read, update, and delete the rows of each table of db (views are read only).
+// The functions take a Queryer, which is satisfied by *sql.DB and *sql.Tx, and pass
+// values through Args and Dests so types registered with RegisterType are converted
+func Generate(db *sql.DB, w io.Writer, opts GenerateOptions) error {
+	if opts.Package == "" {
+		return fmt.Errorf("no package given")
+	}
+	objects, err := Schema(db)
+	if err != nil {
+		return err
+	}
+	wanted := make(map[string]bool)
+	for _, t := range opts.Tables {
+		wanted[strings.ToLower(t)] = true
+	}
+	var tables []genTable
+	imports := map[string]bool{"database/sql": true}
+	for _, obj := range objects {
+		if obj.Kind != "table" && obj.Kind != "view" || obj.Virtual {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[strings.ToLower(obj.Name)] {
+			continue
+		}
+		delete(wanted, strings.ToLower(obj.Name))
+		table, err := genTableFor(db, obj)
+		if err != nil {
+			return err
+		}
+		for _, c := range table.Columns {
+			if strings.Contains(c.GoType, "time.") {
+				imports["time"] = true
+			}
+		}
+		tables = append(tables, table)
+	}
+	for name := range wanted {
+		return fmt.Errorf("no such table or view: %s", name)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Type < tables[j].Type })
+
+	var buf bytes.Buffer
+	data := struct {
+		Package string
+		Imports []string
+		Tables  []genTable
+	}{Package: opts.Package, Tables: tables}
+	for imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+	sort.Strings(data.Imports)
+	if err := codeTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code does not compile: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// GenerateFromSchema is Generate for the tables created by a schema script
+func GenerateFromSchema(schema string, w io.Writer, opts GenerateOptions) error {
+	db, err := Open(":memory:")
+	if err != nil {
+		return err
+	}
+	defer Close(db)
+	// each connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+	if _, err := ExecScript(db, schema); err != nil {
+		return err
+	}
+	return Generate(db, w, opts)
+}
+
+func genTableFor(db *sql.DB, obj SchemaObject) (genTable, error) {
+	table := genTable{Name: obj.Name, Type: goName(obj.Name), View: obj.Kind == "view"}
+	columns, err := exportColumns(db, obj.Name)
+	if err != nil {
+		return table, err
+	}
+	keys := make([]*genColumn, len(columns))
+	fields := make(map[string]int)
+	for _, c := range columns {
+		field := goName(c.name)
+		if n := fields[field]; n > 0 {
+			field = fmt.Sprintf("%s%d", field, n+1)
+		}
+		fields[field]++
+		col := genColumn{Name: c.name, Field: field, Const: table.Type + field, GoType: goType(c.ctype, c.notNull || c.pk > 0)}
+		if col.Const == table.Type+"Table" {
+			col.Const += "Column"
+		}
+		table.Columns = append(table.Columns, col)
+		if c.pk > 0 && c.pk <= len(keys) {
+			keys[c.pk-1] = &table.Columns[len(table.Columns)-1]
+		} else {
+			table.Settable = append(table.Settable, col)
+		}
+	}
+	for _, k := range keys {
+		if k != nil {
+			table.Keys = append(table.Keys, *k)
+		}
+	}
+	if !table.View && len(table.Keys) == 1 && strings.EqualFold(columnType(columns, table.Keys[0].Name), "INTEGER") &&
+		!strings.Contains(strings.ToUpper(obj.SQL), "WITHOUT ROWID") {
+		table.RowID = &table.Keys[0]
+	}
+	return table, nil
+}
+
+func columnType(columns []exportColumn, name string) string {
+	for _, c := range columns {
+		if c.name == name {
+			return c.ctype
+		}
+	}
+	return ""
+}
+
+var codeTemplate = template.Must(template.New("code").Funcs(template.FuncMap{
+	"quote": quoteIdent,
+	"columns": func(cols []genColumn) string {
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = quoteIdent(c.Name)
+		}
+		return strings.Join(names, ", ")
+	},
+	"where": func(cols []genColumn) string {
+		terms := make([]string, len(cols))
+		for i, c := range cols {
+			terms[i] = quoteIdent(c.Name) + " = ?"
+		}
+		return strings.Join(terms, " AND ")
+	},
+	"sets": func(cols []genColumn) string {
+		terms := make([]string, len(cols))
+		for i, c := range cols {
+			terms[i] = quoteIdent(c.Name) + " = ?"
+		}
+		return strings.Join(terms, ", ")
+	},
+	"values": func(t genTable) string {
+		values := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			values[i] = "?"
+			if t.RowID != nil && c.Name == t.RowID.Name {
+				values[i] = "nullif(?, 0)"
+			}
+		}
+		return strings.Join(values, ", ")
+	},
+	"fields": func(prefix string, cols []genColumn) string {
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = prefix + c.Field
+		}
+		return strings.Join(names, ", ")
+	},
+	"params": func(cols []genColumn) string {
+		params := make([]string, len(cols))
+		for i, c := range cols {
+			params[i] = fmt.Sprintf("%s %s", lowerFirst(c.Field), c.GoType)
+		}
+		return strings.Join(params, ", ")
+	},
+	"args": func(cols []genColumn) string {
+		args := make([]string, len(cols))
+		for i, c := range cols {
+			args[i] = lowerFirst(c.Field)
+		}
+		return strings.Join(args, ", ")
+	},
+	"backquote": func(s string) string {
+		if strings.Contains(s, "`") {
+			return fmt.Sprintf("%q", s)
+		}
+		return "`" + s + "`"
+	},
+}).Parse(`// Code generated by sqlite.Generate; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+
+	"github.com/paulstuart/sqlite"
+)
+
+// Queryer is satisfied by *sql.DB and *sql.Tx
+type Queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+{{range .Tables}}{{$t := .}}
+// {{.Type}} is a row of the {{if .View}}view{{else}}table{{end}} {{.Name}}
+type {{.Type}} struct {
+{{- range .Columns}}
+	{{.Field}} {{.GoType}}
+{{- end}}
+}
+
+// Names of the {{.Name}} {{if .View}}view{{else}}table{{end}} and its columns
+const (
+	{{.Type}}Table = {{printf "%q" .Name}}
+{{- range .Columns}}
+	{{.Const}} = {{printf "%q" .Name}}
+{{- end}}
+)
+
+// List{{.Type}} returns the rows of {{.Name}} matching the where clause, or every row if it is empty
+func List{{.Type}}(db Queryer, where string, args ...interface{}) ([]{{.Type}}, error) {
+	query := {{backquote (printf "SELECT %s FROM %s" (columns .Columns) (quote .Name))}}
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := db.Query(query, sqlite.Args(args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []{{.Type}}
+	for rows.Next() {
+		var r {{.Type}}
+		if err := rows.Scan(sqlite.Dests({{fields "&r." .Columns}})...); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+{{if and (not .View) .Keys}}
+// Get{{.Type}} returns the row of {{.Name}} with the given primary key
+func Get{{.Type}}(db Queryer, {{params .Keys}}) (*{{.Type}}, error) {
+	const query = {{backquote (printf "SELECT %s FROM %s WHERE %s" (columns .Columns) (quote .Name) (where .Keys))}}
+	var r {{.Type}}
+	if err := db.QueryRow(query, sqlite.Args({{args .Keys}})...).Scan(sqlite.Dests({{fields "&r." .Columns}})...); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Insert{{.Type}} adds a row to {{.Name}}{{if .RowID}}, setting {{.RowID.Field}} if it is zero{{end}}
+func Insert{{.Type}}(db Queryer, r *{{.Type}}) error {
+	const query = {{backquote (printf "INSERT INTO %s (%s) VALUES (%s)" (quote .Name) (columns .Columns) (values .))}}
+	{{if .RowID}}res{{else}}_{{end}}, err := db.Exec(query, sqlite.Args({{fields "r." .Columns}})...)
+	if err != nil {
+		return err
+	}
+{{- if .RowID}}
+	if r.{{.RowID.Field}}, err = res.LastInsertId(); err != nil {
+		return err
+	}
+{{- end}}
+	return nil
+}
+{{if .Settable}}
+// Update{{.Type}} writes every column of r to the row of {{.Name}} with the same primary key
+func Update{{.Type}}(db Queryer, r *{{.Type}}) error {
+	const query = {{backquote (printf "UPDATE %s SET %s WHERE %s" (quote .Name) (sets .Settable) (where .Keys))}}
+	res, err := db.Exec(query, sqlite.Args({{fields "r." .Settable}}, {{fields "r." .Keys}})...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		if err == nil {
+			err = sql.ErrNoRows
+		}
+		return err
+	}
+	return nil
+}
+{{end}}
+// Delete{{.Type}} deletes the row of {{.Name}} with the given primary key
+func Delete{{.Type}}(db Queryer, {{params .Keys}}) error {
+	const query = {{backquote (printf "DELETE FROM %s WHERE %s" (quote .Name) (where .Keys))}}
+	_, err := db.Exec(query, sqlite.Args({{args .Keys}})...)
+	return err
+}
+{{end}}{{end}}`))
+
+// lowerFirst returns a Go name with its leading capitals in lower case, for use as a parameter,
+// e.g. "ID" becomes "id" and "IDNumber" becomes "idNumber"
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	s := string(runes)
+	switch s {
+	case "type", "func", "map", "range", "select", "case", "default", "go", "chan", "var", "const",
+		"import", "package", "return", "if", "else", "for", "switch", "break", "continue", "defer",
+		"goto", "fallthrough", "interface", "struct", "db", "query", "r", "res", "err":
+		s += "_"
+	}
+	return s
+}
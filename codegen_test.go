@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	const schema = `
+	create table users (id integer primary key, name text not null, email text, created datetime, active boolean not null);
+	create table user_tags (user_id int not null references users(id), tag text not null, primary key (user_id, tag)) without rowid;
+	create view active_users as select id, name from users where active;
+	`
+	var buf bytes.Buffer
+	if err := GenerateFromSchema(schema, &buf, GenerateOptions{Package: "models"}); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "models.go", src, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package models",
+		"Created sql.NullTime",
+		"Active  bool",
+		`UsersEmail   = "email"`,
+		"func GetUsers(db Queryer, id int64) (*Users, error)",
+		"VALUES (nullif(?, 0), ?, ?, ?, ?)",
+		"func DeleteUserTags(db Queryer, userID int64, tag string) error",
+		"func ListActiveUsers(db Queryer, where string, args ...interface{}) ([]ActiveUsers, error)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected generated code to contain %q:\n%s", want, src)
+		}
+	}
+	// views are read only
+	if strings.Contains(src, "InsertActiveUsers") {
+		t.Fatal("expected no insert function for a view")
+	}
+	// user_tags has no columns outside its primary key
+	if strings.Contains(src, "UpdateUserTags") {
+		t.Fatal("expected no update function for a table without other columns")
+	}
+
+	buf.Reset()
+	if err := GenerateFromSchema(schema, &buf, GenerateOptions{Package: "models", Tables: []string{"nosuch"}}); err == nil {
+		t.Fatal("expected error for unknown table")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"user_id":   "UserID",
+		"homepage":  "Homepage",
+		"api-url":   "APIURL",
+		"2fa":       "X2fa",
+		"CamelCase": "CamelCase",
+	} {
+		if got := goName(name); got != want {
+			t.Errorf("%s expected: %q but got: %q", name, want, got)
+		}
+	}
+	for name, want := range map[string]string{"ID": "id", "UserID": "userID", "IDNumber": "idNumber", "Type": "type_"} {
+		if got := lowerFirst(name); got != want {
+			t.Errorf("%s expected: %q but got: %q", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CollationReg contains the fields necessary to register a custom SQLite collation
+type CollationReg struct {
+	Name string
+	Cmp  func(string, string) int
+}
+
+// UnicodeCollations are collations that order text the way end users expect, without the ICU extension
+//
+// NOCASE_UNICODE ignores case for all Unicode letters, NOACCENT ignores both case and accents,
+// and NATSORT orders embedded numbers numerically (e.g., "file2" before "file10")
+var UnicodeCollations = []CollationReg{
+	{"NOCASE_UNICODE", collateNoCase},
+	{"NOACCENT", collateNoAccent},
+	{"NATSORT", collateNatural},
+}
+
+const (
+	// accented and unaccented are parallel lists of letters and their base forms
+	accented = "" +
+		"ÀÁÂÃÄÅÇÈÉÊËÌÍÎÏÑÒÓÔÕÖÙÚÛÜÝàáâãäåçèéêëìíî" +
+		"ïñòóôõöùúûüýÿĀāĂăĄąĆćĈĉĊċČčĎďĒēĔĕĖėĘęĚěĜ" +
+		"ĝĞğĠġĢģĤĥĨĩĪīĬĭĮįİĴĵĶķĹĺĻļĽľŃńŅņŇňŌōŎŏŐő" +
+		"ŔŕŖŗŘřŚśŜŝŞşŠšŢţŤťŨũŪūŬŭŮůŰűŲųŴŵŶŷŸŹźŻżŽ" +
+		"žƠơƯưǍǎǏǐǑǒǓǔǕǖǗǘǙǚǛǜǞǟǠǡǦǧǨǩǪǫǬǭǰǴǵǸǹǺǻ" +
+		"ȀȁȂȃȄȅȆȇȈȉȊȋȌȍȎȏȐȑȒȓȔȕȖȗȘșȚțȞȟȦȧȨȩȪȫȬȭȮȯ" +
+		"ȰȱȲȳḀḁḂḃḄḅḆḇḈḉḊḋḌḍḎḏḐḑḒḓḔḕḖḗḘḙḚḛḜḝḞḟḠḡḢḣ" +
+		"ḤḥḦḧḨḩḪḫḬḭḮḯḰḱḲḳḴḵḶḷḸḹḺḻḼḽḾḿṀṁṂṃṄṅṆṇṈṉṊṋ" +
+		"ṌṍṎṏṐṑṒṓṔṕṖṗṘṙṚṛṜṝṞṟṠṡṢṣṤṥṦṧṨṩṪṫṬṭṮṯṰṱṲṳ" +
+		"ṴṵṶṷṸṹṺṻṼṽṾṿẀẁẂẃẄẅẆẇẈẉẊẋẌẍẎẏẐẑẒẓẔẕẖẗẘẙẠạ" +
+		"ẢảẤấẦầẨẩẪẫẬậẮắẰằẲẳẴẵẶặẸẹẺẻẼẽẾếỀềỂểỄễỆệỈỉ" +
+		"ỊịỌọỎỏỐốỒồỔổỖỗỘộỚớỜờỞởỠỡỢợỤụỦủỨứỪừỬửỮữỰự" +
+		"ỲỳỴỵỶỷỸỹØøĐđŁłı"
+	unaccented = "" +
+		"AAAAAACEEEEIIIINOOOOOUUUUYaaaaaaceeeeiii" +
+		"inooooouuuuyyAaAaAaCcCcCcCcDdEeEeEeEeEeG" +
+		"gGgGgGgHhIiIiIiIiIJjKkLlLlLlNnNnNnOoOoOo" +
+		"RrRrRrSsSsSsSsTtTtUuUuUuUuUuUuWwYyYZzZzZ" +
+		"zOoUuAaIiOoUuUuUuUuUuAaAaGgKkOoOojGgNnAa" +
+		"AaAaEeEeIiIiOoOoRrRrUuUuSsTtHhAaEeOoOoOo" +
+		"OoYyAaBbBbBbCcDdDdDdDdDdEeEeEeEeEeFfGgHh" +
+		"HhHhHhHhIiIiKkKkKkLlLlLlLlMmMmMmNnNnNnNn" +
+		"OoOoOoOoPpPpRrRrRrRrSsSsSsSsSsTtTtTtTtUu" +
+		"UuUuUuUuVvVvWwWwWwWwWwXxXxYyZzZzZzhtwyAa" +
+		"AaAaAaAaAaAaAaAaAaAaAaEeEeEeEeEeEeEeEeIi" +
+		"IiOoOoOoOoOoOoOoOoOoOoOoOoUuUuUuUuUuUuUu" +
+		"YyYyYyYyOoDdLli"
+)
+
+var baseLetters = make(map[rune]rune)
+
+func init() {
+	bases := []rune(unaccented)
+	for i, r := range []rune(accented) {
+		baseLetters[r] = bases[i]
+	}
+}
+
+// Unaccent returns s with accented Latin letters replaced by their base letters
+func Unaccent(s string) string {
+	return strings.Map(func(r rune) rune {
+		if b, ok := baseLetters[r]; ok {
+			return b
+		}
+		return r
+	}, s)
+}
+
+func collateNoCase(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func collateNoAccent(a, b string) int {
+	return strings.Compare(strings.ToLower(Unaccent(a)), strings.ToLower(Unaccent(b)))
+}
+
+// collateNatural compares runs of digits by numeric value and everything else case insensitively
+func collateNatural(a, b string) int {
+	for a != "" && b != "" {
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if isDigit(ra) && isDigit(rb) {
+			da, db := digitRun(a), digitRun(b)
+			if c := compareNumbers(da, db); c != 0 {
+				return c
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		la, lb := unicode.ToLower(ra), unicode.ToLower(rb)
+		if la != lb {
+			if la < lb {
+				return -1
+			}
+			return 1
+		}
+		// invalid bytes all decode as RuneError, so they're told apart by value
+		if ra == utf8.RuneError {
+			if c := strings.Compare(a[:sa], b[:sb]); c != 0 {
+				return c
+			}
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	return len(a) - len(b)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// digitRun returns the leading ASCII digits of s
+func digitRun(s string) string {
+	i := 0
+	for i < len(s) && isDigit(rune(s[i])) {
+		i++
+	}
+	return s[:i]
+}
+
+// compareNumbers compares two strings of digits by value, of any length
+func compareNumbers(a, b string) int {
+	ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(ta) != len(tb) {
+		return len(ta) - len(tb)
+	}
+	if c := strings.Compare(ta, tb); c != 0 {
+		return c
+	}
+	// equal values, so fewer leading zeros sorts first
+	return len(a) - len(b)
+}
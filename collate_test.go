@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnaccent(t *testing.T) {
+	if s := Unaccent("Crème Brûlée à Łódź"); s != "Creme Brulee a Lodz" {
+		t.Fatalf("unexpected: %q", s)
+	}
+}
+
+func TestCollateNatural(t *testing.T) {
+	tests := []struct {
+		a, b string
+		less bool
+	}{
+		{"file2", "file10", true},
+		{"file10", "file2", false},
+		{"File1", "file2", true},
+		{"a", "ab", true},
+		{"x01", "x1", false},
+		{"v1.10", "v1.9", false},
+		{"a\xff", "a\xffb", true},
+		{"\xfe", "\xff", true},
+		{"\xff2", "\xff10", true},
+	}
+	for _, test := range tests {
+		if less := collateNatural(test.a, test.b) < 0; less != test.less {
+			t.Errorf("%q < %q expected: %t", test.a, test.b, test.less)
+		}
+	}
+}
+
+func TestCollations(t *testing.T) {
+	db, err := Open(":memory:", WithCollations(UnicodeCollations...), WithDriver("collations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const setup = `
+	create table names (name text);
+	insert into names values('émile'), ('Zoe'), ('Eve'), ('file10'), ('file2'), ('Édith');
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	collated := func(collation string) []string {
+		var list []string
+		fn := func(_ []string, row []interface{}) {
+			list = append(list, row[0].(string))
+		}
+		if err := query(db, fn, "select name from names order by name collate "+collation); err != nil {
+			t.Fatal(err)
+		}
+		return list
+	}
+
+	expected := []string{"Édith", "émile", "Eve", "file10", "file2", "Zoe"}
+	if list := collated("NOACCENT"); !reflect.DeepEqual(list, expected) {
+		t.Errorf("NOACCENT expected: %q but got: %q", expected, list)
+	}
+	expected = []string{"Eve", "file2", "file10", "Zoe", "Édith", "émile"}
+	if list := collated("NATSORT"); !reflect.DeepEqual(list, expected) {
+		t.Errorf("NATSORT expected: %q but got: %q", expected, list)
+	}
+
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from names where name = 'ÉMILE' collate NOCASE_UNICODE"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 match but got: %d", count)
+	}
+}
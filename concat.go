@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConcatAggregates are variants of group_concat:
+// string_agg(x, separator) as in PostgreSQL, and group_concat_sorted(x [, separator]),
+// which joins the distinct values in sorted order so the result doesn't depend on the
+// order rows were read in. NULLs are ignored by both, and the default separator is ",".
+// A group with no values other than NULL gives NULL in SQL, but as the driver can't
+// return NULL from a text function, it gives empty text here, as in StatAggregates
+var ConcatAggregates = []AggregateReg{
+	{"string_agg", newStringAgg, true},
+	{"group_concat_sorted", newSortedConcat, true},
+}
+
+// text converts an SQL value to the text SQLite would, reporting false for NULL,
+// which the driver passes to functions as a nil []byte
+func text(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", false
+	case []byte:
+		return string(v), v != nil
+	case string:
+		return v, true
+	}
+	return fmt.Sprint(v), true
+}
+
+type stringAgg struct {
+	values    []string
+	separator string
+}
+
+func newStringAgg() *stringAgg {
+	return &stringAgg{}
+}
+
+func (a *stringAgg) Step(v, separator interface{}) {
+	s, ok := text(v)
+	if !ok {
+		return
+	}
+	if sep, ok := text(separator); ok && len(a.values) == 0 {
+		a.separator = sep
+	}
+	a.values = append(a.values, s)
+}
+
+// Done joins the values, which is empty text rather than NULL if there were none
+func (a *stringAgg) Done() string {
+	return strings.Join(a.values, a.separator)
+}
+
+type sortedConcat struct {
+	values    map[string]struct{}
+	separator string
+}
+
+func newSortedConcat() *sortedConcat {
+	return &sortedConcat{values: make(map[string]struct{}), separator: ","}
+}
+
+func (a *sortedConcat) Step(args ...interface{}) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("group_concat_sorted takes 1 or 2 arguments but got %d", len(args))
+	}
+	if len(args) == 2 {
+		if sep, ok := text(args[1]); ok {
+			a.separator = sep
+		}
+	}
+	if s, ok := text(args[0]); ok {
+		a.values[s] = struct{}{}
+	}
+	return nil
+}
+
+func (a *sortedConcat) Done() string {
+	values := make([]string, 0, len(a.values))
+	for v := range a.values {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return strings.Join(values, a.separator)
+}
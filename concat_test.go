@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestConcatAggregates(t *testing.T) {
+	db, err := Open(":memory:", WithAggregates(ConcatAggregates...), WithDriver("concat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const setup = `
+	create table tags (item int, tag text);
+	insert into tags values(1, 'red'), (1, 'blue'), (1, null), (1, 'blue'), (2, 'green');
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	var agg, sorted, sortedSep string
+	const q = `select string_agg(tag, ' | '), group_concat_sorted(tag), group_concat_sorted(tag, ';')
+	from (select tag from tags where item = 1 order by rowid)`
+	if err := row(db, []interface{}{&agg, &sorted, &sortedSep}, q); err != nil {
+		t.Fatal(err)
+	}
+	if agg != "red | blue | blue" {
+		t.Errorf("unexpected string_agg: %q", agg)
+	}
+	if sorted != "blue,red" || sortedSep != "blue;red" {
+		t.Errorf("unexpected group_concat_sorted: %q %q", sorted, sortedSep)
+	}
+	if err := row(db, []interface{}{&agg}, "select string_agg(tag, ',') from tags where tag is null"); err != nil {
+		t.Fatal(err)
+	}
+	if agg != "" {
+		t.Errorf("expected empty text for a group of NULLs, got: %q", agg)
+	}
+}
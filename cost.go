@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PlanStep is a step of a query plan, as reported by EXPLAIN QUERY PLAN
+type PlanStep struct {
+	ID     int
+	Parent int
+	Detail string
+}
+
+// CostLimits are the heuristics CheckCost applies to a query plan.
+// A negative limit is not enforced
+type CostLimits struct {
+	MaxFullScans   int      // tables read in full, not counting those in AllowScan
+	MaxTempBTrees  int      // temporary b-trees built to sort, group, or dedupe rows
+	AllowCartesian bool     // allow a join that scans a table in full for each row of another
+	AllowScan      []string // tables small enough to scan freely, e.g. lookup tables
+}
+
+// DefaultCostLimits allow one full scan and a couple of sorts, but no cartesian joins
+var DefaultCostLimits = CostLimits{MaxFullScans: 1, MaxTempBTrees: 2}
+
+// CostError reports why a query was rejected by CheckCost
+type CostError struct {
+	Query   string
+	Reasons []string
+}
+
+func (e *CostError) Error() string {
+	return fmt.Sprintf("query too costly: %s -- %s", strings.Join(e.Reasons, "; "), e.Query)
+}
+
+// fullScan matches a step that reads a table in full ("SCAN TABLE t" in older releases, "SCAN t" since 3.36)
+var fullScan = regexp.MustCompile(`^SCAN (?:TABLE )?(\S+)`)
+
+// QueryPlan returns the plan SQLite would use to run the query, without running it
+func QueryPlan(db *sql.DB, q string, args ...interface{}) ([]PlanStep, error) {
+	stmts, err := ParseScript(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) != 1 || stmts[0].Command {
+		return nil, fmt.Errorf("expected a single statement but got %d", len(stmts))
+	}
+	rows, err := db.Query("EXPLAIN QUERY PLAN "+stmts[0].Text, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var steps []PlanStep
+	for rows.Next() {
+		var step PlanStep
+		var notUsed int
+		if err := rows.Scan(&step.ID, &step.Parent, &notUsed, &step.Detail); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, rows.Err()
+}
+
+// CheckCost rejects a query, returned as a *CostError, if its plan exceeds the limits.
+// It is meant to screen untrusted queries before they are run, alongside row and time limits
+func CheckCost(db *sql.DB, limits CostLimits, q string, args ...interface{}) error {
+	steps, err := QueryPlan(db, q, args...)
+	if err != nil {
+		return err
+	}
+	if reasons := limits.exceeded(steps); len(reasons) > 0 {
+		return &CostError{Query: q, Reasons: reasons}
+	}
+	return nil
+}
+
+// exceeded returns the reasons the plan exceeds the limits
+func (limits CostLimits) exceeded(steps []PlanStep) []string {
+	allowed := make(map[string]bool)
+	for _, table := range limits.AllowScan {
+		allowed[strings.ToLower(table)] = true
+	}
+	var reasons []string
+	var scans, temps int
+	loops := make(map[int]int) // loops seen so far for each parent
+	for _, step := range steps {
+		if strings.Contains(step.Detail, "TEMP B-TREE") {
+			temps++
+		}
+		loop := strings.HasPrefix(step.Detail, "SCAN ") || strings.HasPrefix(step.Detail, "SEARCH ")
+		if !loop {
+			continue
+		}
+		inner := loops[step.Parent] > 0
+		loops[step.Parent]++
+
+		m := fullScan.FindStringSubmatch(step.Detail)
+		if m == nil || m[1] == "SUBQUERY" || m[1] == "CONSTANT" || strings.HasPrefix(m[1], "(") {
+			continue
+		}
+		table := m[1]
+		if allowed[strings.ToLower(table)] {
+			continue
+		}
+		scans++
+		if inner && !limits.AllowCartesian {
+			reasons = append(reasons, fmt.Sprintf("cartesian join scans %s for each row", table))
+		}
+	}
+	if limits.MaxFullScans >= 0 && scans > limits.MaxFullScans {
+		reasons = append(reasons, fmt.Sprintf("%d full table scans (limit %d)", scans, limits.MaxFullScans))
+	}
+	if limits.MaxTempBTrees >= 0 && temps > limits.MaxTempBTrees {
+		reasons = append(reasons, fmt.Sprintf("%d temporary b-trees (limit %d)", temps, limits.MaxTempBTrees))
+	}
+	return reasons
+}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckCost(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const schema = `
+	create table a (id integer primary key, x int, y int);
+	create table b (id integer primary key, aid int);
+	create index b_aid on b (aid);
+	create table c (n int);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	steps, err := QueryPlan(db, "select * from a where id = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || steps[0].Detail[:6] != "SEARCH" {
+		t.Fatalf("unexpected plan: %+v", steps)
+	}
+
+	ok := []string{
+		"select * from a where id = 1",
+		"select * from a",
+		"select * from a, b where a.id = b.aid and b.aid > 3",
+		"select * from a order by y",
+	}
+	for _, q := range ok {
+		if err := CheckCost(db, DefaultCostLimits, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	var cerr *CostError
+	if err := CheckCost(db, DefaultCostLimits, "select * from a, c"); !errors.As(err, &cerr) {
+		t.Fatalf("expected cartesian join to be rejected but got: %v", err)
+	}
+	if len(cerr.Reasons) != 2 {
+		t.Fatalf("expected cartesian join and scan limit but got: %v", cerr.Reasons)
+	}
+
+	limits := DefaultCostLimits
+	limits.AllowScan = []string{"C"}
+	if err := CheckCost(db, limits, "select * from a, c"); err != nil {
+		t.Fatal(err)
+	}
+
+	limits = CostLimits{MaxFullScans: 0, MaxTempBTrees: -1}
+	if err := CheckCost(db, limits, "select * from a"); !errors.As(err, &cerr) {
+		t.Fatalf("expected full scan to be rejected but got: %v", err)
+	}
+	if err := CheckCost(db, limits, "select * from a; select * from c"); err == nil {
+		t.Fatal("expected error for multiple statements")
+	}
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WithFileMode sets the permissions of a database file created by Open, and of its
+// directory if that's created too, rather than 0666 and 0777 less the umask. The
+// mode is applied as given, whatever the umask, and SQLite gives the journal and
+// WAL files the same. On Windows only the owner's write bit counts, as read-only
+func WithFileMode(mode os.FileMode) Optional {
+	return func(c *Config) {
+		c.fileMode = mode.Perm()
+	}
+}
+
+// WithDurableCreate makes sure that a database file created by Open, and its
+// directory if created too, are on disk before Open returns, by syncing each
+// and the directory that holds it, so they survive a crash right after first boot.
+// Windows can't sync a directory, so there only the file is synced
+func WithDurableCreate() Optional {
+	return func(c *Config) {
+		c.durable = true
+	}
+}
+
+// dirMode is the mode of a directory for files of the mode, searchable by those
+// who can read it
+func dirMode(mode os.FileMode) os.FileMode {
+	return mode | (mode&0444)>>2
+}
+
+// createDir creates the directory of the database file if it doesn't exist
+func (c *Config) createDir(dir string) error {
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return nil
+	}
+	mode := os.FileMode(0777)
+	if c.fileMode != 0 {
+		mode = dirMode(c.fileMode)
+	}
+	if err := os.Mkdir(dir, mode); err != nil {
+		return err
+	}
+	if c.fileMode != 0 {
+		if err := os.Chmod(dir, mode); err != nil {
+			return err
+		}
+	}
+	if c.durable {
+		return syncDir(filepath.Dir(dir))
+	}
+	return nil
+}
+
+// createFile creates the database file if it doesn't exist
+func (c *Config) createFile(filename string) error {
+	mode := os.FileMode(0666)
+	if c.fileMode != 0 {
+		mode = c.fileMode
+	}
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_EXCL, mode)
+	if os.IsExist(err) {
+		// as before, a file that can't be written fails here
+		if f, err = os.OpenFile(filename, os.O_RDWR, 0); err == nil {
+			f.Close()
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if c.fileMode != 0 {
+		// the umask may have taken bits away
+		if err := f.Chmod(mode); err != nil {
+			return err
+		}
+	}
+	if !c.durable {
+		return nil
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(filename))
+}
+
+// syncDir flushes the entries of a directory to disk
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build !windows
+// +build !windows
+
+package sqlite
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFileMode(t *testing.T) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+
+	dir := filepath.Join(t.TempDir(), "data")
+	file := filepath.Join(dir, "mode.db")
+	db, err := Open(file, WithFileMode(0640), WithDurableCreate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0640 {
+		t.Errorf("file mode %o, want 640", mode)
+	}
+	di, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := di.Mode().Perm(); mode != 0750 {
+		t.Errorf("directory mode %o, want 750", mode)
+	}
+
+	// an existing file keeps its mode
+	if err := os.Chmod(file, 0600); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Open(file, WithFileMode(0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Close(again)
+	if fi, err = os.Stat(file); err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0600 {
+		t.Errorf("existing file mode %o, want 600", mode)
+	}
+}
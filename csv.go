@@ -0,0 +1,268 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// CSVOptions control how ImportCSV reads a file
+type CSVOptions struct {
+	Comma     rune     // the field separator, ',' if not set
+	NoHeader  bool     // the first record is data rather than column names
+	Columns   []string // the column names, replacing those of the header
+	EmptyNull bool     // import empty fields as NULL rather than empty text
+	BatchSize int      // the rows inserted per transaction, ImportBatchSize if not set
+	Reporter  Progress // reports on the import, in rows
+}
+
+// ImportCSV inserts the records of a CSV file into a table and returns the number of rows
+// imported. If the table doesn't exist it is created with a TEXT column for each field,
+// named by the header, or c1, c2... if there is none, as the sqlite3 shell's .import does.
+// If it does exist the header names the columns to fill, each of which must be in the
+// table, and without one each record must have a field for every column
+func ImportCSV(db *sql.DB, table string, r io.Reader, opts CSVOptions) (int64, error) {
+	return ImportCSVContext(context.Background(), db, table, r, opts)
+}
+
+// ImportCSVContext is ImportCSV with a context
+func ImportCSVContext(ctx context.Context, db *sql.DB, table string, r io.Reader, opts CSVOptions) (n int64, err error) {
+	report := reporting(opts.Reporter)
+	report.OnStart("import")
+	defer func() { report.OnFinish(err) }()
+
+	n, err = importCSV(ctx, db, table, r, opts, report)
+	if err != nil {
+		err = fmt.Errorf("import csv %s: %w", table, err)
+	}
+	return n, err
+}
+
+func importCSV(ctx context.Context, db *sql.DB, table string, r io.Reader, opts CSVOptions, report Progress) (int64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	var names []string
+	var first []string
+	if !opts.NoHeader {
+		header, err := cr.Read()
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		names = header
+	}
+	if opts.Columns != nil {
+		if names != nil && len(opts.Columns) != len(names) {
+			return 0, fmt.Errorf("%d columns given for %d fields", len(opts.Columns), len(names))
+		}
+		names = opts.Columns
+	}
+
+	existing, err := exportColumns(db, table)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case len(existing) == 0:
+		if names == nil {
+			if first, err = cr.Read(); err == io.EOF {
+				return 0, nil
+			} else if err != nil {
+				return 0, err
+			}
+			names = make([]string, len(first))
+			for i := range names {
+				names[i] = fmt.Sprintf("c%d", i+1)
+			}
+		}
+		defs := make([]string, len(names))
+		for i, name := range names {
+			defs[i] = quoteIdent(name) + " TEXT"
+		}
+		create := fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdent(table), strings.Join(defs, ", "))
+		if _, err := db.ExecContext(ctx, create); err != nil {
+			return 0, err
+		}
+	case names == nil:
+		names = make([]string, len(existing))
+		for i, c := range existing {
+			names[i] = c.name
+		}
+	default:
+		if err := csvColumns(table, names, existing); err != nil {
+			return 0, err
+		}
+	}
+
+	columns := make([]string, len(names))
+	marks := make([]string, len(names))
+	for i, name := range names {
+		columns[i] = quoteIdent(name)
+		marks[i] = "?"
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)", quoteIdent(table), strings.Join(columns, ", "), strings.Join(marks, ", "))
+	batch := batchSize(opts.BatchSize)
+
+	var count int64
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	args := make([]interface{}, len(names))
+	for {
+		record := first
+		if record != nil {
+			first = nil
+		} else if record, err = cr.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return count, err
+		}
+		if len(record) != len(names) {
+			line, _ := cr.FieldPos(0)
+			return count, fmt.Errorf("line %d: %d fields for %d columns", line, len(record), len(names))
+		}
+		if tx == nil {
+			if tx, err = db.BeginTx(ctx, nil); err != nil {
+				return count, err
+			}
+			if stmt, err = tx.PrepareContext(ctx, insert); err != nil {
+				return count, err
+			}
+		}
+		for i, field := range record {
+			if field == "" && opts.EmptyNull {
+				args[i] = nil
+			} else {
+				args[i] = field
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return count, err
+		}
+		count++
+		if count%int64(batch) == 0 {
+			stmt.Close()
+			err, tx = tx.Commit(), nil
+			if err != nil {
+				return count, err
+			}
+			report.OnProgress(count, -1)
+		}
+	}
+	if tx != nil {
+		stmt.Close()
+		err, tx = tx.Commit(), nil
+		if err != nil {
+			return count, err
+		}
+	}
+	report.OnProgress(count, count)
+	return count, nil
+}
+
+// csvColumns checks that the named columns are those of an existing table
+func csvColumns(table string, names []string, existing []exportColumn) error {
+	known := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		known[strings.ToLower(c.name)] = true
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if !known[key] {
+			return fmt.Errorf("table %s has no column named %s", table, name)
+		}
+		if seen[key] {
+			return fmt.Errorf("column %s is named more than once", name)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// ExportCSV writes the results of a query to w as CSV, with a header of the column names.
+// NULL is written as an empty field, blobs as their bytes, and times in the format the
+// driver stores them
+func ExportCSV(db *sql.DB, w io.Writer, query string, args ...interface{}) error {
+	return ExportCSVContext(context.Background(), db, w, query, args...)
+}
+
+// ExportCSVContext is ExportCSV with a context
+func ExportCSVContext(ctx context.Context, db *sql.DB, w io.Writer, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			record[i] = csvField(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvField formats a column value as a CSV field
+func csvField(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return x.Format(sqlite3.SQLiteTimestampFormats[0])
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportCSV(t *testing.T) {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	const data = "\ufeffname,city,note\nalice,\"Portland, OR\",\nbob,Austin,\"says \"\"hi\"\"\"\ncarol,Boston,x\n"
+	var done int64
+	n, err := ImportCSV(db, "people", strings.NewReader(data), CSVOptions{
+		EmptyNull: true,
+		BatchSize: 2,
+		Reporter:  ProgressFunc(func(n, total int64) { done = n }),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || done != 3 {
+		t.Fatalf("imported %d rows, reported %d", n, done)
+	}
+	var city, note string
+	var nulls int
+	if err := db.QueryRow("select city, note from people where name='bob'").Scan(&city, &note); err != nil {
+		t.Fatal(err)
+	}
+	if city != "Austin" || note != `says "hi"` {
+		t.Fatalf("got city %q note %q", city, note)
+	}
+	if err := db.QueryRow("select count(*) from people where note is null").Scan(&nulls); err != nil {
+		t.Fatal(err)
+	}
+	if nulls != 1 {
+		t.Fatalf("expected 1 null note, got %d", nulls)
+	}
+
+	// appending to an existing table by header, in any order
+	if n, err = ImportCSV(db, "people", strings.NewReader("city,name\nDenver,dave\n"), CSVOptions{}); err != nil || n != 1 {
+		t.Fatalf("append: %d rows, %v", n, err)
+	}
+	if _, err := ImportCSV(db, "people", strings.NewReader("name,age\nerin,40\n"), CSVOptions{}); err == nil {
+		t.Fatal("expected error for unknown column")
+	}
+	if _, err := ImportCSV(db, "people", strings.NewReader("frank;Reno;y\n"), CSVOptions{NoHeader: true, Comma: ';'}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportCSV(db, "people", strings.NewReader("gina,Miami\n"), CSVOptions{NoHeader: true}); err == nil {
+		t.Fatal("expected error for a short record")
+	}
+	var count int
+	if err := db.QueryRow("select count(*) from people").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Fatalf("expected 5 rows, got %d", count)
+	}
+}
+
+func TestImportCSVNoHeader(t *testing.T) {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	if _, err := ImportCSV(db, "pairs", strings.NewReader("1,one\n2,two\n"), CSVOptions{NoHeader: true}); err != nil {
+		t.Fatal(err)
+	}
+	var c2 string
+	if err := db.QueryRow("select c2 from pairs where c1 = '2'").Scan(&c2); err != nil {
+		t.Fatal(err)
+	}
+	if c2 != "two" {
+		t.Fatalf("got %q", c2)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	const setup = `
+	create table items (id integer primary key, name text, price real, data blob);
+	insert into items values(1, 'plain', 1.5, x'6869');
+	insert into items values(2, 'with, comma', null, null);
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ExportCSV(db, &buf, "select * from items where id > ? order by id", 0); err != nil {
+		t.Fatal(err)
+	}
+	const expect = "id,name,price,data\n1,plain,1.5,hi\n2,\"with, comma\",,\n"
+	if buf.String() != expect {
+		t.Fatalf("got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := ExportCSV(db, &buf, "select id, name from items where id < 0"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "id,name\n" {
+		t.Fatalf("expected only a header, got %q", buf.String())
+	}
+}
+
+func TestCommandsImportCSV(t *testing.T) {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	file := filepath.Join(t.TempDir(), "colors.csv")
+	if err := os.WriteFile(file, []byte("name,hex\nred,f00\nblue,00f\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := ".import --csv " + file + " colors\n" +
+		".import --skip 1 " + file + " colors\n" +
+		".mode csv\n" +
+		"select name, hex from colors order by rowid;\n"
+	var buf bytes.Buffer
+	if err := Commands(db, script, false, &buf); err != nil {
+		t.Fatal(err)
+	}
+	const expect = "name,hex\nred,f00\nblue,00f\nred,f00\nblue,00f\n"
+	if buf.String() != expect {
+		t.Fatalf("got:\n%s", buf.String())
+	}
+	if err := Commands(db, ".mode box", false, &buf); err == nil {
+		t.Fatal("expected error for unsupported mode")
+	}
+}
@@ -0,0 +1,300 @@
+package sqlite
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DumpOptions control what DumpTo writes
+type DumpOptions struct {
+	Tables    []string // the tables (or views) to dump, with their indexes and triggers; all if not set
+	Exclude   []string // tables left out, with the indexes, triggers, and views that depend on them
+	Checksums bool     // write a checksum comment for each table, which Commands verifies
+	Reporter  Progress // reports on the dump, in tables written
+}
+
+// Dump writes the schema and contents of db, or of the given tables, to w as a script
+// of SQL statements, as the sqlite3 shell's .dump does, which Commands can read back
+func Dump(db *sql.DB, w io.Writer, tables ...string) error {
+	return DumpToContext(context.Background(), db, w, DumpOptions{Tables: tables})
+}
+
+// DumpTo writes a script of the schema and contents of db to w. The rows are read in
+// one transaction, so the script is consistent even while db is written to
+func DumpTo(db *sql.DB, w io.Writer, opts DumpOptions) error {
+	return DumpToContext(context.Background(), db, w, opts)
+}
+
+// DumpToContext is DumpTo with a context
+func DumpToContext(ctx context.Context, db *sql.DB, w io.Writer, opts DumpOptions) (err error) {
+	report := reporting(opts.Reporter)
+	report.OnStart("dump")
+	defer func() { report.OnFinish(err) }()
+
+	objects, err := Schema(db)
+	if err != nil {
+		return err
+	}
+	if len(opts.Tables) > 0 {
+		if objects, err = includeObjects(objects, opts.Tables); err != nil {
+			return err
+		}
+	}
+	schema, err := excludeObjects(objects, opts.Exclude)
+	if err != nil {
+		return err
+	}
+
+	// read the columns first, as a pool of one connection is taken by the transaction
+	inserts := make([]dumpTable, len(schema.tables))
+	for i, obj := range schema.tables {
+		if inserts[i], err = dumpColumns(db, obj.Name); err != nil {
+			return fmt.Errorf("dump %s: %w", obj.Name, err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "PRAGMA foreign_keys=OFF;")
+	fmt.Fprintln(bw, "BEGIN TRANSACTION;")
+	var sums []TableSum
+	var autoincrement []string
+	for i, obj := range schema.tables {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "%s;\n", obj.SQL)
+		sum, err := dumpRows(ctx, tx, bw, inserts[i])
+		if err != nil {
+			return fmt.Errorf("dump %s: %w", obj.Name, err)
+		}
+		if !obj.Virtual {
+			sums = append(sums, sum)
+		}
+		if strings.Contains(strings.ToUpper(obj.SQL), "AUTOINCREMENT") {
+			autoincrement = append(autoincrement, obj.Name)
+		}
+		report.OnProgress(int64(i+1), int64(len(schema.tables)))
+	}
+	if len(autoincrement) > 0 {
+		if err := dumpSequences(ctx, tx, bw, autoincrement); err != nil {
+			return err
+		}
+	}
+	for _, obj := range schema.others {
+		fmt.Fprintf(bw, "%s;\n", obj.SQL)
+	}
+	var version int64
+	if err := tx.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version != 0 {
+		fmt.Fprintf(bw, "PRAGMA user_version=%d;\n", version)
+	}
+	fmt.Fprintln(bw, "COMMIT;")
+	if opts.Checksums {
+		for _, sum := range sums {
+			fmt.Fprintln(bw, sum)
+		}
+	}
+	return bw.Flush()
+}
+
+// includeObjects returns the named tables and views, with the indexes and triggers of the tables
+func includeObjects(objects []SchemaObject, names []string) ([]SchemaObject, error) {
+	want := make(map[string]bool, len(names))
+	for _, name := range names {
+		found := false
+		for _, obj := range objects {
+			if (obj.Kind == "table" || obj.Kind == "view") && strings.EqualFold(obj.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no such table: %s", name)
+		}
+		want[strings.ToLower(name)] = true
+	}
+	var included []SchemaObject
+	for _, obj := range objects {
+		switch obj.Kind {
+		case "table", "view":
+			if want[strings.ToLower(obj.Name)] {
+				included = append(included, obj)
+			}
+		default:
+			if want[strings.ToLower(obj.Table)] {
+				included = append(included, obj)
+			}
+		}
+	}
+	return included, nil
+}
+
+// dumpTable is the insert statement and query for dumping the rows of a table
+type dumpTable struct {
+	name   string
+	insert string // the start of each INSERT statement
+	query  string // the query reading the rows, in the order TableChecksum reads them
+}
+
+// dumpColumns returns the statements for dumping a table
+func dumpColumns(db *sql.DB, table string) (dumpTable, error) {
+	d := dumpTable{name: table, insert: "INSERT INTO " + quoteIdent(table)}
+	columns, err := exportColumns(db, table)
+	if err != nil {
+		return d, err
+	}
+	// generated columns aren't listed by table_info, and can't be inserted
+	all, err := PragmaRows(db, fmt.Sprintf("table_xinfo(%s)", quoteIdent(table)))
+	if err != nil {
+		return d, err
+	}
+	names := make([]string, len(columns))
+	exprs := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = quoteIdent(c.name)
+		// an expression has no declared type, so the driver doesn't convert the value
+		exprs[i] = fmt.Sprintf("coalesce(%s, NULL)", quoteIdent(c.name))
+	}
+	if len(all) > len(columns) {
+		d.insert += "(" + strings.Join(names, ",") + ")"
+	}
+	d.query = fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", strings.Join(exprs, ", "), quoteIdent(table), stableOrder(columns))
+	return d, nil
+}
+
+// dumpRows writes an INSERT statement for each row of a table, returning its checksum
+func dumpRows(ctx context.Context, tx *sql.Tx, w io.Writer, table dumpTable) (TableSum, error) {
+	sum := TableSum{Table: table.name}
+	rows, err := tx.QueryContext(ctx, table.query)
+	if err != nil {
+		return sum, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return sum, err
+	}
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	h := sha256.New()
+	var b strings.Builder
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return sum, err
+		}
+		b.Reset()
+		b.WriteString(table.insert)
+		b.WriteString(" VALUES(")
+		for i, v := range values {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(sqlLiteral(v))
+			hashSQLValue(h, v)
+		}
+		b.WriteString(");\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return sum, err
+		}
+		sum.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return sum, err
+	}
+	sum.Sum = hex.EncodeToString(h.Sum(nil))
+	return sum, nil
+}
+
+// dumpSequences writes the AUTOINCREMENT counters of the tables
+func dumpSequences(ctx context.Context, tx *sql.Tx, w io.Writer, tables []string) error {
+	names := make([]string, len(tables))
+	for i, name := range tables {
+		names[i] = quoteString(name)
+	}
+	q := fmt.Sprintf("SELECT name, seq FROM sqlite_sequence WHERE name IN (%s) ORDER BY rowid", strings.Join(names, ", "))
+	rows, err := tx.QueryContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	fmt.Fprintln(w, "DELETE FROM sqlite_sequence;")
+	for rows.Next() {
+		var name string
+		var seq int64
+		if err := rows.Scan(&name, &seq); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "INSERT INTO sqlite_sequence VALUES(%s,%d);\n", quoteString(name), seq)
+	}
+	return rows.Err()
+}
+
+// sqlLiteral returns a value as an SQL literal: blobs in hex, reals that read back as reals,
+// and line breaks in text as char() so each statement is on one line
+func sqlLiteral(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		switch {
+		case math.IsNaN(x):
+			return "NULL"
+		case math.IsInf(x, 1):
+			return "1e999"
+		case math.IsInf(x, -1):
+			return "-1e999"
+		}
+		s := strconv.FormatFloat(x, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case []byte:
+		return "X'" + hex.EncodeToString(x) + "'"
+	case string:
+		if !strings.ContainsAny(x, "\r\n") {
+			return quoteString(x)
+		}
+		var b strings.Builder
+		start := 0
+		for i := 0; i < len(x); i++ {
+			if c := x[i]; c == '\n' || c == '\r' {
+				if i > start {
+					b.WriteString(quoteString(x[start:i]))
+					b.WriteString("||")
+				}
+				fmt.Fprintf(&b, "char(%d)", c)
+				if i+1 < len(x) {
+					b.WriteString("||")
+				}
+				start = i + 1
+			}
+		}
+		if start < len(x) {
+			b.WriteString(quoteString(x[start:]))
+		}
+		return b.String()
+	}
+	return quoteString(fmt.Sprint(v))
+}
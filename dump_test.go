@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"math"
+	"strings"
+	"testing"
+)
+
+const dumpSchema = `
+create table people (id integer primary key autoincrement, name text not null, photo blob, score real, bio text);
+create table notes (n integer, body text, size integer generated always as (length(body)) virtual);
+create index people_name on people(name);
+create view names as select name from people;
+create trigger people_bio after insert on people when new.bio is null begin
+	update people set bio = 'none' where id = new.id;
+end;
+insert into people (name, photo, score, bio) values('alice', x'00ff10', 2.0, 'line one
+line two');
+insert into people (name, photo, score, bio) values('o''brien', null, 1.5e300, null);
+insert into notes (n, body) values(1, 'semi; colon'), (2, null);
+delete from people where id = 2;
+insert into people (name) values('carol');
+pragma user_version = 7;
+`
+
+func dumpDB(t *testing.T) *sql.DB {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(dumpSchema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDumpRoundTrip(t *testing.T) {
+	src := dumpDB(t)
+	var buf bytes.Buffer
+	var tables int64
+	err := DumpTo(src, &buf, DumpOptions{
+		Checksums: true,
+		Reporter:  ProgressFunc(func(done, total int64) { tables = done }),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := buf.String()
+	if tables != 2 {
+		t.Fatalf("expected 2 tables reported, got %d", tables)
+	}
+	for _, want := range []string{
+		"PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n",
+		`INSERT INTO "people" VALUES(1,'alice',X'00ff10',2.0,'line one'||char(10)||'line two');`,
+		`INSERT INTO "notes"("n","body") VALUES(1,'semi; colon');`,
+		"INSERT INTO sqlite_sequence VALUES('people',3);",
+		"PRAGMA user_version=7;",
+		"-- checksum: \"people\" rows=2",
+	} {
+		if !strings.Contains(script, want) {
+			t.Fatalf("dump is missing %q:\n%s", want, script)
+		}
+	}
+
+	dst := memDB(t)
+	dst.SetMaxOpenConns(1)
+	if err := Commands(dst, script, false, nil); err != nil {
+		t.Fatalf("%v:\n%s", err, script)
+	}
+	for _, table := range []string{"people", "notes"} {
+		a, err := TableChecksum(src, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := TableChecksum(dst, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != b {
+			t.Fatalf("table %s differs after restore: %v != %v", table, a, b)
+		}
+	}
+	var seq, version int
+	if err := dst.QueryRow("select seq from sqlite_sequence where name='people'").Scan(&seq); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.QueryRow("pragma user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if seq != 3 || version != 7 {
+		t.Fatalf("got sequence %d and user_version %d", seq, version)
+	}
+	var count int
+	if err := dst.QueryRow("select count(*) from names").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected the view to show 2 names, got %d", count)
+	}
+}
+
+func TestDumpTables(t *testing.T) {
+	src := dumpDB(t)
+	var buf bytes.Buffer
+	if err := Dump(src, &buf, "notes"); err != nil {
+		t.Fatal(err)
+	}
+	if script := buf.String(); strings.Contains(script, "people") || !strings.Contains(script, "CREATE TABLE notes") {
+		t.Fatalf("expected only notes:\n%s", script)
+	}
+
+	buf.Reset()
+	if err := DumpTo(src, &buf, DumpOptions{Exclude: []string{"people"}}); err != nil {
+		t.Fatal(err)
+	}
+	if script := buf.String(); strings.Contains(script, "people") || strings.Contains(script, "VIEW names") {
+		t.Fatalf("expected people and its view excluded:\n%s", script)
+	}
+	if err := Dump(src, &buf, "nonesuch"); err == nil {
+		t.Fatal("expected error for an unknown table")
+	}
+
+	buf.Reset()
+	if err := Commands(src, ".dump notes", false, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "semi; colon") {
+		t.Fatalf("expected .dump output, got:\n%s", buf.String())
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out string
+	}{
+		{nil, "NULL"},
+		{int64(-3), "-3"},
+		{3.0, "3.0"},
+		{0.25, "0.25"},
+		{math.Inf(1), "1e999"},
+		{math.NaN(), "NULL"},
+		{"it's", "'it''s'"},
+		{"\nend\r\n", "char(10)||'end'||char(13)||char(10)"},
+		{[]byte{0xde, 0xad}, "X'dead'"},
+	}
+	for _, tt := range tests {
+		if got := sqlLiteral(tt.in); got != tt.out {
+			t.Errorf("sqlLiteral(%v) = %s, want %s", tt.in, got, tt.out)
+		}
+	}
+}
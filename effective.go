@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"sync"
+)
+
+// dsns holds the DSN and config each database was opened with, until Close
+var dsns sync.Map
+
+// opened is how a database was opened
+type opened struct {
+	dsn    string
+	config *Config
+}
+
+// Settings is the resolved configuration of an open database
+type Settings struct {
+	Driver     string
+	DSN        string
+	Filename   string
+	Params     url.Values        // parameters given in the DSN
+	Pragmas    map[string]string // pragma values of a pooled connection
+	Functions  []string
+	Aggregates []string
+	Collations []string
+
+	Modules    []string
+
+	ConnectQuery     string // query run for each new connection
+	Hook             bool   // a connection hook is set
+	Hooks            bool   // update, commit, or rollback hooks, or change capture, are set
+	BusyHandler      bool
+	JSON             bool
+	Encrypted        bool
+	AccessStats      bool
+	ExclusiveProcess bool
+	Recovery         bool
+
+	Pool sql.DBStats
+}
+
+// configFor returns the config the driver of db was registered with
+func configFor(db *sql.DB) *Config {
+	imu.Lock()
+	defer imu.Unlock()
+	return configs[db.Driver()]
+}
+
+// EffectiveConfig reports the configuration of db as it is, rather than
+// as it was requested, which is where to start when a setting appears not to apply.
+// The connection settings are those of the driver the connections are made by,
+// which a handle opened without any of its own shares with earlier handles
+func EffectiveConfig(db *sql.DB) (*Settings, error) {
+	settings := &Settings{
+		Pool: db.Stats(),
+	}
+	var handle *Config
+	if v, ok := dsns.Load(db); ok {
+		handle = v.(opened).config
+		settings.DSN = v.(opened).dsn
+		parsed, err := ParseDSN(settings.DSN)
+		if err != nil {
+			return nil, err
+		}
+		settings.Params = parsed.Params
+	}
+	config := configFor(db)
+	if handle == nil {
+		handle = config
+	} else if config == nil {
+		config = handle
+	}
+	if handle != nil {
+		settings.Driver = handle.driver
+		settings.ExclusiveProcess = handle.exclusive
+		settings.Recovery = handle.recover
+	}
+	if config != nil {
+		settings.ConnectQuery = config.query
+		settings.Hook = config.hook != nil
+		settings.Hooks = config.hooks.set()
+		settings.BusyHandler = config.busy != nil
+		settings.JSON = config.json
+		settings.Encrypted = config.currentKey() != nil
+		settings.AccessStats = config.access != nil
+		for _, fn := range config.funcs {
+			settings.Functions = append(settings.Functions, fn.Name)
+		}
+		for _, agg := range config.aggregates {
+			settings.Aggregates = append(settings.Aggregates, agg.Name)
+		}
+		for _, c := range config.collations {
+			settings.Collations = append(settings.Collations, c.Name)
+		}
+		for _, m := range config.modules {
+			settings.Modules = append(settings.Modules, m.name)
+		}
+	}
+
+	// read every pragma from the same connection
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var seq, name string
+	if err := conn.QueryRowContext(context.Background(), "PRAGMA database_list").Scan(&seq, &name, &settings.Filename); err != nil {
+		return nil, err
+	}
+	settings.Pragmas = pragmaValues(context.Background(), conn)
+	return settings, nil
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEffectiveConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "effective.db") + "?_busy_timeout=1234"
+	db, err := Open(file,
+		WithDriver("effective_config"),
+		WithQuery("pragma foreign_keys=on"),
+		WithFunctions(MoneyFuncs...),
+		WithCollations(UnicodeCollations...),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	db.SetMaxOpenConns(3)
+
+	settings, err := EffectiveConfig(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Driver != "effective_config" || settings.DSN != file {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+	if settings.Params.Get("_busy_timeout") != "1234" || settings.Pragmas["busy_timeout"] != "1234" {
+		t.Fatalf("unexpected busy timeout: %v / %v", settings.Params, settings.Pragmas["busy_timeout"])
+	}
+	if settings.Pragmas["foreign_keys"] != "1" || settings.ConnectQuery == "" {
+		t.Fatalf("unexpected foreign keys: %q", settings.Pragmas["foreign_keys"])
+	}
+	if len(settings.Functions) != len(MoneyFuncs) || settings.Functions[0] != MoneyFuncs[0].Name {
+		t.Fatalf("unexpected functions: %q", settings.Functions)
+	}
+	if len(settings.Collations) != len(UnicodeCollations) {
+		t.Fatalf("unexpected collations: %q", settings.Collations)
+	}
+	if settings.Pool.MaxOpenConnections != 3 || filepath.Base(settings.Filename) != "effective.db" {
+		t.Fatalf("unexpected pool: %+v (%s)", settings.Pool, settings.Filename)
+	}
+}
+
+func TestEffectiveConfigHandle(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Open(filepath.Join(dir, "first.db"), WithDriver("effective_handle"), WithFunctions(MoneyFuncs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(first)
+
+	// the settings of the handle are its own, even when it shares the driver
+	second, err := Open(filepath.Join(dir, "second.db"), WithDriver("effective_handle"), WithExclusiveProcess())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(second)
+	settings, err := EffectiveConfig(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !settings.ExclusiveProcess || len(settings.Functions) != len(MoneyFuncs) {
+		t.Fatalf("expected an exclusive handle with the shared functions: %+v", settings)
+	}
+	if settings, err = EffectiveConfig(first); err != nil || settings.ExclusiveProcess {
+		t.Fatalf("expected the first handle not to be exclusive: %+v (%v)", settings, err)
+	}
+
+	third, err := Open(filepath.Join(dir, "third.db"), WithDriver("effective_handle"),
+		WithBusyHandler(func(int, time.Duration) bool { return false }),
+		WithUpdateHook(func(UpdateEvent) {}),
+		WithJSON(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(third)
+	if settings, err = EffectiveConfig(third); err != nil {
+		t.Fatal(err)
+	}
+	if !settings.BusyHandler || !settings.Hooks || !settings.JSON || settings.Encrypted || len(settings.Functions) != 0 {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+}
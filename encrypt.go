@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// ErrNoEncryption is returned when a key is given but the SQLite library
+// was built without an encryption codec, such as SQLCipher or SEE
+var ErrNoEncryption = errors.New("sqlite library has no encryption support")
+
+// keyMu guards the keys of configs, which Rekey changes
+var keyMu sync.Mutex
+
+// WithKey sets the encryption key of each new connection, before anything else is done
+// with it, which requires a build of the driver linked with SQLCipher or SEE.
+// Without one, Open fails with ErrNoEncryption rather than leave the data unencrypted.
+// The key is kept by the handle alone, as WithScope does, so it is never lost to
+// settings already registered for the driver name
+func WithKey(key []byte) Optional {
+	return func(c *Config) {
+		c.key = append([]byte(nil), key...)
+		c.scoped = true
+	}
+}
+
+func (c *Config) currentKey() []byte {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	return c.key
+}
+
+// keyConn sets the key of a new connection, and checks that it took
+func keyConn(conn *sqlite3.SQLiteConn, key []byte) error {
+	if !hasCodec(conn) {
+		return ErrNoEncryption
+	}
+	// hexkey takes any bytes, and is understood by both SQLCipher and SEE
+	if _, err := conn.Exec(fmt.Sprintf("PRAGMA hexkey = '%s'", hex.EncodeToString(key)), nil); err != nil {
+		return fmt.Errorf("setting key: %w", err)
+	}
+	// the key is only checked when the database is read
+	if _, err := conn.Exec("SELECT count(*) FROM sqlite_master", nil); err != nil {
+		return fmt.Errorf("wrong key or not a database: %w", err)
+	}
+	return nil
+}
+
+// hasCodec reports whether the library can encrypt databases
+func hasCodec(conn *sqlite3.SQLiteConn) bool {
+	var found bool
+	fn := func(cols []string, row int, values []driver.Value) error {
+		found = true
+		return nil
+	}
+	// SQLCipher reports its version, and SEE builds are compiled with HAS_CODEC
+	if err := connQuery(conn, fn, "PRAGMA cipher_version"); err == nil && found {
+		return true
+	}
+	err := connQuery(conn, func(cols []string, row int, values []driver.Value) error {
+		if option, ok := values[0].(string); ok && option == "HAS_CODEC" {
+			found = true
+		}
+		return nil
+	}, "PRAGMA compile_options")
+	return err == nil && found
+}
+
+// Rekey changes the encryption key of a database opened with WithKey. New connections use
+// the new key, but connections already open with the old one can fail to read, so it is
+// best done while the database is otherwise idle, and followed by db.SetMaxIdleConns(0)
+// or reopening the database
+func Rekey(db *sql.DB, newKey []byte) error {
+	config := configFor(db)
+	if config == nil || config.currentKey() == nil {
+		return fmt.Errorf("database was not opened with a key")
+	}
+	return rawConn(context.Background(), db, func(conn *sqlite3.SQLiteConn) error {
+		if _, err := conn.Exec(fmt.Sprintf("PRAGMA hexrekey = '%s'", hex.EncodeToString(newKey)), nil); err != nil {
+			return fmt.Errorf("rekey: %w", err)
+		}
+		keyMu.Lock()
+		config.key = append([]byte(nil), newKey...)
+		keyMu.Unlock()
+		return nil
+	})
+}
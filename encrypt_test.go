@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "secret.db")
+	db, err := Open(file, WithDriver("with_key"), WithKey([]byte("secret")))
+	if err == nil {
+		// the driver was built with an encryption codec
+		defer db.Close()
+		if _, err := db.Exec("create table t (n int)"); err != nil {
+			t.Fatal(err)
+		}
+		if err := Rekey(db, []byte("new secret")); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if !errors.Is(err, ErrNoEncryption) {
+		t.Fatalf("expected ErrNoEncryption but got: %v", err)
+	}
+
+	plain := memDB(t)
+	defer plain.Close()
+	if err := Rekey(plain, []byte("secret")); err == nil {
+		t.Fatal("expected error rekeying a database opened without a key")
+	}
+}
+
+func TestWithKeySharedDriver(t *testing.T) {
+	dir := t.TempDir()
+	plain, err := Open(filepath.Join(dir, "plain.db"), WithDriver("with_key_shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+
+	// the driver name is already registered without a key, which must not be used
+	db, err := Open(filepath.Join(dir, "secret.db"), WithDriver("with_key_shared"), WithKey([]byte("secret")))
+	if err == nil {
+		defer db.Close()
+		if config := configFor(db); config == nil || config.currentKey() == nil {
+			t.Fatal("expected the handle to keep its key")
+		}
+		return
+	}
+	if !errors.Is(err, ErrNoEncryption) {
+		t.Fatalf("expected ErrNoEncryption but got: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// EvalTimeout bounds the time an expression given to Eval can run
+var EvalTimeout = time.Second
+
+// Eval evaluates a single scalar SQL expression, such as a formula supplied by a user,
+// and returns its value. Positional arguments are bound to the expression's parameters.
+//
+// The expression may call functions, including the custom functions of the driver,
+// but may not read tables or do anything else than compute a value: it runs under
+// an authorizer that denies every other action, and is interrupted after EvalTimeout
+func Eval(db *sql.DB, expr string, args ...interface{}) (interface{}, error) {
+	return EvalContext(context.Background(), db, expr, args...)
+}
+
+// EvalContext is Eval with a context
+func EvalContext(ctx context.Context, db *sql.DB, expr string, args ...interface{}) (interface{}, error) {
+	stmts, err := ParseScript("SELECT " + expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) != 1 || stmts[0].Command {
+		return nil, fmt.Errorf("not a single expression: %s", expr)
+	}
+	if EvalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, EvalTimeout)
+		defer cancel()
+	}
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("eval %s: argument %d: %w", expr, i+1, err)
+		}
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+
+	var result interface{}
+	err = rawConn(ctx, db, func(conn *sqlite3.SQLiteConn) error {
+		conn.RegisterAuthorizer(sandbox)
+		defer restoreAuthorizer(db, conn)
+
+		rows, err := conn.QueryContext(ctx, stmts[0].Text, values)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if cols := rows.Columns(); len(cols) != 1 {
+			return fmt.Errorf("expected a single expression but got %d", len(cols))
+		}
+		dest := make([]driver.Value, 1)
+		if err := rows.Next(dest); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("expression has no value")
+			}
+			return err
+		}
+		result = dest[0]
+		if err := rows.Next(dest); err != io.EOF {
+			if err == nil {
+				err = fmt.Errorf("expression has more than one value")
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("eval %s: %w", expr, err)
+	}
+	return result, nil
+}
+
+// sandbox is an authorizer that allows nothing but selecting values and calling functions
+func sandbox(op int, arg1, arg2, dbName string) int {
+	switch op {
+	case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_FUNCTION:
+		return sqlite3.SQLITE_OK
+	}
+	return sqlite3.SQLITE_DENY
+}
+
+// restoreAuthorizer puts back the authorizer the connection was opened with, if any
+func restoreAuthorizer(db *sql.DB, conn *sqlite3.SQLiteConn) {
+	if config := configFor(db); config != nil && config.access != nil {
+		conn.RegisterAuthorizer(config.access.authorizer())
+		return
+	}
+	conn.RegisterAuthorizer(nil)
+}
@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	v, err := Eval(db, "round(? * 1.5 + length(?), 1)", 4, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 9.0 {
+		t.Fatalf("expected: %v but got: %v", 9.0, v)
+	}
+
+	for _, expr := range []string{
+		"(select count(*) from structs)",
+		"1; delete from structs",
+		"1, 2",
+		"1 union all select 2",
+		"(select name from sqlite_master)",
+	} {
+		if _, err := Eval(db, expr); err == nil {
+			t.Fatalf("expected %q to be refused", expr)
+		}
+	}
+
+	// the connection is usable as before
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from structs"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("expected: %d but got: %d", 4, count)
+	}
+
+	if _, err := EvalContext(context.Background(), db, "(with recursive c(x) as (select 1 union all select x+1 from c) select max(x) from c)"); err == nil {
+		t.Fatal("expected recursive query to be refused")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := EvalContext(ctx, db, "1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled eval but got: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Dialect is the flavor of SQL used by another database
+type Dialect string
+
+// Supported dialects
+const (
+	DialectSQLite   Dialect = "sqlite"
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+)
+
+// quote quotes an identifier for the dialect
+func (d Dialect) quote(name string) string {
+	if d == DialectMySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return quoteIdent(name)
+}
+
+// placeholder returns the nth (from 1) query parameter for the dialect
+func (d Dialect) placeholder(n int) string {
+	if d == DialectPostgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// columnType returns the dialect's type for a sqlite column type
+func (d Dialect) columnType(sqliteType string) string {
+	aff := affinity(sqliteType)
+	if aff == "" && sqliteType == "" {
+		aff = "BLOB"
+	}
+	switch d {
+	case DialectPostgres:
+		switch aff {
+		case "INTEGER":
+			return "BIGINT"
+		case "REAL":
+			return "DOUBLE PRECISION"
+		case "BLOB":
+			return "BYTEA"
+		case "NUMERIC":
+			return "NUMERIC"
+		}
+		return "TEXT"
+	case DialectMySQL:
+		switch aff {
+		case "INTEGER":
+			return "BIGINT"
+		case "REAL":
+			return "DOUBLE"
+		case "BLOB":
+			return "LONGBLOB"
+		case "NUMERIC":
+			return "DECIMAL(65,10)"
+		}
+		return "LONGTEXT"
+	}
+	return sqliteType
+}
+
+// ExportOptions control ExportTo
+type ExportOptions struct {
+	Dialect   Dialect  // the dialect of the target, DialectSQLite if not set
+	Tables    []string // the tables to export, all if not set
+	BatchSize int      // rows inserted per transaction, ImportBatchSize if not set
+
+	// Resume skips the rows of each table already in the target, continuing an
+	// interrupted export. It relies on the source being unchanged in between
+	Resume bool
+
+	// Progress, if set, is called after each batch with the rows copied so far
+	Progress func(table string, copied int64)
+
+	Reporter Progress // reports on the export as a whole, in rows copied of all tables
+}
+
+// exportColumn is a column of a table being exported
+type exportColumn struct {
+	name    string
+	ctype   string
+	notNull bool
+	pk      int
+}
+
+// exportColumns returns the columns of a table
+func exportColumns(db *sql.DB, table string) ([]exportColumn, error) {
+	rows, err := PragmaRows(db, fmt.Sprintf("table_info(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]exportColumn, len(rows))
+	for i, r := range rows {
+		c := &columns[i]
+		var notNull int
+		if err := pragmaInts("table_info", r, nil, nil, nil, &notNull, nil, &c.pk); err != nil {
+			return nil, err
+		}
+		c.name, c.ctype, c.notNull = r[1], r[2], notNull != 0
+	}
+	return columns, nil
+}
+
+// stableOrder returns the ORDER BY terms that read a table in the same order each time
+func stableOrder(columns []exportColumn) string {
+	keys := make([]string, len(columns))
+	n := 0
+	for _, c := range columns {
+		if c.pk > 0 && c.pk <= len(keys) {
+			keys[c.pk-1] = quoteIdent(c.name)
+			n++
+		}
+	}
+	keys = keys[:n]
+	if n == 0 {
+		return "rowid"
+	}
+	return strings.Join(keys, ", ")
+}
+
+// exportDDL returns the statement creating the table in the target dialect
+func exportDDL(d Dialect, table string, columns []exportColumn) string {
+	defs := make([]string, 0, len(columns)+1)
+	var keys []string
+	for _, c := range columns {
+		def := d.quote(c.name) + " " + d.columnType(c.ctype)
+		if c.notNull {
+			def += " NOT NULL"
+		}
+		defs = append(defs, strings.TrimSpace(def))
+		if c.pk > 0 {
+			keys = append(keys, d.quote(c.name))
+		}
+	}
+	if len(keys) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(keys, ", ")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", d.quote(table), strings.Join(defs, ", "))
+}
+
+// userTables returns the tables of db, excluding internal ones
+func userTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// ExportTo copies tables of src into a database of another driver, creating
+// them if they don't exist, and returns the number of rows copied.
+// Without a list of tables, every table is copied, each after those it references
+func ExportTo(src, dst *sql.DB, opts ExportOptions) (total int64, err error) {
+	report := reporting(opts.Reporter)
+	report.OnStart("export")
+	defer func() { report.OnFinish(err) }()
+
+	if opts.Dialect == "" {
+		opts.Dialect = DialectSQLite
+	}
+	tables := opts.Tables
+	if len(tables) == 0 {
+		if tables, err = schemaTables(src); err != nil {
+			return 0, err
+		}
+	}
+	var done, current int64
+	if opts.Reporter != nil {
+		rows := int64(0)
+		for _, table := range tables {
+			var n int64
+			if err := src.QueryRow("SELECT count(*) FROM " + quoteIdent(table)).Scan(&n); err != nil {
+				return 0, fmt.Errorf("export %s: %w", table, err)
+			}
+			rows += n
+		}
+		report.OnProgress(0, rows)
+		progress := opts.Progress
+		opts.Progress = func(table string, copied int64) {
+			if progress != nil {
+				progress(table, copied)
+			}
+			current = copied
+			report.OnProgress(done+current, rows)
+		}
+	}
+	for _, table := range tables {
+		n, err := exportTable(src, dst, table, opts)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("export %s: %w", table, err)
+		}
+		done, current = done+current, 0
+	}
+	return total, nil
+}
+
+func exportTable(src, dst *sql.DB, table string, opts ExportOptions) (int64, error) {
+	d := opts.Dialect
+	columns, err := exportColumns(src, table)
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("no such table")
+	}
+	if _, err := dst.Exec(exportDDL(d, table, columns)); err != nil {
+		return 0, err
+	}
+
+	var skip int64
+	if opts.Resume {
+		if err := dst.QueryRow("SELECT count(*) FROM " + d.quote(table)).Scan(&skip); err != nil {
+			return 0, err
+		}
+	}
+	names := make([]string, len(columns))
+	targets := make([]string, len(columns))
+	marks := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = quoteIdent(c.name)
+		targets[i] = d.quote(c.name)
+		marks[i] = d.placeholder(i + 1)
+	}
+	// rows are read in a stable order so an export can resume where it stopped
+	q := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT -1 OFFSET ?", strings.Join(names, ", "), quoteIdent(table), stableOrder(columns))
+	rows, err := src.Query(q, skip)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)", d.quote(table), strings.Join(targets, ", "), strings.Join(marks, ", "))
+
+	batch := batchSize(opts.BatchSize)
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var count int64
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	commit := func() error {
+		stmt.Close()
+		err := tx.Commit()
+		tx = nil
+		if err == nil && opts.Progress != nil {
+			opts.Progress(table, skip+count)
+		}
+		return err
+	}
+	for rows.Next() {
+		if tx == nil {
+			if tx, err = dst.Begin(); err != nil {
+				return count, err
+			}
+			if stmt, err = tx.Prepare(insert); err != nil {
+				return count, err
+			}
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return count, err
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			return count, err
+		}
+		count++
+		if count%int64(batch) == 0 {
+			if err := commit(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	if tx != nil {
+		return count, commit()
+	}
+	return count, nil
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExportTo(t *testing.T) {
+	dir := t.TempDir()
+	src, err := Open(filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := Open(filepath.Join(dir, "dst.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	const setup = `
+	create table items (id integer primary key, name text not null, price real, data blob);
+	create table tags (item text, tag text, primary key (tag, item)) without rowid;
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 45)
+	insert into items select n, 'item ' || n, n * 1.5, randomblob(4) from seq;
+	insert into tags values ('a', 'x'), ('b', 'x'), ('a', 'y');
+	`
+	if _, err := src.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []int64
+	opts := ExportOptions{
+		BatchSize: 20,
+		Progress: func(table string, copied int64) {
+			if table == "items" {
+				progress = append(progress, copied)
+			}
+		},
+	}
+	n, err := ExportTo(src, dst, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 48 || len(progress) != 3 || progress[2] != 45 {
+		t.Fatalf("unexpected export: %d rows, progress %v", n, progress)
+	}
+
+	// lose the end of the export, then resume it
+	if _, err := dst.Exec("delete from items where id > 30"); err != nil {
+		t.Fatal(err)
+	}
+	opts.Resume, opts.Tables, opts.Progress = true, []string{"items", "tags"}, nil
+	if n, err = ExportTo(src, dst, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n != 15 {
+		t.Fatalf("expected 15 resumed rows but got: %d", n)
+	}
+	var count int
+	var sum float64
+	if err := row(dst, []interface{}{&count, &sum}, "select count(*), sum(price) from items"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 45 || sum != 1552.5 {
+		t.Fatalf("unexpected items: %d %v", count, sum)
+	}
+
+	if _, err := ExportTo(src, dst, ExportOptions{Tables: []string{"nosuch"}}); err == nil {
+		t.Fatal("expected error for missing table")
+	}
+}
+
+func TestExportDDL(t *testing.T) {
+	columns := []exportColumn{
+		{name: "id", ctype: "INTEGER", pk: 1},
+		{name: "name", ctype: "TEXT", notNull: true},
+		{name: "data", ctype: "BLOB"},
+		{name: "price", ctype: "REAL"},
+	}
+	for d, expected := range map[Dialect]string{
+		DialectPostgres: `CREATE TABLE IF NOT EXISTS "items" ("id" BIGINT, "name" TEXT NOT NULL, "data" BYTEA, "price" DOUBLE PRECISION, PRIMARY KEY ("id"))`,
+		DialectMySQL:    "CREATE TABLE IF NOT EXISTS `items` (`id` BIGINT, `name` LONGTEXT NOT NULL, `data` LONGBLOB, `price` DOUBLE, PRIMARY KEY (`id`))",
+		DialectSQLite:   `CREATE TABLE IF NOT EXISTS "items" ("id" INTEGER, "name" TEXT NOT NULL, "data" BLOB, "price" REAL, PRIMARY KEY ("id"))`,
+	} {
+		if got := exportDDL(d, "items", columns); got != expected {
+			t.Errorf("%s expected: %s\nbut got: %s", d, expected, got)
+		}
+	}
+	if p := DialectPostgres.placeholder(3); p != "$3" {
+		t.Fatalf("expected: $3 but got: %s", p)
+	}
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Features are the optional capabilities of the sqlite library
+type Features struct {
+	JSON1             bool
+	FTS3              bool // includes FTS4
+	FTS5              bool
+	RTree             bool
+	Geopoly           bool
+	Session           bool
+	PreUpdateHook     bool
+	DBStat            bool
+	ColumnMetadata    bool
+	Stat4             bool
+	MathFunctions     bool
+	UpdateDeleteLimit bool // DELETE and UPDATE accept ORDER BY and LIMIT
+
+	// Options are the compile options, with the SQLITE_ prefix removed,
+	// mapped to their value, if any
+	Options map[string]string
+}
+
+// compileOptions returns the compile options of the library
+func compileOptions(db *sql.DB) (map[string]string, error) {
+	rows, err := db.Query("PRAGMA compile_options")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	options := make(map[string]string)
+	for rows.Next() {
+		var option string
+		if err := rows.Scan(&option); err != nil {
+			return nil, err
+		}
+		name, value := option, ""
+		if i := strings.Index(option, "="); i > 0 {
+			name, value = option[:i], option[i+1:]
+		}
+		options[name] = value
+	}
+	return options, rows.Err()
+}
+
+// featureOption returns the compile option for a feature name such as "fts5"
+func featureOption(feature string) string {
+	feature = strings.TrimPrefix(strings.ToUpper(feature), "SQLITE_")
+	switch feature {
+	case "FTS4":
+		return "ENABLE_FTS3"
+	case "MATH":
+		return "ENABLE_MATH_FUNCTIONS"
+	case "SESSIONS":
+		return "ENABLE_SESSION"
+	}
+	return feature
+}
+
+// Has reports whether the library has a feature, given as a compile option
+// with or without its SQLITE_ or ENABLE_ prefix (e.g., "fts5", "ENABLE_RTREE", "THREADSAFE")
+func Has(db *sql.DB, feature string) bool {
+	options, err := compileOptions(db)
+	if err != nil {
+		return false
+	}
+	return hasOption(db, options, featureOption(feature))
+}
+
+func hasOption(db *sql.DB, options map[string]string, option string) bool {
+	switch strings.TrimPrefix(option, "ENABLE_") {
+	case "UPDATE_DELETE_LIMIT":
+		// the option only applies when the parser is rebuilt,
+		// so it is reported by builds that don't support it
+		return deleteLimit(db)
+	case "JSON1":
+		// built in, without the option, since 3.38
+		_, err := db.Exec("SELECT json_valid('1')")
+		return err == nil
+	}
+	if _, ok := options[option]; ok {
+		return true
+	}
+	_, ok := options["ENABLE_"+option]
+	return ok
+}
+
+// deleteLimit reports whether DELETE accepts a LIMIT clause
+func deleteLimit(db *sql.DB) bool {
+	stmt, err := db.Prepare("DELETE FROM sqlite_temp_master LIMIT 1")
+	if err == nil {
+		stmt.Close()
+		return true
+	}
+	return !strings.Contains(err.Error(), "syntax error")
+}
+
+// DetectFeatures returns the features of the library
+func DetectFeatures(db *sql.DB) (Features, error) {
+	options, err := compileOptions(db)
+	if err != nil {
+		return Features{}, err
+	}
+	has := func(option string) bool {
+		return hasOption(db, options, option)
+	}
+	return Features{
+		JSON1:             has("JSON1"),
+		FTS3:              has("FTS3"),
+		FTS5:              has("FTS5"),
+		RTree:             has("RTREE"),
+		Geopoly:           has("GEOPOLY"),
+		Session:           has("SESSION"),
+		PreUpdateHook:     has("PREUPDATE_HOOK"),
+		DBStat:            has("DBSTAT_VTAB"),
+		ColumnMetadata:    has("COLUMN_METADATA"),
+		Stat4:             has("STAT4"),
+		MathFunctions:     has("MATH_FUNCTIONS"),
+		UpdateDeleteLimit: has("UPDATE_DELETE_LIMIT"),
+		Options:           options,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package sqlite
+
+import "testing"
+
+func TestFeatures(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	features, err := DetectFeatures(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !features.FTS3 || !features.RTree || features.Options["THREADSAFE"] == "" {
+		t.Fatalf("unexpected features: %+v", features)
+	}
+	// reported by compile_options, but not supported by the amalgamation
+	if _, ok := features.Options["ENABLE_UPDATE_DELETE_LIMIT"]; ok && features.UpdateDeleteLimit {
+		if _, err := db.Exec("create table t (n integer); delete from t limit 1"); err != nil {
+			t.Fatalf("delete limit reported but failed: %v", err)
+		}
+	}
+
+	for feature, expected := range map[string]bool{
+		"fts3":              true,
+		"FTS4":              true,
+		"ENABLE_RTREE":      true,
+		"SQLITE_THREADSAFE": true,
+		"fts5":              features.FTS5,
+		"json1":             features.JSON1,
+		"no_such_feature":   false,
+	} {
+		if got := Has(db, feature); got != expected {
+			t.Errorf("%s expected: %v but got: %v", feature, expected, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrUnsafeFilesystem is returned by Open for a database on a filesystem where
+// SQLite's file locking is unreliable, when opened WithFilesystemCheck(true)
+var ErrUnsafeFilesystem = errors.New("filesystem locking is unreliable")
+
+// DetectUnsafeFilesystem returns the type of the filesystem holding path if it's
+// one where SQLite's file locking can't be relied on, such as NFS, SMB, or an
+// overlay shared between containers, or "" if it isn't. Two processes writing to
+// a database on such a filesystem can corrupt it. If path doesn't exist, the
+// filesystem of the directory it would be created in is checked
+func DetectUnsafeFilesystem(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(path); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			break
+		}
+		path = parent
+	}
+	return unsafeFilesystem(path)
+}
+
+// WithFilesystemCheck checks the filesystem of the database when it's opened,
+// logging a warning if it's unsafe, or if refuse is set, failing with
+// ErrUnsafeFilesystem
+func WithFilesystemCheck(refuse bool) Optional {
+	return func(c *Config) {
+		c.fsCheck = true
+		c.fsRefuse = refuse
+	}
+}
+
+// checkFilesystem applies the filesystem check of the config to a database file
+func (c *Config) checkFilesystem(filename string) error {
+	if !c.fsCheck {
+		return nil
+	}
+	fs, err := DetectUnsafeFilesystem(filename)
+	if err != nil || fs == "" {
+		return err
+	}
+	if c.fsRefuse {
+		return redactf("%s is on %s: %w", filename, fs, ErrUnsafeFilesystem)
+	}
+	logf("warning: %s is on %s, where file locking is unreliable\n", filename, fs)
+	return nil
+}
@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"bytes"
+	"syscall"
+)
+
+// unsafeFilesystems are the filesystems that don't lock reliably
+var unsafeFilesystems = map[string]bool{
+	"nfs":    true,
+	"smbfs":  true,
+	"afpfs":  true,
+	"webdav": true,
+}
+
+func unsafeFilesystem(path string) (string, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return "", err
+	}
+	name := make([]byte, 0, len(fs.Fstypename))
+	for _, c := range fs.Fstypename {
+		name = append(name, byte(c))
+	}
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	if unsafeFilesystems[string(name)] {
+		return string(name), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,24 @@
+package sqlite
+
+import "syscall"
+
+// unsafeFilesystems are the filesystems, by their magic numbers, that don't lock reliably
+var unsafeFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x794c7630: "overlayfs",
+	0x01021997: "9p",
+	0x5346414f: "afs",
+	0x00c36400: "ceph",
+}
+
+func unsafeFilesystem(path string) (string, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return "", err
+	}
+	// the magic number is signed on 32-bit systems, so it is read as its 32 bits
+	return unsafeFilesystems[uint32(fs.Type)], nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package sqlite
+
+// unsafeFilesystem can't tell the filesystem on this platform
+func unsafeFilesystem(path string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectUnsafeFilesystem(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "missing", "fs.db")
+	fs, err := DetectUnsafeFilesystem(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(file, WithFilesystemCheck(true))
+	if fs != "" {
+		// the test is running on an unsafe filesystem, such as a container's overlay
+		if !errors.Is(err, ErrUnsafeFilesystem) {
+			t.Fatalf("expected the open to be refused on %s, got %v", fs, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+}
@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const driveRemote = 4
+
+var procGetDriveType = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDriveTypeW")
+
+// unsafeFilesystem reports shares, whether by UNC path or mapped to a drive letter
+func unsafeFilesystem(path string) (string, error) {
+	volume := filepath.VolumeName(path)
+	if strings.HasPrefix(volume, `\\`) {
+		return "smb", nil
+	}
+	root, err := syscall.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return "", err
+	}
+	if kind, _, _ := procGetDriveType.Call(uintptr(unsafe.Pointer(root))); kind == driveRemote {
+		return "smb", nil
+	}
+	return "", nil
+}
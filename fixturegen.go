@@ -0,0 +1,249 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FixtureOptions control the code written by GenerateFixture
+type FixtureOptions struct {
+	Package string // the package of the generated file
+	Var     string // the variable holding the rows
+	// Type is the struct type of the rows, declared with a field for each column.
+	// If empty the rows are written as []interface{} values, with the column names
+	// in a variable named after Var with the suffix "Columns"
+	Type string
+}
+
+// GenerateFixture writes Go source declaring the results of a query as a slice
+// literal, to embed a small lookup dataset in a program at compile time. The type
+// of each column follows its values: int64, float64 for a mix of integers and reals,
+// string, []byte, bool, or time.Time, or its sql.Null type if some are NULL, or
+// interface{} for a mix of anything else
+func GenerateFixture(db *sql.DB, w io.Writer, opts FixtureOptions, query string, args ...interface{}) error {
+	if opts.Package == "" {
+		return fmt.Errorf("no package given")
+	}
+	if opts.Var == "" {
+		return fmt.Errorf("no variable given")
+	}
+	rows, err := db.Query(query, Args(args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var data [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		data = append(data, values)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	types := make([]string, len(columns))
+	imports := make(map[string]bool)
+	for i := range columns {
+		types[i] = fixtureType(data, i)
+		if strings.HasPrefix(types[i], "sql.") {
+			imports["database/sql"] = true
+		}
+		if strings.Contains(types[i], "Time") {
+			imports["time"] = true
+		}
+	}
+	if opts.Type == "" {
+		for _, row := range data {
+			for _, v := range row {
+				if _, ok := v.(time.Time); ok {
+					imports["time"] = true
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by sqlite.GenerateFixture; DO NOT EDIT.\n\npackage %s\n\n", opts.Package)
+	if len(imports) > 0 {
+		buf.WriteString("import (\n")
+		for _, imp := range []string{"database/sql", "time"} {
+			if imports[imp] {
+				fmt.Fprintf(&buf, "\t%q\n", imp)
+			}
+		}
+		buf.WriteString(")\n\n")
+	}
+	if opts.Type != "" {
+		fields := make([]string, len(columns))
+		seen := make(map[string]int)
+		fmt.Fprintf(&buf, "// %s is a row of %s\ntype %s struct {\n", opts.Type, opts.Var, opts.Type)
+		for i, name := range columns {
+			field := goName(name)
+			if n := seen[field]; n > 0 {
+				field = fmt.Sprintf("%s%d", field, n+1)
+			}
+			seen[field]++
+			fields[i] = field
+			fmt.Fprintf(&buf, "\t%s %s `db:%q`\n", field, types[i], name)
+		}
+		fmt.Fprintf(&buf, "}\n\n// %s holds the results of: %s\nvar %s = []%s{\n", opts.Var, oneLine(query), opts.Var, opts.Type)
+		for _, row := range data {
+			buf.WriteString("\t{")
+			for i, v := range row {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				fmt.Fprintf(&buf, "%s: %s", fields[i], goLiteral(v, types[i]))
+			}
+			buf.WriteString("},\n")
+		}
+		buf.WriteString("}\n")
+	} else {
+		quoted := make([]string, len(columns))
+		for i, name := range columns {
+			quoted[i] = strconv.Quote(name)
+		}
+		fmt.Fprintf(&buf, "// %sColumns are the columns of %s\nvar %sColumns = []string{%s}\n\n", opts.Var, opts.Var, opts.Var, strings.Join(quoted, ", "))
+		fmt.Fprintf(&buf, "// %s holds the results of: %s\nvar %s = [][]interface{}{\n", opts.Var, oneLine(query), opts.Var)
+		for _, row := range data {
+			values := make([]string, len(row))
+			for i, v := range row {
+				values[i] = goLiteral(v, "interface{}")
+			}
+			fmt.Fprintf(&buf, "\t{%s},\n", strings.Join(values, ", "))
+		}
+		buf.WriteString("}\n")
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("generated code does not compile: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// oneLine collapses the white space of a query, for a comment
+func oneLine(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// fixtureType returns the Go type for the values of a column
+func fixtureType(data [][]interface{}, col int) string {
+	kinds := make(map[string]bool)
+	var null bool
+	for _, row := range data {
+		switch v := row[col].(type) {
+		case nil:
+			null = true
+		case int64:
+			kinds["int64"] = true
+		case float64:
+			kinds["float64"] = true
+		case string:
+			kinds["string"] = true
+		case []byte:
+			kinds["[]byte"] = true
+		case bool:
+			kinds["bool"] = true
+		case time.Time:
+			kinds["time.Time"] = true
+		default:
+			kinds[fmt.Sprintf("%T", v)] = true
+		}
+	}
+	if len(kinds) == 2 && kinds["int64"] && kinds["float64"] {
+		delete(kinds, "int64")
+	}
+	if len(kinds) != 1 {
+		return "interface{}"
+	}
+	var kind string
+	for kind = range kinds {
+	}
+	if !null {
+		return kind
+	}
+	switch kind {
+	case "int64":
+		return "sql.NullInt64"
+	case "float64":
+		return "sql.NullFloat64"
+	case "string":
+		return "sql.NullString"
+	case "bool":
+		return "sql.NullBool"
+	case "time.Time":
+		return "sql.NullTime"
+	}
+	return kind
+}
+
+// goLiteral returns the Go source for a value as a Go type given by fixtureType
+func goLiteral(v interface{}, goType string) string {
+	if v == nil {
+		switch goType {
+		case "interface{}", "[]byte":
+			return "nil"
+		}
+		return goType + "{}"
+	}
+	if strings.HasPrefix(goType, "sql.Null") {
+		field := strings.TrimPrefix(goType, "sql.Null")
+		if field == "Float64" {
+			v = toFloat(v)
+		}
+		return fmt.Sprintf("%s{%s: %s, Valid: true}", goType, field, goLiteral(v, strings.ToLower(field[:1])+field[1:]))
+	}
+	switch v := v.(type) {
+	case int64:
+		if goType == "float64" {
+			return goLiteral(float64(v), goType)
+		}
+		if goType == "interface{}" {
+			return fmt.Sprintf("int64(%d)", v)
+		}
+		return strconv.FormatInt(v, 10)
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if goType == "interface{}" && !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return fmt.Sprintf("[]byte(%q)", v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		v = v.UTC()
+		return fmt.Sprintf("time.Date(%d, time.%s, %d, %d, %d, %d, %d, time.UTC)",
+			v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond())
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// toFloat converts an integer to a float, for a column of both
+func toFloat(v interface{}) interface{} {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFixture(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	const schema = `
+	create table countries (code text primary key, name text not null, population int, area real, founded datetime, flag blob);
+	insert into countries values ('fr', 'France', 67000000, 643801.5, '1958-10-04 00:00:00', x'0102');
+	insert into countries values ('it', 'Italy', null, 301340, null, null);
+	`
+	if _, err := ExecScript(db, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	opts := FixtureOptions{Package: "geo", Var: "Countries", Type: "Country"}
+	if err := GenerateFixture(db, &buf, opts, "select * from countries order by code"); err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	if _, err := parser.ParseFile(token.NewFileSet(), "countries.go", src, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package geo",
+		`"database/sql"`,
+		"type Country struct",
+		"Population sql.NullInt64",
+		"Area       float64",
+		"var Countries = []Country{",
+		`Code: "fr", Name: "France", Population: sql.NullInt64{Int64: 67000000, Valid: true}, Area: 643801.5`,
+		"Founded: sql.NullTime{Time: time.Date(1958, time.October, 4, 0, 0, 0, 0, time.UTC), Valid: true}",
+		`Flag: []byte("\x01\x02")`,
+		"Population: sql.NullInt64{}, Area: 301340, Founded: sql.NullTime{}, Flag: nil",
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected generated code to contain %q:\n%s", want, src)
+		}
+	}
+
+	buf.Reset()
+	opts = FixtureOptions{Package: "geo", Var: "Areas"}
+	if err := GenerateFixture(db, &buf, opts, "select code, area from countries where code = ?", "it"); err != nil {
+		t.Fatal(err)
+	}
+	src = buf.String()
+	for _, want := range []string{
+		`var AreasColumns = []string{"code", "area"}`,
+		`{"it", 301340.0},`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected generated code to contain %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "import") {
+		t.Fatalf("expected no imports:\n%s", src)
+	}
+
+	if err := GenerateFixture(db, &buf, FixtureOptions{Package: "geo"}, "select 1"); err == nil {
+		t.Fatal("expected error for no variable")
+	}
+}
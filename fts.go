@@ -0,0 +1,336 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Tokenizer configures how a full-text index splits text into tokens
+//
+// mattn/go-sqlite3 offers no access to the FTS5 extension API, so tokenizers can't be
+// written in Go; a tokenizer compiled into SQLite can still be selected by Name
+type Tokenizer struct {
+	Name       string // "unicode61" (the default), "ascii", "porter", "trigram", or a compiled in tokenizer
+	Porter     bool   // FTS5 only: apply the porter stemmer to the output of the named tokenizer
+	Diacritics int    // unicode61 only: -1 keeps diacritics, 0 is the default, 1 or 2 remove them
+	Separators string // additional characters to treat as separators
+	TokenChars string // additional characters to treat as part of tokens
+}
+
+// FTSOptions are the options for creating a full-text index
+type FTSOptions struct {
+	Module       string // "fts5" (the default, or "fts4" if fts5 isn't available), "fts4", or "fts3"
+	Tokenizer    Tokenizer
+	Content      string // an external content table, if any
+	ContentRowID string // the rowid column of the external content table (FTS5 only)
+	// Sync creates triggers that keep the index of an external content table in step
+	// with it, and indexes the rows it already has
+	Sync bool
+}
+
+func (opts FTSOptions) module() string {
+	if opts.Module == "" {
+		return "fts5"
+	}
+	return strings.ToLower(opts.Module)
+}
+
+// quoteString quotes an SQL string literal
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// fts5 returns the tokenize option as used by FTS5, e.g. 'porter unicode61 remove_diacritics 2'
+func (t Tokenizer) fts5() string {
+	var args []string
+	if t.Porter {
+		args = append(args, "porter")
+	}
+	name := t.Name
+	if name == "" {
+		name = "unicode61"
+	}
+	args = append(args, name)
+	if t.Diacritics != 0 {
+		args = append(args, "remove_diacritics", fmt.Sprint(diacritics(t.Diacritics)))
+	}
+	if t.Separators != "" {
+		args = append(args, "separators", quoteString(t.Separators))
+	}
+	if t.TokenChars != "" {
+		args = append(args, "tokenchars", quoteString(t.TokenChars))
+	}
+	return "tokenize = " + quoteString(strings.Join(args, " "))
+}
+
+// fts4 returns the tokenize option as used by FTS3 and FTS4, e.g. tokenize=unicode61 "remove_diacritics=2"
+func (t Tokenizer) fts4() string {
+	name := t.Name
+	if name == "" {
+		name = "unicode61"
+	}
+	args := []string{name}
+	if t.Diacritics != 0 {
+		args = append(args, fmt.Sprintf(`"remove_diacritics=%d"`, diacritics(t.Diacritics)))
+	}
+	if t.Separators != "" {
+		args = append(args, quoteIdent("separators="+t.Separators))
+	}
+	if t.TokenChars != "" {
+		args = append(args, quoteIdent("tokenchars="+t.TokenChars))
+	}
+	return "tokenize=" + strings.Join(args, " ")
+}
+
+func diacritics(d int) int {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// ftsCreate returns the statement that creates the full-text index
+func ftsCreate(table string, cols []string, opts FTSOptions) (string, error) {
+	if len(cols) == 0 {
+		return "", fmt.Errorf("no columns for full-text index %q", table)
+	}
+	args := make([]string, len(cols))
+	for i, col := range cols {
+		args[i] = quoteIdent(col)
+	}
+	module := opts.module()
+	switch module {
+	case "fts5":
+		if opts.Tokenizer.Name != "" || opts.Tokenizer.Porter || opts.Tokenizer.Diacritics != 0 ||
+			opts.Tokenizer.Separators != "" || opts.Tokenizer.TokenChars != "" {
+			args = append(args, opts.Tokenizer.fts5())
+		}
+		if opts.Content != "" {
+			args = append(args, "content = "+quoteString(opts.Content))
+		}
+		if opts.ContentRowID != "" {
+			args = append(args, "content_rowid = "+quoteString(opts.ContentRowID))
+		}
+	case "fts3", "fts4":
+		if opts.Tokenizer.Porter {
+			return "", fmt.Errorf("%s can't combine porter with another tokenizer", module)
+		}
+		if opts.Tokenizer != (Tokenizer{}) {
+			args = append(args, opts.Tokenizer.fts4())
+		}
+		if opts.Content != "" {
+			if module == "fts3" {
+				return "", fmt.Errorf("fts3 doesn't support external content")
+			}
+			args = append(args, "content="+quoteIdent(opts.Content))
+		}
+	default:
+		return "", fmt.Errorf("unknown full-text module: %q", opts.Module)
+	}
+	return fmt.Sprintf("CREATE VIRTUAL TABLE %s USING %s(%s)", quoteIdent(table), module, strings.Join(args, ", ")), nil
+}
+
+// CreateFTS creates a full-text index on the given columns
+func CreateFTS(db *sql.DB, table string, cols []string, opts FTSOptions) error {
+	if opts.Module == "" && !Has(db, "fts5") {
+		opts.Module = "fts4"
+	}
+	stmt, err := ftsCreate(table, cols, opts)
+	if err != nil {
+		return err
+	}
+	stmts := []string{stmt}
+	if opts.Sync {
+		triggers, err := FTSTriggers(table, cols, opts)
+		if err != nil {
+			return err
+		}
+		name := quoteIdent(table)
+		stmts = append(stmts, triggers...)
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s(%s) VALUES('rebuild')", name, name))
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("create full-text index: %s -- %w", stmt, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// FTSTriggers returns the statements that create the triggers keeping a full-text
+// index of an external content table in step with the table, named for the index
+// with the suffixes _ai, _ad, and _au, and for FTS3 and FTS4, which read the old
+// values from the table, _bd and _bu in place of _ad
+func FTSTriggers(table string, cols []string, opts FTSOptions) ([]string, error) {
+	if opts.Content == "" {
+		return nil, fmt.Errorf("full-text index %q has no external content table", table)
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("no columns for full-text index %q", table)
+	}
+	rowid := "rowid"
+	if opts.ContentRowID != "" {
+		rowid = quoteIdent(opts.ContentRowID)
+	}
+	names := make([]string, len(cols))
+	newValues := make([]string, len(cols))
+	oldValues := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = quoteIdent(col)
+		newValues[i] = "new." + names[i]
+		oldValues[i] = "old." + names[i]
+	}
+	fts, content := quoteIdent(table), quoteIdent(opts.Content)
+	trigger := func(suffix, when string, body ...string) string {
+		return fmt.Sprintf("CREATE TRIGGER %s %s ON %s BEGIN %s; END", quoteIdent(table+suffix), when, content, strings.Join(body, "; "))
+	}
+	switch module := opts.module(); module {
+	case "fts5":
+		insert := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES(new.%s, %s)", fts, strings.Join(names, ", "), rowid, strings.Join(newValues, ", "))
+		remove := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES('delete', old.%s, %s)", fts, fts, strings.Join(names, ", "), rowid, strings.Join(oldValues, ", "))
+		return []string{
+			trigger("_ai", "AFTER INSERT", insert),
+			trigger("_ad", "AFTER DELETE", remove),
+			trigger("_au", "AFTER UPDATE", remove, insert),
+		}, nil
+	case "fts3", "fts4":
+		insert := fmt.Sprintf("INSERT INTO %s(docid, %s) VALUES(new.rowid, %s)", fts, strings.Join(names, ", "), strings.Join(newValues, ", "))
+		remove := fmt.Sprintf("DELETE FROM %s WHERE docid = old.rowid", fts)
+		return []string{
+			trigger("_ai", "AFTER INSERT", insert),
+			trigger("_bd", "BEFORE DELETE", remove),
+			trigger("_bu", "BEFORE UPDATE", remove),
+			trigger("_au", "AFTER UPDATE", insert),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown full-text module: %q", opts.Module)
+}
+
+// ftsModule returns the module ("fts3", "fts4", or "fts5") used by a full-text table
+func ftsModule(db *sql.DB, table string) (string, error) {
+	var stmt string
+	if err := row(db, []interface{}{&stmt}, "SELECT sql FROM sqlite_master WHERE type='table' AND name=?", table); err != nil {
+		return "", fmt.Errorf("full-text table %q: %w", table, err)
+	}
+	stmt = strings.ToLower(Normalize(stmt))
+	for _, module := range []string{"fts5", "fts4", "fts3"} {
+		if strings.Contains(stmt, "using "+module) {
+			return module, nil
+		}
+	}
+	return "", fmt.Errorf("%q is not a full-text table", table)
+}
+
+// ftsTables returns the names of the full-text tables of the database
+func ftsTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type='table' AND sql LIKE 'CREATE VIRTUAL TABLE%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tables := names[:0]
+	for _, name := range names {
+		if _, err := ftsModule(db, name); err == nil {
+			tables = append(tables, name)
+		}
+	}
+	return tables, nil
+}
+
+var (
+	ftsContentOption = regexp.MustCompile(`(?i)\bcontent\s*=\s*['"]?([^'",)\s]+)`)
+	ftsRowIDOption   = regexp.MustCompile(`(?i)\bcontent_rowid\s*=\s*['"]?([^'",)\s]+)`)
+)
+
+// ftsIndex returns a full-text table that indexes column of table as its external
+// content, and the column of table that its rowids are, if there is one
+func ftsIndex(db *sql.DB, table, column string) (fts, rowid string, ok bool) {
+	tables, err := ftsTables(db)
+	if err != nil {
+		return "", "", false
+	}
+	for _, name := range tables {
+		var stmt string
+		if err := row(db, []interface{}{&stmt}, "SELECT sql FROM sqlite_master WHERE type='table' AND name=?", name); err != nil {
+			continue
+		}
+		content := ftsContentOption.FindStringSubmatch(stmt)
+		if content == nil || !strings.EqualFold(content[1], table) {
+			continue
+		}
+		columns, err := exportColumns(db, name)
+		if err != nil {
+			continue
+		}
+		for _, c := range columns {
+			if strings.EqualFold(c.name, column) {
+				rowid = "rowid"
+				if m := ftsRowIDOption.FindStringSubmatch(stmt); m != nil {
+					rowid = m[1]
+				}
+				return name, rowid, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// ftsCommand runs one of the special full-text commands such as 'optimize'
+func ftsCommand(db *sql.DB, table, command string) error {
+	if _, err := ftsModule(db, table); err != nil {
+		return err
+	}
+	name := quoteIdent(table)
+	stmt := fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)", name, name, quoteString(command))
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("full-text %s of %q failed: %w", command, table, err)
+	}
+	return nil
+}
+
+// FTSOptimize merges the segments of a full-text index for faster queries
+func FTSOptimize(db *sql.DB, table string) error {
+	return ftsCommand(db, table, "optimize")
+}
+
+// FTSRebuild discards a full-text index and rebuilds it from its content table
+func FTSRebuild(db *sql.DB, table string) error {
+	return ftsCommand(db, table, "rebuild")
+}
+
+// FTSIntegrityCheck verifies a full-text index, including that it matches
+// its external content table, if it has one
+func FTSIntegrityCheck(db *sql.DB, table string) error {
+	module, err := ftsModule(db, table)
+	if err != nil {
+		return err
+	}
+	if module != "fts5" {
+		return ftsCommand(db, table, "integrity-check")
+	}
+	name := quoteIdent(table)
+	stmt := fmt.Sprintf("INSERT INTO %s(%s, rank) VALUES('integrity-check', 1)", name, name)
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("full-text integrity-check of %q failed: %w", table, err)
+	}
+	return nil
+}
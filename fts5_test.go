@@ -0,0 +1,34 @@
+//go:build sqlite_fts5 || fts5
+// +build sqlite_fts5 fts5
+
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestCreateFTS5Tokenizer(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	opts := FTSOptions{
+		Tokenizer: Tokenizer{Porter: true, Diacritics: 2, Separators: "'-"},
+	}
+	if err := CreateFTS(db, "recipes", []string{"name"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into recipes(name) values('Crème-brûlées'), ('Pâté')"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := row(db, []interface{}{&name}, "select name from recipes where recipes match 'brulee'"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Crème-brûlées" {
+		t.Fatalf("unexpected match: %q", name)
+	}
+}
+
+func TestSearchFTS5(t *testing.T) {
+	testSearch(t, "fts5")
+}
@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFTSCreateStatement(t *testing.T) {
+	opts := FTSOptions{
+		Tokenizer: Tokenizer{Porter: true, Diacritics: 2, Separators: "'-"},
+		Content:   "docs",
+	}
+	stmt, err := ftsCreate("docs_fts", []string{"title", "body"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `CREATE VIRTUAL TABLE "docs_fts" USING fts5("title", "body", ` +
+		`tokenize = 'porter unicode61 remove_diacritics 2 separators ''''''-''', content = 'docs')`
+	if stmt != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, stmt)
+	}
+
+	opts = FTSOptions{Module: "fts4", Tokenizer: Tokenizer{Porter: true}}
+	if _, err := ftsCreate("docs_fts", []string{"title"}, opts); err == nil {
+		t.Fatal("expected error for porter with fts4")
+	}
+	if _, err := ftsCreate("docs_fts", nil, FTSOptions{}); err == nil {
+		t.Fatal("expected error for no columns")
+	}
+}
+
+func TestCreateFTSTokenizer(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	opts := FTSOptions{
+		Module:    "fts4",
+		Tokenizer: Tokenizer{Diacritics: 2, Separators: "_"},
+	}
+	if err := CreateFTS(db, "recipes", []string{"name"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into recipes(name) values('Crème_brûlée'), ('Pâté')"); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := row(db, []interface{}{&name}, "select name from recipes where recipes match 'brulee'"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Crème_brûlée" {
+		t.Fatalf("unexpected match: %q", name)
+	}
+}
+
+func TestFTSMaintenance(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	const setup = `
+	create table docs (id integer primary key, body text);
+	insert into docs (body) values('the quick brown fox'), ('jumped over the lazy dog');
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateFTS(db, "docs_fts", []string{"body"}, FTSOptions{Module: "fts4", Content: "docs"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := FTSRebuild(db, "docs_fts"); err != nil {
+		t.Fatal(err)
+	}
+	if err := FTSIntegrityCheck(db, "docs_fts"); err != nil {
+		t.Fatal(err)
+	}
+
+	// change the content without updating the index
+	if _, err := db.Exec("update docs set body = 'something else entirely' where id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := FTSIntegrityCheck(db, "docs_fts"); err == nil {
+		t.Fatal("expected integrity check to fail")
+	} else {
+		t.Log("got expected error:", err)
+	}
+	if err := FTSRebuild(db, "docs_fts"); err != nil {
+		t.Fatal(err)
+	}
+	if err := FTSOptimize(db, "docs_fts"); err != nil {
+		t.Fatal(err)
+	}
+	if err := FTSIntegrityCheck(db, "docs_fts"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := FTSOptimize(db, "docs"); err == nil {
+		t.Fatal("expected error for a table that isn't full-text")
+	}
+
+	// the scheduler finds the full-text table, and rebuilds it when it goes stale
+	if tables, err := ftsTables(db); err != nil || len(tables) != 1 || tables[0] != "docs_fts" {
+		t.Fatalf("expected docs_fts only, got %q (%v)", tables, err)
+	}
+	if _, err := db.Exec("update docs set body = 'stale again' where id = 2"); err != nil {
+		t.Fatal(err)
+	}
+	var failed []string
+	m := &MaintenanceScheduler{
+		FTSOptimize: 5 * time.Millisecond,
+		FTSCheck:    5 * time.Millisecond,
+		OnError:     func(task string, err error) { failed = append(failed, task) },
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Run(ctx, db); err != context.DeadlineExceeded {
+		t.Fatalf("expected the scheduler to run until the deadline, got %v", err)
+	}
+	if len(failed) != 1 || failed[0] != "fts_integrity_check" {
+		t.Fatalf("expected one failed integrity check, got %q", failed)
+	}
+	if err := FTSIntegrityCheck(db, "docs_fts"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,312 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// SearchOptions control the results of Search
+type SearchOptions struct {
+	Limit         int       // the most results returned, 20 if not set, or -1 for all
+	Offset        int       // results skipped before those returned
+	Weights       []float64 // the weight of each column in the ranking, 1 for those not given
+	SnippetColumn string    // the column snippets are taken from, the best match if not set
+	Tokens        int       // the tokens in a snippet, 10 if not set, at most 64
+	Open, Close   string    // marks around matched terms, "<b>" and "</b>" if not set
+	Ellipsis      string    // marks text left out of a snippet, "…" if not set
+	Highlight     bool      // return each column with its matched terms marked
+}
+
+// SearchResult is a row matched by Search
+type SearchResult struct {
+	RowID      int64
+	Score      float64  // the bm25 relevance of the row, higher for better matches
+	Snippet    string   // the text around the best match, with the matched terms marked
+	Highlights []string // each column with its matched terms marked, if asked for
+}
+
+// Search returns the rows of a full-text table matching the query, most relevant first
+func Search(db *sql.DB, table, query string) ([]SearchResult, error) {
+	return SearchWithOptions(db, table, query, SearchOptions{})
+}
+
+// SearchWithOptions is Search, with control over the results returned.
+//
+// Results are ranked by bm25. FTS5 ranks them itself; for FTS4 the ranking is done
+// here from matchinfo, which means reading every matching row, and FTS3 results
+// are unranked, in rowid order
+func SearchWithOptions(db *sql.DB, table, query string, opts SearchOptions) ([]SearchResult, error) {
+	module, err := ftsModule(db, table)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := exportColumns(db, table)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit == 0 {
+		opts.Limit = 20
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+	if opts.Tokens <= 0 {
+		opts.Tokens = 10
+	} else if opts.Tokens > 64 {
+		opts.Tokens = 64
+	}
+	if opts.Open == "" && opts.Close == "" {
+		opts.Open, opts.Close = "<b>", "</b>"
+	}
+	if opts.Ellipsis == "" {
+		opts.Ellipsis = "…"
+	}
+	snippet := -1
+	if opts.SnippetColumn != "" {
+		for i, c := range columns {
+			if strings.EqualFold(c.name, opts.SnippetColumn) {
+				snippet = i
+			}
+		}
+		if snippet < 0 {
+			return nil, fmt.Errorf("full-text table %q has no column %q", table, opts.SnippetColumn)
+		}
+	}
+	weights := make([]float64, len(columns))
+	for i := range weights {
+		weights[i] = 1
+		if i < len(opts.Weights) {
+			weights[i] = opts.Weights[i]
+		}
+	}
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	var results []SearchResult
+	if module == "fts5" {
+		results, err = searchFTS5(db, table, query, names, snippet, weights, opts)
+	} else {
+		results, err = searchFTS4(db, table, module, query, names, snippet, weights, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("search %s: %w", table, err)
+	}
+	return results, nil
+}
+
+// searchFTS5 ranks and limits the results in SQL
+func searchFTS5(db *sql.DB, table, query string, columns []string, snippet int, weights []float64, opts SearchOptions) ([]SearchResult, error) {
+	name := quoteIdent(table)
+	bm25 := make([]string, len(weights))
+	for i, w := range weights {
+		bm25[i] = strconv.FormatFloat(w, 'g', -1, 64)
+	}
+	rank := fmt.Sprintf("bm25(%s, %s)", name, strings.Join(bm25, ", "))
+	selected := []string{"rowid", "-" + rank, fmt.Sprintf("snippet(%s, %d, ?, ?, ?, %d)", name, snippet, opts.Tokens)}
+	args := []interface{}{opts.Open, opts.Close, opts.Ellipsis}
+	if opts.Highlight {
+		for i := range columns {
+			selected = append(selected, fmt.Sprintf("highlight(%s, %d, ?, ?)", name, i))
+			args = append(args, opts.Open, opts.Close)
+		}
+	}
+	stmt := fmt.Sprintf("SELECT %s FROM %s WHERE %s MATCH ? ORDER BY %s LIMIT ? OFFSET ?", strings.Join(selected, ", "), name, name, rank)
+	args = append(args, query, opts.Limit, opts.Offset)
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var snippet sql.NullString
+		values := make([]sql.NullString, len(columns))
+		dest := []interface{}{&r.RowID, &r.Score, &snippet}
+		if opts.Highlight {
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		r.Snippet = snippet.String
+		if opts.Highlight {
+			r.Highlights = make([]string, len(columns))
+			for i, v := range values {
+				r.Highlights[i] = v.String
+			}
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// searchFTS4 reads every match, ranking them from matchinfo, and marks highlights from offsets
+func searchFTS4(db *sql.DB, table, module, query string, columns []string, snippet int, weights []float64, opts SearchOptions) ([]SearchResult, error) {
+	name := quoteIdent(table)
+	ranked := module == "fts4"
+	selected := []string{"docid", fmt.Sprintf("snippet(%s, ?, ?, ?, %d, %d)", name, snippet, opts.Tokens)}
+	args := []interface{}{opts.Open, opts.Close, opts.Ellipsis}
+	if ranked {
+		selected = append(selected, fmt.Sprintf("matchinfo(%s, 'pcnalx')", name))
+	}
+	if opts.Highlight {
+		selected = append(selected, fmt.Sprintf("offsets(%s)", name))
+		for _, c := range columns {
+			selected = append(selected, quoteIdent(c))
+		}
+	}
+	stmt := fmt.Sprintf("SELECT %s FROM %s WHERE %s MATCH ?", strings.Join(selected, ", "), name, name)
+	args = append(args, query)
+	if !ranked {
+		stmt += " ORDER BY docid"
+	}
+	rows, err := db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var snippet sql.NullString
+		var info []byte
+		var offsets string
+		values := make([]sql.NullString, len(columns))
+		dest := []interface{}{&r.RowID, &snippet}
+		if ranked {
+			dest = append(dest, &info)
+		}
+		if opts.Highlight {
+			dest = append(dest, &offsets)
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		r.Snippet = snippet.String
+		if ranked {
+			r.Score = bm25(info, weights)
+		}
+		if opts.Highlight {
+			r.Highlights = make([]string, len(columns))
+			for i, v := range values {
+				r.Highlights[i] = v.String
+			}
+			highlightOffsets(r.Highlights, offsets, opts.Open, opts.Close)
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if ranked {
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	}
+	if opts.Offset >= len(results) {
+		return nil, nil
+	}
+	results = results[opts.Offset:]
+	if opts.Limit >= 0 && opts.Limit < len(results) {
+		results = results[:opts.Limit]
+	}
+	return results, nil
+}
+
+// nativeEndian is the byte order of matchinfo, which is that of the machine
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// bm25 scores a row from its FTS4 matchinfo(..., 'pcnalx'), as FTS5's bm25 does,
+// but with higher scores for better matches
+func bm25(info []byte, weights []float64) float64 {
+	const k1, b = 1.2, 0.75
+	values := make([]uint32, len(info)/4)
+	for i := range values {
+		values[i] = nativeEndian.Uint32(info[i*4:])
+	}
+	if len(values) < 3 {
+		return 0
+	}
+	phrases, cols, rows := int(values[0]), int(values[1]), float64(values[2])
+	if len(values) < 3+2*cols+3*cols*phrases {
+		return 0
+	}
+	avg, length, hits := values[3:3+cols], values[3+cols:3+2*cols], values[3+2*cols:]
+	var score float64
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < cols; c++ {
+			x := hits[3*(c+p*cols):]
+			tf, docs := float64(x[0]), float64(x[2])
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log((rows - docs + 0.5) / (docs + 0.5))
+			if idf <= 0 {
+				idf = 1e-6
+			}
+			norm := 1.0
+			if avg[c] > 0 {
+				norm = 1 - b + b*float64(length[c])/float64(avg[c])
+			}
+			w := 1.0
+			if c < len(weights) {
+				w = weights[c]
+			}
+			score += w * idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+	return score
+}
+
+// highlightOffsets marks the matched terms of the columns in place, from the
+// FTS3/FTS4 offsets() of a row: the column, term, byte offset, and size of each match
+func highlightOffsets(columns []string, offsets, open, close string) {
+	fields := strings.Fields(offsets)
+	type span struct{ start, end int }
+	spans := make([][]span, len(columns))
+	for i := 0; i+3 < len(fields); i += 4 {
+		col, err1 := strconv.Atoi(fields[i])
+		start, err2 := strconv.Atoi(fields[i+2])
+		size, err3 := strconv.Atoi(fields[i+3])
+		if err1 != nil || err2 != nil || err3 != nil || col < 0 || col >= len(columns) {
+			continue
+		}
+		spans[col] = append(spans[col], span{start, start + size})
+	}
+	for c, list := range spans {
+		sort.Slice(list, func(i, j int) bool { return list[i].start < list[j].start })
+		text := columns[c]
+		var sb strings.Builder
+		last := 0
+		for _, s := range list {
+			if s.start < last || s.end > len(text) {
+				continue
+			}
+			sb.WriteString(text[last:s.start])
+			sb.WriteString(open)
+			sb.WriteString(text[s.start:s.end])
+			sb.WriteString(close)
+			last = s.end
+		}
+		sb.WriteString(text[last:])
+		columns[c] = sb.String()
+	}
+}
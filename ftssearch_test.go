@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func searchFixture(t *testing.T, module string) *sql.DB {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	const setup = `
+create table article (id integer primary key, title text, body text);
+insert into article (title, body) values
+	('Cooking with cast iron', 'Season the pan before cooking anything acidic.'),
+	('Sourdough basics', 'A starter needs flour, water, and patience. Bread takes time.'),
+	('Bread and butter', 'Bread bread bread: the best bread is fresh bread.');
+`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	opts := FTSOptions{Module: module, Content: "article", ContentRowID: "id", Sync: true}
+	if err := CreateFTS(db, "article_fts", []string{"title", "body"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func testSearch(t *testing.T, module string) {
+	db := searchFixture(t, module)
+
+	results, err := Search(db, "article_fts", "bread")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].RowID != 3 || results[0].Score <= results[1].Score {
+		t.Fatalf("expected the article most about bread first, got %+v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "<b>Bread</b>") {
+		t.Fatalf("expected the match marked in the snippet, got %q", results[0].Snippet)
+	}
+
+	// the triggers keep the index in step with the table
+	if _, err := db.Exec("insert into article (title, body) values ('Quick bread', 'No yeast needed.')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("update article set body = 'A starter needs flour and water.' where id = 2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("delete from article where id = 3"); err != nil {
+		t.Fatal(err)
+	}
+	results, err = SearchWithOptions(db, "article_fts", "bread", SearchOptions{
+		Highlight: true, Open: "[", Close: "]", SnippetColumn: "title",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].RowID != 4 {
+		t.Fatalf("expected the new article only, got %+v", results)
+	}
+	if r := results[0]; r.Snippet != "Quick [bread]" || r.Highlights[0] != "Quick [bread]" || r.Highlights[1] != "No yeast needed." {
+		t.Fatalf("unexpected marks: %+v", r)
+	}
+	if err := FTSIntegrityCheck(db, "article_fts"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = SearchWithOptions(db, "article_fts", "cooking OR flour OR bread", SearchOptions{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected the second of three results, got %+v", results)
+	}
+	results, err = SearchWithOptions(db, "article_fts", "cooking OR flour OR bread", SearchOptions{Offset: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected a negative offset to skip nothing, got %+v", results)
+	}
+	if _, err := SearchWithOptions(db, "article_fts", "bread", SearchOptions{SnippetColumn: "nope"}); err == nil {
+		t.Fatal("expected error for an unknown column")
+	}
+}
+
+func TestSearchFTS4(t *testing.T) {
+	testSearch(t, "fts4")
+}
+
+func TestFTSTriggers(t *testing.T) {
+	if _, err := FTSTriggers("docs_fts", []string{"body"}, FTSOptions{}); err == nil {
+		t.Fatal("expected error without a content table")
+	}
+	triggers, err := FTSTriggers("docs_fts", []string{"body"}, FTSOptions{Module: "fts5", Content: "docs", ContentRowID: "id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `CREATE TRIGGER "docs_fts_ad" AFTER DELETE ON "docs" BEGIN INSERT INTO "docs_fts"("docs_fts", rowid, "body") VALUES('delete', old."id", old."body"); END`
+	if len(triggers) != 3 || triggers[1] != want {
+		t.Fatalf("unexpected triggers: %q", triggers)
+	}
+	if triggers, _ = FTSTriggers("docs_fts", []string{"body"}, FTSOptions{Module: "fts4", Content: "docs"}); len(triggers) != 4 {
+		t.Fatalf("expected 4 triggers for fts4, got %q", triggers)
+	}
+}
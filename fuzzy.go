@@ -0,0 +1,312 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FuzzyFuncs are SQL functions for phonetic and approximate string matching.
+// As the driver can't return NULL from a text function, the soundex and
+// metaphone of NULL are empty, as they are for text without letters
+var FuzzyFuncs = []FuncReg{
+	{"soundex", soundexSQL, true},
+	{"metaphone", metaphoneSQL, true},
+	{"damerau_levenshtein", DamerauLevenshtein, true},
+}
+
+func soundexSQL(v interface{}) string {
+	s, _ := sqlText(v)
+	return Soundex(s)
+}
+
+func metaphoneSQL(v interface{}) string {
+	s, _ := sqlText(v)
+	return Metaphone(s)
+}
+
+// letters returns the ASCII letters of s in upper case
+func letters(s string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToUpper(r)
+		if r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return -1
+	}, Unaccent(s))
+}
+
+// Soundex returns the American Soundex code of s, e.g. "R163" for "Robert"
+func Soundex(s string) string {
+	const codes = "01230120022455012623010202" // A through Z
+	word := letters(s)
+	if word == "" {
+		return ""
+	}
+	code := []byte{word[0]}
+	last := codes[word[0]-'A']
+	for i := 1; i < len(word) && len(code) < 4; i++ {
+		c := word[i]
+		d := codes[c-'A']
+		switch {
+		case c == 'H' || c == 'W':
+			// H and W don't separate letters with the same code
+			continue
+		case d == '0':
+			last = d
+		case d != last:
+			code = append(code, d)
+			last = d
+		}
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("AEIOU", c) >= 0
+}
+
+// Metaphone returns the original Metaphone phonetic key of s
+func Metaphone(s string) string {
+	word := letters(s)
+	if word == "" {
+		return ""
+	}
+	switch {
+	case strings.HasPrefix(word, "KN"), strings.HasPrefix(word, "GN"), strings.HasPrefix(word, "PN"),
+		strings.HasPrefix(word, "AE"), strings.HasPrefix(word, "WR"):
+		word = word[1:]
+	case word[0] == 'X':
+		word = "S" + word[1:]
+	case strings.HasPrefix(word, "WH"):
+		word = "W" + word[2:]
+	}
+
+	at := func(i int) byte {
+		if i < 0 || i >= len(word) {
+			return 0
+		}
+		return word[i]
+	}
+	next := func(i int, s string) bool {
+		return strings.HasPrefix(word[i+1:], s)
+	}
+	frontv := func(c byte) bool {
+		return c == 'E' || c == 'I' || c == 'Y'
+	}
+
+	var key strings.Builder
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		if c != 'C' && c == at(i-1) {
+			continue
+		}
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				key.WriteByte(c)
+			}
+		case 'B':
+			if !(i == len(word)-1 && at(i-1) == 'M') {
+				key.WriteByte('B')
+			}
+		case 'C':
+			switch {
+			case next(i, "IA") || (next(i, "H") && at(i-1) != 'S'):
+				key.WriteByte('X')
+			case frontv(at(i + 1)):
+				if at(i-1) != 'S' {
+					key.WriteByte('S')
+				}
+			default:
+				key.WriteByte('K')
+			}
+		case 'D':
+			if at(i+1) == 'G' && frontv(at(i+2)) {
+				key.WriteByte('J')
+			} else {
+				key.WriteByte('T')
+			}
+		case 'G':
+			switch {
+			case at(i+1) == 'H' && i+2 < len(word) && !isVowel(at(i+2)):
+			case at(i+1) == 'N' && (i+2 == len(word) || (next(i, "NED") && i+4 == len(word))):
+			case frontv(at(i+1)) && at(i-1) != 'G':
+				key.WriteByte('J')
+			default:
+				key.WriteByte('K')
+			}
+		case 'H':
+			if isVowel(at(i+1)) && !strings.ContainsRune("CGPST", rune(at(i-1))) {
+				key.WriteByte('H')
+			}
+		case 'K':
+			if at(i-1) != 'C' {
+				key.WriteByte('K')
+			}
+		case 'P':
+			if at(i+1) == 'H' {
+				key.WriteByte('F')
+			} else {
+				key.WriteByte('P')
+			}
+		case 'Q':
+			key.WriteByte('K')
+		case 'S':
+			if next(i, "H") || next(i, "IO") || next(i, "IA") {
+				key.WriteByte('X')
+			} else {
+				key.WriteByte('S')
+			}
+		case 'T':
+			switch {
+			case next(i, "IA") || next(i, "IO"):
+				key.WriteByte('X')
+			case at(i+1) == 'H':
+				key.WriteByte('0')
+			case !next(i, "CH"):
+				key.WriteByte('T')
+			}
+		case 'V':
+			key.WriteByte('F')
+		case 'W', 'Y':
+			if isVowel(at(i + 1)) {
+				key.WriteByte(c)
+			}
+		case 'X':
+			key.WriteString("KS")
+		case 'Z':
+			key.WriteByte('S')
+		default:
+			key.WriteByte(c)
+		}
+	}
+	return key.String()
+}
+
+// DamerauLevenshtein returns the edit distance between a and b, counting insertions,
+// deletions, substitutions and transpositions of adjacent characters
+func DamerauLevenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] && d[i-2][j-2]+cost < d[i][j] {
+				d[i][j] = d[i-2][j-2] + cost
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// FuzzyMatch is a row found by FuzzySearch
+type FuzzyMatch struct {
+	RowID    int64
+	Value    string
+	Distance int
+}
+
+// FuzzySearch finds rows of table whose column sounds like name (by soundex or metaphone)
+// or is within maxDistance edits of it, ordered by edit distance. Every row is compared,
+// see FuzzySearchFTS for large tables
+//
+// The database must be opened with FuzzyFuncs registered
+func FuzzySearch(db *sql.DB, table, column, name string, maxDistance int) ([]FuzzyMatch, error) {
+	return fuzzySearch(db, table, column, name, maxDistance, false)
+}
+
+// FuzzySearchFTS is FuzzySearch narrowed by a full-text table indexing the column with
+// table as its external content, as made by CreateFTS. Only the rows with a word starting
+// as name or its metaphone key does are compared, so unlike FuzzySearch it misses values
+// whose first letter is misspelled or differs from that of a name sounding the same,
+// e.g. "Wright" for "Right"
+//
+// The database must be opened with FuzzyFuncs registered
+func FuzzySearchFTS(db *sql.DB, table, column, name string, maxDistance int) ([]FuzzyMatch, error) {
+	return fuzzySearch(db, table, column, name, maxDistance, true)
+}
+
+// fuzzySearch runs FuzzySearch, using the full-text index of the column if indexed is set
+func fuzzySearch(db *sql.DB, table, column, name string, maxDistance int, indexed bool) ([]FuzzyMatch, error) {
+	q := fmt.Sprintf(`
+SELECT rowid, %[2]s, damerau_levenshtein(lower(%[2]s), lower(?1)) AS distance
+FROM %[1]s
+WHERE %[2]s IS NOT NULL
+AND (soundex(%[2]s) = soundex(?1) OR metaphone(%[2]s) = metaphone(?1)
+  OR damerau_levenshtein(lower(%[2]s), lower(?1)) <= ?2)
+`, quoteIdent(table), quoteIdent(column))
+	args := []interface{}{name, maxDistance}
+	if indexed {
+		fts, rowid, ok := ftsIndex(db, table, column)
+		if !ok {
+			return nil, fmt.Errorf("no full-text index of %s.%s", table, column)
+		}
+		if match := fuzzyCandidates(name); match != "" {
+			q += fmt.Sprintf("AND %s IN (SELECT rowid FROM %s WHERE %[2]s MATCH ?3)\n", quoteIdent(rowid), quoteIdent(fts))
+			args = append(args, match)
+		}
+	}
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []FuzzyMatch
+	for rows.Next() {
+		var m FuzzyMatch
+		if err := rows.Scan(&m.RowID, &m.Value, &m.Distance); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Distance < list[j].Distance })
+	return list, rows.Err()
+}
+
+// fuzzyCandidates returns a full-text query for the words starting with the first
+// letter of name or of its metaphone key, or nothing if name has no letters
+func fuzzyCandidates(name string) string {
+	word := letters(name)
+	if word == "" {
+		return ""
+	}
+	prefixes := []string{strings.ToLower(word[:1]) + "*"}
+	if key := Metaphone(word); key != "" && key[0] != word[0] && key[0] >= 'A' && key[0] <= 'Z' {
+		prefixes = append(prefixes, strings.ToLower(key[:1])+"*")
+	}
+	return strings.Join(prefixes, " OR ")
+}
+
+// quoteIdent quotes an SQL identifier such as a table or column name
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestSoundex(t *testing.T) {
+	codes := map[string]string{
+		"Robert":   "R163",
+		"Rupert":   "R163",
+		"Ashcraft": "A261",
+		"Tymczak":  "T522",
+		"Pfister":  "P236",
+		"Lee":      "L000",
+		"":         "",
+	}
+	for name, code := range codes {
+		if s := Soundex(name); s != code {
+			t.Errorf("%q expected: %s but got: %s", name, code, s)
+		}
+	}
+}
+
+func TestMetaphone(t *testing.T) {
+	codes := map[string]string{
+		"Thomas":   "0MS",
+		"Knight":   "NT",
+		"Phillips": "FLPS",
+		"Schmidt":  "SKMTT",
+		"Xavier":   "SFR",
+		"Church":   "XRX",
+	}
+	for name, code := range codes {
+		if s := Metaphone(name); s != code {
+			t.Errorf("%q expected: %s but got: %s", name, code, s)
+		}
+	}
+	if Metaphone("Smith") != Metaphone("Smyth") {
+		t.Error("expected Smith and Smyth to match")
+	}
+}
+
+func TestDamerauLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"ca", "ac", 1},
+		{"Jonh", "John", 1},
+		{"müller", "muller", 1},
+	}
+	for _, test := range tests {
+		if d := DamerauLevenshtein(test.a, test.b); d != test.distance {
+			t.Errorf("%q -> %q expected: %d but got: %d", test.a, test.b, test.distance, d)
+		}
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	db, err := Open(":memory:", WithFunctions(FuzzyFuncs...), WithDriver("fuzzy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const setup = `
+	create table people (name text);
+	insert into people values('Smith'), ('Smyth'), ('Jones'), ('Smithe'), (null);
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := FuzzySearch(db, "people", "name", "smith", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("expected 3 matches but got: %+v", matches)
+	}
+	if matches[0].Value != "Smith" || matches[0].Distance != 0 {
+		t.Fatalf("expected exact match first but got: %+v", matches[0])
+	}
+
+	var soundex, metaphone string
+	if err := db.QueryRow("select soundex(null), metaphone(null)").Scan(&soundex, &metaphone); err != nil {
+		t.Fatal(err)
+	}
+	if soundex != "" || metaphone != "" {
+		t.Fatalf("expected empty keys for NULL but got: %q %q", soundex, metaphone)
+	}
+
+	// with a full-text index, candidates are the words starting with the same letter
+	if err := CreateFTS(db, "people_fts", []string{"name"}, FTSOptions{Module: "fts4", Content: "people", Sync: true}); err != nil {
+		t.Fatal(err)
+	}
+	if fts, rowid, ok := ftsIndex(db, "people", "name"); !ok || fts != "people_fts" || rowid != "rowid" {
+		t.Fatalf("expected people_fts to be found but got: %q %q %t", fts, rowid, ok)
+	}
+	if _, err := db.Exec("insert into people values('Xmith')"); err != nil {
+		t.Fatal(err)
+	}
+	matches, err = FuzzySearch(db, "people", "name", "smith", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 4 {
+		t.Fatalf("expected 4 matches including Xmith but got: %+v", matches)
+	}
+	matches, err = FuzzySearchFTS(db, "people", "name", "smith", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 || matches[0].Value != "Smith" {
+		t.Fatalf("expected the same 3 matches but got: %+v", matches)
+	}
+	if _, err := FuzzySearchFTS(db, "people", "rowid", "smith", 1); err == nil {
+		t.Fatal("expected an error for a column without an index")
+	}
+}
@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EarthRadius is the mean radius of the earth in meters, used for distances
+const EarthRadius = 6371008.8
+
+// GeoFuncs are SQL functions for geofencing with points and the polygons made by
+// ToPolygon, with coordinates in degrees:
+//
+//	distance_haversine(lat1, lon1, lat2, lon2)  the great-circle distance in meters
+//	point_in_polygon(lat, lon, polygon)         whether the point is inside the polygon
+//	bbox_contains(min_lat, min_lon, max_lat, max_lon, lat, lon)
+//	                                            whether the point is inside the box
+//
+// A NULL argument gives a NULL distance, and a point that is never inside,
+// as the driver can't return NULL from a boolean function
+var GeoFuncs = []FuncReg{
+	{"distance_haversine", distanceHaversine, true},
+	{"point_in_polygon", pointInPolygon, true},
+	{"bbox_contains", bboxContains, true},
+}
+
+// WithGeoFuncs registers GeoFuncs
+func WithGeoFuncs() Optional {
+	return WithFunctions(GeoFuncs...)
+}
+
+// Point is a position in degrees of latitude and longitude
+type Point struct {
+	Lat, Lon float64
+}
+
+// Haversine returns the great-circle distance between two points in meters
+func Haversine(a, b Point) float64 {
+	const rad = math.Pi / 180
+	dLat := (b.Lat - a.Lat) * rad
+	dLon := (b.Lon - a.Lon) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// InPolygon reports whether the point is inside the polygon, whose last point
+// joins its first. Points on an edge may fall either way
+func InPolygon(pt Point, polygon []Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lon > pt.Lon) != (b.Lon > pt.Lon) &&
+			pt.Lat < (b.Lat-a.Lat)*(pt.Lon-a.Lon)/(b.Lon-a.Lon)+a.Lat {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// EncodePolygon returns the polygon as text, in the format of ToPolygon
+func EncodePolygon(polygon []Point) string {
+	sb := new(strings.Builder)
+	sb.WriteString(`'[`)
+	for i, pt := range polygon {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('[')
+		sb.WriteString(strconv.FormatFloat(pt.Lat, 'f', -1, 64))
+		sb.WriteByte(',')
+		sb.WriteString(strconv.FormatFloat(pt.Lon, 'f', -1, 64))
+		sb.WriteByte(']')
+	}
+	sb.WriteString(`]'`)
+	return sb.String()
+}
+
+// DecodePolygon reads a polygon in the format of ToPolygon, with or without
+// the quotes around it
+func DecodePolygon(text string) ([]Point, error) {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		text = text[1 : len(text)-1]
+	}
+	var pairs [][]float64
+	if err := json.Unmarshal([]byte(text), &pairs); err != nil {
+		return nil, fmt.Errorf("invalid polygon: %w", err)
+	}
+	polygon := make([]Point, len(pairs))
+	for i, pair := range pairs {
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid polygon: point %d has %d coordinates", i+1, len(pair))
+		}
+		polygon[i] = Point{pair[0], pair[1]}
+	}
+	return polygon, nil
+}
+
+// coordinates converts the arguments of a geo function, which are numbers or numeric
+// text, reporting false if any is NULL
+func coordinates(name string, args ...interface{}) ([]float64, bool, error) {
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		if b, ok := arg.([]byte); ok && b == nil {
+			return nil, false, nil // the driver passes NULL as a nil slice
+		}
+		f, ok := number(arg)
+		if !ok {
+			text, isText := sqlText(arg)
+			if !isText {
+				return nil, false, nil
+			}
+			var err error
+			if f, err = strconv.ParseFloat(strings.TrimSpace(text), 64); err != nil {
+				return nil, false, fmt.Errorf("%s: argument %d is not a number: %q", name, i+1, text)
+			}
+		}
+		values[i] = f
+	}
+	return values, true, nil
+}
+
+func distanceHaversine(lat1, lon1, lat2, lon2 interface{}) (float64, error) {
+	c, ok, err := coordinates("distance_haversine", lat1, lon1, lat2, lon2)
+	if err != nil || !ok {
+		// SQLite stores NaN as NULL
+		return math.NaN(), err
+	}
+	return Haversine(Point{c[0], c[1]}, Point{c[2], c[3]}), nil
+}
+
+func pointInPolygon(lat, lon, polygon interface{}) (bool, error) {
+	c, ok, err := coordinates("point_in_polygon", lat, lon)
+	if err != nil || !ok {
+		return false, err
+	}
+	text, ok := sqlText(polygon)
+	if !ok {
+		return false, nil
+	}
+	points, err := DecodePolygon(text)
+	if err != nil {
+		return false, err
+	}
+	return InPolygon(Point{c[0], c[1]}, points), nil
+}
+
+func bboxContains(minLat, minLon, maxLat, maxLon, lat, lon interface{}) (bool, error) {
+	c, ok, err := coordinates("bbox_contains", minLat, minLon, maxLat, maxLon, lat, lon)
+	if err != nil || !ok {
+		return false, err
+	}
+	return c[0] <= c[4] && c[4] <= c[2] && c[1] <= c[5] && c[5] <= c[3], nil
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+)
+
+func TestGeoFuncs(t *testing.T) {
+	db, err := Open(":memory:", WithDriver("geo"), WithGeoFuncs(), WithFunctions(ipFuncs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// London to Paris
+	var meters float64
+	if err := db.QueryRow("SELECT distance_haversine(51.5074, -0.1278, 48.8566, 2.3522)").Scan(&meters); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(meters-343_500) > 1_000 {
+		t.Errorf("got %.0f meters, want about 343500", meters)
+	}
+
+	square := EncodePolygon([]Point{{0, 0}, {0, 10}, {10, 10}, {10, 0}})
+	tests := []struct {
+		lat, lon float64
+		inside   bool
+	}{
+		{5, 5, true},
+		{-1, 5, false},
+		{5, 11, false},
+		{9.5, 0.5, true},
+	}
+	for _, test := range tests {
+		var inside bool
+		if err := db.QueryRow("SELECT point_in_polygon(?, ?, ?)", test.lat, test.lon, square).Scan(&inside); err != nil {
+			t.Fatal(err)
+		}
+		if inside != test.inside {
+			t.Errorf("(%v, %v): got %v, want %v", test.lat, test.lon, inside, test.inside)
+		}
+	}
+
+	// integer coordinates, and a polygon made in SQL
+	var inside bool
+	if err := db.QueryRow("SELECT point_in_polygon(5, 5, polygon(0, 0, 0, 10, 10, 10, 10, 0))").Scan(&inside); err != nil {
+		t.Fatal(err)
+	}
+	if !inside {
+		t.Error("point not in polygon")
+	}
+	if err := db.QueryRow("SELECT bbox_contains(0, 0, 10, 10, 10, 3.5)").Scan(&inside); err != nil {
+		t.Fatal(err)
+	}
+	if !inside {
+		t.Error("point not in box")
+	}
+	if err := db.QueryRow("SELECT bbox_contains(0, 0, 10, 10, 'x', 1)").Scan(&inside); err == nil {
+		t.Error("expected an error for text coordinates")
+	}
+	if err := db.QueryRow("SELECT bbox_contains(0, 0, 10, 10, '5', ' 1.5 ')").Scan(&inside); err != nil {
+		t.Fatal(err)
+	}
+	if !inside {
+		t.Error("numeric text not in box")
+	}
+
+	var distance sql.NullFloat64
+	if err := db.QueryRow("SELECT distance_haversine(NULL, 0, 1, 1)").Scan(&distance); err != nil {
+		t.Fatal(err)
+	}
+	if distance.Valid {
+		t.Errorf("expected NULL distance but got: %f", distance.Float64)
+	}
+	if err := db.QueryRow("SELECT point_in_polygon(1, 1, NULL) OR bbox_contains(0, 0, 10, 10, NULL, 1)").Scan(&inside); err != nil {
+		t.Fatal(err)
+	}
+	if inside {
+		t.Error("expected NULL points to be outside")
+	}
+}
+
+func TestDecodePolygon(t *testing.T) {
+	polygon := []Point{{1.5, -2}, {3, 4.25}, {-5, 6}}
+	got, err := DecodePolygon(EncodePolygon(polygon))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(polygon) {
+		t.Fatalf("got %v, want %v", got, polygon)
+	}
+	for i := range got {
+		if got[i] != polygon[i] {
+			t.Errorf("point %d: got %v, want %v", i, got[i], polygon[i])
+		}
+	}
+	if got, err := DecodePolygon(ToPolygon(1.0, 2.0, 3.0, 4.0)); err != nil || len(got) != 2 || got[1] != (Point{3, 4}) {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if _, err := DecodePolygon("[[1,2,3]]"); err == nil {
+		t.Error("expected an error for a point of three coordinates")
+	}
+}
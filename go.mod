@@ -1,5 +1,5 @@
 module github.com/paulstuart/sqlite
 
-go 1.13
+go 1.18
 
 require github.com/mattn/go-sqlite3 v1.14.6
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// Row change operations, as reported by UpdateEvent
+const (
+	OpInsert = sqlite3.SQLITE_INSERT
+	OpUpdate = sqlite3.SQLITE_UPDATE
+	OpDelete = sqlite3.SQLITE_DELETE
+)
+
+// UpdateEvent reports a row inserted, updated, or deleted by a connection. It is sent
+// when the change is made, before the transaction making it is committed or rolled back
+type UpdateEvent struct {
+	Op       int    // OpInsert, OpUpdate, or OpDelete
+	Database string // e.g. "main", or the name of an attached database
+	Table    string
+	RowID    int64
+}
+
+// OpName returns the name of the operation, e.g. "INSERT"
+func (e UpdateEvent) OpName() string {
+	switch e.Op {
+	case OpInsert:
+		return "INSERT"
+	case OpUpdate:
+		return "UPDATE"
+	case OpDelete:
+		return "DELETE"
+	}
+	return "UNKNOWN"
+}
+
+// connHooks are the callbacks registered on each new connection
+type connHooks struct {
+	update   []func(UpdateEvent)
+	commit   []func() error
+	rollback []func()
+	capture  *changeCapture
+}
+
+// set reports whether there are any hooks
+func (h *connHooks) set() bool {
+	return len(h.update) > 0 || len(h.commit) > 0 || len(h.rollback) > 0 || h.capture != nil
+}
+
+// register sets the hooks of a new connection
+func (h *connHooks) register(conn *sqlite3.SQLiteConn) {
+	update, commit, rollback := h.update, h.commit, h.rollback
+	if h.capture != nil {
+		// each connection buffers the changes of its transaction until it commits,
+		// and the capture goes last so the other commit hooks can refuse the commit first.
+		// There's no hook for after the commit, so a commit that fails from here on
+		// has still been published, as ChangeStream documents
+		var tx changeBuffer
+		update = append(update[:len(update):len(update)], tx.add)
+		commit = append(commit[:len(commit):len(commit)], func() error {
+			h.capture.publish(tx.flush())
+			return nil
+		})
+		rollback = append(rollback[:len(rollback):len(rollback)], tx.discard)
+	}
+	if len(update) > 0 {
+		conn.RegisterUpdateHook(func(op int, db, table string, rowid int64) {
+			event := UpdateEvent{Op: op, Database: db, Table: table, RowID: rowid}
+			for _, fn := range update {
+				fn(event)
+			}
+		})
+	}
+	if len(commit) > 0 {
+		conn.RegisterCommitHook(func() int {
+			for _, fn := range commit {
+				if fn() != nil {
+					// the commit becomes a rollback
+					return 1
+				}
+			}
+			return 0
+		})
+	}
+	if len(rollback) > 0 {
+		conn.RegisterRollbackHook(func() {
+			for _, fn := range rollback {
+				fn()
+			}
+		})
+	}
+}
+
+// WithUpdateHook calls fn for each row inserted, updated, or deleted by any connection.
+// It is called on the connection's goroutine and must not use the database
+func WithUpdateHook(fn func(UpdateEvent)) Optional {
+	return func(c *Config) {
+		c.hooks.update = append(c.hooks.update, fn)
+	}
+}
+
+// WithUpdateChannel sends each row change to ch, blocking the connection making the
+// change until it is received, so changes are never dropped
+func WithUpdateChannel(ch chan<- UpdateEvent) Optional {
+	return WithUpdateHook(func(e UpdateEvent) {
+		ch <- e
+	})
+}
+
+// WithCommitHook calls fn when a transaction is about to be committed by any connection.
+// If it returns an error the transaction is rolled back instead, and the commit fails
+func WithCommitHook(fn func() error) Optional {
+	return func(c *Config) {
+		c.hooks.commit = append(c.hooks.commit, fn)
+	}
+}
+
+// WithRollbackHook calls fn when a transaction is rolled back by any connection,
+// including a commit turned into a rollback by a commit hook
+func WithRollbackHook(fn func()) Optional {
+	return func(c *Config) {
+		c.hooks.rollback = append(c.hooks.rollback, fn)
+	}
+}
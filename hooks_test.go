@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var events []UpdateEvent
+	var commits, rollbacks int
+	veto := errors.New("veto")
+	var refuse bool
+	db, err := Open(":memory:",
+		WithDriver("hooks"),
+		WithUpdateHook(func(e UpdateEvent) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		}),
+		WithCommitHook(func() error {
+			commits++
+			if refuse {
+				return veto
+			}
+			return nil
+		}),
+		WithRollbackHook(func() { rollbacks++ }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const script = `
+	create table t (n int);
+	insert into t values (1), (2);
+	update t set n = 3 where n = 2;
+	delete from t where n = 1;
+	`
+	if _, err := db.Exec(script); err != nil {
+		t.Fatal(err)
+	}
+	var ops []string
+	for _, e := range events {
+		if e.Table != "t" || e.Database != "main" {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+		ops = append(ops, e.OpName())
+	}
+	if len(ops) != 4 || ops[0] != "INSERT" || ops[2] != "UPDATE" || ops[3] != "DELETE" {
+		t.Fatalf("unexpected events: %v", ops)
+	}
+	if commits == 0 {
+		t.Fatal("expected commit hook to be called")
+	}
+
+	refuse = true
+	if _, err := db.Exec("insert into t values (4)"); err == nil {
+		t.Fatal("expected commit to be refused")
+	}
+	refuse = false
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from t where n = 4"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || rollbacks == 0 {
+		t.Fatalf("expected refused commit to roll back: %d rows, %d rollbacks", count, rollbacks)
+	}
+}
+
+func TestUpdateChannel(t *testing.T) {
+	ch := make(chan UpdateEvent, 10)
+	db, err := Open(":memory:", WithDriver("update_channel"), WithUpdateChannel(ch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("create table t (n int); insert into t values (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if e := <-ch; e.Op != OpInsert || e.RowID != 1 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
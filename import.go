@@ -0,0 +1,196 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ImportBatchSize is the number of rows ImportFrom inserts per transaction,
+// 1000 if it's not positive
+var ImportBatchSize = 1000
+
+// batchSize returns n, or ImportBatchSize if n isn't positive
+func batchSize(n int) int {
+	if n > 0 {
+		return n
+	}
+	if ImportBatchSize > 0 {
+		return ImportBatchSize
+	}
+	return 1000
+}
+
+// TableMapping describes a table to copy with ImportFrom
+type TableMapping struct {
+	Source  string            // the source table
+	Query   string            // the query to read rows, "SELECT * FROM Source" if not set
+	Target  string            // the table to create or append to, Source if not set
+	Columns map[string]string // renamed columns, from source to target name
+}
+
+// integerTypes are the integer types of common databases, which
+// can't be matched by name alone as INT is part of POINT and INTERVAL
+var integerTypes = map[string]bool{
+	"INT": true, "INTEGER": true, "INT2": true, "INT4": true, "INT8": true,
+	"TINYINT": true, "SMALLINT": true, "MEDIUMINT": true, "BIGINT": true,
+}
+
+// affinity returns the sqlite type for a column type reported by another driver
+func affinity(dbType string) string {
+	t := strings.ToUpper(dbType)
+	if i := strings.Index(t, "("); i > 0 {
+		t = t[:i]
+	}
+	t = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(t), " UNSIGNED"))
+	switch {
+	case integerTypes[t], strings.HasPrefix(t, "BOOL"), strings.HasSuffix(t, "SERIAL"):
+		return "INTEGER"
+	case t == "INTERVAL":
+		return "TEXT"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "TEXT"), strings.Contains(t, "CLOB"),
+		strings.Contains(t, "DATE"), strings.Contains(t, "TIME"),
+		t == "UUID", strings.HasPrefix(t, "JSON"), t == "ENUM", t == "SET":
+		return "TEXT"
+	case strings.Contains(t, "BLOB"), strings.Contains(t, "BINARY"), t == "BYTEA":
+		return "BLOB"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "REAL"
+	case strings.Contains(t, "DEC"), strings.Contains(t, "NUM"), t == "MONEY":
+		return "NUMERIC"
+	}
+	return ""
+}
+
+// coerce converts a value read from another driver to one stored with the given affinity
+func coerce(v interface{}, aff string) interface{} {
+	switch x := v.(type) {
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case bool:
+		if x {
+			return int64(1)
+		}
+		return int64(0)
+	case []byte:
+		// many drivers return text as bytes
+		if aff != "BLOB" {
+			return string(x)
+		}
+		return append([]byte(nil), x...)
+	}
+	return v
+}
+
+// ImportFrom copies tables from a database of any driver into dst, creating the target
+// tables if they don't exist, and returns the number of rows copied. Column types are mapped
+// to their sqlite affinity, times are stored as RFC 3339 text, and booleans as 0 or 1.
+// The rows are copied in batches of ImportBatchSize, each in a transaction, so if a table
+// fails part way the count is of the rows committed before
+func ImportFrom(dst, src *sql.DB, tables ...TableMapping) (int64, error) {
+	var total int64
+	for _, m := range tables {
+		n, err := importTable(dst, src, m)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("import %s: %w", m.Source, err)
+		}
+	}
+	return total, nil
+}
+
+func importTable(dst, src *sql.DB, m TableMapping) (int64, error) {
+	q, target := m.Query, m.Target
+	if q == "" {
+		q = "SELECT * FROM " + m.Source
+	}
+	if target == "" {
+		target = m.Source
+	}
+	rows, err := src.Query(q)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	columns := make([]string, len(types))
+	affinities := make([]string, len(types))
+	defs := make([]string, len(types))
+	marks := make([]string, len(types))
+	for i, ct := range types {
+		name := ct.Name()
+		if renamed, ok := m.Columns[name]; ok {
+			name = renamed
+		}
+		columns[i] = quoteIdent(name)
+		affinities[i] = affinity(ct.DatabaseTypeName())
+		defs[i] = strings.TrimSpace(columns[i] + " " + affinities[i])
+		marks[i] = "?"
+	}
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoteIdent(target), strings.Join(defs, ", "))
+	if _, err := dst.Exec(create); err != nil {
+		return 0, err
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)", quoteIdent(target), strings.Join(columns, ", "), strings.Join(marks, ", "))
+
+	values := make([]interface{}, len(types))
+	ptrs := make([]interface{}, len(types))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	batch := batchSize(0)
+	// count is the rows inserted, and committed those in committed batches
+	var count, committed int64
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	for rows.Next() {
+		if tx == nil {
+			if tx, err = dst.Begin(); err != nil {
+				return committed, err
+			}
+			if stmt, err = tx.Prepare(insert); err != nil {
+				return committed, err
+			}
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return committed, err
+		}
+		args := make([]interface{}, len(values))
+		for i, v := range values {
+			args[i] = coerce(v, affinities[i])
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return committed, err
+		}
+		count++
+		if count%int64(batch) == 0 {
+			stmt.Close()
+			err, tx = tx.Commit(), nil
+			if err != nil {
+				return committed, err
+			}
+			committed = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return committed, err
+	}
+	if tx != nil {
+		stmt.Close()
+		err, tx = tx.Commit(), nil
+		if err != nil {
+			return committed, err
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImportFrom(t *testing.T) {
+	dir := t.TempDir()
+	src, err := Open(filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	dst, err := Open(filepath.Join(dir, "dst.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	const setup = `
+	create table users (id integer primary key, name varchar(40), balance decimal(10,2), joined timestamp, active boolean, avatar blob);
+	create table empty (n int);
+	`
+	if _, err := src.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	joined := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 25; i++ {
+		if _, err := src.Exec("insert into users (name, balance, joined, active, avatar) values(?, ?, ?, ?, ?)",
+			"user", 1.5, joined, i%2 == 0, []byte{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(size int) { ImportBatchSize = size }(ImportBatchSize)
+	ImportBatchSize = 10
+	n, err := ImportFrom(dst, src,
+		TableMapping{Source: "users", Target: "people", Columns: map[string]string{"name": "full_name"}},
+		TableMapping{Source: "empty"},
+		TableMapping{Source: "users", Query: "select id from users where active", Target: "active_ids"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 25+13 {
+		t.Fatalf("expected: %d but got: %d", 38, n)
+	}
+
+	// a batch size that isn't positive uses the default
+	ImportBatchSize = 0
+	if n, err := ImportFrom(dst, src, TableMapping{Source: "users", Target: "unbatched"}); err != nil || n != 25 {
+		t.Fatalf("expected 25 rows but got: %d (%v)", n, err)
+	}
+
+	var name, when, kind string
+	var active int
+	var avatar []byte
+	if err := row(dst, []interface{}{&name, &when, &active, &avatar, &kind}, "select full_name, joined, active, avatar, typeof(balance) from people where id=1"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "user" || when != joined.Format(time.RFC3339Nano) || active != 1 || len(avatar) != 3 || kind != "real" {
+		t.Fatalf("unexpected row: %q %q %d %v %s", name, when, active, avatar, kind)
+	}
+	var count int
+	if err := row(dst, []interface{}{&count}, "select count(*) from empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failure part way through a batch counts only the rows committed
+	ImportBatchSize = 10
+	if _, err := dst.Exec("create table limited (id integer check (id <= 15))"); err != nil {
+		t.Fatal(err)
+	}
+	n, err = ImportFrom(dst, src, TableMapping{Source: "users", Query: "select id from users order by id", Target: "limited"})
+	if err == nil || n != 10 {
+		t.Fatalf("expected 10 committed rows and an error but got: %d (%v)", n, err)
+	}
+	if err := row(dst, []interface{}{&count}, "select count(*) from limited"); err != nil || count != 10 {
+		t.Fatalf("expected 10 rows but got: %d (%v)", count, err)
+	}
+}
+
+func TestAffinity(t *testing.T) {
+	for dbType, expected := range map[string]string{
+		"BIGINT":      "INTEGER",
+		"varchar":     "TEXT",
+		"TIMESTAMPTZ": "TEXT",
+		"BYTEA":       "BLOB",
+		"DOUBLE":      "REAL",
+		"NUMERIC":     "NUMERIC",
+		"JSONB":       "TEXT",
+		"GEOMETRY":    "",
+		"POINT":       "",
+		"INTERVAL":    "TEXT",
+		"int(11)":     "INTEGER",
+		"BIGSERIAL":   "INTEGER",
+	} {
+		if got := affinity(dbType); got != expected {
+			t.Errorf("%s expected: %q but got: %q", dbType, expected, got)
+		}
+	}
+}
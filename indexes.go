@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// IndexSpec describes an index for EnsureIndex to create
+type IndexSpec struct {
+	Name    string
+	Table   string
+	Unique  bool
+	Columns []string // columns to index, which are quoted
+	Exprs   []string // expressions to index after the columns, as SQL, e.g. "lower(email)"
+	Where   string   // the condition of a partial index, as SQL, e.g. "deleted_at IS NULL"
+}
+
+// SQL returns the CREATE INDEX statement of the index
+func (s IndexSpec) SQL() string {
+	terms := make([]string, 0, len(s.Columns)+len(s.Exprs))
+	for _, c := range s.Columns {
+		terms = append(terms, quoteIdent(c))
+	}
+	terms = append(terms, s.Exprs...)
+	unique := ""
+	if s.Unique {
+		unique = "UNIQUE "
+	}
+	stmt := fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, quoteIdent(s.Name), quoteIdent(s.Table), strings.Join(terms, ", "))
+	if s.Where != "" {
+		stmt += " WHERE " + s.Where
+	}
+	return stmt
+}
+
+// EnsureIndex creates the index, or if an index of that name exists with another
+// definition, drops it and creates it as specified, in one transaction. It reports
+// whether the index was created. Differences of spacing, case, and quoting between
+// the definitions don't count
+func EnsureIndex(db *sql.DB, spec IndexSpec) (bool, error) {
+	if spec.Name == "" || spec.Table == "" || len(spec.Columns)+len(spec.Exprs) == 0 {
+		return false, fmt.Errorf("index %q needs a name, a table, and something to index", spec.Name)
+	}
+	stmt := spec.SQL()
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	var kind string
+	var current sql.NullString
+	err = tx.QueryRow("SELECT type, sql FROM sqlite_master WHERE name = ? COLLATE NOCASE", spec.Name).Scan(&kind, &current)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return false, err
+	case kind != "index":
+		return false, fmt.Errorf("%s is a %s, not an index", spec.Name, kind)
+	case !current.Valid:
+		return false, fmt.Errorf("index %s belongs to a constraint", spec.Name)
+	case sqlKey(current.String) == sqlKey(stmt):
+		return false, nil
+	default:
+		if _, err := tx.Exec("DROP INDEX " + quoteIdent(spec.Name)); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(stmt); err != nil {
+		return false, fmt.Errorf("index %s: %w", spec.Name, err)
+	}
+	return true, tx.Commit()
+}
+
+// EnsurePartialIndex ensures an index of the columns of the rows of table matching where,
+// e.g. "archived = 0", as EnsureIndex does
+func EnsurePartialIndex(db *sql.DB, table, name string, columns []string, where string) error {
+	_, err := EnsureIndex(db, IndexSpec{Name: name, Table: table, Columns: columns, Where: where})
+	return err
+}
+
+// EnsureExpressionIndex ensures an index of the expressions of table, e.g. "lower(email)",
+// as EnsureIndex does. If where isn't empty the index is partial
+func EnsureExpressionIndex(db *sql.DB, table, name string, exprs []string, where string) error {
+	_, err := EnsureIndex(db, IndexSpec{Name: name, Table: table, Exprs: exprs, Where: where})
+	return err
+}
+
+// indexDefinition returns the indexed terms of a CREATE INDEX statement, and the
+// condition of a partial index
+func indexDefinition(stmt string) ([]string, string) {
+	s := &dumpScanner{src: stmt, dialect: DialectSQLite}
+	st, _, err := s.statement()
+	if err != nil {
+		return nil, ""
+	}
+	tokens := st.tokens
+	i := 0
+	for i < len(tokens) && !tokens[i].is("ON") {
+		i++
+	}
+	for i < len(tokens) && !tokens[i].punct("(") {
+		i++
+	}
+	if i == len(tokens) {
+		return nil, ""
+	}
+	body, end := group(tokens, i)
+	var terms []string
+	for _, item := range splitList(body) {
+		terms = append(terms, renderTokens(item))
+	}
+	var where string
+	if end < len(tokens) && tokens[end].is("WHERE") {
+		where = renderTokens(tokens[end+1:])
+	}
+	return terms, where
+}
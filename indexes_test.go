@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnsureIndex(t *testing.T) {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("create table account (id integer primary key, email text unique, archived int default 0, name text)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsurePartialIndex(db, "account", "live_names", []string{"name"}, "archived = 0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureExpressionIndex(db, "account", "email_lower", []string{"lower(email)", "name COLLATE NOCASE DESC"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := InspectSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := info.Table("account")
+	live := account.index("live_names")
+	if live == nil || !live.Partial || live.Where != "archived = 0" || strings.Join(live.Terms, ",") != `"name"` {
+		t.Fatalf("unexpected partial index: %+v", live)
+	}
+	lower := account.index("email_lower")
+	if lower == nil || lower.Where != "" || strings.Join(lower.Terms, "|") != "lower(email)|name COLLATE NOCASE DESC" {
+		t.Fatalf("unexpected expression index: %+v", lower)
+	}
+	if lower.Columns[0] != "" || lower.Columns[1] != "name" {
+		t.Fatalf("unexpected columns: %q", lower.Columns)
+	}
+
+	// the same definition, written differently, is left alone
+	created, err := EnsureIndex(db, IndexSpec{Name: "LIVE_NAMES", Table: "Account", Columns: []string{"NAME"}, Where: "ARCHIVED=0"})
+	if err != nil || created {
+		t.Fatalf("expected the index to be kept, got %v (%v)", created, err)
+	}
+	created, err = EnsureIndex(db, IndexSpec{Name: "live_names", Table: "account", Unique: true, Columns: []string{"name"}, Where: "archived = 1"})
+	if err != nil || !created {
+		t.Fatalf("expected the index to be replaced, got %v (%v)", created, err)
+	}
+	var stmt string
+	if err := db.QueryRow("select sql from sqlite_master where name = 'live_names'").Scan(&stmt); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stmt, "CREATE UNIQUE INDEX") || !strings.HasSuffix(stmt, "archived = 1") {
+		t.Fatalf("unexpected index: %s", stmt)
+	}
+
+	if _, err := EnsureIndex(db, IndexSpec{Name: "account", Table: "account", Columns: []string{"name"}}); err == nil {
+		t.Fatal("expected error for a name that isn't an index")
+	}
+	if _, err := EnsureIndex(db, IndexSpec{Name: "sqlite_autoindex_account_1", Table: "account", Columns: []string{"name"}}); err == nil {
+		t.Fatal("expected error replacing the index of a constraint")
+	}
+	if err := EnsurePartialIndex(db, "account", "bad", []string{"name"}, "no_such_column > 0"); err == nil {
+		t.Fatal("expected error for an invalid condition")
+	}
+}
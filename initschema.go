@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// WithSchema creates the schema when a new database is opened, one with a user_version
+// and application_id of zero. The schema is applied in a single transaction, which also
+// sets the user_version to 1 if the schema leaves it at zero, so it is applied only once
+func WithSchema(schema string) Optional {
+	return func(c *Config) {
+		c.schema = schema
+	}
+}
+
+// WithSchemaFS is WithSchema for a schema in the .sql files at the top of fsys, which are
+// applied in order of their names, e.g. 01_tables.sql before 02_views.sql
+func WithSchemaFS(fsys fs.FS) Optional {
+	return func(c *Config) {
+		c.schemaFS = fsys
+	}
+}
+
+// schemaScript returns the schema of the config, reading it from its files if necessary
+func (c *Config) schemaScript() (string, error) {
+	if c.schemaFS == nil {
+		return c.schema, nil
+	}
+	entries, err := fs.ReadDir(c.schemaFS, ".")
+	if err != nil {
+		return "", fmt.Errorf("schema: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && path.Ext(entry.Name()) == ".sql" {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString(c.schema)
+	for _, name := range names {
+		script, err := fs.ReadFile(c.schemaFS, name)
+		if err != nil {
+			return "", fmt.Errorf("schema: %w", err)
+		}
+		sb.WriteString("\n")
+		sb.Write(script)
+		// in case the last statement has no semicolon
+		sb.WriteString("\n;\n")
+	}
+	return sb.String(), nil
+}
+
+// initSchema applies the schema if the database is new. The check and the schema share an
+// immediate transaction, so of several processes opening a new database only one applies it
+func (c *Config) initSchema(ctx context.Context, db *sql.DB) error {
+	script, err := c.schemaScript()
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseScript(script)
+	if err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+	// the schema runs in a transaction of its own
+	var stmts []Statement
+	for _, stmt := range parsed {
+		if stmt.Command || !transactionControl(stmt.Text) {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return rawConn(ctx, db, func(conn *sqlite3.SQLiteConn) error {
+		if _, err := conn.Exec("BEGIN IMMEDIATE", nil); err != nil {
+			return err
+		}
+		err := func() error {
+			version, err := pragmaInt(conn, "user_version")
+			if err != nil {
+				return err
+			}
+			id, err := pragmaInt(conn, "application_id")
+			if err != nil || version != 0 || id != 0 {
+				return err
+			}
+			if err := execStatements(ctx, conn, stmts); err != nil {
+				return fmt.Errorf("schema: %w", err)
+			}
+			if version, err = pragmaInt(conn, "user_version"); err != nil || version != 0 {
+				return err
+			}
+			_, err = conn.Exec("PRAGMA user_version = 1", nil)
+			return err
+		}()
+		if err != nil {
+			if !conn.AutoCommit() {
+				conn.Exec("ROLLBACK", nil)
+			}
+			return err
+		}
+		_, err = conn.Exec("COMMIT", nil)
+		return err
+	})
+}
+
+// pragmaInt returns the integer value of a pragma on a driver connection
+func pragmaInt(conn *sqlite3.SQLiteConn, name string) (int64, error) {
+	var value int64
+	fn := func(cols []string, row int, values []driver.Value) error {
+		value, _ = values[0].(int64)
+		return nil
+	}
+	return value, connQuery(conn, fn, "PRAGMA "+name)
+}
@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithSchema(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "schema.db")
+	const schema = `
+	BEGIN TRANSACTION;
+	create table t (n int);
+	insert into t values (1);
+	COMMIT;
+	`
+	for i := 0; i < 2; i++ {
+		db, err := Open(file, WithSchema(schema))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count, version int
+		if err := row(db, []interface{}{&count}, "select count(*) from t"); err != nil {
+			t.Fatal(err)
+		}
+		if err := row(db, []interface{}{&version}, "pragma user_version"); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		// applied once, on the first open
+		if count != 1 || version != 1 {
+			t.Fatalf("open %d: expected 1 row and version 1 but got: %d and %d", i, count, version)
+		}
+	}
+
+	fsys := fstest.MapFS{
+		"02_view.sql":  {Data: []byte("create view v as select n from t; pragma user_version = 7")},
+		"01_table.sql": {Data: []byte("create table t (n int)")},
+		"notes.txt":    {Data: []byte("ignored")},
+	}
+	db, err := Open(filepath.Join(t.TempDir(), "fs.db"), WithSchemaFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version, count int
+	if err := row(db, []interface{}{&count}, "select count(*) from v"); err != nil {
+		t.Fatal(err)
+	}
+	if err := row(db, []interface{}{&version}, "pragma user_version"); err != nil {
+		t.Fatal(err)
+	}
+	if version != 7 {
+		t.Fatalf("expected the version set by the schema but got: %d", version)
+	}
+
+	// a broken schema leaves nothing behind
+	bad := filepath.Join(t.TempDir(), "bad.db")
+	if _, err := Open(bad, WithSchema("create table t (n int); insert into nosuch values (1)")); err == nil {
+		t.Fatal("expected schema error")
+	}
+	db, err = Open(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := row(db, []interface{}{&count}, "select count(*) from sqlite_master"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected an empty database but found %d objects", count)
+	}
+}
@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrNotDatabase is returned by Inspect for a file that isn't a sqlite database
+var ErrNotDatabase = errors.New("not a sqlite database")
+
+// headerSize is the size of the header at the start of a database file
+const headerSize = 100
+
+// FileInfo is what the header of a database file says about it
+type FileInfo struct {
+	Size              int64  // the size of the file
+	PageSize          int    // in bytes
+	WriteVersion      int    // 1 for a rollback journal, 2 for WAL
+	ReadVersion       int    // as WriteVersion
+	ReservedBytes     int    // unused bytes at the end of each page, e.g. for encryption
+	ChangeCounter     uint32 // incremented by each transaction that changes the file, except in WAL mode
+	Pages             uint32 // the size of the database in pages, 0 if the header value is stale
+	FreelistPages     uint32
+	SchemaCookie      uint32 // incremented by each change of the schema
+	SchemaFormat      int    // 1 to 4
+	DefaultCacheSize  int32
+	AutoVacuum        bool
+	IncrementalVacuum bool
+	Encoding          string // UTF-8, UTF-16le, or UTF-16be, as PRAGMA encoding reports it
+	UserVersion       int32
+	ApplicationID     int32
+	VersionValidFor   uint32 // the change counter when SQLiteVersion was stored
+	SQLiteVersion     int    // the library that last wrote the file, e.g. 3034000
+}
+
+// WAL reports whether the file is in WAL mode
+func (fi FileInfo) WAL() bool {
+	return fi.WriteVersion == 2 || fi.ReadVersion == 2
+}
+
+// Empty reports whether the file is empty, which sqlite opens as a new database
+func (fi FileInfo) Empty() bool {
+	return fi.Size == 0
+}
+
+// encodings are the text encodings by their number in the header
+var encodings = map[uint32]string{1: "UTF-8", 2: "UTF-16le", 3: "UTF-16be"}
+
+// Inspect reads the header of a database file without opening it as a database, so a file
+// can be classified, or rejected, before it is opened. A file that isn't a database returns
+// an error wrapping ErrNotDatabase. An empty file returns a FileInfo with only Size set.
+// The header of a database in WAL mode may be behind the log, whose changes aren't counted
+func Inspect(path string) (FileInfo, error) {
+	var fi FileInfo
+	f, err := os.Open(path)
+	if err != nil {
+		return fi, redactError(err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return fi, redactError(err)
+	}
+	if st.IsDir() {
+		return fi, redactf("%s: %w", path, ErrNotDatabase)
+	}
+	fi.Size = st.Size()
+	if fi.Size == 0 {
+		return fi, nil
+	}
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return fi, redactf("%s: %w", path, ErrNotDatabase)
+		}
+		return fi, redactError(err)
+	}
+	if err := parseHeader(header, &fi); err != nil {
+		return fi, redactf("%s: %w", path, err)
+	}
+	return fi, nil
+}
+
+// parseHeader fills in fi from the header of a database file
+func parseHeader(header []byte, fi *FileInfo) error {
+	if !bytes.HasPrefix(header, sqliteHeader) {
+		return ErrNotDatabase
+	}
+	be := binary.BigEndian
+	fi.PageSize = int(be.Uint16(header[16:]))
+	if fi.PageSize == 1 {
+		fi.PageSize = 65536
+	}
+	if fi.PageSize < 512 || fi.PageSize&(fi.PageSize-1) != 0 {
+		return fmt.Errorf("%w: invalid page size %d", ErrNotDatabase, fi.PageSize)
+	}
+	// the payload fractions must be 64, 32, and 32
+	if header[21] != 64 || header[22] != 32 || header[23] != 32 {
+		return fmt.Errorf("%w: invalid payload fractions", ErrNotDatabase)
+	}
+	fi.WriteVersion, fi.ReadVersion = int(header[18]), int(header[19])
+	fi.ReservedBytes = int(header[20])
+	fi.ChangeCounter = be.Uint32(header[24:])
+	fi.Pages = be.Uint32(header[28:])
+	fi.FreelistPages = be.Uint32(header[36:])
+	fi.SchemaCookie = be.Uint32(header[40:])
+	fi.SchemaFormat = int(be.Uint32(header[44:]))
+	fi.DefaultCacheSize = int32(be.Uint32(header[48:]))
+	fi.AutoVacuum = be.Uint32(header[52:]) != 0
+	fi.Encoding = encodings[be.Uint32(header[56:])]
+	fi.UserVersion = int32(be.Uint32(header[60:]))
+	fi.IncrementalVacuum = be.Uint32(header[64:]) != 0
+	fi.ApplicationID = int32(be.Uint32(header[68:]))
+	fi.VersionValidFor = be.Uint32(header[92:])
+	fi.SQLiteVersion = int(be.Uint32(header[96:]))
+	// the page count is only valid if it was written with the change counter
+	if fi.VersionValidFor != fi.ChangeCounter {
+		fi.Pages = 0
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "inspect.db")
+	db, err := Open(file, WithDriver("inspect_wal"), WithJournalMode(JournalWAL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const setup = `
+PRAGMA user_version = 7;
+PRAGMA application_id = 1234;
+create table t (id integer primary key, s text);
+insert into t (s) values ('a'), ('b');
+`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	fi, err := Inspect(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.PageSize != 4096 || !fi.WAL() || fi.Encoding != "UTF-8" || fi.UserVersion != 7 || fi.ApplicationID != 1234 {
+		t.Fatalf("unexpected header: %+v", fi)
+	}
+	if fi.Pages < 2 || int64(fi.Pages)*int64(fi.PageSize) != fi.Size {
+		t.Fatalf("expected %d pages of %d bytes, got %+v", fi.Pages, fi.PageSize, fi)
+	}
+	if fi.SchemaFormat != 4 || fi.SQLiteVersion < 3000000 || fi.SchemaCookie == 0 {
+		t.Fatalf("unexpected header: %+v", fi)
+	}
+
+	empty := filepath.Join(dir, "empty.db")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := Inspect(empty); err != nil || !fi.Empty() {
+		t.Fatalf("expected an empty file, got %+v: %v", fi, err)
+	}
+
+	text := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(text, []byte("SQLite format 3 is not enough"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Inspect(text); !errors.Is(err, ErrNotDatabase) {
+		t.Fatalf("expected not a database, got %v", err)
+	}
+	if _, err := Inspect(dir); !errors.Is(err, ErrNotDatabase) {
+		t.Fatalf("expected not a database for a directory, got %v", err)
+	}
+	if _, err := Inspect(filepath.Join(dir, "missing.db")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing file, got %v", err)
+	}
+}
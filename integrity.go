@@ -0,0 +1,349 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// Problem is a problem reported by PRAGMA integrity_check or quick_check
+type Problem struct {
+	Message  string // as sqlite reported it, without the database header
+	Database string // the schema the problem is in, e.g. "main"
+	Table    string // the table with the problem, if known
+	Index    string // the index with the problem, if any
+	Column   string // the column holding a NULL that isn't allowed, if that is the problem
+	Page     int    // the page with the problem, if any
+	Row      int64  // the rowid of the row with the problem, if any
+}
+
+func (p Problem) String() string {
+	return p.Message
+}
+
+var (
+	// problemHeader starts the first problem found in each attached database
+	problemHeader = regexp.MustCompile(`^\*\*\* in database (\S+) \*\*\*\n`)
+
+	problemPatterns = []struct {
+		re     *regexp.Regexp
+		fields []string
+	}{
+		{regexp.MustCompile(`^row (\d+) missing from index (.+)$`), []string{"row", "index"}},
+		{regexp.MustCompile(`^wrong # of entries in index (.+)$`), []string{"index"}},
+		{regexp.MustCompile(`^non-unique entry in index (.+)$`), []string{"index"}},
+		{regexp.MustCompile(`^NULL value in ([^.]+)\.(.+)$`), []string{"table", "column"}},
+		{regexp.MustCompile(`^CHECK constraint failed in (.+)$`), []string{"table"}},
+		{regexp.MustCompile(`(?i)\bpage (\d+)`), []string{"page"}},
+	}
+)
+
+// parseProblem returns the parts of a problem message
+func parseProblem(msg, database string) Problem {
+	p := Problem{Message: msg, Database: database}
+	if m := problemHeader.FindStringSubmatch(msg); m != nil {
+		p.Database, p.Message = m[1], msg[len(m[0]):]
+	}
+	for _, pattern := range problemPatterns {
+		m := pattern.re.FindStringSubmatch(p.Message)
+		if m == nil {
+			continue
+		}
+		for i, field := range pattern.fields {
+			switch value := m[i+1]; field {
+			case "row":
+				p.Row, _ = strconv.ParseInt(value, 10, 64)
+			case "page":
+				p.Page, _ = strconv.Atoi(value)
+			case "index":
+				p.Index = value
+			case "table":
+				p.Table = value
+			case "column":
+				p.Column = value
+			}
+		}
+		break
+	}
+	return p
+}
+
+// IntegrityCheck returns the problems found by PRAGMA integrity_check, or by the quicker
+// quick_check, which doesn't check that indexes match their tables, if full is false.
+// It returns nil if there are none. The table of a problem with an index is filled in.
+// Damage that stops the check part way is returned as the last problem
+func IntegrityCheck(db *sql.DB, full bool) ([]Problem, error) {
+	return IntegrityCheckContext(context.Background(), db, full)
+}
+
+// IntegrityCheckContext is IntegrityCheck with a context
+func IntegrityCheckContext(ctx context.Context, db *sql.DB, full bool) ([]Problem, error) {
+	pragma := "quick_check"
+	if full {
+		pragma = "integrity_check"
+	}
+	rows, err := db.QueryContext(ctx, "PRAGMA "+pragma)
+	if err != nil {
+		return nil, fmt.Errorf("pragma %s: %w", pragma, err)
+	}
+	defer rows.Close()
+	var problems []Problem
+	database := "main"
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, err
+		}
+		if msg == "ok" {
+			continue
+		}
+		p := parseProblem(msg, database)
+		database = p.Database
+		problems = append(problems, p)
+	}
+	if err := rows.Err(); err != nil {
+		// the damage can stop the check part way, which is a problem of its own
+		var serr sqlite3.Error
+		if !errors.As(err, &serr) || serr.Code != sqlite3.ErrCorrupt {
+			return nil, err
+		}
+		problems = append(problems, Problem{Message: err.Error(), Database: database})
+	}
+	rows.Close()
+	for i := range problems {
+		p := &problems[i]
+		if p.Index == "" || p.Table != "" {
+			continue
+		}
+		q := fmt.Sprintf("SELECT tbl_name FROM %s.sqlite_master WHERE type = 'index' AND name = ?", quoteIdent(p.Database))
+		if err := db.QueryRowContext(ctx, q, p.Index).Scan(&p.Table); err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// RepairReport describes what Repair salvaged
+type RepairReport struct {
+	Tables  []TableRepair
+	Skipped []string // the objects that could not be created in the new database, and why
+}
+
+// TableRepair is what Repair salvaged of a table
+type TableRepair struct {
+	Table  string
+	Rows   int64 // the rows copied
+	Errors int   // the damaged places skipped past, around which rows may be lost
+}
+
+// Repair salvages what it can read of db into a new database file dest, as the sqlite3
+// shell's .recover does, though it only reads the database through SQL. Rows are read in
+// rowid order, and when a damaged page stops the read it starts again past the damage.
+// Indexes, views, and triggers are created after the rows are copied, and any that can't
+// be, such as a unique index broken by the damage, are listed in the report
+func Repair(db *sql.DB, dest string) (*RepairReport, error) {
+	return RepairContext(context.Background(), db, dest)
+}
+
+// RepairContext is Repair with a context
+func RepairContext(ctx context.Context, db *sql.DB, dest string) (*RepairReport, error) {
+	if _, err := os.Stat(dest); err == nil {
+		return nil, redactf("repair: %s already exists", dest)
+	}
+	objects, err := Schema(db)
+	if err != nil {
+		return nil, fmt.Errorf("repair: schema is unreadable: %w", err)
+	}
+	to, err := Open(dest)
+	if err != nil {
+		return nil, err
+	}
+	defer to.Close()
+
+	report := &RepairReport{}
+	skip := func(obj SchemaObject, err error) {
+		report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s: %v", obj.Kind, obj.Name, err))
+	}
+	var sequences bool
+	for _, obj := range objects {
+		if obj.Kind != "table" {
+			continue
+		}
+		if _, err := to.ExecContext(ctx, obj.SQL); err != nil {
+			skip(obj, err)
+			continue
+		}
+		sequences = sequences || strings.Contains(strings.ToUpper(obj.SQL), "AUTOINCREMENT")
+		repaired, err := repairTable(ctx, db, to, obj)
+		if err != nil {
+			return report, fmt.Errorf("repair %s: %w", obj.Name, err)
+		}
+		report.Tables = append(report.Tables, repaired)
+	}
+	for _, obj := range objects {
+		if obj.Kind == "table" {
+			continue
+		}
+		if _, err := to.ExecContext(ctx, obj.SQL); err != nil {
+			skip(obj, err)
+		}
+	}
+	if sequences {
+		// best effort: the counters only keep rowids from being used again
+		repairSequences(ctx, db, to)
+	}
+	for _, pragma := range []string{"user_version", "application_id"} {
+		var n int64
+		if err := db.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(&n); err == nil && n != 0 {
+			if _, err := to.ExecContext(ctx, fmt.Sprintf("PRAGMA %s=%d", pragma, n)); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// repairTable copies the readable rows of a table, skipping past damaged pages
+func repairTable(ctx context.Context, db, to *sql.DB, obj SchemaObject) (TableRepair, error) {
+	repaired := TableRepair{Table: obj.Name}
+	columns, err := exportColumns(db, obj.Name)
+	if err != nil {
+		return repaired, err
+	}
+	rowid := !withoutRowID(obj.SQL)
+	var names, exprs, marks []string
+	if rowid {
+		names, exprs, marks = append(names, "rowid"), append(exprs, "rowid"), append(marks, "?")
+	}
+	for _, c := range columns {
+		names = append(names, quoteIdent(c.name))
+		// an expression has no declared type, so the driver doesn't convert the value
+		exprs = append(exprs, fmt.Sprintf("coalesce(%s, NULL)", quoteIdent(c.name)))
+		marks = append(marks, "?")
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), quoteIdent(obj.Name))
+	if rowid {
+		query += " WHERE rowid >= ? ORDER BY rowid"
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(obj.Name), strings.Join(names, ", "), strings.Join(marks, ", "))
+
+	tx, err := to.BeginTx(ctx, nil)
+	if err != nil {
+		return repaired, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, insert)
+	if err != nil {
+		return repaired, err
+	}
+	defer stmt.Close()
+
+	values := make([]interface{}, len(names))
+	ptrs := make([]interface{}, len(names))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var args []interface{}
+	from := int64(-1 << 63)
+	for {
+		if rowid {
+			args = []interface{}{from}
+		}
+		last, n, err := repairRows(ctx, db, stmt, query, args, values, ptrs)
+		repaired.Rows += n
+		if werr, ok := err.(writeError); ok {
+			return repaired, werr.err
+		}
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return repaired, ctx.Err()
+		}
+		repaired.Errors++
+		if !rowid {
+			break
+		}
+		if n > 0 {
+			from = last
+		}
+		var ok bool
+		if from, ok = nextReadable(ctx, db, obj.Name, from); !ok {
+			break
+		}
+	}
+	return repaired, tx.Commit()
+}
+
+// writeError is an error writing a salvaged row, which unlike one reading it stops Repair
+type writeError struct{ err error }
+
+func (e writeError) Error() string { return e.err.Error() }
+
+// repairRows copies rows until the end of the table or an error, returning the rowid
+// of the last row copied and how many were
+func repairRows(ctx context.Context, db *sql.DB, stmt *sql.Stmt, query string, args, values, ptrs []interface{}) (int64, int64, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	var last, n int64
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return last, n, err
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return last, n, writeError{err}
+		}
+		if id, ok := values[0].(int64); ok {
+			last = id
+		}
+		n++
+	}
+	return last, n, rows.Err()
+}
+
+// nextReadable looks for a rowid after the damage following rowid from that a read
+// can start at, trying ever further ahead
+func nextReadable(ctx context.Context, db *sql.DB, table string, from int64) (int64, bool) {
+	q := fmt.Sprintf("SELECT rowid FROM %s WHERE rowid > ? ORDER BY rowid LIMIT 1", quoteIdent(table))
+	for step := int64(1); step > 0 && from+step > from; step *= 2 {
+		var next int64
+		switch err := db.QueryRowContext(ctx, q, from+step).Scan(&next); err {
+		case nil:
+			return next, true
+		case sql.ErrNoRows:
+			return 0, false
+		}
+		if ctx.Err() != nil {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// repairSequences copies the AUTOINCREMENT counters that can be read
+func repairSequences(ctx context.Context, db, to *sql.DB) {
+	rows, err := db.QueryContext(ctx, "SELECT name, seq FROM sqlite_sequence")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var seq int64
+		if rows.Scan(&name, &seq) != nil {
+			return
+		}
+		// copying the rows set the counters to the largest rowid copied, which may be less
+		to.ExecContext(ctx, "UPDATE sqlite_sequence SET seq = max(seq, ?) WHERE name = ?", seq, name)
+	}
+}
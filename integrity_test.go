@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProblem(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want Problem
+	}{
+		{"*** in database main ***\nPage 7 is never used", Problem{Message: "Page 7 is never used", Database: "main", Page: 7}},
+		{"row 12 missing from index idx_name", Problem{Message: "row 12 missing from index idx_name", Database: "aux", Index: "idx_name", Row: 12}},
+		{"NULL value in people.name", Problem{Message: "NULL value in people.name", Database: "aux", Table: "people", Column: "name"}},
+		{"On tree page 3 cell 0: invalid page number 99", Problem{Message: "On tree page 3 cell 0: invalid page number 99", Database: "aux", Page: 3}},
+	}
+	for _, tt := range tests {
+		if got := parseProblem(tt.msg, "aux"); got != tt.want {
+			t.Errorf("parseProblem(%q) = %+v, want %+v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestIntegrityCheckAndRepair(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "damaged.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const setup = `
+PRAGMA user_version = 3;
+create table item (id integer primary key autoincrement, name text, body text);
+create index item_name on item(name);
+create view names as select name from item;
+with recursive n(i) as (select 1 union all select i + 1 from n where i < 2000)
+insert into item (name, body) select 'item ' || i, printf('%.200c', 'x') from n;
+`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := IntegrityCheck(db, true)
+	if err != nil || problems != nil {
+		t.Fatalf("expected no problems, got %v (%v)", problems, err)
+	}
+	var pages int
+	if err := db.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// overwrite a leaf page of the table in the middle of the file
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pageSize = 4096
+	page := pages / 4
+	copy(data[page*pageSize:], bytes.Repeat([]byte{0xff}, pageSize))
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	problems, err = IntegrityCheck(db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) == 0 {
+		t.Fatal("expected problems with the damaged page")
+	}
+
+	dest := filepath.Join(dir, "repaired.db")
+	report, err := Repair(db, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Tables) != 1 || report.Tables[0].Errors == 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if rows := report.Tables[0].Rows; rows < 1900 || rows >= 2000 {
+		t.Fatalf("expected most rows to be salvaged, got %d", rows)
+	}
+
+	repaired, err := Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repaired.Close()
+	if problems, err := IntegrityCheck(repaired, true); err != nil || problems != nil {
+		t.Fatalf("expected the repaired database to be sound, got %v (%v)", problems, err)
+	}
+	var last, version int64
+	if err := repaired.QueryRow("select max(id) from item").Scan(&last); err != nil {
+		t.Fatal(err)
+	}
+	if err := repaired.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if last != 2000 || version != 3 {
+		t.Fatalf("expected the last row and user_version, got %d and %d", last, version)
+	}
+	if _, err := repaired.Exec("select * from names limit 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Repair(db, dest); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected error repairing into an existing file, got %v", err)
+	}
+}
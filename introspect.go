@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+// ErrNoIntrospection is returned when the library was built without the introspection pragmas
+var ErrNoIntrospection = errors.New("sqlite was built without introspection pragmas")
+
+// FunctionInfo is a row of PRAGMA function_list, one per name and number of arguments
+type FunctionInfo struct {
+	Name     string
+	Builtin  bool
+	Type     string // "s" for scalar, "a" for aggregate, or "w" for window functions
+	Encoding string
+	Args     int // -1 for any number
+	Flags    int
+}
+
+// ListCollations returns the sorted names of the collations of a connection
+func ListCollations(db *sql.DB) ([]string, error) {
+	list, err := CollationList(db)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// ListFunctions returns the functions of a connection, sorted by name,
+// including those registered by this package
+func ListFunctions(db *sql.DB) ([]FunctionInfo, error) {
+	rows, err := PragmaRows(db, "function_list")
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		// unknown pragmas are ignored, and there are always builtin functions
+		return nil, ErrNoIntrospection
+	}
+	list := make([]FunctionInfo, len(rows))
+	for i, row := range rows {
+		fn := &list[i]
+		var builtin int
+		if err := pragmaInts("function_list", row, nil, &builtin, nil, nil, &fn.Args, &fn.Flags); err != nil {
+			return nil, err
+		}
+		fn.Name, fn.Builtin, fn.Type, fn.Encoding = row[0], builtin != 0, row[2], row[3]
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// ListModules returns the sorted names of the virtual table modules of a connection
+func ListModules(db *sql.DB) ([]string, error) {
+	rows, err := PragmaRows(db, "module_list")
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrNoIntrospection
+	}
+	list := make([]string, len(rows))
+	for i, row := range rows {
+		list[i] = row[0]
+	}
+	sort.Strings(list)
+	return list, nil
+}
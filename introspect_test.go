@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestIntrospection(t *testing.T) {
+	db, err := Open(":memory:",
+		WithDriver("introspection"),
+		WithFunctions(FuzzyFuncs...),
+		WithAggregates(StatAggregates...),
+		WithCollations(UnicodeCollations...),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	collations, err := ListCollations(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range UnicodeCollations {
+		if i := sort.SearchStrings(collations, c.Name); i == len(collations) || collations[i] != c.Name {
+			t.Errorf("collation %s not listed: %q", c.Name, collations)
+		}
+	}
+
+	functions, err := ListFunctions(db)
+	if errors.Is(err, ErrNoIntrospection) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, fn := range functions {
+		if !fn.Builtin {
+			types[fn.Name] = fn.Type
+		}
+	}
+	for _, fn := range FuzzyFuncs {
+		if types[fn.Name] != "s" {
+			t.Errorf("function %s not listed", fn.Name)
+		}
+	}
+	for _, agg := range StatAggregates {
+		if types[agg.Name] == "" {
+			t.Errorf("aggregate %s not listed", agg.Name)
+		}
+	}
+
+	modules, err := ListModules(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := sort.SearchStrings(modules, "rtree"); i == len(modules) || modules[i] != "rtree" {
+		t.Fatalf("rtree not listed: %q", modules)
+	}
+}
@@ -0,0 +1,310 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// JSONFuncs are Go versions of the JSON1 functions json_extract and json_valid,
+// for a library built without JSON1. As Go functions can't return values of
+// varying type, json_extract returns every value as text, with true and false
+// as 1 and 0, and null or a missing value as empty text
+var JSONFuncs = []FuncReg{
+	{"json_extract", jsonExtract, true},
+	{"json_valid", jsonValid, true},
+}
+
+// WithJSON makes sure the JSON1 functions json_extract and json_valid can be used,
+// registering JSONFuncs on connections whose library doesn't have them
+func WithJSON() Optional {
+	return func(c *Config) {
+		c.json = true
+	}
+}
+
+// jsonConn registers JSONFuncs on the connection if the library lacks JSON1
+func jsonConn(conn *sqlite3.SQLiteConn) error {
+	if _, err := conn.Exec("SELECT json_extract('{}', '$')", nil); err == nil {
+		return nil
+	}
+	for _, fn := range JSONFuncs {
+		if err := conn.RegisterFunc(fn.Name, fn.Impl, fn.Pure); err != nil {
+			return fmt.Errorf("failed to register %q: %w", fn.Name, err)
+		}
+	}
+	return nil
+}
+
+func jsonValid(doc string) bool {
+	return json.Valid([]byte(doc))
+}
+
+func jsonExtract(doc string, paths ...string) (string, error) {
+	v, err := jsonDecode([]byte(doc))
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 1 {
+		found, ok, err := jsonPath(v, paths[0])
+		if err != nil || !ok {
+			return "", err
+		}
+		return jsonText(found), nil
+	}
+	// several paths give an array of the values found, as JSON
+	values := make([]interface{}, len(paths))
+	for i, path := range paths {
+		found, _, err := jsonPath(v, path)
+		if err != nil {
+			return "", err
+		}
+		values[i] = found
+	}
+	out, err := json.Marshal(values)
+	return string(out), err
+}
+
+// jsonDecode decodes a JSON document, keeping numbers as written
+func jsonDecode(doc []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("malformed JSON")
+	}
+	return v, nil
+}
+
+// jsonText returns a decoded JSON value as json_extract returns it, as text
+func jsonText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
+
+// jsonPath returns the value at a JSON1 path, such as `$.items[0]."first name"` or
+// `$.items[#-1]`, and whether there is one
+func jsonPath(v interface{}, path string) (interface{}, bool, error) {
+	bad := fmt.Errorf("JSON path error near '%s'", path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, false, bad
+	}
+	for rest := path[1:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			var key string
+			if rest = rest[1:]; strings.HasPrefix(rest, `"`) {
+				end := strings.IndexByte(rest[1:], '"')
+				if end < 0 {
+					return nil, false, bad
+				}
+				key, rest = rest[1:end+1], rest[end+2:]
+			} else {
+				end := strings.IndexAny(rest, ".[")
+				if end < 0 {
+					end = len(rest)
+				}
+				key, rest = rest[:end], rest[end:]
+			}
+			if key == "" {
+				return nil, false, bad
+			}
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false, nil
+			}
+			if v, ok = obj[key]; !ok {
+				return nil, false, nil
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, false, bad
+			}
+			index := rest[1:end]
+			rest = rest[end+1:]
+			arr, ok := v.([]interface{})
+			fromEnd := strings.HasPrefix(index, "#")
+			if fromEnd {
+				index = strings.TrimPrefix(strings.TrimPrefix(index, "#"), "-")
+				if index == "" {
+					index = "0"
+				}
+			}
+			i, err := strconv.Atoi(index)
+			if err != nil || i < 0 {
+				return nil, false, bad
+			}
+			if !ok {
+				return nil, false, nil
+			}
+			if fromEnd {
+				i = len(arr) - i
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, false, nil
+			}
+			v = arr[i]
+		default:
+			return nil, false, bad
+		}
+	}
+	return v, true, nil
+}
+
+// jsonComposite reports whether values of the type are stored as JSON text
+func jsonComposite(t reflect.Type) bool {
+	if _, ok := lookupType(t); ok {
+		return false
+	}
+	if t.Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Array:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Struct:
+		return t != reflect.TypeOf(time.Time{})
+	case reflect.Ptr:
+		return jsonComposite(t.Elem())
+	}
+	return false
+}
+
+// InsertJSON inserts a struct into table, with a column for each exported field,
+// named by its `db` tag or else the field name. Fields that are structs, maps, or
+// slices are stored as JSON text. It returns the rowid of the new row
+func InsertJSON(db *sql.DB, table string, v interface{}) (int64, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("can't insert %T: not a struct", v)
+	}
+	t := rv.Type()
+	var columns, marks []string
+	var args []interface{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		name := f.Tag.Get("db")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		arg := rv.Field(i).Interface()
+		if jsonComposite(f.Type) {
+			text, err := json.Marshal(arg)
+			if err != nil {
+				return 0, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			arg = string(text)
+		}
+		columns = append(columns, quoteIdent(name))
+		marks = append(marks, "?")
+		args = append(args, arg)
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("can't insert %T: no exported fields", v)
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)", quoteIdent(table), strings.Join(columns, ", "), strings.Join(marks, ", "))
+	res, err := db.Exec(stmt, Args(args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// SelectJSON reads the JSON documents in a column of the rows of table matching
+// where, which may be empty, and fills a T from each, setting the fields tagged with
+// a JSON1 path to the value found there, e.g. `jsonpath:"$.address.city"`. Fields
+// of a path that isn't in a document are left unset. The documents are read here,
+// whether or not the library has JSON1
+func SelectJSON[T any](db *sql.DB, table, column, where string, args ...interface{}) ([]T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't select into %s: not a struct", t)
+	}
+	type field struct {
+		index int
+		path  string
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if path := f.Tag.Get("jsonpath"); path != "" && f.PkgPath == "" {
+			fields = append(fields, field{i, path})
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s has no fields tagged with a jsonpath", t)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", quoteIdent(column), quoteIdent(table))
+	if where != "" {
+		query += " WHERE " + where
+	}
+	rows, err := db.Query(query, Args(args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []T
+	for rows.Next() {
+		var doc sql.NullString
+		if err := rows.Scan(&doc); err != nil {
+			return nil, err
+		}
+		var v T
+		if doc.Valid {
+			decoded, err := jsonDecode([]byte(doc.String))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", len(results)+1, err)
+			}
+			rv := reflect.ValueOf(&v).Elem()
+			for _, f := range fields {
+				found, ok, err := jsonPath(decoded, f.path)
+				if err != nil {
+					return nil, err
+				}
+				if !ok || found == nil {
+					continue
+				}
+				text, err := json.Marshal(found)
+				if err != nil {
+					return nil, err
+				}
+				if err := json.Unmarshal(text, rv.Field(f.index).Addr().Interface()); err != nil {
+					return nil, fmt.Errorf("row %d: %s: %w", len(results)+1, t.Field(f.index).Name, err)
+				}
+			}
+		}
+		results = append(results, v)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestJSONExtract(t *testing.T) {
+	db, err := Open(":memory:", WithDriver("json_funcs"), WithJSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	doc := `{"a": {"b": [1, 2, {"c": "three"}]}, "x y": true, "n": null}`
+	tests := []struct {
+		path, want string
+	}{
+		{"$.a.b[0]", "1"},
+		{"$.a.b[2].c", "three"},
+		{"$.a.b[#-1].c", "three"},
+		{`$."x y"`, "1"},
+		{"$.n", ""},
+		{"$.missing", ""},
+		{"$.a.b", "[1,2,{\"c\":\"three\"}]"},
+	}
+	for _, test := range tests {
+		var got string
+		if err := db.QueryRow("SELECT ifnull(json_extract(?, ?), '')", doc, test.path).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", test.path, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.path, got, test.want)
+		}
+	}
+	var valid bool
+	if err := db.QueryRow("SELECT json_valid('{')").Scan(&valid); err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("invalid JSON is valid")
+	}
+}
+
+func TestInsertSelectJSON(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	}
+	type person struct {
+		ID      int64 `db:"id"`
+		Name    string
+		Tags    []string
+		Address address
+		Skip    string `db:"-"`
+	}
+	if _, err := db.Exec("CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, tags TEXT, address TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	id, err := InsertJSON(db, "people", person{ID: 7, Name: "ann", Tags: []string{"a", "b"}, Address: address{"Paris", "75001"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 7 {
+		t.Errorf("got id %d, want 7", id)
+	}
+	if _, err := InsertJSON(db, "people", &person{ID: 8, Name: "bob", Address: address{City: "Rome"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	type place struct {
+		City string `jsonpath:"$.city"`
+		Zip  string `jsonpath:"$.zip"`
+	}
+	places, err := SelectJSON[place](db, "people", "address", "name = ?", "ann")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(places) != 1 || places[0] != (place{"Paris", "75001"}) {
+		t.Errorf("got %+v", places)
+	}
+
+	type tagged struct {
+		First string   `jsonpath:"$[0]"`
+		All   []string `jsonpath:"$"`
+	}
+	tags, err := SelectJSON[tagged](db, "people", "tags", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].First != "a" || len(tags[0].All) != 2 || tags[1].First != "" {
+		t.Errorf("got %+v", tags)
+	}
+}
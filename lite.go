@@ -1,33 +1,36 @@
 package sqlite
 
 import (
+	"bufio"
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
-	"path/filepath"
-	"regexp"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	sqlite3 "github.com/mattn/go-sqlite3"
 )
 
 var (
-	rmu, imu sync.Mutex
+	imu sync.Mutex
 )
 
-// N/A, impacts db, or multi-column -- ignore for now
+// N/A, impacts db, or multi-column -- see PragmaRows
 //collation_list
 //database_list
 //foreign_key_check
 //foreign_key_list
-//quick_check
+//integrity_check, quick_check -- see IntegrityCheck
 //wal_checkpoint
 
 const (
@@ -74,36 +77,23 @@ const (
 )
 
 var (
-	pragmas    = strings.Fields(pragmaList)
-	commentC   = regexp.MustCompile(`(?s)/\*.*?\*/`)
-	commentSQL = regexp.MustCompile(`\s*--.*`)
+	pragmas = strings.Fields(pragmaList)
 
-	registry    = make(map[string]*sqlite3.SQLiteConn)
-	initialized = make(map[string]struct{})
+	initialized = make(map[string]*Config)
+	configs     = make(map[driver.Driver]*Config)
 
 	// Debug enables debugging  output
 	Debug = false
+
+	// StrictMode makes helpers return the errors they would otherwise ignore, such as failing
+	// to read the file name of a database, which is then taken to be a memory database.
+	// Filename and Pragmas, which have no error to return, log them instead
+	StrictMode = false
 )
 
 // Hook is an SQLite connection hook
 type Hook func(*sqlite3.SQLiteConn) error
 
-func register(file string, conn *sqlite3.SQLiteConn) {
-	file, _ = filepath.Abs(file)
-	if len(file) > 0 {
-		rmu.Lock()
-		registry[file] = conn
-		rmu.Unlock()
-	}
-}
-
-func registered(file string) *sqlite3.SQLiteConn {
-	rmu.Lock()
-	conn := registry[file]
-	rmu.Unlock()
-	return conn
-}
-
 func toIPv4(ip int64) string {
 	a := (ip >> 24) & 0xFF
 	b := (ip >> 16) & 0xFF
@@ -132,6 +122,15 @@ type FuncReg struct {
 	Pure bool
 }
 
+// AggregateReg contains the fields necessary to register a custom Sqlite aggregate function
+//
+// Impl is a constructor returning a type with Step and Done methods, see sqlite3.RegisterAggregator
+type AggregateReg struct {
+	Name string
+	Impl interface{}
+	Pure bool
+}
+
 // ipFuncs have example functions to convert ipv4 to and from int32
 var ipFuncs = []FuncReg{
 	{"iptoa", toIPv4, true},
@@ -139,44 +138,144 @@ var ipFuncs = []FuncReg{
 	{"polygon", ToPolygon, true},
 }
 
-// The only way to get access to the sqliteconn, which is needed to be able to generate
-// a backup from the database while it is open. This is a less than satisfactory approach
-// because there's no way to have multiple instances open associate the connection with the DSN
-//
-// Since our use case is to normally have one instance open this should be workable for now
+// sqlInit registers a driver with the given connection settings
 func sqlInit(driverName, query string, hook Hook, funcs ...FuncReg) {
+	sqlRegister(&Config{driver: driverName, query: query, hook: hook, funcs: funcs})
+}
+
+// sqlRegister registers a driver for the config, once per driver name.
+// Connections are not tracked here: conn-level features such as Backup take a
+// connection from the pool of the *sql.DB they are given (see RawConn), so any
+// number of databases and pools can be open at once. It returns the config
+// registered for the driver name, which is an earlier one if already registered
+func sqlRegister(config *Config) *Config {
+	driverName := config.driver
 	if Debug {
-		log.Println("registering driver:", driverName)
+		logf("registering driver: %s", driverName)
 	}
 	imu.Lock()
 	defer imu.Unlock()
 
-	if _, ok := initialized[driverName]; ok {
-		return
+	if registered, ok := initialized[driverName]; ok {
+		return registered
+	}
+	initialized[driverName] = config
+
+	drvr := newDriver(config)
+	configs[drvr] = config
+	sql.Register(driverName, drvr)
+	return config
+}
+
+// connects reports whether the config sets up connections in any way, rather
+// than leaving that to the config registered for its driver name
+func (c *Config) connects() bool {
+	return c.key != nil || c.json || len(c.funcs) > 0 || len(c.aggregates) > 0 || len(c.collations) > 0 ||
+		len(c.modules) > 0 || c.access != nil || c.hooks.set() || c.busy != nil || len(c.pragmas) > 0 ||
+		c.query != "" || c.hook != nil
+}
+
+// sameConnections reports whether two configs set up connections the same way.
+// Hooks and handlers can't be told apart, as closures of one function share its
+// code, so configs with any are never the same
+func sameConnections(a, b *Config) bool {
+	for _, c := range []*Config{a, b} {
+		if c.key != nil || len(c.modules) > 0 || c.access != nil || c.hooks.set() || c.busy != nil || c.hook != nil {
+			return false
+		}
+	}
+	return a.connectionKey() == b.connectionKey()
+}
+
+// connectionKey describes the functions, collations, pragmas, and query of the config
+func (c *Config) connectionKey() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%t %q %q", c.json, c.query, c.pragmas)
+	for _, fn := range c.funcs {
+		fmt.Fprintf(&b, " func %s %x %t", fn.Name, funcPointer(fn.Impl), fn.Pure)
+	}
+	for _, agg := range c.aggregates {
+		fmt.Fprintf(&b, " aggregate %s %x %t", agg.Name, funcPointer(agg.Impl), agg.Pure)
 	}
-	initialized[driverName] = struct{}{}
+	for _, coll := range c.collations {
+		fmt.Fprintf(&b, " collation %s %x", coll.Name, funcPointer(coll.Cmp))
+	}
+	return b.String()
+}
 
-	drvr := &sqlite3.SQLiteDriver{
+// funcPointer returns the code of a function, or 0 for anything else
+func funcPointer(fn interface{}) uintptr {
+	if v := reflect.ValueOf(fn); v.Kind() == reflect.Func {
+		return v.Pointer()
+	}
+	return 0
+}
+
+// newDriver returns a driver whose connections are set up as the config says
+func newDriver(config *Config) *sqlite3.SQLiteDriver {
+	funcs, aggregates, collations := config.funcs, config.aggregates, config.collations
+	query, hook, access, pragmas := config.query, config.hook, config.access, config.pragmas
+	return &sqlite3.SQLiteDriver{
 		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// the key must be set before anything reads the database
+			if key := config.currentKey(); key != nil {
+				if err := keyConn(conn, key); err != nil {
+					return err
+				}
+			}
+			if config.json {
+				if err := jsonConn(conn); err != nil {
+					return err
+				}
+			}
 			for _, fn := range funcs {
 				if err := conn.RegisterFunc(fn.Name, fn.Impl, fn.Pure); err != nil {
 					return fmt.Errorf("failed to register %q: %w", fn.Name, err)
 				}
 				if Debug {
-					log.Println("registered function:", fn.Name)
+					logf("registered function: %s", fn.Name)
 				}
 			}
-			if filename, err := connFilename(conn); err == nil {
-				register(filename, conn)
-			} else {
-				return fmt.Errorf("couldn't get filename for connection: %+v, error: %w", conn, err)
+			for _, agg := range aggregates {
+				if err := conn.RegisterAggregator(agg.Name, agg.Impl, agg.Pure); err != nil {
+					return fmt.Errorf("failed to register aggregate %q: %w", agg.Name, err)
+				}
+			}
+			for _, c := range collations {
+				if err := conn.RegisterCollation(c.Name, c.Cmp); err != nil {
+					return fmt.Errorf("failed to register collation %q: %w", c.Name, err)
+				}
+			}
+			for _, m := range config.modules {
+				if err := m.register(conn); err != nil {
+					return fmt.Errorf("failed to register module %q: %w", m.name, err)
+				}
+			}
+			if access != nil {
+				conn.RegisterAuthorizer(access.authorizer())
+			}
+			config.hooks.register(conn)
+			if config.busy != nil {
+				if err := setBusyHandler(conn, config.busy); err != nil {
+					return err
+				}
+			}
+			for _, pragma := range pragmas {
+				if _, err := conn.Exec(pragma, nil); err != nil {
+					return fmt.Errorf("connection pragma failed: %s -- %w", pragma, err)
+				}
 			}
-
 			if query != "" {
 				if _, err := conn.Exec(query, nil); err != nil {
 					return fmt.Errorf("connection query failed: %s -- %w", query, err)
 				}
 			}
+			if err := config.moduleConn(conn); err != nil {
+				return err
+			}
+			if err := config.attachConn(conn); err != nil {
+				return err
+			}
 
 			if hook != nil {
 				return hook(conn)
@@ -184,62 +283,123 @@ func sqlInit(driverName, query string, hook Hook, funcs ...FuncReg) {
 			return nil
 		},
 	}
-	sql.Register(driverName, drvr)
 }
 
 // Filename returns the filename of the DB
 func Filename(db *sql.DB) string {
-	var seq, name, file string
-	_ = row(db, []interface{}{&seq, &name, &file}, "PRAGMA database_list")
+	file, err := FilenameE(db)
+	if err != nil && StrictMode {
+		logf("can't read database filename: %v\n", err)
+	}
 	return file
 }
 
-// connFilename returns the filename of the connection
-func connFilename(conn *sqlite3.SQLiteConn) (string, error) {
-	var filename string
-	fn := func(cols []string, row int, values []driver.Value) error {
-		if len(values) < 3 {
-			return fmt.Errorf("only got %d values", len(values))
-		}
-		if values[2] == nil {
-			return fmt.Errorf("nil values")
-		}
-		filename = string(values[2].(string))
-		return nil
+// FilenameE returns the file of the main database, which is empty for a memory
+// or temporary database, or the error reading it
+func FilenameE(db *sql.DB) (string, error) {
+	var seq, name, file string
+	err := row(db, []interface{}{&seq, &name, &file}, "PRAGMA database_list")
+	return file, err
+}
+
+// filename returns the file of the main database for a helper that returns errors,
+// with the error reading it only in StrictMode
+func filename(db *sql.DB) (string, error) {
+	file, err := FilenameE(db)
+	if !StrictMode {
+		err = nil
 	}
-	return filename, connQuery(conn, fn, "PRAGMA database_list")
+	return file, err
 }
 
-// Close cleans up the database before closing (checkpoints WAL)
+// Close cleans up the database before closing (checkpoints WAL).
+// A database opened WithShared is closed when its last holder closes it
 func Close(db *sql.DB) {
+	if releaseShared(db) {
+		return
+	}
+	stopMaintenance(db)
+	defer removeTemp(db)
+	defer unpin(db)
+	defer dsns.Delete(db)
+	defer unscope(db)
+	defer unlockProcess(Filename(db))
+	start := time.Now()
 	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
-		log.Printf("error executing WAL checkpoint: %v\n", err)
+		logf("error executing WAL checkpoint: %v\n", err)
 	}
+	observe(MetricCheckpoint, start)
 	if err := db.Close(); err != nil {
-		log.Printf("error closing database: %v\n", err)
+		logf("error closing database: %v\n", err)
 	}
 }
 
 // Backup backs up the open database
 func Backup(db *sql.DB, dest string) error {
-	return backup(db, dest, 1024, ioutil.Discard)
+	return backup(context.Background(), db, dest, BackupOptions{})
+}
+
+// BackupContext backs up the open database, stopping between steps if ctx is done
+func BackupContext(ctx context.Context, db *sql.DB, dest string) error {
+	return backup(ctx, db, dest, BackupOptions{})
+}
+
+// BackupOptions control the pace of a backup
+type BackupOptions struct {
+	StepPages int           // pages copied per step, 1024 if not set, or -1 to copy all at once
+	Sleep     time.Duration // pause between steps, to let writers in on a busy database
+	Progress  func(pageCount, remaining int)
+	// Exclude lists tables left out of the backup, with their indexes and triggers and
+	// the views that use them. The backup is then built by copying the other tables row
+	// by row rather than page by page, and progress counts tables rather than pages
+	Exclude []string
+
+	Reporter Progress // reports on the backup as a whole, in pages copied
+}
+
+// BackupWithProgress backs up the open database in steps, reporting progress after each
+func BackupWithProgress(db *sql.DB, dest string, opts BackupOptions) error {
+	return backup(context.Background(), db, dest, opts)
+}
+
+// BackupWithProgressContext is BackupWithProgress, stopping between steps if ctx is done
+func BackupWithProgressContext(ctx context.Context, db *sql.DB, dest string, opts BackupOptions) error {
+	return backup(ctx, db, dest, opts)
 }
 
-func backup(db *sql.DB, dest string, step int, w io.Writer) error {
+func backup(ctx context.Context, db *sql.DB, dest string, opts BackupOptions) (err error) {
+	report := reporting(opts.Reporter)
+	report.OnStart("backup")
+	defer func() { report.OnFinish(err) }()
+
+	step := opts.StepPages
+	if step == 0 {
+		step = 1024
+	}
+
 	os.Remove(dest)
 
-	destDb, err := Open(dest)
+	destDb, err := OpenContext(ctx, dest)
 	if err != nil {
 		return err
 	}
 	defer destDb.Close()
 
-	if err = destDb.Ping(); err != nil {
+	if err = destDb.PingContext(ctx); err != nil {
 		return err
 	}
 
-	from := registered(Filename(db))
-	to := registered(Filename(destDb))
+	if len(opts.Exclude) > 0 {
+		return partialBackup(ctx, db, destDb, dest, opts)
+	}
+	return rawConn(ctx, db, func(from *sqlite3.SQLiteConn) error {
+		return rawConn(ctx, destDb, func(to *sqlite3.SQLiteConn) error {
+			return backupStep(ctx, to, from, step, opts)
+		})
+	})
+}
+
+func backupStep(ctx context.Context, to, from *sqlite3.SQLiteConn, step int, opts BackupOptions) (err error) {
 	bk, err := to.Backup("main", from, "main")
 	if err != nil {
 		return err
@@ -247,44 +407,91 @@ func backup(db *sql.DB, dest string, step int, w io.Writer) error {
 
 	defer func() {
 		berr := bk.Finish()
-		if err != nil {
+		if err == nil {
 			err = berr
 		}
 	}()
 
 	for {
-		fmt.Fprintf(w, "pagecount: %d remaining: %d\n", bk.PageCount(), bk.Remaining())
+		if err = ctx.Err(); err != nil {
+			break
+		}
 		var done bool
+		start := time.Now()
 		done, err = bk.Step(step)
+		observe(MetricBackupStep, start)
+		if opts.Progress != nil {
+			opts.Progress(bk.PageCount(), bk.Remaining())
+		}
+		if opts.Reporter != nil {
+			opts.Reporter.OnProgress(int64(bk.PageCount()-bk.Remaining()), int64(bk.PageCount()))
+		}
 		if done || err != nil {
 			break
 		}
+		if opts.Sleep > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.Sleep):
+			}
+		}
 	}
 	return err
 }
 
 // Pragmas lists all relevant Sqlite pragmas
 func Pragmas(db *sql.DB, w io.Writer) {
+	PragmasContext(context.Background(), db, w)
+}
+
+// PragmasContext lists all relevant Sqlite pragmas, stopping if ctx is done
+func PragmasContext(ctx context.Context, db *sql.DB, w io.Writer) {
+	if err := listPragmas(ctx, db, w, StrictMode); err != nil && StrictMode {
+		logf("can't list pragmas: %v\n", err)
+	}
+}
+
+// PragmasE lists all relevant Sqlite pragmas, returning the first error reading or writing them
+func PragmasE(db *sql.DB, w io.Writer) error {
+	return PragmasContextE(context.Background(), db, w)
+}
+
+// PragmasContextE is PragmasE, stopping if ctx is done
+func PragmasContextE(ctx context.Context, db *sql.DB, w io.Writer) error {
+	return listPragmas(ctx, db, w, true)
+}
+
+// listPragmas writes the pragmas, leaving out the values that can't be read unless strict
+func listPragmas(ctx context.Context, db *sql.DB, w io.Writer, strict bool) error {
 	for _, pragma := range pragmas {
-		row := db.QueryRow("PRAGMA " + pragma)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row := db.QueryRowContext(ctx, "PRAGMA "+pragma)
 		var value string
-		_ = row.Scan(&value)
-		fmt.Fprintf(w, "pragma %s = %s\n", pragma, value)
+		// pragmas the library doesn't support return no rows
+		if err := row.Scan(&value); err != nil && err != sql.ErrNoRows && strict {
+			return fmt.Errorf("pragma %s: %w", pragma, err)
+		}
+		if _, err := fmt.Fprintf(w, "pragma %s = %s\n", pragma, value); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // CompileOptions lists all SQLite compiler options
 func CompileOptions(db *sql.DB, w io.Writer) {
 	rows, err := db.Query("PRAGMA compile_options")
 	if err != nil {
-		log.Println("can't get compiled options:", err)
+		logf("can't get compiled options: %v", err)
 		return
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var option string
 		if err := rows.Scan(&option); err != nil {
-			log.Println("can't scan row:", err)
+			logf("can't scan row: %v", err)
 			return
 		}
 		fmt.Fprintln(w, option)
@@ -293,18 +500,23 @@ func CompileOptions(db *sql.DB, w io.Writer) {
 
 // File emulates ".read FILENAME"
 func File(db *sql.DB, file string, echo bool, w io.Writer) error {
+	return FileContext(context.Background(), db, file, echo, w)
+}
+
+// FileContext emulates ".read FILENAME", stopping if ctx is done
+func FileContext(ctx context.Context, db *sql.DB, file string, echo bool, w io.Writer) error {
 	out, err := ioutil.ReadFile(file)
 	if err != nil {
 		return err
 	}
-	return Commands(db, string(out), echo, w)
+	return CommandsContext(ctx, db, string(out), echo, w)
 }
 
 func startsWith(data, sub string) bool {
 	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(data)), strings.ToUpper(sub))
 }
 
-func listTables(db *sql.DB, w io.Writer) error {
+func listTables(ctx context.Context, db *sql.DB, w io.Writer) error {
 	q := `
 SELECT name FROM sqlite_master
 WHERE type='table'
@@ -315,96 +527,229 @@ ORDER BY name
 			fmt.Fprintln(w, row[0])
 		}
 	}
-	return query(db, fn, q)
+	return queryContext(ctx, db, fn, q)
 }
 
-// showRow is a handler for the query func
-func showRow(columns []string, row []interface{}) {
-	if columns != nil {
-		fmt.Println(strings.Join(columns, "\t"))
-	}
-	for i, r := range row {
-		if i > 0 {
-			fmt.Print("\t")
+// showRow returns a handler for the query func that writes rows to w
+func showRow(w io.Writer) handler {
+	return func(columns []string, row []interface{}) {
+		if columns != nil {
+			fmt.Fprintln(w, strings.Join(columns, "\t"))
 		}
-		fmt.Print(r)
+		for i, r := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, r)
+		}
+		fmt.Fprint(w, "\n")
 	}
-	fmt.Print("\n")
 }
 
-// Commands emulates the client reading a series of commands
-func Commands(db *sql.DB, buffer string, echo bool, w io.Writer) error {
-	if w == nil {
-		w = os.Stdout
+// onOff parses a shell setting such as "on" or "off"
+func onOff(s string) bool {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "on") {
+		return true
 	}
-	// strip comments
-	clean := commentC.ReplaceAll([]byte(buffer), []byte{})
-	clean = commentSQL.ReplaceAll(clean, []byte{})
-
-	lines := strings.Split(string(clean), ";\n")
-	multiline := "" // triggers are multiple lines
-	trigger := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		switch {
-		case strings.HasPrefix(line, ".echo "):
-			echo, _ = strconv.ParseBool(line[6:])
-			continue
-		case strings.HasPrefix(line, ".read "):
-			name := strings.TrimSpace(line[6:])
-			if err := File(db, name, echo, w); err != nil {
-				return fmt.Errorf("read file: %s, error: %w", name, err)
-			}
-			continue
-		case strings.HasPrefix(line, ".print "):
-			str := strings.TrimSpace(line[7:])
-			str = strings.Trim(str, `"`)
-			str = strings.Trim(str, "'")
-			fmt.Fprintln(w, str)
-			continue
-		case strings.HasPrefix(line, ".tables"):
-			if err := listTables(db, w); err != nil {
-				return fmt.Errorf("table error: %w", err)
-			}
-			continue
-		case startsWith(line, "CREATE TRIGGER"):
-			multiline = line
-			trigger = true
-			continue
-		case startsWith(line, "END;"):
-			line = multiline + "\n" + line
-			multiline = ""
-			trigger = false
-		case trigger:
-			multiline += "\n" + line // restore our 'split' transaction
-			continue
-		}
-		if len(multiline) > 0 {
-			multiline += "\n" + line // restore our 'split' transaction
-		} else {
-			multiline = line
+	b, _ := strconv.ParseBool(s)
+	return b
+}
+
+// StatementTiming is the time taken by a statement run by the shell
+type StatementTiming struct {
+	Line    int
+	Text    string
+	Elapsed time.Duration
+}
+
+// ExecutionReport summarizes the statements run by the shell
+type ExecutionReport struct {
+	Statements int
+	Commands   int // dot commands
+	Elapsed    time.Duration
+	Timings    []StatementTiming // recorded while ".timer on"
+}
+
+// shell holds the settings of the emulated client
+type shell struct {
+	ctx    context.Context
+	db     *sql.DB
+	w      io.Writer
+	echo   bool
+	timer  bool
+	csv    bool
+	report *ExecutionReport
+}
+
+// command runs a shell command such as ".read FILENAME"
+func (s *shell) command(line string) error {
+	s.report.Commands++
+	switch {
+	case strings.HasPrefix(line, ".echo "):
+		s.echo = onOff(line[6:])
+	case strings.HasPrefix(line, ".timer "):
+		s.timer = onOff(line[7:])
+	case strings.HasPrefix(line, ".read "):
+		name := strings.TrimSpace(line[6:])
+		out, err := ioutil.ReadFile(name)
+		if err == nil {
+			err = s.run(string(out))
+		}
+		if err != nil {
+			return redactf("read file: %s, error: %w", name, err)
+		}
+	case strings.HasPrefix(line, ".print "):
+		str := strings.TrimSpace(line[7:])
+		str = strings.Trim(str, `"`)
+		str = strings.Trim(str, "'")
+		fmt.Fprintln(s.w, str)
+	case strings.HasPrefix(line, ".mode "):
+		switch mode := strings.TrimSpace(line[6:]); mode {
+		case "csv":
+			s.csv = true
+		case "list", "tabs":
+			s.csv = false
+		default:
+			return fmt.Errorf("unsupported mode: %s", mode)
 		}
-		if strings.Contains(line, ";") {
-			continue
+	case strings.HasPrefix(line, ".import "):
+		return s.importCSV(strings.Fields(line[8:]))
+	case line == ".dump" || strings.HasPrefix(line, ".dump "):
+		if err := DumpToContext(s.ctx, s.db, s.w, DumpOptions{Tables: strings.Fields(line[5:])}); err != nil {
+			return fmt.Errorf("dump error: %w", err)
 		}
-		if echo {
-			fmt.Println("CMD> ", multiline)
+	case strings.HasPrefix(line, ".tables"):
+		if err := listTables(s.ctx, s.db, s.w); err != nil {
+			return fmt.Errorf("table error: %w", err)
 		}
-		if startsWith(multiline, "SELECT") {
-			if err := query(db, showRow, multiline); err != nil {
-				return fmt.Errorf("SELECT QUERY: %s FILE: %s ERROR: %w", line, Filename(db), err)
+	default:
+		return fmt.Errorf("unsupported command: %s", line)
+	}
+	return nil
+}
+
+// importCSV runs ".import [--csv] [--skip N] FILE TABLE". As with the sqlite3 shell, the
+// first row names the columns of a new table, but is data if the table exists
+func (s *shell) importCSV(args []string) error {
+	var skip int
+	var names []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--csv":
+		case "--skip":
+			if i++; i < len(args) {
+				skip, _ = strconv.Atoi(args[i])
 			}
-		} else if _, err := db.Exec(multiline); err != nil {
-			return fmt.Errorf("EXEC QUERY: %s FILE: %s ERROR: %w", line, Filename(db), err)
+		default:
+			names = append(names, args[i])
+		}
+	}
+	if len(names) != 2 {
+		return fmt.Errorf("usage: .import [--csv] [--skip N] FILE TABLE")
+	}
+	file, table := names[0], names[1]
+	f, err := os.Open(file)
+	if err != nil {
+		return redactf("import file: %s, error: %w", file, err)
+	}
+	defer f.Close()
+	columns, err := exportColumns(s.db, table)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(f)
+	for ; skip > 0; skip-- {
+		if _, err := br.ReadString('\n'); err != nil {
+			break
+		}
+	}
+	if _, err := ImportCSVContext(s.ctx, s.db, table, br, CSVOptions{NoHeader: len(columns) > 0}); err != nil {
+		return redactf("import file: %s, error: %w", file, err)
+	}
+	return nil
+}
+
+// statement runs a single SQL statement
+func (s *shell) statement(stmt Statement) error {
+	if s.echo {
+		fmt.Fprintln(s.w, "CMD> ", stmt.Text)
+	}
+	start := time.Now()
+	if startsWith(stmt.Text, "SELECT") && s.csv {
+		if err := ExportCSVContext(s.ctx, s.db, s.w, stmt.Text); err != nil {
+			return redactf("SELECT QUERY: %s FILE: %s ERROR: %w", stmt.Text, Filename(s.db), err)
+		}
+	} else if startsWith(stmt.Text, "SELECT") {
+		if err := queryContext(s.ctx, s.db, showRow(s.w), stmt.Text); err != nil {
+			return redactf("SELECT QUERY: %s FILE: %s ERROR: %w", stmt.Text, Filename(s.db), err)
+		}
+	} else if _, err := s.db.ExecContext(s.ctx, stmt.Text); err != nil {
+		return redactf("EXEC QUERY: %s FILE: %s ERROR: %w", stmt.Text, Filename(s.db), err)
+	}
+	elapsed := time.Since(start)
+	s.report.Statements++
+	s.report.Elapsed += elapsed
+	if s.timer {
+		s.report.Timings = append(s.report.Timings, StatementTiming{Line: stmt.Line, Text: stmt.Text, Elapsed: elapsed})
+		fmt.Fprintf(s.w, "Run Time: real %.3f\n", elapsed.Seconds())
+	}
+	return nil
+}
+
+// run executes the statements and commands of a script
+func (s *shell) run(buffer string) error {
+	stmts, err := ParseScript(buffer)
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	for _, stmt := range stmts {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		if stmt.Command {
+			err = s.command(stmt.Text)
+		} else {
+			err = s.statement(stmt)
+		}
+		if err != nil {
+			return err
 		}
-		multiline = ""
+	}
+	// a script from WriteChecksums has the contents it should produce
+	if strings.Contains(buffer, "-- checksum: ") {
+		return VerifyChecksums(s.db, buffer)
 	}
 	return nil
 }
 
+// Commands emulates the client reading a series of commands
+func Commands(db *sql.DB, buffer string, echo bool, w io.Writer) error {
+	return CommandsContext(context.Background(), db, buffer, echo, w)
+}
+
+// CommandsContext emulates the client reading a series of commands, stopping if ctx is done
+func CommandsContext(ctx context.Context, db *sql.DB, buffer string, echo bool, w io.Writer) error {
+	_, err := CommandsReportContext(ctx, db, buffer, echo, w)
+	return err
+}
+
+// CommandsReport is Commands, returning a report of the statements run,
+// including those run before an error
+func CommandsReport(db *sql.DB, buffer string, echo bool, w io.Writer) (*ExecutionReport, error) {
+	return CommandsReportContext(context.Background(), db, buffer, echo, w)
+}
+
+// CommandsReportContext is CommandsReport, stopping if ctx is done
+func CommandsReportContext(ctx context.Context, db *sql.DB, buffer string, echo bool, w io.Writer) (*ExecutionReport, error) {
+	if w == nil {
+		w = os.Stdout
+	}
+	s := &shell{ctx: ctx, db: db, w: w, echo: echo, report: new(ExecutionReport)}
+	err := s.run(buffer)
+	return s.report, ContextError(ctx, err)
+}
+
 // connQuery executes a query on a driver connection
 func connQuery(conn *sqlite3.SQLiteConn, fn func([]string, int, []driver.Value) error, query string, args ...driver.Value) error {
 	rows, err := conn.Query(query, args)
@@ -431,6 +776,29 @@ func connQuery(conn *sqlite3.SQLiteConn, fn func([]string, int, []driver.Value)
 	return err
 }
 
+// RawConn calls fn with the driver connection of a connection taken from the pool of db,
+// which is held for the duration of the call
+func RawConn(ctx context.Context, db *sql.DB, fn func(*sqlite3.SQLiteConn) error) error {
+	return rawConn(ctx, db, fn)
+}
+
+// rawConn runs fn against the driver connection underlying one of the pool's connections
+func rawConn(ctx context.Context, db *sql.DB, fn func(*sqlite3.SQLiteConn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer conn.Close()
+	err = conn.Raw(func(dc interface{}) error {
+		sc, ok := dc.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection: %T", dc)
+		}
+		return fn(sc)
+	})
+	return ContextError(ctx, err)
+}
+
 // DataVersion returns the version number of the schema
 func DataVersion(db *sql.DB) (int64, error) {
 	var version int64
@@ -445,11 +813,44 @@ func Version() (string, int, string) {
 
 // Config represents the sqlite configuration options
 type Config struct {
-	fail   bool
-	query  string
-	driver string
-	hook   Hook
-	funcs  []FuncReg
+	fail       bool
+	query      string
+	driver     string
+	hook       Hook
+	funcs      []FuncReg
+	aggregates []AggregateReg
+	collations []CollationReg
+	modules    []moduleReg
+	recover    bool
+	exclusive  bool
+	access     *AccessStats
+	pragmas    []string
+	pool       *PoolSettings
+	key        []byte
+	schema     string
+	schemaFS   fs.FS
+	hooks      connHooks
+	busy       BusyHandler
+	fsCheck    bool
+	fsRefuse   bool
+	shared     bool
+	json       bool
+	scoped     bool
+	fileMode   os.FileMode
+	durable    bool
+
+	// attached are the databases attached to each connection, by main database file
+	attachMu sync.Mutex
+	attached map[string][]attachment
+
+	// tables are the modules registered on each connection, by main database file
+	tablesMu sync.Mutex
+	tables   map[string][]moduleReg
+
+	maintenance *Maintenance
+
+	// err is an invalid option, which fails the Open
+	err error
 }
 
 type Optional func(*Config)
@@ -475,7 +876,10 @@ func WithHook(hook Hook) Optional {
 	}
 }
 
-//WithDriver sets the driver name used
+// WithDriver sets the driver name used. Connections are set up as the first Open
+// of a driver name says, and a later Open that gives no connection settings of its
+// own, such as functions, hooks, or pragmas, takes on those. One that gives
+// different settings gets a driver of its own, as WithScope does
 func WithDriver(driver string) Optional {
 	return func(c *Config) {
 		c.driver = driver
@@ -489,50 +893,129 @@ func WithFunctions(functions ...FuncReg) Optional {
 	}
 }
 
+// WithAggregates registers custom aggregate functions
+func WithAggregates(aggregates ...AggregateReg) Optional {
+	return func(c *Config) {
+		c.aggregates = append(c.aggregates, aggregates...)
+	}
+}
+
+// WithCollations registers custom collations
+func WithCollations(collations ...CollationReg) Optional {
+	return func(c *Config) {
+		c.collations = append(c.collations, collations...)
+	}
+}
+
 // open returns a db handler for the given file
-func open(file string, config *Config) (*sql.DB, error) {
+func open(ctx context.Context, file string, config *Config) (db *sql.DB, err error) {
+	// errors name the file, or its DSN parameters
+	defer func() { err = redactError(err) }()
 	if config == nil {
 		config = &Config{driver: DefaultDriver}
 	}
-	sqlInit(config.driver, config.query, config.hook, config.funcs...)
-	if !strings.Contains(file, ":memory:") {
-		filename := file
-		filename = strings.TrimPrefix(filename, "file:")
-		filename = strings.TrimPrefix(filename, "//")
-		if i := strings.Index(filename, "?"); i > 0 {
-			filename = filename[:i]
+	if config.err != nil {
+		return nil, config.err
+	}
+	if !config.scoped {
+		// connection settings given to this Open would be lost to those of an earlier
+		// registration of the driver name, so the handle gets a driver of its own
+		if registered := sqlRegister(config); registered != config && config.connects() && !sameConnections(registered, config) {
+			config.scoped = true
 		}
+	}
+	dsn, err := ParseDSN(file)
+	if err != nil {
+		return nil, err
+	}
+	var hot bool
+	if !dsn.Memory {
+		filename := dsn.Filename
 
 		// create directory if necessary
-		dirName := path.Dir(filename)
-		if _, err := os.Stat(dirName); os.IsNotExist(err) {
-			if err := os.Mkdir(dirName, 0777); err != nil {
+		if err := config.createDir(path.Dir(filename)); err != nil {
+			return nil, err
+		}
+
+		if err := config.checkFilesystem(filename); err != nil {
+			return nil, err
+		}
+
+		if config.exclusive {
+			if err := lockProcess(filename); err != nil {
+				return nil, err
+			}
+			defer func() {
+				if err != nil {
+					unlockProcess(filename)
+				}
+			}()
+		}
+
+		if config.recover {
+			if hot, err = hotJournal(filename); err != nil {
+				return nil, err
+			}
+			if err := recoverOpen(filename); err != nil {
 				return nil, err
 			}
 		}
 
 		if !config.fail {
-			if _, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0666); err != nil {
+			if err := config.createFile(filename); err != nil {
 				return nil, fmt.Errorf("os file: %s, error: %w", file, err)
 			}
 		} else if _, err := os.Stat(filename); os.IsNotExist(err) {
 			return nil, err
 		}
 	}
-	db, err := sql.Open(config.driver, file)
+	if config.scoped {
+		db = openScoped(file, config)
+	} else {
+		db, err = sql.Open(config.driver, file)
+	}
 	if err != nil {
 		return db, fmt.Errorf("sql file: %s, error: %w", file, err)
 	}
-	return db, db.Ping()
+	if config.pool != nil {
+		config.pool.apply(db)
+	}
+	if err = db.PingContext(ctx); err != nil {
+		return db, err
+	}
+	dsns.Store(db, opened{dsn: file, config: config})
+	if hot {
+		if err = rollback(db, dsn.Filename); err != nil {
+			return db, err
+		}
+		logf("recovery: rolled back %s-journal\n", dsn.Filename)
+	}
+	if config.schema != "" || config.schemaFS != nil {
+		if err = config.initSchema(ctx, db); err != nil {
+			return db, err
+		}
+	}
+	if config.maintenance != nil {
+		config.maintenance.start(db)
+	}
+	return db, nil
 }
 
 // Open returns a db handler for the given file
 func Open(file string, opts ...Optional) (*sql.DB, error) {
+	return OpenContext(context.Background(), file, opts...)
+}
+
+// OpenContext returns a db handler for the given file, using ctx for the initial connection
+func OpenContext(ctx context.Context, file string, opts ...Optional) (*sql.DB, error) {
 	config := new(Config)
 	for _, opt := range opts {
 		opt(config)
 	}
-	return open(file, config)
+	if config.shared {
+		return openShared(ctx, file, config)
+	}
+	return open(ctx, file, config)
 }
 
 // Opener returns func to open db handler for a given file
@@ -542,12 +1025,15 @@ func Opener(opts ...Optional) func(string) (*sql.DB, error) {
 		opt(config)
 	}
 	return func(file string) (*sql.DB, error) {
-		return open(file, config)
+		if config.shared {
+			return openShared(context.Background(), file, config)
+		}
+		return open(context.Background(), file, config)
 	}
 }
 
 func row(db *sql.DB, dest []interface{}, query string, args ...interface{}) error {
-	return db.QueryRow(query, args...).Scan(dest...)
+	return db.QueryRow(query, Args(args...)...).Scan(Dests(dest...)...)
 }
 
 // Note that columns is nil after the first row
@@ -567,7 +1053,11 @@ func getColumns(row *sql.Rows) ([]string, error) {
 }
 
 func query(db *sql.DB, fn handler, query string, args ...interface{}) error {
-	rows, err := db.Query(query, args...)
+	return queryContext(context.Background(), db, fn, query, args...)
+}
+
+func queryContext(ctx context.Context, db *sql.DB, fn handler, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, Args(args...)...)
 	if err != nil {
 		return err
 	}
@@ -1,13 +1,19 @@
 package sqlite
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	sqlite3 "github.com/mattn/go-sqlite3"
 )
@@ -185,6 +191,91 @@ func TestBackup(t *testing.T) {
 	}
 }
 
+func TestBackupMultiple(t *testing.T) {
+	dir := t.TempDir()
+	mem := memDB(t)
+	defer mem.Close()
+	mem.SetMaxOpenConns(1)
+
+	dbs := []*sql.DB{mem}
+	for _, name := range []string{"one.db", "two.db", "one.db"} {
+		db, err := Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		dbs = append(dbs, db)
+	}
+	for i, db := range dbs[:3] {
+		if _, err := db.Exec("create table which (n integer); insert into which values(?)", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the second pool on one.db sees the table written through the first
+	wants := []int{0, 1, 2, 1}
+	for i, db := range dbs {
+		dest := filepath.Join(dir, fmt.Sprintf("backup%d.db", i))
+		if err := Backup(db, dest); err != nil {
+			t.Fatal(err)
+		}
+		copied, err := Open(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		err = row(copied, []interface{}{&n}, "select n from which")
+		copied.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != wants[i] {
+			t.Fatalf("backup %d expected: %d but got: %d", i, wants[i], n)
+		}
+	}
+}
+
+func TestBackupWithProgress(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "progress.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const setup = `
+	create table numbers (n integer, pad text);
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 200)
+	insert into numbers select n, hex(randomblob(200)) from seq;
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	var steps, remaining int
+	opts := BackupOptions{
+		StepPages: 5,
+		Sleep:     time.Millisecond,
+		Progress: func(pageCount, left int) {
+			steps++
+			remaining = left
+		},
+	}
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := BackupWithProgress(db, dest, opts); err != nil {
+		t.Fatal(err)
+	}
+	if steps < 5 || remaining != 0 {
+		t.Fatalf("unexpected progress: %d steps, %d remaining", steps, remaining)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	opts.Progress = func(pageCount, left int) {
+		cancel()
+	}
+	if err := BackupWithProgressContext(ctx, db, dest, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled backup but got: %v", err)
+	}
+}
+
 func TestBackupBadDir(t *testing.T) {
 	db, err := Open(testFile)
 	if err != nil {
@@ -193,13 +284,48 @@ func TestBackupBadDir(t *testing.T) {
 	defer db.Close()
 
 	prepare(db)
-	if err := backup(db, "/this/path/does/not/exist/test_backup.db", 1024, testout); err == nil {
+	if err := backup(context.Background(), db, "/this/path/does/not/exist/test_backup.db", BackupOptions{}); err == nil {
 		t.Fatal("expected backup error")
 	} else {
 		t.Log(err)
 	}
 }
 
+func TestContextVariants(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	file := filepath.Join(t.TempDir(), "context.db")
+	if _, err := OpenContext(canceled, file); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled open but got: %v", err)
+	}
+
+	db, err := OpenContext(context.Background(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	prepare(db)
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := BackupContext(canceled, db, dest); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled backup but got: %v", err)
+	}
+	if err := BackupContext(context.Background(), db, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	script := "select 1;\n" + endless + ";\nselect 2;\n"
+	if err := CommandsContext(ctx, db, script, false, testout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded but got: %v", err)
+	}
+	if err := CommandsContext(canceled, db, script, false, testout); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled commands but got: %v", err)
+	}
+	PragmasContext(canceled, db, testout)
+}
+
 func TestFile(t *testing.T) {
 	db := memDB(t)
 	if err := os.Chdir("sql"); err != nil {
@@ -253,6 +379,54 @@ func TestPragmas(t *testing.T) {
 	Pragmas(db, testout)
 }
 
+func TestPragmasE(t *testing.T) {
+	db := memDB(t)
+	var buf bytes.Buffer
+	if err := PragmasE(db, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "pragma page_size = ") {
+		t.Fatalf("unexpected pragmas: %s", buf.String())
+	}
+	db.Close()
+	if err := PragmasE(db, &buf); err == nil {
+		t.Fatal("expected error listing pragmas of a closed database")
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "strict.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, err := FilenameE(db); err != nil || name != file {
+		t.Fatalf("got filename %q, error %v", name, err)
+	}
+	db.Close()
+	if _, err := FilenameE(db); err == nil {
+		t.Fatal("expected error reading the filename of a closed database")
+	}
+
+	// migrations are never run unlocked for want of the file name
+	if _, err := lockMigrations(context.Background(), db, 0); err == nil {
+		t.Fatal("expected error locking migrations of a closed database")
+	}
+
+	// without strict mode a closed database looks like a memory database
+	if _, err := filename(db); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { StrictMode = false }()
+	StrictMode = true
+	if _, err := filename(db); err == nil {
+		t.Fatal("expected error in strict mode")
+	}
+	if name := Filename(db); name != "" {
+		t.Fatalf("expected no filename, got %q", name)
+	}
+}
+
 func TestCommandsBadQuery(t *testing.T) {
 	db := memDB(t)
 	query := "select asdf xyz m'kay;\n"
@@ -273,6 +447,32 @@ func TestCommandsReadMissingFile(t *testing.T) {
 	}
 }
 
+func TestCommandsTimer(t *testing.T) {
+	db := memDB(t)
+	const script = `
+create table timed (n integer);
+.timer on
+insert into timed values(1);
+select n from timed;
+.timer off
+insert into timed values(2);
+`
+	var buf bytes.Buffer
+	report, err := CommandsReport(db, script, false, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Statements != 4 || report.Commands != 2 || len(report.Timings) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.Timings[1].Line != 5 || report.Timings[1].Text != "select n from timed" {
+		t.Fatalf("unexpected timing: %+v", report.Timings[1])
+	}
+	if n := strings.Count(buf.String(), "Run Time: real "); n != 2 {
+		t.Fatalf("expected 2 timings but got %d: %s", n, buf.String())
+	}
+}
+
 func TestCommandsTrigger(t *testing.T) {
 	db := structDb(t)
 	const (
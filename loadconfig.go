@@ -0,0 +1,233 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PoolSettings are the connection pool settings of a database
+type PoolSettings struct {
+	MaxOpen     int
+	MaxIdle     int
+	MaxLifetime time.Duration
+	MaxIdleTime time.Duration
+}
+
+func (p PoolSettings) apply(db *sql.DB) {
+	if p.MaxOpen > 0 {
+		db.SetMaxOpenConns(p.MaxOpen)
+	}
+	if p.MaxIdle != 0 {
+		db.SetMaxIdleConns(p.MaxIdle)
+	}
+	if p.MaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.MaxLifetime)
+	}
+	if p.MaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.MaxIdleTime)
+	}
+}
+
+// Maintenance is how often routine maintenance is run on an open database
+type Maintenance struct {
+	Checkpoint        time.Duration // PRAGMA wal_checkpoint(PASSIVE)
+	Optimize          time.Duration // PRAGMA optimize
+	IncrementalVacuum time.Duration // PRAGMA incremental_vacuum, for databases in INCREMENTAL auto_vacuum mode
+	FTSOptimize       time.Duration // FTSOptimize of every full-text table
+	FTSCheck          time.Duration // FTSIntegrityCheck of every full-text table, rebuilding any that fail
+}
+
+// maintaining holds the cancel func of the maintenance of each database, until Close
+var maintaining sync.Map
+
+// start performs maintenance in the background until the database is closed
+func (m Maintenance) start(db *sql.DB) {
+	scheduler := &MaintenanceScheduler{
+		IncrementalVacuum: m.IncrementalVacuum,
+		Optimize:          m.Optimize,
+		Checkpoint:        m.Checkpoint,
+		FTSOptimize:       m.FTSOptimize,
+		FTSCheck:          m.FTSCheck,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	maintaining.Store(db, cancel)
+	go scheduler.Run(ctx, db)
+}
+
+// stopMaintenance stops the maintenance of the database, if it has any
+func stopMaintenance(db *sql.DB) {
+	if cancel, ok := maintaining.LoadAndDelete(db); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+// WithPragmas sets pragmas on each new connection, e.g. {"journal_mode": "wal"}
+func WithPragmas(pragmas map[string]string) Optional {
+	return func(c *Config) {
+		names := make([]string, 0, len(pragmas))
+		for name := range pragmas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			withPragma(name, pragmas[name])(c)
+		}
+	}
+}
+
+// WithPool applies the pool settings when the database is opened
+func WithPool(pool PoolSettings) Optional {
+	return func(c *Config) {
+		c.pool = &pool
+	}
+}
+
+// WithMaintenance runs routine maintenance while the database is open
+func WithMaintenance(m Maintenance) Optional {
+	return func(c *Config) {
+		c.maintenance = &m
+	}
+}
+
+// functionSets are the functions that can be enabled by name in a config file
+var functionSets = map[string]struct {
+	funcs      []FuncReg
+	aggregates []AggregateReg
+}{
+	"ip":      {funcs: ipFuncs},
+	"net":     {funcs: NetFuncs},
+	"money":   {funcs: MoneyFuncs},
+	"unicode": {funcs: UnicodeFuncs},
+	"fuzzy":   {funcs: FuzzyFuncs},
+	"vector":  {funcs: VectorFuncs},
+	"geo":     {funcs: GeoFuncs},
+	"sketch":  {funcs: SketchFuncs, aggregates: SketchAggregates},
+	"std":     {funcs: StdFuncs},
+	"stats":   {aggregates: StatAggregates},
+	"concat":  {aggregates: ConcatAggregates},
+}
+
+// collationSets are the collations that can be enabled by name in a config file
+var collationSets = map[string][]CollationReg{
+	"unicode": UnicodeCollations,
+}
+
+// duration is a time.Duration read from a string such as "5m"
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	*d = duration(v)
+	return err
+}
+
+// configFile is the layout read by LoadConfig
+type configFile struct {
+	Driver  string            `json:"driver"`
+	Exists  bool              `json:"exists"`
+	Query   string            `json:"query"`
+	Pragmas map[string]string `json:"pragmas"`
+	Pool    *struct {
+		MaxOpen     int      `json:"max_open"`
+		MaxIdle     int      `json:"max_idle"`
+		MaxLifetime duration `json:"max_lifetime"`
+		MaxIdleTime duration `json:"max_idle_time"`
+	} `json:"pool"`
+	Functions   []string `json:"functions"`
+	Collations  []string `json:"collations"`
+	Maintenance *struct {
+		Checkpoint        duration `json:"checkpoint"`
+		Optimize          duration `json:"optimize"`
+		IncrementalVacuum duration `json:"incremental_vacuum"`
+		FTSOptimize       duration `json:"fts_optimize"`
+		FTSCheck          duration `json:"fts_check"`
+	} `json:"maintenance"`
+}
+
+// LoadConfig reads options for Open from a JSON config file, such as:
+//
+//	{
+//	  "driver": "app",
+//	  "pragmas": {"journal_mode": "wal", "busy_timeout": "5000"},
+//	  "pool": {"max_open": 4, "max_idle_time": "5m"},
+//	  "functions": ["money", "stats"],
+//	  "collations": ["unicode"],
+//	  "maintenance": {"checkpoint": "1m", "optimize": "1h", "fts_optimize": "24h"}
+//	}
+//
+// Only JSON is read, as the package has no dependencies for TOML or YAML, and
+// there is no matching export of the options of an open database. A TOML or
+// YAML file can be decoded by the caller's library of choice and re-encoded
+// as JSON with the same layout.
+//
+// Unknown settings are an error. As each driver is configured once, the driver
+// should be named if the config differs from that of other databases
+func LoadConfig(r io.Reader) ([]Optional, error) {
+	var file configFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var opts []Optional
+	if file.Driver != "" {
+		opts = append(opts, WithDriver(file.Driver))
+	}
+	if file.Exists {
+		opts = append(opts, WithExists(true))
+	}
+	if file.Query != "" {
+		opts = append(opts, WithQuery(file.Query))
+	}
+	if len(file.Pragmas) > 0 {
+		opts = append(opts, WithPragmas(file.Pragmas))
+	}
+	if p := file.Pool; p != nil {
+		opts = append(opts, WithPool(PoolSettings{
+			MaxOpen:     p.MaxOpen,
+			MaxIdle:     p.MaxIdle,
+			MaxLifetime: time.Duration(p.MaxLifetime),
+			MaxIdleTime: time.Duration(p.MaxIdleTime),
+		}))
+	}
+	for _, name := range file.Functions {
+		set, ok := functionSets[name]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown functions: %q", name)
+		}
+		if len(set.funcs) > 0 {
+			opts = append(opts, WithFunctions(set.funcs...))
+		}
+		if len(set.aggregates) > 0 {
+			opts = append(opts, WithAggregates(set.aggregates...))
+		}
+	}
+	for _, name := range file.Collations {
+		set, ok := collationSets[name]
+		if !ok {
+			return nil, fmt.Errorf("config: unknown collations: %q", name)
+		}
+		opts = append(opts, WithCollations(set...))
+	}
+	if m := file.Maintenance; m != nil {
+		opts = append(opts, WithMaintenance(Maintenance{
+			Checkpoint:        time.Duration(m.Checkpoint),
+			Optimize:          time.Duration(m.Optimize),
+			IncrementalVacuum: time.Duration(m.IncrementalVacuum),
+			FTSOptimize:       time.Duration(m.FTSOptimize),
+			FTSCheck:          time.Duration(m.FTSCheck),
+		}))
+	}
+	return opts, nil
+}
@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	const config = `{
+	  "driver": "load_config",
+	  "pragmas": {"journal_mode": "wal", "foreign_keys": "on"},
+	  "pool": {"max_open": 2, "max_idle_time": "5m"},
+	  "functions": ["money", "stats"],
+	  "collations": ["unicode"],
+	  "maintenance": {"checkpoint": "10ms", "fts_optimize": "1h", "fts_check": "24h"}
+	}`
+	opts, err := LoadConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(filepath.Join(t.TempDir(), "config.db"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, ok := maintaining.Load(db); !ok {
+		t.Fatal("expected maintenance to be running")
+	}
+
+	settings, err := EffectiveConfig(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Pragmas["journal_mode"] != "wal" || settings.Pragmas["foreign_keys"] != "1" {
+		t.Fatalf("unexpected pragmas: %v", settings.Pragmas)
+	}
+	if settings.Pool.MaxOpenConnections != 2 {
+		t.Fatalf("unexpected pool: %+v", settings.Pool)
+	}
+	var median float64
+	var money string
+	if err := row(db, []interface{}{&median, &money}, "select median(n), money_format(12345) from (select 1 as n union select 5 union select 9)"); err != nil {
+		t.Fatal(err)
+	}
+	if median != 5 || money != "123.45" {
+		t.Fatalf("unexpected results: %v %q", median, money)
+	}
+
+	// closing the database stops its maintenance
+	Close(db)
+	if _, ok := maintaining.Load(db); ok {
+		t.Fatal("expected maintenance to be stopped")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		`{"no_such_setting": true}`,
+		`{"functions": ["no_such_functions"]}`,
+		`{"collations": ["no_such_collations"]}`,
+		`{"pool": {"max_lifetime": 5}}`,
+		`{"maintenance": {"optimize": "often"}}`,
+		`{"maintenance": {"fts_check": 5}}`,
+		`not json`,
+	} {
+		if _, err := LoadConfig(strings.NewReader(config)); err == nil {
+			t.Errorf("expected error for: %s", config)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LockError is returned when another process already has a database open
+type LockError struct {
+	File string
+	Err  error
+}
+
+func (e *LockError) Error() string {
+	return fmt.Sprintf("database %s is open in another process: %v", e.File, e.Err)
+}
+
+func (e *LockError) Unwrap() error {
+	return e.Err
+}
+
+// locks holds the lock files of databases opened with WithExclusiveProcess
+var locks = struct {
+	sync.Mutex
+	files map[string]*os.File
+}{files: make(map[string]*os.File)}
+
+// lockProcess takes an advisory lock on the sidecar lock file of the database,
+// which is held until the database is closed with Close
+func lockProcess(file string) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	locks.Lock()
+	defer locks.Unlock()
+	if _, ok := locks.files[file]; ok {
+		return &LockError{File: file, Err: fmt.Errorf("already locked by this process")}
+	}
+	f, err := os.OpenFile(file+".lock", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	if err := flock(f); err != nil {
+		f.Close()
+		return &LockError{File: file, Err: err}
+	}
+	locks.files[file] = f
+	return nil
+}
+
+// unlockProcess releases the lock taken by lockProcess
+func unlockProcess(file string) {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	locks.Lock()
+	defer locks.Unlock()
+	if f, ok := locks.files[file]; ok {
+		f.Close()
+		delete(locks.files, file)
+	}
+}
+
+// WithExclusiveProcess fails the open with a *LockError if another process has
+// the database open with the same option, preventing two writers on a shared volume.
+// The lock is released by Close
+func WithExclusiveProcess() Optional {
+	return func(c *Config) {
+		c.exclusive = true
+	}
+}
+
+// lockFile takes an advisory lock on the file at path, creating it if necessary,
+// retrying until it is free, wait has passed, or ctx is done. The lock is released by closing the file
+func lockFile(ctx context.Context, path string, wait time.Duration) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		err = flock(f)
+		if err == nil {
+			return f, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExclusiveProcess(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "exclusive.db")
+
+	// another process holding the lock
+	other, err := os.OpenFile(file+".lock", os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := flock(other); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(file, WithExclusiveProcess())
+	var locked *LockError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected lock error but got: %v", err)
+	}
+	other.Close()
+
+	db, err := Open(file, WithExclusiveProcess())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(file, WithExclusiveProcess()); !errors.As(err, &locked) {
+		t.Fatalf("expected lock error but got: %v", err)
+	}
+	Close(db)
+
+	db, err = Open(file, WithExclusiveProcess())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Close(db)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package sqlite
+
+import (
+	"os"
+	"syscall"
+)
+
+// flock takes an exclusive lock on f without blocking
+func flock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
@@ -0,0 +1,28 @@
+//go:build windows
+// +build windows
+
+package sqlite
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// flock takes an exclusive lock on f without blocking
+func flock(f *os.File) error {
+	var overlapped syscall.Overlapped
+	flags := uintptr(lockfileExclusiveLock | lockfileFailImmediately)
+	r, _, err := procLockFileEx.Call(f.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(&overlapped)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+var (
+	memorySeq uint64
+
+	// pinned holds a connection to each shared memory database opened by OpenMemory,
+	// which would otherwise be freed whenever the pool closed its last connection
+	pinned sync.Map
+
+	// temps holds the directory of each database opened by OpenTemp
+	temps sync.Map
+)
+
+// MemoryDSN returns the DSN of a named in-memory database shared by the connections that open it
+func MemoryDSN(name string) string {
+	return fmt.Sprintf("file:%s?mode=memory&cache=shared", url.PathEscape(name))
+}
+
+// OpenMemory opens a named in-memory database that every connection of the pool shares,
+// unlike ":memory:", which gives each connection a private database of its own.
+// Other pools opened with the same name in the process share it as well.
+// If name is empty a unique one is used. The database lasts until it is closed with Close
+func OpenMemory(name string, opts ...Optional) (*sql.DB, error) {
+	if name == "" {
+		name = fmt.Sprintf("memory%d", atomic.AddUint64(&memorySeq, 1))
+	}
+	dsn := MemoryDSN(name)
+	pin, err := driverConn(dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := Open(dsn, opts...)
+	if err != nil {
+		pin.Close()
+		return nil, err
+	}
+	pinned.Store(db, pin)
+	return db, nil
+}
+
+// unpin releases the connection held for a database opened by OpenMemory
+func unpin(db *sql.DB) {
+	if pin, ok := pinned.LoadAndDelete(db); ok {
+		pin.(*sqlite3.SQLiteConn).Close()
+	}
+}
+
+// OpenTemp opens a database in a new temporary file, which is deleted, along with any
+// journal, when the database is closed with Close
+func OpenTemp(opts ...Optional) (*sql.DB, error) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		return nil, err
+	}
+	db, err := Open(filepath.Join(dir, "temp.db"), opts...)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	temps.Store(db, dir)
+	return db, nil
+}
+
+// removeTemp deletes the files of a database opened by OpenTemp
+func removeTemp(db *sql.DB) {
+	if dir, ok := temps.LoadAndDelete(db); ok {
+		os.RemoveAll(dir.(string))
+	}
+}
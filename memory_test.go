@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestOpenMemory(t *testing.T) {
+	db, err := OpenMemory("shared test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	db.SetMaxOpenConns(3)
+	db.SetMaxIdleConns(0)
+
+	if _, err := db.Exec("create table t (n int); insert into t values(1)"); err != nil {
+		t.Fatal(err)
+	}
+	// the table is seen by every connection, and outlives them
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var n int
+		if err := conn.QueryRowContext(ctx, "select n from t").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other, err := OpenMemory("shared test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(other)
+	var n int
+	if err := row(other, []interface{}{&n}, "select n from t"); err != nil {
+		t.Fatal(err)
+	}
+
+	private, err := OpenMemory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(private)
+	if err := row(private, []interface{}{&n}, "select n from t"); err == nil {
+		t.Fatal("expected a database of its own")
+	}
+}
+
+func TestOpenTemp(t *testing.T) {
+	db, err := OpenTemp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("create table t (n int); insert into t values(1)"); err != nil {
+		t.Fatal(err)
+	}
+	file := Filename(db)
+	if _, err := os.Stat(file); err != nil {
+		t.Fatal(err)
+	}
+	Close(db)
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be deleted: %v", file, err)
+	}
+}
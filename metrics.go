@@ -0,0 +1,163 @@
+package sqlite
+
+import (
+	"expvar"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Names of the duration histograms the package records
+const (
+	MetricBackupStep = "backup_step" // each step of a page by page backup
+	MetricCheckpoint = "checkpoint"  // WAL checkpoints by WALCheckpoint and Close
+	MetricMigration  = "migration"   // each migration applied or reverted
+	MetricScript     = "script"      // scripts run by ExecScript and ExecChunked
+)
+
+// histogramBounds are the upper bounds of the buckets of a histogram, doubling from 50µs
+// to about 50s, beyond which is one more bucket
+var histogramBounds = func() []time.Duration {
+	bounds := make([]time.Duration, 21)
+	for i := range bounds {
+		bounds[i] = 50 * time.Microsecond << i
+	}
+	return bounds
+}()
+
+// Histogram counts durations in buckets of exponentially increasing size
+type Histogram struct {
+	mu      sync.Mutex
+	count   uint64
+	sum     time.Duration
+	min     time.Duration
+	max     time.Duration
+	buckets []uint64
+}
+
+// HistogramBucket counts the durations above the bound of the bucket before it, up to its own
+type HistogramBucket struct {
+	UpperBound time.Duration // math.MaxInt64 for the last bucket
+	Count      uint64
+}
+
+// HistogramSnapshot is the state of a histogram at a point in time
+type HistogramSnapshot struct {
+	Count   uint64
+	Sum     time.Duration
+	Min     time.Duration
+	Max     time.Duration
+	Buckets []HistogramBucket `json:",omitempty"` // only those with a count
+}
+
+// Observe adds a duration to the histogram
+func (h *Histogram) Observe(d time.Duration) {
+	i := sort.Search(len(histogramBounds), func(i int) bool { return d <= histogramBounds[i] })
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(histogramBounds)+1)
+	}
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+	h.buckets[i]++
+}
+
+// Snapshot returns the current state of the histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := HistogramSnapshot{Count: h.count, Sum: h.sum, Min: h.min, Max: h.max}
+	for i, n := range h.buckets {
+		if n == 0 {
+			continue
+		}
+		bound := time.Duration(math.MaxInt64)
+		if i < len(histogramBounds) {
+			bound = histogramBounds[i]
+		}
+		s.Buckets = append(s.Buckets, HistogramBucket{UpperBound: bound, Count: n})
+	}
+	return s
+}
+
+// Mean returns the average duration
+func (s HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile estimates the duration that the fraction q of durations don't exceed,
+// e.g. 0.99, as the upper bound of its bucket, though no more than the maximum
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	var seen uint64
+	for _, b := range s.Buckets {
+		seen += b.Count
+		if seen >= rank {
+			if b.UpperBound > s.Max {
+				return s.Max
+			}
+			return b.UpperBound
+		}
+	}
+	return s.Max
+}
+
+var (
+	histogramMu sync.Mutex
+	histograms  = make(map[string]*Histogram)
+)
+
+// histogram returns the named histogram, creating it if need be
+func histogram(name string) *Histogram {
+	histogramMu.Lock()
+	defer histogramMu.Unlock()
+	h, ok := histograms[name]
+	if !ok {
+		h = &Histogram{}
+		histograms[name] = h
+	}
+	return h
+}
+
+// observe records the time since start in the named histogram, for use with defer
+func observe(name string, start time.Time) {
+	histogram(name).Observe(time.Since(start))
+}
+
+// Histograms returns the state of the package's duration histograms, by name
+func Histograms() map[string]HistogramSnapshot {
+	histogramMu.Lock()
+	defer histogramMu.Unlock()
+	snapshots := make(map[string]HistogramSnapshot, len(histograms))
+	for name, h := range histograms {
+		snapshots[name] = h.Snapshot()
+	}
+	return snapshots
+}
+
+// ResetHistograms discards the durations recorded so far
+func ResetHistograms() {
+	histogramMu.Lock()
+	defer histogramMu.Unlock()
+	histograms = make(map[string]*Histogram)
+}
+
+// PublishMetrics publishes the histograms with expvar under the given name, e.g. "sqlite",
+// so they are served as JSON at /debug/vars. Like expvar.Publish, it panics if the name is taken
+func PublishMetrics(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return Histograms() }))
+}
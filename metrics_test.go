@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for _, d := range []time.Duration{10 * time.Microsecond, time.Millisecond, time.Millisecond, 3 * time.Millisecond, time.Hour} {
+		h.Observe(d)
+	}
+	s := h.Snapshot()
+	if s.Count != 5 || s.Min != 10*time.Microsecond || s.Max != time.Hour {
+		t.Fatalf("unexpected snapshot: %+v", s)
+	}
+	if len(s.Buckets) != 4 || s.Buckets[0].UpperBound != 50*time.Microsecond || s.Buckets[3].UpperBound != math.MaxInt64 {
+		t.Fatalf("unexpected buckets: %+v", s.Buckets)
+	}
+	if q := s.Quantile(0.5); q != 1600*time.Microsecond {
+		t.Fatalf("unexpected median: %s", q)
+	}
+	if q := s.Quantile(1); q != time.Hour {
+		t.Fatalf("expected maximum, got %s", q)
+	}
+	if (HistogramSnapshot{}).Quantile(0.5) != 0 || (HistogramSnapshot{}).Mean() != 0 {
+		t.Fatal("expected empty snapshot to be zero")
+	}
+}
+
+func TestHistograms(t *testing.T) {
+	ResetHistograms()
+	db := memDB(t)
+	defer db.Close()
+	if _, err := ExecScript(db, "create table t (n); insert into t values (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WALCheckpoint(db, "PASSIVE"); err != nil {
+		t.Fatal(err)
+	}
+	metrics := Histograms()
+	for _, name := range []string{MetricScript, MetricCheckpoint} {
+		if metrics[name].Count != 1 {
+			t.Errorf("expected one %s, got %+v", name, metrics[name])
+		}
+	}
+	if _, err := json.Marshal(metrics); err != nil {
+		t.Fatal(err)
+	}
+	ResetHistograms()
+	if len(Histograms()) != 0 {
+		t.Fatal("expected reset to discard histograms")
+	}
+}
@@ -0,0 +1,356 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MigrationsTable records the versions of the migrations applied to a database
+const MigrationsTable = "schema_migrations"
+
+// ErrMigrationLocked is returned when another process kept migrating the database for too long
+var ErrMigrationLocked = errors.New("another process is migrating the database")
+
+// MigrationLockTimeout is how long Migrate waits for another process migrating the same database
+var MigrationLockTimeout = time.Minute
+
+// MigrationHook is called in the transaction of a migration, before or after it is applied or reverted
+type MigrationHook func(tx *sql.Tx, mig Migration, up bool) error
+
+// Migration is a numbered change to the schema, with the script to apply
+// it and, optionally, the script to revert it. Changes that SQL can't express,
+// such as data transforms, can be made by Go functions instead, which run
+// after the script (if any) in the same transaction
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	UpFunc   func(*sql.Tx) error
+	DownFunc func(*sql.Tx) error
+	Before   MigrationHook
+	After    MigrationHook
+}
+
+// Checksum returns a hash of the scripts of the migration, which is recorded when it is applied
+// so that later changes to it can be detected. Go functions are not included
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + "\x00" + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+// migrationFile matches 001_name.sql, 001_name.up.sql, and 001_name.down.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+?)(\.up|\.down)?\.sql$`)
+
+// LoadMigrations reads migrations from the .sql files at the top of fsys, named by
+// version and name, e.g. 001_create_users.sql. A migration that can be reverted
+// is given as a pair of files instead: 001_create_users.up.sql and 001_create_users.down.sql.
+// Other files are ignored; use fs.Sub to load from a subdirectory
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		script, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == ".down" {
+			mig.Down = string(script)
+		} else if mig.Up != "" {
+			return nil, fmt.Errorf("migration %d has more than one up script", version)
+		} else {
+			mig.Up = string(script)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies the migrations that have not been applied yet, in order of version
+func Migrate(db *sql.DB, migrations []Migration) error {
+	return MigrateTo(db, migrations, -1)
+}
+
+// MigrateOptions control how processes sharing a database file coordinate migrations
+type MigrateOptions struct {
+	LockTimeout  time.Duration // how long to wait for another process migrating, MigrationLockTimeout if 0
+	Follow       bool          // don't migrate, but wait until another process has applied every migration
+	PollInterval time.Duration // how often a follower checks, 100ms if 0
+	Reporter     Progress      // reports on the migrations applied or reverted
+}
+
+// MigrateContext is Migrate for replicas that start at the same time: either each takes
+// its turn (only the first finds work to do), or one migrates and the others follow,
+// waiting until the migrations are applied. Waiting stops if ctx is done
+func MigrateContext(ctx context.Context, db *sql.DB, migrations []Migration, opts MigrateOptions) error {
+	if !opts.Follow {
+		return migrateTo(ctx, db, migrations, -1, opts.LockTimeout, opts.Reporter)
+	}
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return err
+	}
+	poll := opts.PollInterval
+	if poll <= 0 {
+		poll = 100 * time.Millisecond
+	}
+	for {
+		pending, err := pendingMigrations(db, sorted)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for migrations %v: %w", pending, ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+// pendingMigrations returns the versions of the migrations that have not been applied
+func pendingMigrations(db *sql.DB, sorted []Migration) ([]int64, error) {
+	var exists bool
+	if err := db.QueryRow("SELECT count(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = ?", MigrationsTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]string)
+	if exists {
+		var err error
+		if applied, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+	var pending []int64
+	for _, mig := range sorted {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig.Version)
+		}
+	}
+	return pending, nil
+}
+
+// MigrateTo brings the schema to the given version, applying the migrations up to
+// and including it, and reverting those after it, newest first. A version of -1
+// applies all of the migrations. Each migration runs in a transaction of its own,
+// along with the update of MigrationsTable, so a failed migration leaves no trace.
+//
+// Processes migrating the same database file take turns, waiting up to MigrationLockTimeout
+// for each other, so that each migration is applied once
+func MigrateTo(db *sql.DB, migrations []Migration, version int64) error {
+	return migrateTo(context.Background(), db, migrations, version, MigrationLockTimeout, nil)
+}
+
+func migrateTo(ctx context.Context, db *sql.DB, migrations []Migration, version int64, wait time.Duration, progress Progress) (err error) {
+	report := reporting(progress)
+	report.OnStart("migrate")
+	defer func() { report.OnFinish(err) }()
+
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return err
+	}
+	if wait <= 0 {
+		wait = MigrationLockTimeout
+	}
+	unlock, err := lockMigrations(ctx, db, wait)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := createMigrationsTable(db); err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+	type step struct {
+		mig Migration
+		up  bool
+	}
+	var plan []step
+	for _, mig := range sorted {
+		if _, ok := applied[mig.Version]; ok || (version >= 0 && mig.Version > version) {
+			continue
+		}
+		plan = append(plan, step{mig, true})
+	}
+	for i := len(sorted) - 1; i >= 0 && version >= 0; i-- {
+		mig := sorted[i]
+		if _, ok := applied[mig.Version]; !ok || mig.Version <= version {
+			continue
+		}
+		plan = append(plan, step{mig, false})
+	}
+	report.OnProgress(0, int64(len(plan)))
+	for i, s := range plan {
+		if err := runMigration(db, s.mig, s.up); err != nil {
+			return err
+		}
+		report.OnProgress(int64(i+1), int64(len(plan)))
+	}
+	return nil
+}
+
+// sortMigrations returns a sorted copy of migrations, which must have unique, positive versions
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, mig := range sorted {
+		if mig.Version <= 0 {
+			return nil, fmt.Errorf("migration %q has invalid version %d", mig.Name, mig.Version)
+		}
+		if i > 0 && sorted[i-1].Version == mig.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", mig.Version)
+		}
+	}
+	return sorted, nil
+}
+
+// lockMigrations takes the lock that keeps processes from migrating a database file at the same time
+func lockMigrations(ctx context.Context, db *sql.DB, wait time.Duration) (func(), error) {
+	file, err := FilenameE(db)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		// a memory or temporary database can't be shared with other processes
+		return func() {}, nil
+	}
+	f, err := lockFile(ctx, file+".migrate.lock", wait)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, redactf("waiting to migrate %s: %w", file, err)
+		}
+		return nil, redactf("%w: %s: %v", ErrMigrationLocked, file, err)
+	}
+	return func() { f.Close() }, nil
+}
+
+func createMigrationsTable(db *sql.DB) error {
+	const create = `CREATE TABLE IF NOT EXISTS %s (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at INTEGER NOT NULL,
+	checksum   TEXT NOT NULL DEFAULT ''
+)`
+	if _, err := db.Exec(fmt.Sprintf(create, MigrationsTable)); err != nil {
+		return err
+	}
+	// tables created before checksums were recorded
+	columns, err := exportColumns(db, MigrationsTable)
+	if err != nil {
+		return err
+	}
+	for _, c := range columns {
+		if c.name == "checksum" {
+			return nil
+		}
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN checksum TEXT NOT NULL DEFAULT ''", MigrationsTable))
+	return err
+}
+
+// appliedMigrations returns the names of the applied migrations by version
+func appliedMigrations(db *sql.DB) (map[int64]string, error) {
+	applied := make(map[int64]string)
+	rows, err := db.Query(fmt.Sprintf("SELECT version, name FROM %s", MigrationsTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var name string
+		if err := rows.Scan(&version, &name); err != nil {
+			return nil, err
+		}
+		applied[version] = name
+	}
+	return applied, rows.Err()
+}
+
+// runMigration applies (or reverts) a migration and records it in a single transaction
+func runMigration(db *sql.DB, mig Migration, up bool) (err error) {
+	defer observe(MetricMigration, time.Now())
+	script, fn, record := mig.Up, mig.UpFunc, fmt.Sprintf("INSERT INTO %s (version, name, applied_at, checksum) VALUES (?, ?, ?, ?)", MigrationsTable)
+	args := []interface{}{mig.Version, mig.Name, time.Now().Unix(), mig.Checksum()}
+	direction := "up"
+	if !up {
+		if mig.Down == "" && mig.DownFunc == nil {
+			return fmt.Errorf("migration %d %s cannot be reverted: it has no down script", mig.Version, mig.Name)
+		}
+		script, fn, record = mig.Down, mig.DownFunc, fmt.Sprintf("DELETE FROM %s WHERE version = ?", MigrationsTable)
+		args = args[:1]
+		direction = "down"
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("migration %d %s (%s): %w", mig.Version, mig.Name, direction, err)
+		}
+	}()
+	if mig.Before != nil {
+		if err = mig.Before(tx, mig, up); err != nil {
+			return err
+		}
+	}
+	if script != "" {
+		if _, err = tx.Exec(script); err != nil {
+			return err
+		}
+	}
+	if fn != nil {
+		if err = fn(tx); err != nil {
+			return err
+		}
+	}
+	if mig.After != nil {
+		if err = mig.After(tx, mig, up); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
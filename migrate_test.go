@@ -0,0 +1,237 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	fsys := fstest.MapFS{
+		"001_users.up.sql":   {Data: []byte("create table users (id integer primary key, name text);")},
+		"001_users.down.sql": {Data: []byte("drop table users;")},
+		"002_email.sql":      {Data: []byte("alter table users add column email text;")},
+		"003_index.up.sql":   {Data: []byte("create index users_email on users (email);")},
+		"003_index.down.sql": {Data: []byte("drop index users_email;")},
+		"README.md":          {Data: []byte("not a migration")},
+	}
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 || migrations[1].Name != "email" || migrations[0].Down == "" {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+
+	if err := Migrate(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+	// applying again is a no-op
+	if err := Migrate(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from schema_migrations"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected: %d but got: %d", 3, count)
+	}
+
+	if err := MigrateTo(db, migrations, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := row(db, []interface{}{&count}, "select count(*) from sqlite_master where name = 'users_email'"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("expected index to be dropped")
+	}
+	// 002 has no down script
+	if err := MigrateTo(db, migrations, 0); err == nil {
+		t.Fatal("expected error reverting migration without a down script")
+	}
+
+	// a failing migration is rolled back along with its record
+	bad := append(migrations, Migration{Version: 4, Name: "bad", Up: "create table t (n int); insert into nosuch values(1);"})
+	if err := Migrate(db, bad); err == nil {
+		t.Fatal("expected migration error")
+	}
+	if err := row(db, []interface{}{&count}, "select count(*) from sqlite_master where name = 't'"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatal("expected failed migration to be rolled back")
+	}
+	if err := Migrate(db, append(migrations, migrations[0])); err == nil {
+		t.Fatal("expected duplicate version error")
+	}
+}
+
+func TestMigrateFuncs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "migrate.db")
+	var calls int32
+	var events []string
+	hook := func(tx *sql.Tx, mig Migration, up bool) error {
+		events = append(events, mig.Name)
+		return nil
+	}
+	migrations := []Migration{
+		{Version: 1, Name: "people", Up: "create table people (name text, first text, last text)", Down: "drop table people"},
+		{Version: 2, Name: "split names", Before: hook, After: hook,
+			UpFunc: func(tx *sql.Tx) error {
+				atomic.AddInt32(&calls, 1)
+				// give a concurrent migrator time to get in the way
+				time.Sleep(50 * time.Millisecond)
+				rows, err := tx.Query("select rowid, name from people")
+				if err != nil {
+					return err
+				}
+				names := make(map[int64]string)
+				for rows.Next() {
+					var id int64
+					var name string
+					if err := rows.Scan(&id, &name); err != nil {
+						rows.Close()
+						return err
+					}
+					names[id] = name
+				}
+				rows.Close()
+				for id, name := range names {
+					parts := strings.SplitN(name, " ", 2)
+					if _, err := tx.Exec("update people set first=?, last=? where rowid=?", parts[0], parts[1], id); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			DownFunc: func(tx *sql.Tx) error {
+				_, err := tx.Exec("update people set first=null, last=null")
+				return err
+			},
+		},
+	}
+
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := MigrateTo(db, migrations, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into people (name) values ('Ada Lovelace')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// separate pools migrating at once apply the Go migration once
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			other, err := Open(file)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer other.Close()
+			errs[i] = Migrate(other, migrations)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected migration to run once but ran %d times", calls)
+	}
+	if strings.Join(events, ",") != "split names,split names" {
+		t.Fatalf("unexpected hook calls: %v", events)
+	}
+	var last string
+	if err := row(db, []interface{}{&last}, "select last from people"); err != nil {
+		t.Fatal(err)
+	}
+	if last != "Lovelace" {
+		t.Fatalf("expected: %q but got: %q", "Lovelace", last)
+	}
+
+	if err := MigrateTo(db, migrations, 1); err != nil {
+		t.Fatal(err)
+	}
+	var null sql.NullString
+	if err := row(db, []interface{}{&null}, "select last from people"); err != nil {
+		t.Fatal(err)
+	}
+	if null.Valid {
+		t.Fatal("expected down function to clear the split names")
+	}
+}
+
+func TestMigrateFollow(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "follow.db")
+	release := make(chan struct{})
+	migrations := []Migration{
+		{Version: 1, Name: "slow", UpFunc: func(tx *sql.Tx) error {
+			<-release
+			_, err := tx.Exec("create table t (n int)")
+			return err
+		}},
+	}
+	leader, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leader.Close()
+	follower, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- Migrate(leader, migrations) }()
+	// give the leader time to take the lock
+	time.Sleep(50 * time.Millisecond)
+
+	// another migrator gives up waiting for the lock
+	opts := MigrateOptions{LockTimeout: 20 * time.Millisecond}
+	if err := MigrateContext(context.Background(), follower, migrations, opts); !errors.Is(err, ErrMigrationLocked) {
+		t.Fatalf("expected ErrMigrationLocked but got: %v", err)
+	}
+
+	// a follower times out, then succeeds once the leader is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	opts = MigrateOptions{Follow: true, PollInterval: 5 * time.Millisecond}
+	if err := MigrateContext(ctx, follower, migrations, opts); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected follower to time out but got: %v", err)
+	}
+	close(release)
+	if err := MigrateContext(context.Background(), follower, migrations, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := row(follower, []interface{}{&count}, "select count(*) from t"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// migrationRegistry holds the migrations registered with RegisterMigrations
+var migrationRegistry = struct {
+	sync.Mutex
+	migrations []Migration
+}{}
+
+// RegisterMigrations adds migrations to those used by MigrationStatus and Rollback,
+// typically from the init functions of the packages that own them
+func RegisterMigrations(migrations ...Migration) {
+	migrationRegistry.Lock()
+	migrationRegistry.migrations = append(migrationRegistry.migrations, migrations...)
+	migrationRegistry.Unlock()
+}
+
+// RegisteredMigrations returns the registered migrations in order of version
+func RegisteredMigrations() []Migration {
+	migrationRegistry.Lock()
+	defer migrationRegistry.Unlock()
+	migrations := append([]Migration(nil), migrationRegistry.migrations...)
+	sort.SliceStable(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+// MigrationInfo is the state of a migration in a database
+type MigrationInfo struct {
+	Version         int64
+	Name            string
+	Applied         bool
+	AppliedAt       time.Time
+	Checksum        string // of the registered migration
+	AppliedChecksum string // of the migration when it was applied, if known
+	Modified        bool   // the migration was changed after it was applied
+	Unknown         bool   // the migration was applied but is not registered, e.g. by a newer release
+}
+
+// MigrationStatus returns the state of each registered migration, and any applied
+// migrations that are not registered, in order of version
+func MigrationStatus(db *sql.DB) ([]MigrationInfo, error) {
+	if err := createMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(fmt.Sprintf("SELECT version, name, applied_at, checksum FROM %s", MigrationsTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]MigrationInfo)
+	for rows.Next() {
+		var info MigrationInfo
+		var at int64
+		if err := rows.Scan(&info.Version, &info.Name, &at, &info.AppliedChecksum); err != nil {
+			return nil, err
+		}
+		info.Applied, info.AppliedAt, info.Unknown = true, time.Unix(at, 0), true
+		applied[info.Version] = info
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var status []MigrationInfo
+	for _, mig := range RegisteredMigrations() {
+		info, ok := applied[mig.Version]
+		if !ok {
+			info = MigrationInfo{Version: mig.Version, Name: mig.Name}
+		}
+		info.Unknown = false
+		info.Checksum = mig.Checksum()
+		info.Modified = info.Applied && info.AppliedChecksum != "" && info.AppliedChecksum != info.Checksum
+		delete(applied, mig.Version)
+		status = append(status, info)
+	}
+	for _, info := range applied {
+		status = append(status, info)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, nil
+}
+
+// Rollback reverts the applied migrations after toVersion, newest first, using the down
+// scripts and functions of the registered migrations. It fails before changing anything
+// if one of them is not registered or can't be reverted
+func Rollback(db *sql.DB, toVersion int64) error {
+	if toVersion < 0 {
+		return fmt.Errorf("invalid version: %d", toVersion)
+	}
+	status, err := MigrationStatus(db)
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int64]Migration)
+	for _, mig := range RegisteredMigrations() {
+		byVersion[mig.Version] = mig
+	}
+	var revert []Migration
+	for _, info := range status {
+		if !info.Applied || info.Version <= toVersion {
+			continue
+		}
+		mig, ok := byVersion[info.Version]
+		if !ok {
+			return fmt.Errorf("migration %d %s is not registered", info.Version, info.Name)
+		}
+		if mig.Down == "" && mig.DownFunc == nil {
+			return fmt.Errorf("migration %d %s cannot be reverted: it has no down script", mig.Version, mig.Name)
+		}
+		revert = append(revert, mig)
+	}
+	// only applied migrations are given, so none are applied
+	return MigrateTo(db, revert, toVersion)
+}
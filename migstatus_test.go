@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestMigrationStatus(t *testing.T) {
+	saved := migrationRegistry.migrations
+	defer func() { migrationRegistry.migrations = saved }()
+	migrationRegistry.migrations = nil
+
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	RegisterMigrations(
+		Migration{Version: 2, Name: "index", Up: "create index t_n on t (n)", Down: "drop index t_n"},
+		Migration{Version: 1, Name: "table", Up: "create table t (n int)", Down: "drop table t"},
+		Migration{Version: 3, Name: "view", Up: "create view v as select n from t", Down: "drop view v"},
+	)
+	if err := MigrateTo(db, RegisteredMigrations(), 2); err != nil {
+		t.Fatal(err)
+	}
+	// a migration applied by a newer release
+	if _, err := db.Exec("insert into schema_migrations (version, name, applied_at) values (9, 'future', 0)"); err != nil {
+		t.Fatal(err)
+	}
+	// an applied migration edited afterwards
+	migrationRegistry.migrations[0].Up = "create index t_n on t (n desc)"
+
+	status, err := MigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 4 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	one, two, three, nine := status[0], status[1], status[2], status[3]
+	if !one.Applied || one.Modified || one.Checksum != one.AppliedChecksum {
+		t.Fatalf("unexpected status: %+v", one)
+	}
+	if !two.Applied || !two.Modified {
+		t.Fatalf("expected edited migration to be modified: %+v", two)
+	}
+	if three.Applied || three.Modified {
+		t.Fatalf("expected pending migration: %+v", three)
+	}
+	if !nine.Applied || !nine.Unknown {
+		t.Fatalf("expected unknown migration: %+v", nine)
+	}
+
+	// version 9 isn't registered, so it can't be reverted
+	if err := Rollback(db, 1); err == nil {
+		t.Fatal("expected error rolling back an unknown migration")
+	}
+	if _, err := db.Exec("delete from schema_migrations where version = 9"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Rollback(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from sqlite_master where name in ('t', 't_n', 'v')"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected everything to be rolled back, %d objects remain", count)
+	}
+}
@@ -0,0 +1,171 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// MirrorInterval is how often a Mirror checks its database for changes
+var MirrorInterval = 100 * time.Millisecond
+
+// Mirror is an in-memory replica of a database, which serves reads from memory
+// while writes go to the database on disk. It's refreshed when the database
+// changes, so reads may lag writes made elsewhere by up to MirrorInterval
+type Mirror struct {
+	db    *sql.DB
+	watch *sqlite3.SQLiteConn // a private connection to the database, for data_version
+
+	mu  sync.RWMutex
+	mem *sql.DB
+
+	refresh sync.Mutex
+	version int64
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// MirrorToMemory copies the database into memory and keeps the copy up to date
+// until the mirror is closed. It suits small databases with latency-critical reads,
+// as each change to the database copies all of it again
+func MirrorToMemory(db *sql.DB) (*Mirror, error) {
+	file, err := FilenameE(db)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return nil, fmt.Errorf("mirror needs a database file")
+	}
+	watch, err := driverConn(file)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mirror{db: db, watch: watch, version: -1, stop: make(chan struct{}), done: make(chan struct{})}
+	if err := m.Refresh(); err != nil {
+		watch.Close()
+		return nil, err
+	}
+	go m.run()
+	return m, nil
+}
+
+// run refreshes the mirror when the database changes, until it's closed
+func (m *Mirror) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(MirrorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			if err := m.Refresh(); err != nil {
+				logf("mirror refresh failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// Refresh copies the database into memory if it has changed since the last copy
+func (m *Mirror) Refresh() error {
+	m.refresh.Lock()
+	defer m.refresh.Unlock()
+	var version int64
+	err := connQuery(m.watch, func(_ []string, _ int, values []driver.Value) error {
+		version, _ = values[0].(int64)
+		return nil
+	}, "PRAGMA data_version")
+	if err != nil {
+		return err
+	}
+	if version == m.version {
+		return nil
+	}
+
+	// the copy is made into a new database, which replaces the old one for
+	// the reads that follow, while those in progress finish with the old one
+	mem, err := OpenMemory("")
+	if err != nil {
+		return err
+	}
+	err = rawConn(context.Background(), mem, func(dest *sqlite3.SQLiteConn) error {
+		return copyConn(dest, m.watch)
+	})
+	if err != nil {
+		Close(mem)
+		return fmt.Errorf("mirror copy failed: %w", err)
+	}
+	m.mu.Lock()
+	old := m.mem
+	m.mem = mem
+	m.mu.Unlock()
+	m.version = version
+	if old != nil {
+		Close(old)
+	}
+	return nil
+}
+
+// memory returns the current in-memory copy
+func (m *Mirror) memory() *sql.DB {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.mem
+}
+
+// Query runs a query on the in-memory copy
+func (m *Mirror) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return m.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext runs a query on the in-memory copy
+func (m *Mirror) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.mem.QueryContext(ctx, query, args...)
+}
+
+// QueryRow runs a query that returns one row on the in-memory copy
+func (m *Mirror) QueryRow(query string, args ...interface{}) *sql.Row {
+	return m.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext runs a query that returns one row on the in-memory copy
+func (m *Mirror) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.mem.QueryRowContext(ctx, query, args...)
+}
+
+// Exec runs a statement on the database, and refreshes the copy so that
+// the reads that follow see the change
+func (m *Mirror) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return m.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext runs a statement on the database, and refreshes the copy so that
+// the reads that follow see the change
+func (m *Mirror) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := m.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return res, err
+	}
+	return res, m.Refresh()
+}
+
+// Close stops refreshing the mirror and releases its memory. The database stays open
+func (m *Mirror) Close() error {
+	close(m.stop)
+	<-m.done
+	m.refresh.Lock()
+	defer m.refresh.Unlock()
+	if mem := m.memory(); mem != nil {
+		Close(mem)
+	}
+	return m.watch.Close()
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMirrorToMemory(t *testing.T) {
+	defer func(d time.Duration) { MirrorInterval = d }(MirrorInterval)
+	MirrorInterval = 5 * time.Millisecond
+
+	file := filepath.Join(t.TempDir(), "mirror.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("create table kv (k text primary key, v text); insert into kv values ('a', '1')"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := MirrorToMemory(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	var v string
+	if err := m.QueryRow("select v from kv where k = 'a'").Scan(&v); err != nil || v != "1" {
+		t.Fatalf("expected 1, got %q (%v)", v, err)
+	}
+
+	// writes through the mirror are seen at once
+	if _, err := m.Exec("update kv set v = '2' where k = 'a'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.QueryRow("select v from kv where k = 'a'").Scan(&v); err != nil || v != "2" {
+		t.Fatalf("expected 2, got %q (%v)", v, err)
+	}
+
+	// and writes made elsewhere after the next check
+	other, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err := other.Exec("insert into kv values ('b', '3')"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := m.QueryRow("select v from kv where k = 'b'").Scan(&v)
+		if err == nil && v == "3" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the mirror to be refreshed, got %q (%v)", v, err)
+		}
+		time.Sleep(MirrorInterval)
+	}
+
+	rows, err := m.Query("select k from kv order by k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %v", keys)
+	}
+
+	if _, err := MirrorToMemory(memDB(t)); err == nil {
+		t.Fatal("expected error mirroring a memory database")
+	}
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// moduleReg registers a virtual table module on a connection, see WithModule
+type moduleReg struct {
+	name     string
+	register func(*sqlite3.SQLiteConn) error
+}
+
+// moduleConn registers the modules added to the open database on a new connection
+func (c *Config) moduleConn(conn *sqlite3.SQLiteConn) error {
+	c.tablesMu.Lock()
+	defer c.tablesMu.Unlock()
+	if len(c.tables) == 0 {
+		return nil
+	}
+	main, err := connFilename(conn)
+	if err != nil {
+		return err
+	}
+	for _, m := range c.tables[main] {
+		if err := m.register(conn); err != nil {
+			return fmt.Errorf("failed to register module %q: %w", m.name, err)
+		}
+	}
+	return nil
+}
+
+// addModule registers a module on every connection of an open database, including
+// those opened later. Connections in use while it's called get the module when
+// they're next opened, and as with Attach, connections are told apart by their
+// main database file, so those of an in-memory database get it only while idle
+func addModule(db *sql.DB, m moduleReg) error {
+	config := configFor(db)
+	if config == nil {
+		return fmt.Errorf("module %s: database was not opened by this package", m.name)
+	}
+	main, err := FilenameE(db)
+	if err != nil {
+		return err
+	}
+	if main != "" {
+		config.tablesMu.Lock()
+		if config.tables == nil {
+			config.tables = make(map[string][]moduleReg)
+		}
+		// a module registered again replaces the one before
+		list := config.tables[main][:0:0]
+		for _, old := range config.tables[main] {
+			if !strings.EqualFold(old.name, m.name) {
+				list = append(list, old)
+			}
+		}
+		config.tables[main] = append(list, m)
+		config.tablesMu.Unlock()
+	}
+	ctx := context.Background()
+	return idleConns(ctx, db, func(conn *sql.Conn) error {
+		return conn.Raw(func(dc interface{}) error {
+			sc, ok := dc.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection: %T", dc)
+			}
+			return m.register(sc)
+		})
+	})
+}
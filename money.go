@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MoneyScale is the number of decimal places in a unit of Money
+var MoneyScale = 2
+
+// Money is a currency amount stored as an integer count of minor units (e.g., cents),
+// so that arithmetic is exact and never subject to float rounding
+type Money int64
+
+// moneyFactor returns 10^MoneyScale
+func moneyFactor() int64 {
+	f := int64(1)
+	for i := 0; i < MoneyScale; i++ {
+		f *= 10
+	}
+	return f
+}
+
+// ParseMoney parses a decimal string such as "-12.34" exactly
+func ParseMoney(s string) (Money, error) {
+	text := strings.TrimSpace(s)
+	neg := strings.HasPrefix(text, "-")
+	if neg || strings.HasPrefix(text, "+") {
+		text = text[1:]
+	}
+	whole, frac := text, ""
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		whole, frac = text[:i], text[i+1:]
+	}
+	digits := whole + frac
+	if digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+	if len(frac) > MoneyScale {
+		return 0, fmt.Errorf("amount %q has more than %d decimal places", s, MoneyScale)
+	}
+	units, err := strconv.ParseInt(digits+strings.Repeat("0", MoneyScale-len(frac)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+	if neg {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+// String formats the amount as a decimal, e.g. "-12.34"
+func (m Money) String() string {
+	factor := moneyFactor()
+	sign := ""
+	u := uint64(m)
+	if m < 0 {
+		sign = "-"
+		u = uint64(-m)
+	}
+	if MoneyScale == 0 {
+		return fmt.Sprintf("%s%d", sign, u)
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, u/uint64(factor), MoneyScale, u%uint64(factor))
+}
+
+// Sortable encodes the amount as fixed width text that sorts in numerical order
+func (m Money) Sortable() string {
+	if m < 0 {
+		return fmt.Sprintf("N%019d", int64(m)-math.MinInt64)
+	}
+	return fmt.Sprintf("P%019d", int64(m))
+}
+
+// ParseSortable decodes text created by Money.Sortable
+func ParseSortable(s string) (Money, error) {
+	if len(s) != 20 || (s[0] != 'N' && s[0] != 'P') {
+		return 0, fmt.Errorf("invalid sortable amount: %q", s)
+	}
+	u, err := strconv.ParseInt(s[1:], 10, 64)
+	if err != nil || u < 0 {
+		return 0, fmt.Errorf("invalid sortable amount: %q", s)
+	}
+	if s[0] == 'N' {
+		return Money(u + math.MinInt64), nil
+	}
+	return Money(u), nil
+}
+
+// Value implements driver.Valuer, storing the amount as integer minor units
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan implements sql.Scanner, accepting minor units, decimal text, or sortable text
+func (m *Money) Scan(src interface{}) error {
+	var err error
+	switch src := src.(type) {
+	case int64:
+		*m = Money(src)
+	case nil:
+		*m = 0
+	case []byte:
+		*m, err = parseMoneyText(string(src))
+	case string:
+		*m, err = parseMoneyText(src)
+	default:
+		err = fmt.Errorf("can't scan %T as money", src)
+	}
+	return err
+}
+
+func parseMoneyText(s string) (Money, error) {
+	if len(s) == 20 && (s[0] == 'N' || s[0] == 'P') {
+		return ParseSortable(s)
+	}
+	return ParseMoney(s)
+}
+
+// moneyFormat is the SQL function money_format(units)
+func moneyFormat(units int64) string {
+	return Money(units).String()
+}
+
+// moneyParse is the SQL function money_parse(text), returning minor units
+func moneyParse(s string) (int64, error) {
+	m, err := ParseMoney(s)
+	return int64(m), err
+}
+
+// moneyMul is the SQL function money_mul(units, factor), where factor is a number
+// such as a tax rate. The result is rounded half to even
+func moneyMul(units int64, factor interface{}) (int64, error) {
+	var text string
+	switch f := factor.(type) {
+	case string:
+		text = f
+	case int64:
+		text = strconv.FormatInt(f, 10)
+	case float64:
+		text = strconv.FormatFloat(f, 'g', -1, 64)
+	default:
+		return 0, fmt.Errorf("invalid factor: %v", factor)
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return 0, fmt.Errorf("invalid factor: %q", text)
+	}
+	return roundRat(r.Mul(r, new(big.Rat).SetInt64(units)))
+}
+
+// moneyDiv is the SQL function money_div(units, divisor), rounded half to even
+func moneyDiv(units, divisor int64) (int64, error) {
+	if divisor == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return roundRat(big.NewRat(units, divisor))
+}
+
+// roundRat rounds r half to even
+func roundRat(r *big.Rat) (int64, error) {
+	num, den := r.Num(), r.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	twice := new(big.Int).Mul(new(big.Int).Abs(m), big.NewInt(2))
+	if c := twice.Cmp(den); c > 0 || (c == 0 && q.Bit(0) == 1) {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("amount overflows: %s", q)
+	}
+	return q.Int64(), nil
+}
+
+// MoneyFuncs are SQL functions for working with amounts stored as Money
+var MoneyFuncs = []FuncReg{
+	{"money_format", moneyFormat, true},
+	{"money_parse", moneyParse, true},
+	{"money_mul", moneyMul, true},
+	{"money_div", moneyDiv, true},
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	good := map[string]Money{
+		"12.34":  1234,
+		"-0.05":  -5,
+		"7":      700,
+		"+1.5":   150,
+		".25":    25,
+		"100.00": 10000,
+	}
+	for text, expected := range good {
+		m, err := ParseMoney(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != expected {
+			t.Errorf("%q expected: %d but got: %d", text, expected, m)
+		}
+	}
+	for _, text := range []string{"", "-", "1.234", "1,00", "--1", "1e3"} {
+		if _, err := ParseMoney(text); err == nil {
+			t.Errorf("expected error for: %q", text)
+		}
+	}
+	if s := Money(-1205).String(); s != "-12.05" {
+		t.Fatalf("expected: -12.05 but got: %s", s)
+	}
+}
+
+func TestMoneySortable(t *testing.T) {
+	amounts := []Money{500, -3, 0, -1 << 62, 1 << 62, -250, 7}
+	text := make([]string, len(amounts))
+	for i, m := range amounts {
+		text[i] = m.Sortable()
+	}
+	sort.Strings(text)
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+	for i, s := range text {
+		m, err := ParseSortable(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != amounts[i] {
+			t.Fatalf("expected: %d but got: %d", amounts[i], m)
+		}
+	}
+}
+
+func TestMoneyFuncs(t *testing.T) {
+	db, err := Open(":memory:", WithFunctions(MoneyFuncs...), WithDriver("money"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("create table ledger (amount integer)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []Money{1999, 1, -500} {
+		if _, err := db.Exec("insert into ledger values(?)", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var total Money
+	var formatted string
+	if err := row(db, []interface{}{&total, &formatted}, "select sum(amount), money_format(sum(amount)) from ledger"); err != nil {
+		t.Fatal(err)
+	}
+	if total != 1500 || formatted != "15.00" {
+		t.Fatalf("unexpected total: %d (%s)", total, formatted)
+	}
+
+	var taxed, split, parsed int64
+	const q = "select money_mul(1999, 0.0825), money_div(1000, 3), money_parse('-3.10')"
+	if err := row(db, []interface{}{&taxed, &split, &parsed}, q); err != nil {
+		t.Fatal(err)
+	}
+	if taxed != 165 || split != 333 || parsed != -310 {
+		t.Fatalf("unexpected results: %d %d %d", taxed, split, parsed)
+	}
+}
@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// NetFuncs are SQL functions for IPv4 and IPv6 addresses. Addresses are stored as
+// 16 byte blobs, with IPv4 addresses mapped into IPv6, so that both sort and index
+// together and a range of addresses can be found with BETWEEN:
+//
+//	atoip6(text)               the blob of an address
+//	ip6toa(blob)               the text of an address
+//	cidr_contains(ip, prefix)  whether the address is in a prefix such as '10.0.0.0/8'
+//	ip_family(ip)              4 or 6, or 0 if it's not an address
+//
+// Where an address is taken it may be text, a 4 or 16 byte blob, or an IPv4
+// address as an integer, as from atoip. A NULL address gives a NULL blob from
+// atoip6, but as the driver can't return NULL from the others, empty text from
+// ip6toa, false from cidr_contains, and 0 from ip_family
+var NetFuncs = []FuncReg{
+	{"atoip6", atoIP6, true},
+	{"ip6toa", ip6ToA, true},
+	{"cidr_contains", cidrContains, true},
+	{"ip_family", ipFamily, true},
+}
+
+// WithNetFuncs registers NetFuncs
+func WithNetFuncs() Optional {
+	return WithFunctions(NetFuncs...)
+}
+
+// netAddr converts an address given to a net function, reporting false for NULL
+func netAddr(v interface{}) (netip.Addr, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return netip.Addr{}, false, nil
+	case string:
+		addr, err := netip.ParseAddr(v)
+		return addr, err == nil, err
+	case []byte:
+		if v == nil {
+			return netip.Addr{}, false, nil // the driver passes NULL as a nil slice
+		}
+		if addr, ok := netip.AddrFromSlice(v); ok {
+			return addr, true, nil
+		}
+		return netip.Addr{}, false, fmt.Errorf("address of %d bytes", len(v))
+	case int64:
+		if v < 0 || v > 0xFFFFFFFF {
+			return netip.Addr{}, false, fmt.Errorf("IPv4 address out of range: %d", v)
+		}
+		return netip.AddrFrom4([4]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}), true, nil
+	}
+	return netip.Addr{}, false, fmt.Errorf("not an address: %v", v)
+}
+
+func atoIP6(ip interface{}) ([]byte, error) {
+	addr, ok, err := netAddr(ip)
+	if err != nil || !ok {
+		return nil, err
+	}
+	b := addr.As16()
+	return b[:], nil
+}
+
+func ip6ToA(ip interface{}) (string, error) {
+	addr, ok, err := netAddr(ip)
+	if err != nil || !ok {
+		return "", err
+	}
+	return addr.Unmap().String(), nil
+}
+
+func cidrContains(ip, prefix interface{}) (bool, error) {
+	addr, ok, err := netAddr(ip)
+	if err != nil || !ok {
+		return false, err
+	}
+	text, ok := sqlText(prefix)
+	if !ok {
+		return false, nil
+	}
+	p, err := netip.ParsePrefix(text)
+	if err != nil {
+		return false, err
+	}
+	return p.Contains(addr.Unmap()), nil
+}
+
+func ipFamily(ip interface{}) int64 {
+	addr, ok, err := netAddr(ip)
+	switch {
+	case err != nil || !ok:
+		return 0
+	case addr.Unmap().Is4():
+		return 4
+	}
+	return 6
+}
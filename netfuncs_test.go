@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestNetFuncs(t *testing.T) {
+	db, err := Open(":memory:", WithDriver("net_funcs"), WithNetFuncs(), WithFunctions(ipFuncs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const schema = `
+	create table hosts (name text, ip blob);
+	create index hosts_ip on hosts(ip);
+	insert into hosts values ('v4', atoip6('10.1.2.3'));
+	insert into hosts values ('v6', atoip6('2001:db8::1'));
+	insert into hosts values ('other', atoip6('2001:db9::1'));
+	`
+	if _, err := ExecScript(db, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var name, ip string
+	if err := db.QueryRow("select name, ip6toa(ip) from hosts where cidr_contains(ip, '2001:db8::/32')").Scan(&name, &ip); err != nil {
+		t.Fatal(err)
+	}
+	if name != "v6" || ip != "2001:db8::1" {
+		t.Errorf("got %s %s", name, ip)
+	}
+	if err := db.QueryRow("select ip6toa(ip) from hosts where cidr_contains(ip, '10.0.0.0/8')").Scan(&ip); err != nil {
+		t.Fatal(err)
+	}
+	if ip != "10.1.2.3" {
+		t.Errorf("got %s, want 10.1.2.3", ip)
+	}
+
+	// a range by index
+	var n int
+	if err := db.QueryRow("select count(*) from hosts where ip between atoip6('2001::') and atoip6('2001:db8::ffff')").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("got %d hosts in range, want 1", n)
+	}
+
+	for _, test := range []struct {
+		query  string
+		family int
+	}{
+		{"select ip_family('::1')", 6},
+		{"select ip_family(atoip6('192.168.0.1'))", 4},
+		{"select ip_family(atoip('192.168.0.1'))", 4},
+		{"select ip_family('nonsense')", 0},
+		{"select ip_family(null)", 0},
+	} {
+		var family int
+		if err := db.QueryRow(test.query).Scan(&family); err != nil {
+			t.Fatal(err)
+		}
+		if family != test.family {
+			t.Errorf("%s: got %d, want %d", test.query, family, test.family)
+		}
+	}
+	var in bool
+	if err := db.QueryRow("select cidr_contains(atoip('192.168.1.20'), '192.168.1.0/24')").Scan(&in); err != nil {
+		t.Fatal(err)
+	}
+	if !in {
+		t.Error("integer address not in prefix")
+	}
+	if err := db.QueryRow("select atoip6('300.1.1.1')").Scan(&ip); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+
+	var blob []byte
+	if err := db.QueryRow("select atoip6(null), ip6toa(null), cidr_contains(null, '10.0.0.0/8')").Scan(&blob, &ip, &in); err != nil {
+		t.Fatal(err)
+	}
+	if blob != nil || ip != "" || in {
+		t.Errorf("expected NULL results for NULL addresses but got: %v %q %t", blob, ip, in)
+	}
+}
@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// JournalMode is a setting of PRAGMA journal_mode
+type JournalMode string
+
+// Journal modes
+const (
+	JournalDelete   JournalMode = "DELETE"
+	JournalTruncate JournalMode = "TRUNCATE"
+	JournalPersist  JournalMode = "PERSIST"
+	JournalMemory   JournalMode = "MEMORY"
+	JournalWAL      JournalMode = "WAL"
+	JournalOff      JournalMode = "OFF"
+)
+
+// Synchronous is a setting of PRAGMA synchronous
+type Synchronous string
+
+// Synchronous levels
+const (
+	SyncOff    Synchronous = "OFF"
+	SyncNormal Synchronous = "NORMAL"
+	SyncFull   Synchronous = "FULL"
+	SyncExtra  Synchronous = "EXTRA"
+)
+
+// WithJournalMode sets the journal mode of each new connection
+func WithJournalMode(mode JournalMode) Optional {
+	return withPragma("journal_mode", string(mode))
+}
+
+// WithBusyTimeout sets how long each new connection waits for a lock before failing with SQLITE_BUSY
+func WithBusyTimeout(timeout time.Duration) Optional {
+	return withPragma("busy_timeout", fmt.Sprint(timeout.Milliseconds()))
+}
+
+// WithForeignKeys turns enforcement of foreign keys on or off for each new connection
+func WithForeignKeys(enforce bool) Optional {
+	if enforce {
+		return withPragma("foreign_keys", "ON")
+	}
+	return withPragma("foreign_keys", "OFF")
+}
+
+// WithSynchronous sets how carefully each new connection syncs writes to disk
+func WithSynchronous(level Synchronous) Optional {
+	return withPragma("synchronous", string(level))
+}
+
+// WithCacheSize sets the page cache size of each new connection: n pages, or -n KiB if negative
+func WithCacheSize(n int) Optional {
+	return withPragma("cache_size", fmt.Sprint(n))
+}
+
+// withPragma sets a pragma on each new connection, quoting the value as SetPragma
+// does. An invalid name fails the Open
+func withPragma(name, value string) Optional {
+	return func(c *Config) {
+		if !pragmaName.MatchString(name) {
+			if c.err == nil {
+				c.err = fmt.Errorf("invalid pragma name: %q", name)
+			}
+			return
+		}
+		if !pragmaWord.MatchString(value) {
+			value = quoteString(value)
+		}
+		c.pragmas = append(c.pragmas, fmt.Sprintf("PRAGMA %s=%s", name, value))
+	}
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPragmaOptions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "options.db")
+	db, err := Open(file,
+		WithDriver("pragma_options"),
+		WithJournalMode(JournalWAL),
+		WithBusyTimeout(2500*time.Millisecond),
+		WithForeignKeys(true),
+		WithSynchronous(SyncNormal),
+		WithCacheSize(-8000),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	settings, err := AllPragmas(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"journal_mode": "wal",
+		"busy_timeout": "2500",
+		"foreign_keys": "1",
+		"synchronous":  "1",
+		"cache_size":   "-8000",
+	}
+	for name, want := range expected {
+		if got := settings[name]; got != want {
+			t.Errorf("pragma %s expected: %q but got: %q", name, want, got)
+		}
+	}
+}
+
+func TestPragmaOptionsSharedDriver(t *testing.T) {
+	dir := t.TempDir()
+	first, err := Open(filepath.Join(dir, "first.db"), WithDriver("pragma_shared"), WithCacheSize(-4000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	db, err := Open(filepath.Join(dir, "second.db"), WithDriver("pragma_shared"), WithCacheSize(-6000), WithForeignKeys(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for handle, expected := range map[*sql.DB]map[string]string{
+		first: {"cache_size": "-4000", "foreign_keys": "0"},
+		db:    {"cache_size": "-6000", "foreign_keys": "1"},
+	} {
+		settings, err := AllPragmas(handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range expected {
+			if got := settings[name]; got != want {
+				t.Errorf("pragma %s expected: %q but got: %q", name, want, got)
+			}
+		}
+	}
+}
+
+func TestPragmaOptionsInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "invalid.db")
+	if db, err := Open(file, WithPragmas(map[string]string{"journal_mode; DROP TABLE t": "wal"})); err == nil {
+		db.Close()
+		t.Fatal("expected an invalid pragma name to fail")
+	}
+
+	// a value that isn't a plain word is quoted, so it can't run another statement
+	db, err := Open(file, WithDriver("pragma_invalid"), WithPragmas(map[string]string{"journal_mode": "wal; DROP TABLE t"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("create table t (n int)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into t values (1)"); err != nil {
+		t.Fatal(err)
+	}
+}
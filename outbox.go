@@ -0,0 +1,255 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Outbox message states
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead" // gave up after too many failed attempts
+)
+
+// OutboxMessage is a message queued for delivery
+type OutboxMessage struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	Attempts  int
+	Created   time.Time
+	LastError string
+}
+
+// Outbox is a table of messages to be delivered outside the database, written in
+// the same transaction as the changes they describe so neither is lost without the other
+type Outbox struct {
+	db    *sql.DB
+	table string
+}
+
+// NewOutbox returns the outbox stored in table, creating it if necessary
+func NewOutbox(db *sql.DB, table string) (*Outbox, error) {
+	name := quoteIdent(table)
+	const create = `CREATE TABLE IF NOT EXISTS %s (
+	id         INTEGER PRIMARY KEY,
+	topic      TEXT NOT NULL,
+	payload    BLOB NOT NULL,
+	status     TEXT NOT NULL DEFAULT 'pending',
+	attempts   INTEGER NOT NULL DEFAULT 0,
+	next_try   INTEGER NOT NULL,
+	created    INTEGER NOT NULL,
+	last_error TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS %s ON %s (status, next_try);`
+	index := quoteIdent(table + "_pending")
+	if _, err := db.Exec(fmt.Sprintf(create, name, index, name)); err != nil {
+		return nil, fmt.Errorf("outbox %s: %w", table, err)
+	}
+	return &Outbox{db: db, table: table}, nil
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Enqueue adds a message to the outbox
+func (o *Outbox) Enqueue(topic string, payload []byte) (int64, error) {
+	return o.enqueue(o.db, topic, payload)
+}
+
+// EnqueueTx adds a message to the outbox as part of tx
+func (o *Outbox) EnqueueTx(tx *sql.Tx, topic string, payload []byte) (int64, error) {
+	return o.enqueue(tx, topic, payload)
+}
+
+func (o *Outbox) enqueue(e execer, topic string, payload []byte) (int64, error) {
+	if payload == nil {
+		payload = []byte{}
+	}
+	now := time.Now().UnixNano()
+	q := fmt.Sprintf("INSERT INTO %s (topic, payload, next_try, created) VALUES(?, ?, ?, ?)", quoteIdent(o.table))
+	result, err := e.Exec(q, topic, payload, now, now)
+	if err != nil {
+		return 0, fmt.Errorf("outbox %s: %w", o.table, err)
+	}
+	return result.LastInsertId()
+}
+
+// messages returns up to limit messages with the given status, oldest first,
+// only those due by the given time if due is not zero
+func (o *Outbox) messages(status string, due time.Time, limit int) ([]OutboxMessage, error) {
+	q := fmt.Sprintf("SELECT id, topic, payload, attempts, created, last_error FROM %s WHERE status=?", quoteIdent(o.table))
+	args := []interface{}{status}
+	if !due.IsZero() {
+		q += " AND next_try <= ?"
+		args = append(args, due.UnixNano())
+	}
+	q += " ORDER BY id LIMIT ?"
+	args = append(args, limit)
+	rows, err := o.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("outbox %s: %w", o.table, err)
+	}
+	defer rows.Close()
+	var list []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		var created int64
+		if err := rows.Scan(&m.ID, &m.Topic, &m.Payload, &m.Attempts, &created, &m.LastError); err != nil {
+			return nil, err
+		}
+		m.Created = time.Unix(0, created)
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// Dead returns the messages that could not be delivered
+func (o *Outbox) Dead() ([]OutboxMessage, error) {
+	return o.messages(OutboxDead, time.Time{}, -1)
+}
+
+// Requeue makes a dead message pending again, with its attempts reset
+func (o *Outbox) Requeue(id int64) error {
+	q := fmt.Sprintf("UPDATE %s SET status=?, attempts=0, next_try=? WHERE id=? AND status=?", quoteIdent(o.table))
+	result, err := o.db.Exec(q, OutboxPending, time.Now().UnixNano(), id, OutboxDead)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("outbox %s: no dead message %d", o.table, id)
+	}
+	return nil
+}
+
+// Purge removes delivered messages created before the given time
+func (o *Outbox) Purge(before time.Time) (int64, error) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE status=? AND created < ?", quoteIdent(o.table))
+	result, err := o.db.Exec(q, OutboxDelivered, before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Dispatcher delivers the messages of an Outbox, retrying failures with exponential backoff
+// and setting aside as dead those that fail MaxAttempts times. Delivery is at least once,
+// as a message delivered just before a crash is delivered again
+type Dispatcher struct {
+	Outbox  *Outbox
+	Deliver func(context.Context, OutboxMessage) error
+
+	MaxAttempts int           // attempts before a message is dead, 5 if not set
+	Backoff     time.Duration // delay after the first failure, doubled for each after, 1s if not set
+	MaxBackoff  time.Duration // the longest delay, 1h if not set
+	Interval    time.Duration // how often Run checks for messages, 1s if not set
+	BatchSize   int           // messages read per check, 100 if not set
+
+	// OnDead, if set, is called when a message is set aside
+	OnDead func(OutboxMessage, error)
+
+	now func() time.Time
+}
+
+func (d *Dispatcher) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+// backoff returns the delay before the next attempt of a message that has failed attempts times
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay, max := d.Backoff, d.MaxBackoff
+	if delay <= 0 {
+		delay = time.Second
+	}
+	if max <= 0 {
+		max = time.Hour
+	}
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// DispatchOnce attempts delivery of the messages that are due, returning how many were delivered
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	limit := d.BatchSize
+	if limit <= 0 {
+		limit = 100
+	}
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	o := d.Outbox
+	list, err := o.messages(OutboxPending, d.clock(), limit)
+	if err != nil {
+		return 0, err
+	}
+	name := quoteIdent(o.table)
+	delivered := 0
+	for _, m := range list {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+		derr := d.Deliver(ctx, m)
+		if derr == nil {
+			q := fmt.Sprintf("UPDATE %s SET status=?, attempts=attempts+1, last_error='' WHERE id=?", name)
+			if _, err := o.db.Exec(q, OutboxDelivered, m.ID); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+		// a delivery cut short by ctx isn't the message's failure
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+		m.Attempts++
+		m.LastError = derr.Error()
+		status := OutboxPending
+		if m.Attempts >= maxAttempts {
+			status = OutboxDead
+		}
+		next := d.clock().Add(d.backoff(m.Attempts)).UnixNano()
+		q := fmt.Sprintf("UPDATE %s SET status=?, attempts=?, next_try=?, last_error=? WHERE id=?", name)
+		if _, err := o.db.Exec(q, status, m.Attempts, next, m.LastError, m.ID); err != nil {
+			return delivered, err
+		}
+		if status == OutboxDead && d.OnDead != nil {
+			d.OnDead(m, derr)
+		}
+	}
+	return delivered, nil
+}
+
+// Run dispatches messages every Interval until ctx is done. If the database is
+// busy or locked the messages are tried again at the next interval
+func (d *Dispatcher) Run(ctx context.Context) error {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchOnce(ctx); err != nil && !IsBusy(err) {
+			return ContextError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
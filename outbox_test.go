@@ -0,0 +1,197 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDispatcher(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	outbox, err := NewOutbox(db, "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.EnqueueTx(tx, "orders", []byte("good")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.EnqueueTx(tx, "orders", []byte("poison")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	var got []string
+	var dead []OutboxMessage
+	d := &Dispatcher{
+		Outbox: outbox,
+		Deliver: func(ctx context.Context, m OutboxMessage) error {
+			got = append(got, string(m.Payload))
+			if string(m.Payload) == "poison" {
+				return errors.New("rejected")
+			}
+			return nil
+		},
+		MaxAttempts: 3,
+		Backoff:     time.Minute,
+		OnDead: func(m OutboxMessage, err error) {
+			dead = append(dead, m)
+		},
+		now: func() time.Time { return now },
+	}
+	ctx := context.Background()
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("expected 1 delivered but got: %d (%v)", n, err)
+	}
+	// the failed message waits for its backoff
+	if n, err := d.DispatchOnce(ctx); err != nil || n != 0 || len(got) != 2 {
+		t.Fatalf("unexpected retry: %d %q (%v)", n, got, err)
+	}
+	now = now.Add(time.Minute)
+	d.DispatchOnce(ctx)
+	now = now.Add(time.Minute) // the second backoff is 2m
+	d.DispatchOnce(ctx)
+	if len(got) != 3 {
+		t.Fatalf("unexpected deliveries: %q", got)
+	}
+	now = now.Add(time.Minute)
+	d.DispatchOnce(ctx)
+	if len(got) != 4 || len(dead) != 1 || dead[0].Attempts != 3 {
+		t.Fatalf("expected dead message: %q %+v", got, dead)
+	}
+	now = now.Add(time.Hour)
+	if d.DispatchOnce(ctx); len(got) != 4 {
+		t.Fatalf("dead message was retried: %q", got)
+	}
+
+	list, err := outbox.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].LastError != "rejected" || string(list[0].Payload) != "poison" {
+		t.Fatalf("unexpected dead messages: %+v", list)
+	}
+	if err := outbox.Requeue(list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Requeue(list[0].ID); err == nil {
+		t.Fatal("expected error requeueing a pending message")
+	}
+	d.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if d.DispatchOnce(ctx); len(got) != 5 {
+		t.Fatalf("requeued message not retried: %q", got)
+	}
+
+	if n, err := outbox.Purge(time.Now()); err != nil || n != 1 {
+		t.Fatalf("expected 1 purged but got: %d (%v)", n, err)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempts, expected := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if got := d.backoff(attempts); got != expected {
+			t.Errorf("attempt %d expected: %v but got: %v", attempts, expected, got)
+		}
+	}
+}
+
+func TestDispatcherCanceled(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	outbox, err := NewOutbox(db, "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.Enqueue("orders", []byte("slow")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		Outbox: outbox,
+		Deliver: func(ctx context.Context, m OutboxMessage) error {
+			cancel()
+			return ctx.Err()
+		},
+	}
+	if _, err := d.DispatchOnce(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the dispatch to be canceled: %v", err)
+	}
+	list, err := outbox.messages(OutboxPending, time.Time{}, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Attempts != 0 {
+		t.Fatalf("expected the message to be pending without an attempt: %+v", list)
+	}
+}
+
+func TestDispatcherBusy(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "outbox.db")
+	db, err := Open(file + "?_busy_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	outbox, err := NewOutbox(db, "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.Enqueue("orders", []byte("order")); err != nil {
+		t.Fatal(err)
+	}
+
+	// another process holds the database for a while
+	holder, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	conn, err := holder.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(context.Background(), "BEGIN EXCLUSIVE"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	delivered := make(chan struct{})
+	d := &Dispatcher{
+		Outbox:   outbox,
+		Interval: 10 * time.Millisecond,
+		Deliver: func(ctx context.Context, m OutboxMessage) error {
+			close(delivered)
+			return nil
+		},
+	}
+	done := make(chan error, 1)
+	go func() { done <- d.Run(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-delivered:
+	case err := <-done:
+		t.Fatalf("expected Run to wait out the lock: %v", err)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
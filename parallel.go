@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// ParallelScan calls fn for every row of table, splitting the table into rowid ranges
+// that are read concurrently by up to workers separate connections
+//
+// The columns passed to fn start with "rowid", and fn must be safe to call from multiple
+// goroutines. Scanning stops at the first error. In-memory databases are private to
+// each connection, so they are always scanned by a single worker
+func ParallelScan(db *sql.DB, table string, workers int, fn func(columns []string, row []interface{}) error) error {
+	var lo, hi sql.NullInt64
+	if err := row(db, []interface{}{&lo, &hi}, fmt.Sprintf("SELECT min(rowid), max(rowid) FROM %s", quoteIdent(table))); err != nil {
+		return err
+	}
+	if !lo.Valid {
+		return nil // empty table
+	}
+	file, err := filename(db)
+	if err != nil {
+		return err
+	}
+	if workers < 1 || file == "" {
+		workers = 1
+	}
+	span := (hi.Int64 - lo.Int64) / int64(workers)
+	if span < 1 {
+		span = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var scanErr error
+	q := fmt.Sprintf("SELECT rowid, * FROM %s WHERE rowid BETWEEN ? AND ?", quoteIdent(table))
+	for start := lo.Int64; start <= hi.Int64; start += span {
+		end := start + span - 1
+		if end >= hi.Int64-span/2 || workers == 1 {
+			end = hi.Int64 // the last range takes the remainder
+		}
+		wg.Add(1)
+		go func(start, end int64) {
+			defer wg.Done()
+			if err := scanRange(ctx, db, q, start, end, fn); err != nil {
+				once.Do(func() {
+					scanErr = err
+					cancel()
+				})
+			}
+		}(start, end)
+		if end == hi.Int64 {
+			break
+		}
+	}
+	wg.Wait()
+	return scanErr
+}
+
+// scanRange reads one partition of a ParallelScan on its own connection
+func scanRange(ctx context.Context, db *sql.DB, q string, start, end int64, fn func([]string, []interface{}) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, q, start, end)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		dest := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range dest {
+			ptrs[i] = &dest[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		if err := fn(columns, dest); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
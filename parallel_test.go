@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelScan(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "parallel.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const setup = `
+	create table numbers (n integer);
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 1000)
+	insert into numbers select n from seq;
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 3, 8, 2000} {
+		var count, sum int64
+		err := ParallelScan(db, "numbers", workers, func(columns []string, row []interface{}) error {
+			if columns[0] != "rowid" {
+				return fmt.Errorf("unexpected columns: %q", columns)
+			}
+			atomic.AddInt64(&count, 1)
+			atomic.AddInt64(&sum, row[1].(int64))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1000 || sum != 500500 {
+			t.Fatalf("%d workers: expected 1000 rows summing to 500500 but got %d rows summing to %d", workers, count, sum)
+		}
+	}
+
+	err = ParallelScan(db, "numbers", 4, func(_ []string, row []interface{}) error {
+		if row[1].(int64) == 500 {
+			return os.ErrInvalid
+		}
+		return nil
+	})
+	if err != os.ErrInvalid {
+		t.Fatalf("expected callback error but got: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package sqlite
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// Statement is a single SQL statement or dot command parsed from a script
+type Statement struct {
+	Text    string // the statement without comments or a trailing semicolon
+	Line    int    // the line in the script where the statement starts
+	Command bool   // true if this is a dot command, e.g. ".read FILENAME"
+}
+
+// ParseScript splits a script into statements and dot commands, the way the sqlite3 shell reads its input
+//
+// Comments are removed, string literals and quoted identifiers are preserved intact,
+// and trigger bodies are kept together until their closing "END;".
+func ParseScript(script string) ([]Statement, error) {
+	var list []Statement
+	var sb strings.Builder
+	line, start := 1, 0
+	blank := true // nothing but whitespace and comments since the last statement
+
+	emit := func() {
+		text := strings.TrimSpace(sb.String())
+		text = strings.TrimSpace(strings.TrimSuffix(text, ";"))
+		if text != "" {
+			list = append(list, Statement{Text: text, Line: start})
+		}
+		sb.Reset()
+		blank = true
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		if blank {
+			start = line
+		}
+		switch {
+		case c == '\n':
+			line++
+			sb.WriteByte(c)
+		case c == '.' && blank:
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			text := strings.TrimSpace(script[i : i+end])
+			list = append(list, Statement{Text: text, Line: line, Command: true})
+			sb.Reset()
+			i += end - 1
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end - 1
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				return list, fmt.Errorf("unterminated comment at line %d", line)
+			}
+			line += strings.Count(script[i:i+2+end], "\n")
+			sb.WriteByte(' ')
+			i += end + 3
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			n, err := quoted(script[i:])
+			if err != nil {
+				return list, fmt.Errorf("%w at line %d", err, line)
+			}
+			line += strings.Count(script[i:i+n], "\n")
+			sb.WriteString(script[i : i+n])
+			i += n - 1
+			blank = false
+		case c == ';':
+			sb.WriteByte(c)
+			if !isTrigger(sb.String()) || triggerDone(sb.String()) {
+				emit()
+			}
+		default:
+			sb.WriteByte(c)
+			if !unicode.IsSpace(rune(c)) {
+				blank = false
+			}
+		}
+	}
+	emit()
+	return list, nil
+}
+
+// quoted returns the length of the quoted string or identifier at the start of s
+func quoted(s string) (int, error) {
+	closer := s[0]
+	if closer == '[' {
+		closer = ']'
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] == closer {
+			// a doubled quote is an escaped quote
+			if closer != ']' && i+1 < len(s) && s[i+1] == closer {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quote %q", s[0])
+}
+
+// isTrigger reports whether the statement creates a trigger
+func isTrigger(stmt string) bool {
+	words := strings.Fields(strings.ToUpper(stmt))
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TEMP" || words[1] == "TEMPORARY" {
+		words = words[1:]
+	}
+	return len(words) > 1 && words[1] == "TRIGGER"
+}
+
+// triggerDone reports whether a trigger statement ends with "; END;"
+func triggerDone(stmt string) bool {
+	stmt = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(stmt), ";"))
+	if len(stmt) < 3 || !strings.EqualFold(stmt[len(stmt)-3:], "END") {
+		return false
+	}
+	return strings.HasSuffix(strings.TrimSpace(stmt[:len(stmt)-3]), ";")
+}
+
+// Normalize returns the statement with comments removed, whitespace outside
+// of quotes collapsed, and any trailing semicolon dropped
+func Normalize(stmt string) string {
+	var sb strings.Builder
+	space := false
+	literal := 0 // the end of the last quoted text, which must be kept intact
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		switch {
+		case c == '-' && strings.HasPrefix(stmt[i:], "--"):
+			if end := strings.IndexByte(stmt[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(stmt)
+			}
+			space = true
+		case c == '/' && strings.HasPrefix(stmt[i:], "/*"):
+			if end := strings.Index(stmt[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(stmt)
+			}
+			space = true
+		case unicode.IsSpace(rune(c)):
+			space = true
+		default:
+			if space && sb.Len() > 0 {
+				sb.WriteByte(' ')
+			}
+			space = false
+			if c == '\'' || c == '"' || c == '`' || c == '[' {
+				n, err := quoted(stmt[i:])
+				if err != nil {
+					n = len(stmt) - i
+				}
+				sb.WriteString(stmt[i : i+n])
+				literal = sb.Len()
+				i += n - 1
+				continue
+			}
+			sb.WriteByte(c)
+		}
+	}
+	text := sb.String()
+	return text[:literal] + strings.TrimRight(text[literal:], "; ")
+}
+
+// DSN is a parsed SQLite data source name
+type DSN struct {
+	Filename string     // the database file, without any "file:" prefix or parameters
+	Memory   bool       // true for in-memory databases
+	Params   url.Values // parameters following the "?"
+}
+
+// ParseDSN parses a filename or URI style data source name as accepted by Open
+func ParseDSN(dsn string) (DSN, error) {
+	var d DSN
+	filename := strings.TrimPrefix(dsn, "file:")
+	filename = strings.TrimPrefix(filename, "//")
+	if i := strings.Index(filename, "?"); i >= 0 {
+		params, err := url.ParseQuery(filename[i+1:])
+		if err != nil {
+			return d, fmt.Errorf("invalid dsn parameters: %w", err)
+		}
+		d.Params = params
+		filename = filename[:i]
+	}
+	d.Filename = filename
+	d.Memory = strings.Contains(dsn, ":memory:") || d.Params.Get("mode") == "memory"
+	return d, nil
+}
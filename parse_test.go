@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+const parseScript = `.echo on
+-- a comment; with a semicolon
+create table t (id integer, name text default 'a;b'); /* block ; comment */
+insert into t values(1, 'it''s');
+CREATE TRIGGER t_insert AFTER INSERT ON t
+BEGIN
+    update t set name = case when NEW.id > 1 then 'big' else 'small' end;
+END;
+.read other.sql
+select * from t`
+
+func TestParseScript(t *testing.T) {
+	stmts, err := ParseScript(parseScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Statement{
+		{Text: ".echo on", Line: 1, Command: true},
+		{Text: "create table t (id integer, name text default 'a;b')", Line: 3},
+		{Text: "insert into t values(1, 'it''s')", Line: 4},
+		{Text: "CREATE TRIGGER t_insert AFTER INSERT ON t\nBEGIN\n    update t set name = case when NEW.id > 1 then 'big' else 'small' end;\nEND", Line: 5},
+		{Text: ".read other.sql", Line: 9, Command: true},
+		{Text: "select * from t", Line: 10},
+	}
+	if len(stmts) != len(expected) {
+		t.Fatalf("expected %d statements but got %d: %+v", len(expected), len(stmts), stmts)
+	}
+	for i, stmt := range stmts {
+		if stmt != expected[i] {
+			t.Errorf("statement %d expected: %+v but got: %+v", i, expected[i], stmt)
+		}
+	}
+}
+
+func TestParseScriptUnterminated(t *testing.T) {
+	if _, err := ParseScript("select 'abc;"); err == nil {
+		t.Fatal("expected error for unterminated string")
+	}
+	if _, err := ParseScript("select 1; /* abc"); err == nil {
+		t.Fatal("expected error for unterminated comment")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	const (
+		stmt     = "  select  a,\n\tb -- comment\n from /* x */ t where c = 'x  y';  "
+		expected = "select a, b from t where c = 'x  y'"
+	)
+	if got := Normalize(stmt); got != expected {
+		t.Fatalf("expected: %q but got: %q", expected, got)
+	}
+}
+
+func TestParseDSN(t *testing.T) {
+	dsn, err := ParseDSN("file:/tmp/test.db?cache=shared&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dsn.Filename != "/tmp/test.db" || dsn.Memory {
+		t.Fatalf("unexpected dsn: %+v", dsn)
+	}
+	if dsn.Params.Get("_busy_timeout") != "5000" {
+		t.Fatalf("unexpected params: %v", dsn.Params)
+	}
+
+	dsn, err = ParseDSN("file:shared?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dsn.Memory {
+		t.Fatalf("expected memory dsn: %+v", dsn)
+	}
+
+	if _, err := ParseDSN("test.db?a=%zz"); err == nil {
+		t.Fatal("expected error for bad parameters")
+	}
+}
+
+func FuzzParseScript(f *testing.F) {
+	f.Add(parseScript)
+	f.Add("select 'a''b'; .tables\n/* x */")
+	f.Fuzz(func(t *testing.T, script string) {
+		stmts, err := ParseScript(script)
+		if err != nil {
+			return
+		}
+		for _, stmt := range stmts {
+			if stmt.Line < 1 {
+				t.Fatalf("invalid line number for: %+v", stmt)
+			}
+		}
+	})
+}
+
+func FuzzNormalize(f *testing.F) {
+	f.Add("select  1 -- one\n;")
+	f.Fuzz(func(t *testing.T, stmt string) {
+		once := Normalize(stmt)
+		if twice := Normalize(once); twice != once {
+			t.Fatalf("not idempotent: %q -> %q", once, twice)
+		}
+	})
+}
+
+func FuzzParseDSN(f *testing.F) {
+	f.Add("file:test.db?cache=shared")
+	f.Add(":memory:")
+	f.Fuzz(func(t *testing.T, dsn string) {
+		_, _ = ParseDSN(dsn)
+	})
+}
@@ -0,0 +1,149 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// partialSchema is the subset of objects of a backup without the excluded tables,
+// with the tables split from the objects created after their rows are copied
+type partialSchema struct {
+	tables []SchemaObject
+	others []SchemaObject
+}
+
+// excludeObjects returns the objects to create without the excluded tables and what depends on them
+func excludeObjects(objects []SchemaObject, exclude []string) (partialSchema, error) {
+	var schema partialSchema
+	skip := make(map[string]bool)
+	for _, name := range exclude {
+		found := false
+		for _, obj := range objects {
+			if obj.Kind == "table" && strings.EqualFold(obj.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return schema, fmt.Errorf("no such table: %s", name)
+		}
+		skip[strings.ToLower(name)] = true
+		for _, obj := range dependents(objects, name) {
+			skip[strings.ToLower(obj.Name)] = true
+		}
+	}
+	for _, obj := range objects {
+		if skip[strings.ToLower(obj.Name)] || (obj.Kind == "index" && skip[strings.ToLower(obj.Table)]) {
+			continue
+		}
+		if obj.Kind == "table" {
+			schema.tables = append(schema.tables, obj)
+		} else {
+			schema.others = append(schema.others, obj)
+		}
+	}
+	return schema, nil
+}
+
+// partialBackup creates the schema of db in destDb, without the excluded tables,
+// and copies the rows of the other tables by attaching dest to a connection of db,
+// in one read transaction so the copy is consistent
+func partialBackup(ctx context.Context, db, destDb *sql.DB, dest string, opts BackupOptions) error {
+	objects, err := Schema(db)
+	if err != nil {
+		return err
+	}
+	schema, err := excludeObjects(objects, opts.Exclude)
+	if err != nil {
+		return err
+	}
+	for _, obj := range schema.tables {
+		if _, err := destDb.ExecContext(ctx, obj.SQL); err != nil {
+			return fmt.Errorf("create %s: %w", obj.Name, err)
+		}
+	}
+	const alias = "backup_dest"
+	err = rawConn(ctx, db, func(conn *sqlite3.SQLiteConn) (err error) {
+		if _, err := conn.Exec("ATTACH DATABASE ? AS "+alias, []driver.Value{dest}); err != nil {
+			return err
+		}
+		defer func() {
+			if _, derr := conn.Exec("DETACH DATABASE "+alias, nil); err == nil {
+				err = derr
+			}
+		}()
+		if _, err := conn.Exec("BEGIN", nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				conn.Exec("ROLLBACK", nil)
+			}
+		}()
+		for i, obj := range schema.tables {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			table := quoteIdent(obj.Name)
+			q := fmt.Sprintf("INSERT INTO %s.%s SELECT * FROM main.%s", alias, table, table)
+			if _, err := conn.ExecContext(ctx, q, nil); err != nil {
+				return fmt.Errorf("copy %s: %w", obj.Name, err)
+			}
+			if opts.Progress != nil {
+				opts.Progress(len(schema.tables), len(schema.tables)-i-1)
+			}
+			if opts.Reporter != nil {
+				opts.Reporter.OnProgress(int64(i+1), int64(len(schema.tables)))
+			}
+		}
+		if err := copySequences(conn, alias, schema.tables); err != nil {
+			return err
+		}
+		for _, pragma := range []string{"user_version", "application_id"} {
+			n, err := pragmaInt(conn, pragma)
+			if err != nil {
+				return err
+			}
+			if _, err := conn.Exec(fmt.Sprintf("PRAGMA %s.%s = %d", alias, pragma, n), nil); err != nil {
+				return err
+			}
+		}
+		_, err = conn.Exec("COMMIT", nil)
+		return err
+	})
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	for _, obj := range schema.others {
+		if _, err := destDb.ExecContext(ctx, obj.SQL); err != nil {
+			return fmt.Errorf("create %s: %w", obj.Name, err)
+		}
+	}
+	return nil
+}
+
+// copySequences copies the AUTOINCREMENT counters of the copied tables
+func copySequences(conn *sqlite3.SQLiteConn, alias string, tables []SchemaObject) error {
+	var exists bool
+	for _, obj := range tables {
+		if strings.Contains(strings.ToUpper(obj.SQL), "AUTOINCREMENT") {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		return nil
+	}
+	names := make([]string, len(tables))
+	for i, obj := range tables {
+		names[i] = quoteString(obj.Name)
+	}
+	q := fmt.Sprintf("INSERT INTO %s.sqlite_sequence SELECT * FROM main.sqlite_sequence WHERE name IN (%s)", alias, strings.Join(names, ", "))
+	_, err := conn.Exec(q, nil)
+	return err
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPartialBackup(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "partial.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	const schema = `
+	create table users (id integer primary key autoincrement, name text);
+	create index users_name on users (name);
+	create table sessions (id integer primary key, user_id int references users(id), token text);
+	create index sessions_token on sessions (token);
+	create view active as select name from users join sessions on users.id = sessions.user_id;
+	create view everyone as select name from users;
+	create trigger sessions_log after insert on sessions begin select 1; end;
+	insert into users (name) values ('ann'), ('bob');
+	insert into sessions (user_id, token) values (1, 'x'), (2, 'y');
+	pragma user_version = 7;
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var steps int
+	dest := filepath.Join(dir, "backup.db")
+	opts := BackupOptions{
+		Exclude:  []string{"sessions"},
+		Progress: func(count, remaining int) { steps++ },
+	}
+	if err := BackupWithProgress(db, dest, opts); err != nil {
+		t.Fatal(err)
+	}
+	if steps != 1 {
+		t.Fatalf("expected progress for one table, got %d", steps)
+	}
+	copied, err := Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+
+	objects, err := Schema(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, obj := range objects {
+		names = append(names, obj.Name)
+	}
+	if len(names) != 3 || names[0] != "users" || names[1] != "everyone" || names[2] != "users_name" {
+		t.Fatalf("unexpected objects: %v", names)
+	}
+	var count, seq, version int
+	if err := row(copied, []interface{}{&count}, "select count(*) from everyone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := row(copied, []interface{}{&seq}, "select seq from sqlite_sequence where name = 'users'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := row(copied, []interface{}{&version}, "pragma user_version"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || seq != 2 || version != 7 {
+		t.Fatalf("unexpected copy: %d rows, sequence %d, version %d", count, seq, version)
+	}
+
+	opts.Exclude = []string{"nonesuch"}
+	if err := BackupWithProgress(db, dest, opts); err == nil {
+		t.Fatal("expected error for unknown table")
+	}
+}
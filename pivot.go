@@ -0,0 +1,206 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// functionCall matches an expression that may be a single call, such as "sum(amount)"
+var functionCall = regexp.MustCompile(`(?is)^\s*(\w+)\s*\((.*)\)\s*$`)
+
+// builtinAggregates are the aggregate functions of SQLite itself
+var builtinAggregates = []string{"count", "sum", "total", "avg", "min", "max", "group_concat"}
+
+// aggregateNames returns the names of the aggregates of the database, in lower case
+func aggregateNames(db *sql.DB) map[string]bool {
+	names := make(map[string]bool)
+	for _, name := range builtinAggregates {
+		names[name] = true
+	}
+	if config := configFor(db); config != nil {
+		for _, agg := range config.aggregates {
+			names[strings.ToLower(agg.Name)] = true
+		}
+	}
+	return names
+}
+
+// splitArgs splits the arguments of a call at their top level commas, reporting
+// false if its parentheses don't balance, as for the "a) + sum(b" of "sum(a) + sum(b)"
+func splitArgs(s string) ([]string, bool) {
+	var args []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth--; depth < 0 {
+				return nil, false
+			}
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if depth != 0 || quote != 0 {
+		return nil, false
+	}
+	return append(args, strings.TrimSpace(s[start:])), true
+}
+
+// aggregateOf returns the aggregate, its argument, and any arguments after it of
+// an expression that is a single call of an aggregate, such as "sum(amount)" or
+// "group_concat(name, ';')" or "count(DISTINCT name)"
+func aggregateOf(expr string, aggregates map[string]bool) (agg, value, rest string, ok bool) {
+	m := functionCall.FindStringSubmatch(expr)
+	if m == nil || !aggregates[strings.ToLower(m[1])] {
+		return "", "", "", false
+	}
+	args, ok := splitArgs(m[2])
+	if !ok || len(args) == 0 {
+		return "", "", "", false
+	}
+	// min and max of several arguments are scalar functions
+	if name := strings.ToLower(m[1]); len(args) > 1 && (name == "min" || name == "max") {
+		return "", "", "", false
+	}
+	agg, value = m[1], args[0]
+	if value == "*" {
+		value = "1"
+	}
+	if len(value) > 9 && strings.EqualFold(value[:9], "DISTINCT ") {
+		agg, value = agg+"(DISTINCT ", strings.TrimSpace(value[9:])
+	} else {
+		agg += "("
+	}
+	for _, arg := range args[1:] {
+		rest += ", " + arg
+	}
+	return agg, value, rest, true
+}
+
+// hasAggregate reports whether an expression calls any of the aggregates
+func hasAggregate(expr string, aggregates map[string]bool) bool {
+	for _, m := range aggregateName.FindAllStringSubmatch(expr, -1) {
+		if aggregates[strings.ToLower(m[1])] {
+			return true
+		}
+	}
+	return false
+}
+
+var aggregateName = regexp.MustCompile(`(\w+)\s*\(`)
+
+// PivotTable is the result of a Pivot query
+type PivotTable struct {
+	Columns []string        // the row key followed by each distinct column key
+	Rows    [][]interface{} // the values of each row, in column order
+}
+
+// Pivot cross-tabulates the results of baseQuery, with a row for each distinct rowKey
+// and a column for each distinct colKey
+//
+// The valueExpr is an aggregate such as "sum(amount)" or "count(*)", which is computed
+// separately for each column. An expression that isn't an aggregate uses max(), and one
+// that combines aggregates, such as "sum(amount) * 2", is computed for each row and column
+func Pivot(db *sql.DB, baseQuery, rowKey, colKey, valueExpr string, args ...interface{}) (*PivotTable, error) {
+	base := strings.TrimRight(strings.TrimSpace(baseQuery), ";")
+
+	var keys []interface{}
+	fn := func(_ []string, row []interface{}) {
+		keys = append(keys, pivotKey(row[0]))
+	}
+	q := fmt.Sprintf("SELECT DISTINCT %s FROM (%s) ORDER BY 1", colKey, base)
+	if err := query(db, fn, q, args...); err != nil {
+		return nil, fmt.Errorf("pivot keys: %w", err)
+	}
+
+	aggregates := aggregateNames(db)
+	agg, value, rest, ok := aggregateOf(valueExpr, aggregates)
+	if !ok {
+		if hasAggregate(valueExpr, aggregates) {
+			return pivotGrouped(db, base, rowKey, colKey, valueExpr, keys, args)
+		}
+		agg, value = "max(", valueExpr
+	}
+
+	table := &PivotTable{Columns: []string{rowKey}}
+	exprs := []string{rowKey}
+	params := append([]interface{}{}, args...)
+	for _, key := range keys {
+		name := "NULL"
+		test := "IS NULL"
+		if key != nil {
+			name = fmt.Sprint(key)
+			test = "= ?"
+			params = append(params, key)
+		}
+		table.Columns = append(table.Columns, name)
+		exprs = append(exprs, fmt.Sprintf("%sCASE WHEN %s %s THEN %s END%s) AS %s", agg, colKey, test, value, rest, quoteIdent(name)))
+	}
+
+	// the base query comes first, so its parameters precede the column keys
+	q = fmt.Sprintf("WITH pivot_base AS (%s) SELECT %s FROM pivot_base GROUP BY %s ORDER BY %[3]s",
+		base, strings.Join(exprs, ", "), rowKey)
+	fn = func(_ []string, row []interface{}) {
+		table.Rows = append(table.Rows, append([]interface{}{}, row...))
+	}
+	if err := query(db, fn, q, params...); err != nil {
+		return nil, fmt.Errorf("pivot: %w", err)
+	}
+	return table, nil
+}
+
+// pivotKey returns a column key as it is compared and named
+func pivotKey(key interface{}) interface{} {
+	if b, ok := key.([]byte); ok {
+		return string(b)
+	}
+	return key
+}
+
+// pivotGrouped computes an expression of aggregates for each row and column key,
+// and arranges the results in Go, as the expression can't be split by column
+func pivotGrouped(db *sql.DB, base, rowKey, colKey, valueExpr string, keys, args []interface{}) (*PivotTable, error) {
+	table := &PivotTable{Columns: []string{rowKey}}
+	columns := make(map[interface{}]int, len(keys))
+	for i, key := range keys {
+		columns[key] = i + 1
+		name := "NULL"
+		if key != nil {
+			name = fmt.Sprint(key)
+		}
+		table.Columns = append(table.Columns, name)
+	}
+	q := fmt.Sprintf("WITH pivot_base AS (%s) SELECT %s, %s, %s FROM pivot_base GROUP BY 1, 2 ORDER BY 1",
+		base, rowKey, colKey, valueExpr)
+	var last interface{}
+	fn := func(_ []string, row []interface{}) {
+		key := pivotKey(row[0])
+		if len(table.Rows) == 0 || !reflect.DeepEqual(key, last) {
+			values := make([]interface{}, len(table.Columns))
+			values[0] = row[0]
+			table.Rows = append(table.Rows, values)
+			last = key
+		}
+		if i, ok := columns[pivotKey(row[1])]; ok {
+			table.Rows[len(table.Rows)-1][i] = row[2]
+		}
+	}
+	if err := query(db, fn, q, args...); err != nil {
+		return nil, fmt.Errorf("pivot: %w", err)
+	}
+	return table, nil
+}
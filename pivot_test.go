@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPivot(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	const setup = `
+	create table sales (region text, quarter text, amount integer);
+	insert into sales values
+		('east', 'Q1', 100), ('east', 'Q2', 150), ('east', 'Q2', 50),
+		('west', 'Q1', 80), ('west', 'Q3', 20), ('north', 'Q1', 5);
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	const base = "select region, quarter, amount from sales where amount > ?"
+	table, err := Pivot(db, base, "region", "quarter", "sum(amount)", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := []string{"region", "Q1", "Q2", "Q3"}
+	if !reflect.DeepEqual(table.Columns, columns) {
+		t.Fatalf("expected columns: %q but got: %q", columns, table.Columns)
+	}
+	expected := [][]interface{}{
+		{"east", int64(100), int64(200), nil},
+		{"west", int64(80), nil, int64(20)},
+	}
+	if !reflect.DeepEqual(table.Rows, expected) {
+		t.Fatalf("expected rows: %v but got: %v", expected, table.Rows)
+	}
+
+	table, err = Pivot(db, "select * from sales", "region", "quarter", "count(*)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 3 || table.Rows[0][2] != int64(2) {
+		t.Fatalf("unexpected counts: %v", table.Rows)
+	}
+
+	// an expression that isn't an aggregate uses max()
+	table, err = Pivot(db, "select region, quarter, amount from sales where region = 'east'", "region", "quarter", "abs(amount)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][]interface{}{{"east", int64(100), int64(150)}}; !reflect.DeepEqual(table.Rows, expected) {
+		t.Fatalf("expected rows: %v but got: %v", expected, table.Rows)
+	}
+
+	// expressions of aggregates are computed for each row and column
+	table, err = Pivot(db, base, "region", "quarter", "sum(amount) * 2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = [][]interface{}{
+		{"east", int64(200), int64(400), nil},
+		{"west", int64(160), nil, int64(40)},
+	}
+	if !reflect.DeepEqual(table.Columns, columns) || !reflect.DeepEqual(table.Rows, expected) {
+		t.Fatalf("expected rows: %v but got: %q %v", expected, table.Columns, table.Rows)
+	}
+
+	// arguments after the first, and DISTINCT, are kept
+	table, err = Pivot(db, "select * from sales where region = 'east'", "region", "quarter", "group_concat(amount, '+')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Rows[0][1] != "100" || table.Rows[0][2] != "150+50" {
+		t.Fatalf("unexpected group_concat: %v", table.Rows)
+	}
+	table, err = Pivot(db, "select * from sales", "quarter", "region", "count(DISTINCT amount > 60)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Rows[0][3] != int64(1) || table.Rows[1][1] != int64(2) {
+		t.Fatalf("unexpected distinct counts: %q %v", table.Columns, table.Rows)
+	}
+}
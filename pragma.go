@@ -0,0 +1,283 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// pragmaName matches a pragma name, optionally qualified by a schema, e.g. "main.journal_mode"
+	pragmaName = regexp.MustCompile(`^(?:[A-Za-z_][A-Za-z0-9_]*\.)?[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// pragmaWord matches a value that can be given to a pragma unquoted, e.g. WAL, -2000, or 1
+	pragmaWord = regexp.MustCompile(`^[A-Za-z0-9_+-]+$`)
+)
+
+// GetPragma returns the value of a pragma, e.g. "journal_mode". Many pragmas are settings
+// of the connection rather than the database, and the value returned is that of
+// whichever connection of the pool ran the query
+func GetPragma(db *sql.DB, name string) (string, error) {
+	if !pragmaName.MatchString(name) {
+		return "", fmt.Errorf("invalid pragma name: %q", name)
+	}
+	var value sql.NullString
+	if err := db.QueryRow("PRAGMA " + name).Scan(&value); err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("pragma %s has no value", name)
+		}
+		return "", fmt.Errorf("pragma %s: %w", name, err)
+	}
+	return value.String, nil
+}
+
+// SetPragma sets a pragma, quoting the value if it is not a plain word or number.
+// A setting of the connection applies only to the connection of the pool that ran it,
+// so to apply one to every connection use WithPragmas when opening the database instead
+func SetPragma(db *sql.DB, name, value string) error {
+	if !pragmaName.MatchString(name) {
+		return fmt.Errorf("invalid pragma name: %q", name)
+	}
+	var known bool
+	bare := name
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		bare = name[i+1:]
+	}
+	if err := db.QueryRow("SELECT count(*) > 0 FROM pragma_pragma_list WHERE name = ?", bare).Scan(&known); err != nil {
+		return err
+	}
+	if !known {
+		return fmt.Errorf("unknown pragma: %s", name)
+	}
+	if !pragmaWord.MatchString(value) {
+		value = quoteString(value)
+	}
+	// some pragmas report the new value, which must be read for the statement to complete
+	rows, err := db.Query(fmt.Sprintf("PRAGMA %s = %s", name, value))
+	if err != nil {
+		return fmt.Errorf("pragma %s: %w", name, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// AllPragmas returns the values of the pragmas listed by Pragmas, read from a single connection
+func AllPragmas(db *sql.DB) (map[string]string, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return pragmaValues(ctx, conn), nil
+}
+
+// pragmaValues reads the values of the pragmas listed by Pragmas,
+// leaving out compile_options and any the library doesn't support
+func pragmaValues(ctx context.Context, conn *sql.Conn) map[string]string {
+	values := make(map[string]string)
+	for _, pragma := range pragmas {
+		if pragma == "compile_options" {
+			continue
+		}
+		var value string
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(&value); err == nil {
+			values[pragma] = value
+		}
+	}
+	return values
+}
+
+// PragmaRows returns every row and column of a pragma, with NULL as "".
+// The name may include an argument, e.g. "table_info(t)", and is not quoted
+func PragmaRows(db *sql.DB, name string) ([][]string, error) {
+	rows, err := db.Query("PRAGMA " + name)
+	if err != nil {
+		return nil, fmt.Errorf("pragma %s: %w", name, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var results [][]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result := make([]string, len(columns))
+		for i, v := range values {
+			result[i] = v.String
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// pragmaInts converts the given columns of a pragma row to ints, skipping nil destinations
+func pragmaInts(name string, row []string, dest ...*int) error {
+	for i, d := range dest {
+		if d == nil || row[i] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(row[i])
+		if err != nil {
+			return fmt.Errorf("pragma %s: column %d: %w", name, i, err)
+		}
+		*d = n
+	}
+	return nil
+}
+
+// DatabaseEntry is a row of PRAGMA database_list
+type DatabaseEntry struct {
+	Seq  int
+	Name string
+	File string // empty for memory and temporary databases
+}
+
+// DatabaseList returns the attached databases
+func DatabaseList(db *sql.DB) ([]DatabaseEntry, error) {
+	rows, err := PragmaRows(db, "database_list")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]DatabaseEntry, len(rows))
+	for i, row := range rows {
+		if err := pragmaInts("database_list", row, &list[i].Seq); err != nil {
+			return nil, err
+		}
+		list[i].Name, list[i].File = row[1], row[2]
+	}
+	return list, nil
+}
+
+// CollationList returns the names of the collations of a connection
+func CollationList(db *sql.DB) ([]string, error) {
+	rows, err := PragmaRows(db, "collation_list")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, len(rows))
+	for i, row := range rows {
+		list[i] = row[1]
+	}
+	return list, nil
+}
+
+// ForeignKey is a row of PRAGMA foreign_key_list
+type ForeignKey struct {
+	ID       int
+	Seq      int // the column of a multi-column key
+	Table    string
+	From     string
+	To       string // empty if the key refers to the primary key
+	OnUpdate string
+	OnDelete string
+	Match    string
+}
+
+// ForeignKeyList returns the foreign keys of a table
+func ForeignKeyList(db *sql.DB, table string) ([]ForeignKey, error) {
+	rows, err := PragmaRows(db, fmt.Sprintf("foreign_key_list(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ForeignKey, len(rows))
+	for i, row := range rows {
+		fk := &list[i]
+		if err := pragmaInts("foreign_key_list", row, &fk.ID, &fk.Seq); err != nil {
+			return nil, err
+		}
+		fk.Table, fk.From, fk.To = row[2], row[3], row[4]
+		fk.OnUpdate, fk.OnDelete, fk.Match = row[5], row[6], row[7]
+	}
+	return list, nil
+}
+
+// ForeignKeyViolation is a row of PRAGMA foreign_key_check
+type ForeignKeyViolation struct {
+	Table  string
+	RowID  int64 // 0 for a WITHOUT ROWID table
+	Parent string
+	FKID   int // the ID of the key in ForeignKeyList of the table
+}
+
+// ForeignKeyCheck returns the rows that violate foreign key constraints
+func ForeignKeyCheck(db *sql.DB) ([]ForeignKeyViolation, error) {
+	rows, err := PragmaRows(db, "foreign_key_check")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ForeignKeyViolation, len(rows))
+	for i, row := range rows {
+		v := &list[i]
+		v.Table, v.Parent = row[0], row[2]
+		if row[1] != "" {
+			if v.RowID, err = strconv.ParseInt(row[1], 10, 64); err != nil {
+				return nil, fmt.Errorf("pragma foreign_key_check: %w", err)
+			}
+		}
+		if err := pragmaInts("foreign_key_check", row[3:], &v.FKID); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// QuickCheck returns the problems found by PRAGMA quick_check, or nil if there are none
+func QuickCheck(db *sql.DB) ([]string, error) {
+	rows, err := PragmaRows(db, "quick_check")
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, row := range rows {
+		if row[0] != "ok" {
+			problems = append(problems, row[0])
+		}
+	}
+	return problems, nil
+}
+
+// CheckpointResult is the result of PRAGMA wal_checkpoint
+type CheckpointResult struct {
+	Busy         bool // the checkpoint could not complete
+	Frames       int  // frames in the log, or -1 if not in WAL mode
+	Checkpointed int  // frames checkpointed into the database
+}
+
+// WALCheckpoint runs a checkpoint, where mode is one of "PASSIVE", "FULL", "RESTART", or "TRUNCATE"
+func WALCheckpoint(db *sql.DB, mode string) (CheckpointResult, error) {
+	defer observe(MetricCheckpoint, time.Now())
+	var result CheckpointResult
+	switch mode {
+	case "PASSIVE", "FULL", "RESTART", "TRUNCATE":
+	default:
+		return result, fmt.Errorf("invalid checkpoint mode: %q", mode)
+	}
+	rows, err := PragmaRows(db, fmt.Sprintf("wal_checkpoint(%s)", mode))
+	if err != nil {
+		return result, err
+	}
+	if len(rows) != 1 {
+		return result, fmt.Errorf("pragma wal_checkpoint: expected 1 row but got %d", len(rows))
+	}
+	var busy int
+	if err := pragmaInts("wal_checkpoint", rows[0], &busy, &result.Frames, &result.Checkpointed); err != nil {
+		return result, err
+	}
+	result.Busy = busy != 0
+	return result, nil
+}
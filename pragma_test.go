@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPragmaRows(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pragma.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const setup = `
+	pragma journal_mode=wal;
+	create table parent (id integer primary key);
+	create table child (id integer primary key, parent_id integer references parent(id) on delete cascade);
+	insert into parent values(1);
+	insert into child values(1, 1), (2, 2);
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := PragmaRows(db, "table_info(child)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][1] != "parent_id" || rows[1][4] != "" {
+		t.Fatalf("unexpected table info: %q", rows)
+	}
+
+	list, err := DatabaseList(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "main" || filepath.Base(list[0].File) != "pragma.db" {
+		t.Fatalf("unexpected database list: %+v", list)
+	}
+
+	collations, err := CollationList(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, c := range collations {
+		found = found || c == "NOCASE"
+	}
+	if !found {
+		t.Fatalf("NOCASE not in collations: %q", collations)
+	}
+
+	keys, err := ForeignKeyList(db, "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Table != "parent" || keys[0].From != "parent_id" || keys[0].OnDelete != "CASCADE" {
+		t.Fatalf("unexpected foreign keys: %+v", keys)
+	}
+
+	violations, err := ForeignKeyCheck(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].Table != "child" || violations[0].RowID != 2 || violations[0].Parent != "parent" {
+		t.Fatalf("unexpected violations: %+v", violations)
+	}
+
+	problems, err := QuickCheck(db)
+	if err != nil || problems != nil {
+		t.Fatalf("unexpected quick check: %q (%v)", problems, err)
+	}
+
+	result, err := WALCheckpoint(db, "TRUNCATE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Busy || result.Frames != 0 {
+		t.Fatalf("unexpected checkpoint: %+v", result)
+	}
+	if _, err := WALCheckpoint(db, "NOW"); err == nil {
+		t.Fatal("expected invalid mode error")
+	}
+}
+
+func TestGetSetPragma(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := SetPragma(db, "journal_mode", "wal"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetPragma(db, "main.cache_size", "-4000"); err != nil {
+		t.Fatal(err)
+	}
+	mode, err := GetPragma(db, "journal_mode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Fatalf("expected: %q but got: %q", "wal", mode)
+	}
+	all, err := AllPragmas(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all["cache_size"] != "-4000" || all["journal_mode"] != "wal" {
+		t.Fatalf("unexpected pragmas: %v", all)
+	}
+
+	if err := SetPragma(db, "no_such_pragma", "1"); err == nil {
+		t.Fatal("expected error for unknown pragma")
+	}
+	if _, err := GetPragma(db, "cache_size; drop table x"); err == nil {
+		t.Fatal("expected error for invalid name")
+	}
+	if _, err := GetPragma(db, "no_such_pragma"); err == nil {
+		t.Fatal("expected error for pragma without a value")
+	}
+}
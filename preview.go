@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// PreviewCountLimit is the most rows Preview counts before reporting an inexact total
+var PreviewCountLimit int64 = 100000
+
+// PreviewResult is the first rows of a query and the number of rows it returns
+type PreviewResult struct {
+	Columns []string
+	Rows    [][]interface{}
+	Total   int64 // the rows returned by the query, or a lower bound if not Exact
+	Exact   bool
+}
+
+// readOnlyQuery returns the query if it is a single SELECT, WITH, or VALUES statement
+func readOnlyQuery(q string) (string, error) {
+	stmts, err := ParseScript(q)
+	if err != nil {
+		return "", err
+	}
+	if len(stmts) != 1 || stmts[0].Command {
+		return "", fmt.Errorf("expected a single query but got %d statements", len(stmts))
+	}
+	text := stmts[0].Text
+	fields := strings.Fields(strings.ToUpper(text))
+	switch fields[0] {
+	case "SELECT", "VALUES":
+	case "WITH":
+		// a common table expression can precede a write
+		for _, f := range fields {
+			switch f {
+			case "INSERT", "UPDATE", "DELETE", "REPLACE":
+				return "", fmt.Errorf("query is not read only: %s", text)
+			}
+		}
+	default:
+		return "", fmt.Errorf("query is not a SELECT: %s", text)
+	}
+	return text, nil
+}
+
+// Preview returns the first n rows of a SELECT query and a count of all its rows,
+// which is exact unless there are more than PreviewCountLimit
+func Preview(db *sql.DB, q string, n int, args ...interface{}) (*PreviewResult, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("preview: rows must be positive: %d", n)
+	}
+	base, err := readOnlyQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	result := &PreviewResult{}
+	fn := func(columns []string, row []interface{}) {
+		if columns != nil {
+			result.Columns = columns
+		}
+		values := make([]interface{}, len(row))
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				v = append([]byte(nil), b...)
+			}
+			values[i] = v
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	limited := fmt.Sprintf("SELECT * FROM (%s) LIMIT %d", base, n)
+	if err := query(db, fn, limited, args...); err != nil {
+		return nil, fmt.Errorf("preview: %w", err)
+	}
+	if result.Columns == nil {
+		// no rows, but the columns are still wanted
+		rows, err := db.Query(fmt.Sprintf("SELECT * FROM (%s) LIMIT 0", base), Args(args...)...)
+		if err != nil {
+			return nil, fmt.Errorf("preview: %w", err)
+		}
+		result.Columns, err = getColumns(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if int64(len(result.Rows)) < int64(n) {
+		result.Total, result.Exact = int64(len(result.Rows)), true
+		return result, nil
+	}
+	count := fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM (%s) LIMIT %d)", base, PreviewCountLimit+1)
+	if err := row(db, []interface{}{&result.Total}, count, args...); err != nil {
+		return nil, fmt.Errorf("preview count: %w", err)
+	}
+	result.Exact = result.Total <= PreviewCountLimit
+	if !result.Exact {
+		result.Total = PreviewCountLimit
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package sqlite
+
+import "testing"
+
+func TestPreview(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	const setup = `
+	create table numbers (n integer, name text);
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 250)
+	insert into numbers select n, 'n' || n from seq;
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Preview(db, "select n, name from numbers where n > ? order by n desc;", 10, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 10 || result.Total != 200 || !result.Exact {
+		t.Fatalf("unexpected preview: %d rows of %d (%v)", len(result.Rows), result.Total, result.Exact)
+	}
+	if result.Columns[1] != "name" || result.Rows[0][0].(int64) != 250 || result.Rows[9][0].(int64) != 241 {
+		t.Fatalf("unexpected rows: %v %v", result.Columns, result.Rows)
+	}
+
+	defer func(limit int64) { PreviewCountLimit = limit }(PreviewCountLimit)
+	PreviewCountLimit = 100
+	if result, err = Preview(db, "with big as (select * from numbers) select * from big", 5); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 100 || result.Exact {
+		t.Fatalf("expected inexact total but got: %d (%v)", result.Total, result.Exact)
+	}
+
+	if result, err = Preview(db, "select * from numbers where n < 0", 5); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 0 || result.Total != 0 || len(result.Columns) != 2 {
+		t.Fatalf("unexpected empty preview: %+v", result)
+	}
+
+	for _, bad := range []string{
+		"delete from numbers",
+		"select 1; drop table numbers",
+		"with x as (select 1) delete from numbers",
+	} {
+		if _, err := Preview(db, bad, 5); err == nil {
+			t.Errorf("expected error for: %s", bad)
+		}
+	}
+	for _, n := range []int{0, -1} {
+		if _, err := Preview(db, "select * from numbers", n); err == nil {
+			t.Errorf("expected error for %d rows", n)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ProfileOptions control how Profile reads a table
+type ProfileOptions struct {
+	SampleRows int64 // the most rows read, 100000 if not set; larger tables are sampled at random
+	TopK       int   // the most frequent values reported for each column, 5 if not set
+}
+
+// TableProfile describes the values in the columns of a table
+type TableProfile struct {
+	Table   string
+	Rows    int64 // the rows in the table
+	Sampled int64 // the rows profiled, fewer than Rows if the table was sampled
+	Columns []ColumnProfile
+}
+
+// ColumnProfile describes the values in a column. If the table was sampled the
+// counts are of the sample, and the distinct count is a lower bound for the table
+type ColumnProfile struct {
+	Name      string
+	Type      string // the declared type
+	Nulls     int64
+	Distinct  int64       // distinct values other than NULL
+	Min, Max  interface{} // in SQLite's ordering, where numbers come before text and text before blobs
+	AvgLength float64     // the average length of the values other than NULL, in characters for text and bytes otherwise
+	Top       []ValueCount
+}
+
+// ValueCount is a value and the number of times it occurs
+type ValueCount struct {
+	Value interface{}
+	Count int64
+}
+
+// Selectivity is the fraction of the profiled rows with distinct values in the
+// column, which is close to 1 for a column that makes a good index
+func (c ColumnProfile) Selectivity(rows int64) float64 {
+	if rows == 0 {
+		return 0
+	}
+	return float64(c.Distinct) / float64(rows)
+}
+
+// Profile returns the null and distinct counts, range, average length, and most
+// frequent values of each column of a table
+func Profile(db *sql.DB, table string) (*TableProfile, error) {
+	return ProfileWithOptions(db, table, ProfileOptions{})
+}
+
+// ProfileWithOptions is Profile, with control over sampling and the values reported
+func ProfileWithOptions(db *sql.DB, table string, opts ProfileOptions) (*TableProfile, error) {
+	if opts.SampleRows <= 0 {
+		opts.SampleRows = 100000
+	}
+	if opts.TopK <= 0 {
+		opts.TopK = 5
+	}
+	columns, err := exportColumns(db, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("profile: no such table: %s", table)
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	profile := &TableProfile{Table: table}
+	name := quoteIdent(table)
+	if err := conn.QueryRowContext(ctx, "SELECT count(*) FROM "+name).Scan(&profile.Rows); err != nil {
+		return nil, err
+	}
+	source := name
+	profile.Sampled = profile.Rows
+	if profile.Rows > opts.SampleRows {
+		// the sample is kept so that each column is profiled from the same rows
+		const sample = "temp.profile_sample"
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM %s ORDER BY random() LIMIT %d", sample, name, opts.SampleRows)); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", table, err)
+		}
+		defer conn.ExecContext(ctx, "DROP TABLE IF EXISTS "+sample)
+		source, profile.Sampled = sample, opts.SampleRows
+	}
+
+	exprs := make([]string, 0, 5*len(columns))
+	for _, c := range columns {
+		col := quoteIdent(c.name)
+		exprs = append(exprs,
+			fmt.Sprintf("count(%s)", col),
+			fmt.Sprintf("count(DISTINCT %s)", col),
+			fmt.Sprintf("min(%s)", col),
+			fmt.Sprintf("max(%s)", col),
+			fmt.Sprintf("ifnull(avg(length(%s)), 0)", col),
+		)
+	}
+	profile.Columns = make([]ColumnProfile, len(columns))
+	dest := make([]interface{}, 0, len(exprs))
+	counts := make([]int64, len(columns))
+	for i, c := range columns {
+		p := &profile.Columns[i]
+		p.Name, p.Type = c.name, c.ctype
+		dest = append(dest, &counts[i], &p.Distinct, &p.Min, &p.Max, &p.AvgLength)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), source)
+	if err := conn.QueryRowContext(ctx, query).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", table, err)
+	}
+
+	for i := range profile.Columns {
+		p := &profile.Columns[i]
+		p.Nulls = profile.Sampled - counts[i]
+		col := quoteIdent(p.Name)
+		query := fmt.Sprintf("SELECT %s, count(*) FROM %s WHERE %s IS NOT NULL GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT %d", col, source, col, opts.TopK)
+		rows, err := conn.QueryContext(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", table, err)
+		}
+		for rows.Next() {
+			var vc ValueCount
+			if err := rows.Scan(&vc.Value, &vc.Count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p.Top = append(p.Top, vc)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	const schema = `
+	create table items (id integer primary key, color text, price real, note text);
+	insert into items (color, price, note) values ('red', 1.5, 'a'), ('red', 2.5, null), ('blue', 10, 'abc'), (null, 4, null), ('red', 2, 'abcde');
+	`
+	if _, err := ExecScript(db, schema); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := Profile(db, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Rows != 5 || profile.Sampled != 5 || len(profile.Columns) != 4 {
+		t.Fatalf("got %+v", profile)
+	}
+	color := profile.Columns[1]
+	if color.Name != "color" || color.Nulls != 1 || color.Distinct != 2 || color.Min != "blue" || color.Max != "red" {
+		t.Errorf("color: got %+v", color)
+	}
+	if len(color.Top) != 2 || color.Top[0] != (ValueCount{"red", 3}) || color.Top[1] != (ValueCount{"blue", 1}) {
+		t.Errorf("color top values: got %+v", color.Top)
+	}
+	price := profile.Columns[2]
+	if price.Min != 1.5 || price.Max != 10.0 || price.Nulls != 0 {
+		t.Errorf("price: got %+v", price)
+	}
+	note := profile.Columns[3]
+	if note.Nulls != 2 || note.AvgLength != 3 {
+		t.Errorf("note: got %+v", note)
+	}
+	if s := profile.Columns[0].Selectivity(profile.Sampled); s != 1 {
+		t.Errorf("id selectivity: got %v, want 1", s)
+	}
+
+	profile, err = ProfileWithOptions(db, "items", ProfileOptions{SampleRows: 3, TopK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Rows != 5 || profile.Sampled != 3 {
+		t.Errorf("got %d rows, %d sampled", profile.Rows, profile.Sampled)
+	}
+	for _, c := range profile.Columns {
+		if c.Nulls+int64(len(c.Top)) == 0 || len(c.Top) > 1 {
+			t.Errorf("%s: got %+v", c.Name, c)
+		}
+	}
+	if _, err := Profile(db, "nosuch"); err == nil {
+		t.Error("expected an error for a missing table")
+	}
+}
@@ -0,0 +1,39 @@
+package sqlite
+
+// Progress receives reports on a long-running operation, such as to drive a progress bar.
+// OnStart is called with the name of the operation, e.g. "backup", then OnProgress as it
+// goes with the amount done and the total, in units of the operation (pages for Backup,
+// rows for ExportTo, migrations for MigrateContext), and OnFinish with its outcome.
+// A total that isn't known is -1
+type Progress interface {
+	OnStart(op string)
+	OnProgress(done, total int64)
+	OnFinish(err error)
+}
+
+// ProgressFunc is a Progress that only needs the amount done
+type ProgressFunc func(done, total int64)
+
+// OnStart does nothing
+func (f ProgressFunc) OnStart(op string) {}
+
+// OnProgress calls f
+func (f ProgressFunc) OnProgress(done, total int64) { f(done, total) }
+
+// OnFinish does nothing
+func (f ProgressFunc) OnFinish(err error) {}
+
+// noProgress ignores reports
+type noProgress struct{}
+
+func (noProgress) OnStart(string)          {}
+func (noProgress) OnProgress(int64, int64) {}
+func (noProgress) OnFinish(error)          {}
+
+// reporting returns p, or a Progress that ignores reports if p is nil
+func reporting(p Progress) Progress {
+	if p == nil {
+		return noProgress{}
+	}
+	return p
+}
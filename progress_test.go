@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// progressRecorder records the reports it receives
+type progressRecorder struct {
+	ops      []string
+	done     []int64
+	total    int64
+	finished int
+	err      error
+}
+
+func (p *progressRecorder) OnStart(op string) { p.ops = append(p.ops, op) }
+
+func (p *progressRecorder) OnProgress(done, total int64) {
+	p.done = append(p.done, done)
+	p.total = total
+}
+
+func (p *progressRecorder) OnFinish(err error) {
+	p.finished++
+	p.err = err
+}
+
+// check verifies one operation was reported, finishing with all of it done
+func (p *progressRecorder) check(t *testing.T, op string) {
+	t.Helper()
+	if len(p.ops) != 1 || p.ops[0] != op || p.finished != 1 || p.err != nil {
+		t.Fatalf("%s: unexpected reports: %+v", op, p)
+	}
+	if len(p.done) == 0 || p.done[len(p.done)-1] != p.total {
+		t.Fatalf("%s: expected to finish with %d done: %v", op, p.total, p.done)
+	}
+	for i := 1; i < len(p.done); i++ {
+		if p.done[i] < p.done[i-1] {
+			t.Fatalf("%s: progress went backwards: %v", op, p.done)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "progress.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	migrate := &progressRecorder{}
+	migrations := []Migration{
+		{Version: 1, Name: "items", Up: "create table items (id integer primary key, name text)"},
+		{Version: 2, Name: "fill", Up: `with recursive seq(n) as (select 1 union all select n+1 from seq where n < 300)
+			insert into items select n, hex(randomblob(100)) from seq`},
+	}
+	if err := MigrateContext(context.Background(), db, migrations, MigrateOptions{Reporter: migrate}); err != nil {
+		t.Fatal(err)
+	}
+	migrate.check(t, "migrate")
+	if migrate.total != 2 {
+		t.Fatalf("expected 2 migrations, got %d", migrate.total)
+	}
+
+	backup := &progressRecorder{}
+	backupFile := filepath.Join(dir, "backup.db")
+	if err := BackupWithProgress(db, backupFile, BackupOptions{StepPages: 5, Reporter: backup}); err != nil {
+		t.Fatal(err)
+	}
+	backup.check(t, "backup")
+	if len(backup.done) < 2 {
+		t.Fatalf("expected several steps: %v", backup.done)
+	}
+
+	dst, err := Open(filepath.Join(dir, "export.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	export := &progressRecorder{}
+	if _, err := ExportTo(db, dst, ExportOptions{Tables: []string{"items"}, BatchSize: 100, Reporter: export}); err != nil {
+		t.Fatal(err)
+	}
+	export.check(t, "export")
+	if export.total != 300 {
+		t.Fatalf("expected 300 rows, got %d", export.total)
+	}
+
+	restore := &progressRecorder{}
+	opts := RestoreOptions{Rename: map[string]string{"items": "items_restored"}, Tables: []string{"items"}, Reporter: restore}
+	if err := RestoreTables(db, backupFile, opts); err != nil {
+		t.Fatal(err)
+	}
+	restore.check(t, "restore")
+
+	failed := &progressRecorder{}
+	opts.Reporter = failed
+	if err := RestoreTables(db, backupFile, opts); err == nil {
+		t.Fatal("expected restore over an existing table to fail")
+	}
+	if failed.finished != 1 || failed.err == nil {
+		t.Fatalf("expected failure to be reported: %+v", failed)
+	}
+
+	var calls int
+	f := ProgressFunc(func(done, total int64) { calls++ })
+	f.OnStart("x")
+	f.OnProgress(1, 2)
+	f.OnFinish(errors.New("x"))
+	if calls != 1 {
+		t.Fatalf("expected one call, got %d", calls)
+	}
+}
@@ -0,0 +1,184 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Projection maintains derived tables from the records of an AppendLog
+type Projection struct {
+	Name string
+
+	// Reduce applies a record to the derived tables, within the transaction
+	// that also records it as applied, so each record is applied exactly once
+	Reduce func(tx *sql.Tx, rec LogRecord) error
+
+	// Reset clears the derived tables before a replay
+	Reset func(tx *sql.Tx) error
+}
+
+// Projector runs projections of an AppendLog, tracking the last record
+// each has applied in the table "<log>_offsets"
+type Projector struct {
+	log         *AppendLog
+	offsets     string
+	projections map[string]Projection
+	order       []string
+
+	// BatchSize is the number of records applied per transaction
+	BatchSize int
+}
+
+// NewProjector returns a Projector for the log
+func NewProjector(log *AppendLog) (*Projector, error) {
+	offsets := log.table + "_offsets"
+	const create = "CREATE TABLE IF NOT EXISTS %s (name TEXT PRIMARY KEY, seq INTEGER NOT NULL)"
+	if _, err := log.db.Exec(fmt.Sprintf(create, quoteIdent(offsets))); err != nil {
+		return nil, fmt.Errorf("projection offsets: %w", err)
+	}
+	return &Projector{
+		log:         log,
+		offsets:     offsets,
+		projections: make(map[string]Projection),
+		BatchSize:   100,
+	}, nil
+}
+
+// Register adds a projection, which is brought up to date by Run
+func (p *Projector) Register(proj Projection) error {
+	if proj.Name == "" || proj.Reduce == nil {
+		return fmt.Errorf("projection needs a name and a reducer")
+	}
+	if _, ok := p.projections[proj.Name]; ok {
+		return fmt.Errorf("projection %q is already registered", proj.Name)
+	}
+	p.projections[proj.Name] = proj
+	p.order = append(p.order, proj.Name)
+	return nil
+}
+
+// Offset returns the last record applied by a projection
+func (p *Projector) Offset(name string) (int64, error) {
+	var seq sql.NullInt64
+	if err := row(p.log.db, []interface{}{&seq}, p.offsetQuery(), name); err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return seq.Int64, nil
+}
+
+func (p *Projector) offsetQuery() string {
+	return fmt.Sprintf("SELECT seq FROM %s WHERE name=?", quoteIdent(p.offsets))
+}
+
+// setOffset records the last record applied by a projection
+func (p *Projector) setOffset(tx *sql.Tx, name string, seq int64) error {
+	q := fmt.Sprintf("INSERT OR REPLACE INTO %s (name, seq) VALUES(?, ?)", quoteIdent(p.offsets))
+	_, err := tx.Exec(q, name, seq)
+	return err
+}
+
+// Run brings every projection up to date, returning the number of records applied
+func (p *Projector) Run() (int, error) {
+	total := 0
+	for _, name := range p.order {
+		n, err := p.catchUp(p.projections[name])
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// catchUp applies the records a projection has not yet seen, a batch per transaction
+func (p *Projector) catchUp(proj Projection) (int, error) {
+	offset, err := p.Offset(proj.Name)
+	if err != nil {
+		return 0, err
+	}
+	batch := p.BatchSize
+	if batch <= 0 {
+		batch = 1
+	}
+	total := 0
+	for {
+		// the batch is read before the transaction begins so a
+		// single-connection database doesn't wait on itself
+		var records []LogRecord
+		err := p.log.read(offset+1, batch, func(rec LogRecord) error {
+			records = append(records, rec)
+			return nil
+		})
+		if err != nil || len(records) == 0 {
+			return total, err
+		}
+		applied, err := p.apply(proj, offset, records)
+		if err != nil {
+			return total, err
+		}
+		if !applied {
+			// another Projector got there first, so carry on from where it left off
+			if offset, err = p.Offset(proj.Name); err != nil {
+				return total, err
+			}
+			continue
+		}
+		total += len(records)
+		offset = records[len(records)-1].Seq
+	}
+}
+
+// apply reduces records and advances the offset in one transaction, which holds
+// the write lock from the start. The records were read after offset, so if the
+// offset has moved since then, as another Projector of the same log has applied
+// them, nothing is done and apply reports false
+func (p *Projector) apply(proj Projection, offset int64, records []LogRecord) (bool, error) {
+	var applied bool
+	err := WithTx(context.Background(), p.log.db, func(tx *sql.Tx) error {
+		applied = false
+		var current sql.NullInt64
+		if err := tx.QueryRow(p.offsetQuery(), proj.Name).Scan(&current); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if current.Int64 != offset {
+			return nil
+		}
+		for _, rec := range records {
+			if err := proj.Reduce(tx, rec); err != nil {
+				return fmt.Errorf("projection %s: record %d: %w", proj.Name, rec.Seq, err)
+			}
+		}
+		if err := p.setOffset(tx, proj.Name, records[len(records)-1].Seq); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// Replay resets a projection and applies every record again
+func (p *Projector) Replay(name string) (int, error) {
+	proj, ok := p.projections[name]
+	if !ok {
+		return 0, fmt.Errorf("projection %q is not registered", name)
+	}
+	tx, err := p.log.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if proj.Reset != nil {
+		if err := proj.Reset(tx); err != nil {
+			return 0, fmt.Errorf("projection %s: reset: %w", name, err)
+		}
+	}
+	if err := p.setOffset(tx, name, 0); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return p.catchUp(proj)
+}
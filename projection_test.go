@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjection(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	log, err := NewAppendLog(db, "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("create table balances (account text primary key, amount integer)"); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProjector(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.BatchSize = 2
+
+	// records are "account:deposit"
+	fail := ""
+	balances := Projection{
+		Name: "balances",
+		Reduce: func(tx *sql.Tx, rec LogRecord) error {
+			parts := strings.SplitN(string(rec.Data), ":", 2)
+			if parts[0] == fail {
+				return errors.New("reducer failed")
+			}
+			_, err := tx.Exec(`insert into balances values(?, ?)
+				on conflict(account) do update set amount = amount + excluded.amount`, parts[0], parts[1])
+			return err
+		},
+		Reset: func(tx *sql.Tx) error {
+			_, err := tx.Exec("delete from balances")
+			return err
+		},
+	}
+	if err := p.Register(balances); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Register(balances); err == nil {
+		t.Fatal("expected duplicate projection error")
+	}
+
+	balance := func(account string) int {
+		var amount int
+		if err := row(db, []interface{}{&amount}, "select amount from balances where account=?", account); err != nil {
+			t.Fatal(err)
+		}
+		return amount
+	}
+
+	if _, err := log.Append([]byte("a:10"), []byte("b:5"), []byte("a:7")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := p.Run(); err != nil || n != 3 {
+		t.Fatalf("expected 3 applied but got: %d (%v)", n, err)
+	}
+	if n, err := p.Run(); err != nil || n != 0 {
+		t.Fatalf("expected nothing to apply but got: %d (%v)", n, err)
+	}
+	if a := balance("a"); a != 17 {
+		t.Fatalf("expected: %d but got: %d", 17, a)
+	}
+
+	// a failed batch is not applied, and is retried by the next run
+	if _, err := log.Append([]byte("b:1"), []byte("c:2")); err != nil {
+		t.Fatal(err)
+	}
+	fail = "c"
+	if _, err := p.Run(); err == nil {
+		t.Fatal("expected reducer error")
+	}
+	if b := balance("b"); b != 5 {
+		t.Fatalf("expected failed batch to be rolled back, got: %d", b)
+	}
+	if offset, _ := p.Offset("balances"); offset != 3 {
+		t.Fatalf("expected offset: %d but got: %d", 3, offset)
+	}
+	fail = ""
+	if n, err := p.Run(); err != nil || n != 2 {
+		t.Fatalf("expected 2 applied but got: %d (%v)", n, err)
+	}
+
+	if n, err := p.Replay("balances"); err != nil || n != 5 {
+		t.Fatalf("expected 5 replayed but got: %d (%v)", n, err)
+	}
+	if a, b := balance("a"), balance("b"); a != 17 || b != 6 {
+		t.Fatalf("unexpected balances after replay: %d %d", a, b)
+	}
+
+	// records read by another projector before the offset moved are not applied again
+	other, err := NewProjector(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := []LogRecord{{Seq: 1, Data: []byte("a:10")}}
+	if applied, err := other.apply(balances, 0, stale); err != nil || applied {
+		t.Fatalf("expected stale records to be skipped but got: %t (%v)", applied, err)
+	}
+	if a := balance("a"); a != 17 {
+		t.Fatalf("expected: %d but got: %d", 17, a)
+	}
+}
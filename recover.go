@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+)
+
+// journalMagic starts the header of a rollback journal that has not been committed
+var journalMagic = []byte{0xd9, 0xd5, 0x05, 0xf9, 0x20, 0xa1, 0x63, 0xd7}
+
+// fileSize returns the size of file and whether it exists
+func fileSize(file string) (int64, bool) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return 0, false
+	}
+	return fi.Size(), true
+}
+
+// hotJournal reports whether file has a rollback journal left by an interrupted transaction
+func hotJournal(file string) (bool, error) {
+	f, err := os.Open(file + "-journal")
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	header := make([]byte, len(journalMagic))
+	if _, err := io.ReadFull(f, header); err != nil {
+		// empty or truncated journals are not hot
+		return false, nil
+	}
+	return bytes.Equal(header, journalMagic), nil
+}
+
+// Recover inspects the files left next to a database by a process that crashed,
+// removing those that are safe to remove, and returns a description of what it found.
+// A hot journal is left in place, as sqlite rolls it back on the next read.
+//
+// An empty WAL file, and a shared memory file without a WAL, are only removed while
+// holding an exclusive lock on the database, as a database in use in WAL mode can
+// have them too. If the lock can't be taken they are reported and left in place
+func Recover(file string) ([]string, error) {
+	var found []string
+
+	hot, err := hotJournal(file)
+	if err != nil {
+		return nil, err
+	}
+	if hot {
+		found = append(found, fmt.Sprintf("hot journal %s-journal will be rolled back", file))
+	}
+
+	wal, shm := file+"-wal", file+"-shm"
+	walSize, walExists := fileSize(wal)
+	_, shmExists := fileSize(shm)
+	if !(walExists && walSize == 0) && !(shmExists && !walExists) {
+		return found, nil
+	}
+	if _, exists := fileSize(file); !exists {
+		return found, nil
+	}
+
+	// in exclusive locking mode the WAL index is kept in memory rather than
+	// the shared memory file, and closing the connection removes the WAL
+	conn, err := driverConn(fmt.Sprintf("file:%s?_locking_mode=EXCLUSIVE&_busy_timeout=0", url.PathEscape(file)))
+	if err == nil {
+		defer conn.Close()
+		_, err = conn.Exec("BEGIN EXCLUSIVE", nil)
+	}
+	if IsBusy(err) {
+		for _, name := range []string{wal, shm} {
+			if _, exists := fileSize(name); exists {
+				found = append(found, fmt.Sprintf("left %s, the database is in use", name))
+			}
+		}
+		return found, nil
+	}
+	if err != nil {
+		return found, err
+	}
+	if _, exists := fileSize(shm); exists {
+		if err := os.Remove(shm); err != nil {
+			return found, err
+		}
+		found = append(found, fmt.Sprintf("removed stale %s", shm))
+	}
+	if walExists {
+		if size, exists := fileSize(wal); exists && size == 0 {
+			if err := os.Remove(wal); err != nil {
+				return found, err
+			}
+			found = append(found, fmt.Sprintf("removed empty %s", wal))
+		}
+	}
+	_, err = conn.Exec("ROLLBACK", nil)
+	return found, err
+}
+
+// rollback forces sqlite to roll back a hot journal by reading the database
+func rollback(db *sql.DB, file string) error {
+	var version int
+	if err := row(db, []interface{}{&version}, "PRAGMA schema_version"); err != nil {
+		return err
+	}
+	hot, err := hotJournal(file)
+	if err != nil {
+		return err
+	}
+	if hot {
+		return redactf("hot journal %s-journal was not rolled back", file)
+	}
+	return nil
+}
+
+// WithRecovery checks for files left by a crashed process when opening a database,
+// logging what is found and recovered (see Recover)
+func WithRecovery(recover bool) Optional {
+	return func(c *Config) {
+		c.recover = recover
+	}
+}
+
+// recoverOpen runs Recover for the file about to be opened
+func recoverOpen(file string) error {
+	found, err := Recover(file)
+	for _, msg := range found {
+		logf("recovery: %s\n", msg)
+	}
+	return err
+}
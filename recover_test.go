@@ -0,0 +1,153 @@
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+	b, err := ioutil.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(to, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverHotJournal(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "crash.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const setup = `
+	pragma journal_mode=delete;
+	pragma cache_size=1;
+	create table numbers (n integer, pad text);
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 2000)
+	insert into numbers select n, hex(randomblob(100)) from seq;
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	// copy the files mid-transaction, as a crash would leave them
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("update numbers set n = 0, pad = ''"); err != nil {
+		t.Fatal(err)
+	}
+	crashed := filepath.Join(dir, "crashed.db")
+	copyFile(t, file, crashed)
+	copyFile(t, file+"-journal", crashed+"-journal")
+	tx.Rollback()
+
+	if hot, err := hotJournal(crashed); err != nil || !hot {
+		t.Fatalf("expected hot journal but got: %v (%v)", hot, err)
+	}
+	found, err := Recover(crashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("unexpected recovery: %q", found)
+	}
+
+	db2, err := Open(crashed, WithRecovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db2.Close()
+	var sum int
+	if err := row(db2, []interface{}{&sum}, "select sum(n) from numbers"); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 2001000 {
+		t.Fatalf("expected: %d but got: %d", 2001000, sum)
+	}
+}
+
+func TestRecoverStaleFiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stale.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("create table t (n integer)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	for _, name := range []string{file + "-wal", file + "-shm"} {
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err = Open(file, WithRecovery(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	for _, name := range []string{file + "-wal", file + "-shm"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed", name)
+		}
+	}
+
+	found, err := Recover(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("unexpected recovery: %q", found)
+	}
+}
+
+func TestRecoverInUse(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "live.db")
+	db, err := Open(file, WithJournalMode(JournalWAL), WithDriver("recover_live"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("create table t (n integer); insert into t values (1)"); err != nil {
+		t.Fatal(err)
+	}
+	// a live database in WAL mode has an empty WAL after a truncating checkpoint
+	if _, err := db.Exec("pragma wal_checkpoint(TRUNCATE)"); err != nil {
+		t.Fatal(err)
+	}
+	if size, ok := fileSize(file + "-wal"); !ok || size != 0 {
+		t.Fatalf("expected an empty WAL, got %d bytes (%v)", size, ok)
+	}
+
+	found, err := Recover(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("unexpected recovery: %q", found)
+	}
+	for _, name := range []string{file + "-wal", file + "-shm"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Fatalf("expected %s to be left: %v", name, err)
+		}
+	}
+	if _, err := db.Exec("insert into t values (2)"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := row(db, []interface{}{&n}, "select count(*) from t"); err != nil || n != 2 {
+		t.Fatalf("got %d rows (%v)", n, err)
+	}
+}
@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets removed by Redact
+const Redacted = "[redacted]"
+
+// DefaultRedactions are the patterns Redact starts with: secret DSN parameters such as
+// _auth_pass, keys given to PRAGMA key, and passwords in URLs
+var DefaultRedactions = []string{
+	`(?i)[?&](?:_auth_pass|_auth_user|_key|key|hexkey|password|passwd|pwd|secret|token)=([^&#\s'"]*)`,
+	`(?i)pragma\s+(?:\w+\.)?(?:hex)?(?:re)?key\s*(?:=|\()\s*'([^']*)'`,
+	`://[^/:@\s]+:([^/@\s]+)@`,
+}
+
+// redactions are the compiled patterns in use
+var redactions struct {
+	sync.RWMutex
+	patterns []*regexp.Regexp
+}
+
+func init() {
+	if err := SetRedactions(DefaultRedactions...); err != nil {
+		panic(err)
+	}
+}
+
+// SetRedactions replaces the patterns Redact removes, which are regular expressions.
+// If a pattern has groups only their text is replaced, so `[?&]key=([^&]*)` keeps the
+// name of the parameter, otherwise the whole match is. Use
+// SetRedactions(append(DefaultRedactions, more...)...) to keep the defaults
+func SetRedactions(patterns ...string) error {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("redaction %q: %w", p, err)
+		}
+		compiled[i] = re
+	}
+	redactions.Lock()
+	redactions.patterns = compiled
+	redactions.Unlock()
+	return nil
+}
+
+// AddRedaction adds a pattern for Redact to remove, such as `/home/[^/\s]+` to hide
+// user names in paths
+func AddRedaction(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("redaction %q: %w", pattern, err)
+	}
+	redactions.Lock()
+	redactions.patterns = append(redactions.patterns[:len(redactions.patterns):len(redactions.patterns)], re)
+	redactions.Unlock()
+	return nil
+}
+
+// Redact returns s with the text matching the redaction patterns replaced. It is applied
+// to the messages the package logs and to errors that name files or connection strings
+func Redact(s string) string {
+	redactions.RLock()
+	patterns := redactions.patterns
+	redactions.RUnlock()
+	for _, re := range patterns {
+		s = redact(re, s)
+	}
+	return s
+}
+
+// redact replaces the matches of a pattern, or their groups if it has any
+func redact(re *regexp.Regexp, s string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		spans := m[2:]
+		if len(spans) == 0 {
+			spans = m[:2]
+		}
+		for i := 0; i < len(spans); i += 2 {
+			start, end := spans[i], spans[i+1]
+			if start < last || start == end {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(Redacted)
+			last = end
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// redactedError is an error with a redacted message which still wraps its cause
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError returns err with its message redacted, still wrapping it
+func redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if msg := Redact(err.Error()); msg != err.Error() {
+		return &redactedError{msg: msg, err: err}
+	}
+	return err
+}
+
+// redactf is fmt.Errorf with the message redacted
+func redactf(format string, args ...interface{}) error {
+	return redactError(fmt.Errorf(format, args...))
+}
+
+// logf logs a redacted message
+func logf(format string, args ...interface{}) {
+	log.Print(Redact(fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,637 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// A WAL file starts with a header, followed by frames of a header and a page each
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+)
+
+// errWALBreak means the WAL was reset without the replicator shipping all of it
+var errWALBreak = errors.New("write-ahead log was reset")
+
+// ReplicaWriter stores the files of a replica, such as in a directory or an object store
+type ReplicaWriter interface {
+	Put(name string, r io.Reader) error
+}
+
+// ReplicaStore is a ReplicaWriter whose files can be read back, to restore from
+type ReplicaStore interface {
+	ReplicaWriter
+	Get(name string) (io.ReadCloser, error)
+	List(prefix string) ([]string, error) // the names that start with prefix, in order
+}
+
+// Replicator ships the write-ahead log of a database to a ReplicaWriter, as it grows, so a copy
+// can be rebuilt as of any point in time with RestoreReplica. Each generation of a replica is a
+// snapshot of the database followed by segments of the log, each shipped at a commit.
+//
+// The replicator checkpoints the database itself so the log doesn't grow without end, and
+// ships the whole log before each checkpoint that lets it be reset. A reset it didn't see
+// coming, such as from a checkpoint by another connection, means some of the log may
+// be lost, so the replicator starts a new generation. Open the database with the pragma
+// wal_autocheckpoint set to 0 to leave checkpoints to the replicator
+type Replicator struct {
+	Interval         time.Duration // how often Run ships the log, 1 second if not set
+	CheckpointFrames int           // frames shipped before a checkpoint, 1000 if not set
+
+	mu      sync.Mutex
+	file    string
+	store   ReplicaWriter
+	lock    *sqlite3.SQLiteConn // holds the write lock while the log is read
+	read    *sqlite3.SQLiteConn // takes snapshots and checkpoints
+	gen     string
+	segment int
+	wal     walPosition
+	frames  int  // frames shipped since the last reset of the log
+	reset   bool // whether the log was checkpointed once all of it was shipped
+}
+
+// walPosition is how far the log has been read, with the state needed to validate what follows
+type walPosition struct {
+	header    bool // whether the log had a header
+	bigEndian bool
+	pageSize  int
+	ckpt      uint32 // the checkpoint sequence number of the header
+	salt      [2]uint32
+	offset    int64
+	sum       [2]uint32
+}
+
+// NewReplicator returns a replicator for db, which must be a file in WAL mode.
+// It doesn't ship anything until Sync or Run is called
+func NewReplicator(db *sql.DB, store ReplicaWriter) (*Replicator, error) {
+	file, err := FilenameE(db)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		return nil, errors.New("cannot replicate a database without a file")
+	}
+	var mode string
+	if err := row(db, []interface{}{&mode}, "PRAGMA journal_mode"); err != nil {
+		return nil, err
+	}
+	if mode != "wal" {
+		return nil, fmt.Errorf("cannot replicate a database in %s journal mode", mode)
+	}
+	dsn := "file:" + url.PathEscape(file) + "?_busy_timeout=5000"
+	lock, err := driverConn(dsn)
+	if err != nil {
+		return nil, err
+	}
+	read, err := driverConn(dsn)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return &Replicator{file: file, store: store, lock: lock, read: read}, nil
+}
+
+// Generation returns the name of the current generation, once one has started
+func (r *Replicator) Generation() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.gen
+}
+
+// Close releases the replicator's connections, shipping what remains of the log first
+func (r *Replicator) Close() error {
+	err := r.Sync(context.Background())
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lock.Close()
+	r.read.Close()
+	return err
+}
+
+// Run ships the log every Interval until ctx is done
+func (r *Replicator) Run(ctx context.Context) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sync(ctx); err != nil {
+			return ContextError(ctx, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync ships the transactions committed since the last sync, starting a generation
+// if there isn't one, and checkpoints the database when enough of the log has been shipped
+func (r *Replicator) Sync(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := r.locked(r.sync); err != nil {
+		return err
+	}
+	limit := r.CheckpointFrames
+	if limit <= 0 {
+		limit = 1000
+	}
+	if r.frames < limit {
+		return nil
+	}
+	return r.checkpoint()
+}
+
+// locked runs fn holding the write lock, so the log doesn't change while it's read
+func (r *Replicator) locked(fn func() error) (err error) {
+	if _, err := r.lock.Exec("BEGIN IMMEDIATE", nil); err != nil {
+		return err
+	}
+	defer func() {
+		if _, cerr := r.lock.Exec("COMMIT", nil); err == nil {
+			err = cerr
+		}
+	}()
+	return fn()
+}
+
+// sync ships the log, starting a generation if there isn't one or the log was reset unexpectedly
+func (r *Replicator) sync() error {
+	if r.gen == "" {
+		return r.snapshot()
+	}
+	err := r.ship()
+	if err == errWALBreak {
+		return r.snapshot()
+	}
+	return err
+}
+
+// snapshot starts a generation with a copy of the database, as of the end of the log
+func (r *Replicator) snapshot() error {
+	f, err := ioutil.TempFile("", "replica-*.db")
+	if err != nil {
+		return err
+	}
+	temp := f.Name()
+	f.Close()
+	defer os.Remove(temp)
+	dest, err := driverConn(temp)
+	if err != nil {
+		return err
+	}
+	err = copyConn(dest, r.read)
+	if cerr := dest.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("replica snapshot: %w", err)
+	}
+
+	gen := fmt.Sprintf("%016x", time.Now().UnixNano())
+	f, err = os.Open(temp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := r.store.Put(gen+"/snapshot", f); err != nil {
+		return fmt.Errorf("replica snapshot: %w", err)
+	}
+	// the snapshot has everything in the log, so shipping continues from its end
+	r.gen, r.segment, r.wal, r.frames, r.reset = gen, 0, walPosition{}, 0, false
+	_, _, err = r.readLog()
+	return err
+}
+
+// ship sends the frames of the transactions committed since the last sync as a segment
+func (r *Replicator) ship() error {
+	wal, shipped, reset := r.wal, r.frames, r.reset
+	data, frames, err := r.readLog()
+	if err != nil || frames == 0 {
+		return err
+	}
+	name := fmt.Sprintf("%s/wal/%08d-%016x", r.gen, r.segment+1, time.Now().UnixNano())
+	if err := r.store.Put(name, bytes.NewReader(data)); err != nil {
+		// the frames are read again next time
+		r.wal, r.frames, r.reset = wal, shipped, reset
+		return fmt.Errorf("replica segment: %w", err)
+	}
+	r.segment++
+	r.frames += frames
+	return nil
+}
+
+// readLog returns the frames of the log after the current position up to the last commit,
+// and moves the position past them. A log with another header than the last one read
+// is a break, unless no header had been read before, or it is the first reset since
+// a checkpoint of the whole log
+func (r *Replicator) readLog() ([]byte, int, error) {
+	f, err := os.Open(r.file + "-wal")
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	header := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		// an empty log has been truncated by a checkpoint; its next header is a reset
+		if r.wal.header && !r.reset {
+			return nil, 0, errWALBreak
+		}
+		return nil, 0, nil
+	}
+	pos, ok := parseWALHeader(header)
+	if !ok {
+		return nil, 0, redactf("invalid header in %s-wal", r.file)
+	}
+	switch {
+	case !r.wal.header:
+		r.wal = pos
+	case pos.salt == r.wal.salt:
+	case r.reset && pos.ckpt == r.wal.ckpt+1:
+		r.wal, r.frames = pos, 0
+	default:
+		return nil, 0, errWALBreak
+	}
+
+	if _, err := f.Seek(r.wal.offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	var data []byte
+	var frames int
+	start := r.wal.offset
+	size := walFrameHeaderSize + r.wal.pageSize
+	frame := make([]byte, size)
+	pending, sum := 0, r.wal.sum
+	for {
+		if _, err := io.ReadFull(f, frame); err != nil {
+			break
+		}
+		if r.wal.uint32(frame[8:]) != r.wal.salt[0] || r.wal.uint32(frame[12:]) != r.wal.salt[1] {
+			break
+		}
+		sum = r.wal.checksum(frame[:8], sum)
+		sum = r.wal.checksum(frame[walFrameHeaderSize:], sum)
+		if sum[0] != binary.BigEndian.Uint32(frame[16:]) || sum[1] != binary.BigEndian.Uint32(frame[20:]) {
+			break
+		}
+		data = append(data, frame...)
+		pending++
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			// a commit frame ends a transaction
+			frames += pending
+			pending = 0
+			r.wal.offset = start + int64(size*frames)
+			r.wal.sum = sum
+		}
+	}
+	if frames > 0 {
+		// frames added without a reset mean the log may be reset again by a checkpoint that wasn't ours
+		r.reset = false
+	}
+	return data[:frames*size], frames, nil
+}
+
+// checkpoint checkpoints the database having shipped the whole log, so the next writer
+// can reset the log and shipping continue from its start in the same generation
+func (r *Replicator) checkpoint() error {
+	return r.locked(func() error {
+		if err := r.sync(); err != nil {
+			return err
+		}
+		// the lock connection can't checkpoint inside its transaction, but
+		// a passive checkpoint by another runs while it holds the write lock
+		var busy, log, done int64
+		fn := func(cols []string, row int, values []driver.Value) error {
+			busy, _ = values[0].(int64)
+			log, _ = values[1].(int64)
+			done, _ = values[2].(int64)
+			return nil
+		}
+		if err := connQuery(r.read, fn, "PRAGMA wal_checkpoint(PASSIVE)"); err != nil {
+			return err
+		}
+		// if readers kept the checkpoint from finishing, it's tried again after the next sync
+		r.reset = busy == 0 && done == log
+		return nil
+	})
+}
+
+// parseWALHeader returns the position at the start of the frames of a log
+func parseWALHeader(header []byte) (walPosition, bool) {
+	magic := binary.BigEndian.Uint32(header)
+	if magic&^1 != 0x377f0682 {
+		return walPosition{}, false
+	}
+	pos := walPosition{
+		header:    true,
+		bigEndian: magic&1 == 1,
+		pageSize:  int(binary.BigEndian.Uint32(header[8:])),
+		ckpt:      binary.BigEndian.Uint32(header[12:]),
+		salt:      [2]uint32{binary.BigEndian.Uint32(header[16:]), binary.BigEndian.Uint32(header[20:])},
+		offset:    walHeaderSize,
+	}
+	if pos.pageSize == 1 {
+		pos.pageSize = 65536
+	}
+	pos.sum = pos.checksum(header[:24], [2]uint32{})
+	if pos.sum[0] != binary.BigEndian.Uint32(header[24:]) || pos.sum[1] != binary.BigEndian.Uint32(header[28:]) {
+		return walPosition{}, false
+	}
+	return pos, true
+}
+
+// uint32 reads a salt as stored in a frame header
+func (p walPosition) uint32(b []byte) uint32 {
+	return binary.BigEndian.Uint32(b)
+}
+
+// checksum continues the checksum of a log over b, whose length is a multiple of 8
+func (p walPosition) checksum(b []byte, sum [2]uint32) [2]uint32 {
+	order := binary.ByteOrder(binary.LittleEndian)
+	if p.bigEndian {
+		order = binary.BigEndian
+	}
+	s0, s1 := sum[0], sum[1]
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+	return [2]uint32{s0, s1}
+}
+
+// RestoreReplica rebuilds the database file dest from a replica, as of the last transaction
+// shipped at or before the given time, or of the last one shipped if at is zero
+func RestoreReplica(store ReplicaStore, dest string, at time.Time) error {
+	names, err := store.List("")
+	if err != nil {
+		return err
+	}
+	var gen string
+	for _, name := range names {
+		g := strings.TrimSuffix(name, "/snapshot")
+		if g == name || !replicaBefore(g, at) {
+			continue
+		}
+		if g > gen {
+			gen = g
+		}
+	}
+	if gen == "" {
+		return errors.New("no replica generation to restore")
+	}
+	segments, err := store.List(gen + "/wal/")
+	if err != nil {
+		return err
+	}
+
+	temp := dest + ".restore"
+	if err := restoreReplica(store, gen, segments, temp, at); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("restore replica %s: %w", gen, err)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		os.Remove(dest + suffix)
+	}
+	return os.Rename(temp, dest)
+}
+
+// replicaBefore reports whether the time in a name, in hex nanoseconds, isn't after at
+func replicaBefore(name string, at time.Time) bool {
+	if i := strings.LastIndexByte(name, '-'); i >= 0 {
+		name = name[i+1:]
+	}
+	nanos, err := strconv.ParseInt(name, 16, 64)
+	if err != nil {
+		return false
+	}
+	return at.IsZero() || nanos <= at.UnixNano()
+}
+
+// restoreReplica writes the snapshot of a generation to file, then applies its segments
+func restoreReplica(store ReplicaStore, gen string, segments []string, file string, at time.Time) error {
+	snap, err := store.Get(gen + "/snapshot")
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		snap.Close()
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, snap)
+	snap.Close()
+	if err != nil {
+		return err
+	}
+	header := make([]byte, 100)
+	if _, err := f.ReadAt(header, 0); err != nil || !bytes.HasPrefix(header, sqliteHeader) {
+		return errors.New("snapshot is not a sqlite database")
+	}
+	pageSize := int64(binary.BigEndian.Uint16(header[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	sort.Strings(segments)
+	for _, name := range segments {
+		if !replicaBefore(name, at) {
+			break
+		}
+		seg, err := store.Get(name)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(seg)
+		seg.Close()
+		if err != nil {
+			return err
+		}
+		if err := applyFrames(f, data, pageSize); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return f.Sync()
+}
+
+// applyFrames writes the pages of log frames to a database file, sizing it at each commit
+func applyFrames(f *os.File, data []byte, pageSize int64) error {
+	size := walFrameHeaderSize + int(pageSize)
+	if len(data)%size != 0 {
+		return fmt.Errorf("segment of %d bytes is not whole frames of %d", len(data), size)
+	}
+	for i := 0; i < len(data); i += size {
+		frame := data[i : i+size]
+		page := int64(binary.BigEndian.Uint32(frame))
+		if _, err := f.WriteAt(frame[walFrameHeaderSize:], (page-1)*pageSize); err != nil {
+			return err
+		}
+		if commit := int64(binary.BigEndian.Uint32(frame[4:])); commit != 0 {
+			if err := f.Truncate(commit * pageSize); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DirReplica stores a replica as files in a directory
+type DirReplica string
+
+// Put writes a file of the replica, atomically
+func (d DirReplica) Put(name string, r io.Reader) error {
+	path := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Get opens a file of the replica
+func (d DirReplica) Get(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// List returns the names of the files of the replica that start with prefix, in order
+func (d DirReplica) List(prefix string) ([]string, error) {
+	var names []string
+	root := string(d)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".put-") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// streamReplica writes a replica as a stream of named records
+type streamReplica struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// StreamReplica returns a ReplicaWriter that writes each file to w as a record of its name
+// and contents, such as to a network connection; ReadReplicaStream reads them back
+func StreamReplica(w io.Writer) ReplicaWriter {
+	return &streamReplica{w: w}
+}
+
+// Put writes a record of the file
+func (s *streamReplica) Put(name string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	putString(&buf, name)
+	putString(&buf, string(data))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(buf.Bytes())
+	return err
+}
+
+// memoryReplica is a replica held in memory
+type memoryReplica map[string][]byte
+
+func (m memoryReplica) Put(name string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	m[name] = data
+	return err
+}
+
+func (m memoryReplica) Get(name string) (io.ReadCloser, error) {
+	data, ok := m[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m memoryReplica) List(prefix string) ([]string, error) {
+	var names []string
+	for name := range m {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ReadReplicaStream reads the records written by StreamReplica into a store in memory
+func ReadReplicaStream(r io.Reader) (ReplicaStore, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	store := make(memoryReplica)
+	cr := changesetReader{bytes.NewReader(data)}
+	for cr.Len() > 0 {
+		name, err := cr.string()
+		if err != nil {
+			return nil, fmt.Errorf("invalid replica stream: %w", err)
+		}
+		contents, err := cr.string()
+		if err != nil {
+			return nil, fmt.Errorf("invalid replica stream: %w", err)
+		}
+		store[name] = []byte(contents)
+	}
+	return store, nil
+}
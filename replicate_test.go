@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplicator(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "live.db"), WithDriver("replicate"), WithJournalMode(JournalWAL), WithPragmas(map[string]string{"wal_autocheckpoint": "0"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("create table t (n integer, s text)"); err != nil {
+		t.Fatal(err)
+	}
+	store := DirReplica(filepath.Join(dir, "replica"))
+	var stream bytes.Buffer
+	r, err := NewReplicator(db, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CheckpointFrames = 5
+
+	insert := func(from, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			if _, err := db.Exec("insert into t values (?, hex(randomblob(500)))", i); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert(0, 10)
+	gen := r.Generation()
+	insert(10, 20)
+	time.Sleep(time.Millisecond)
+	middle := time.Now()
+	time.Sleep(time.Millisecond)
+	for i := 2; i < 10; i++ {
+		insert(i*10, i*10+10)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Generation() != gen {
+		t.Fatal("expected checkpoints by the replicator to keep the generation")
+	}
+
+	count := func(file string) int {
+		t.Helper()
+		copied, err := sql.Open("sqlite3", file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer copied.Close()
+		var n int
+		var check string
+		if err := copied.QueryRow("pragma integrity_check").Scan(&check); err != nil || check != "ok" {
+			t.Fatalf("restored database is not intact: %v %s", err, check)
+		}
+		if err := copied.QueryRow("select count(*) from t").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	latest := filepath.Join(dir, "latest.db")
+	if err := RestoreReplica(store, latest, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(latest); n != 100 {
+		t.Fatalf("expected 100 rows, got %d", n)
+	}
+	past := filepath.Join(dir, "past.db")
+	if err := RestoreReplica(store, past, middle); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(past); n != 20 {
+		t.Fatalf("expected 20 rows as of the middle, got %d", n)
+	}
+
+	// a checkpoint the replicator doesn't control starts a new generation
+	r, err = NewReplicator(db, StreamReplica(&stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert(100, 110)
+	gen = r.Generation()
+	if _, err := db.Exec("pragma wal_checkpoint(truncate)"); err != nil {
+		t.Fatal(err)
+	}
+	insert(110, 120)
+	if r.Generation() == gen {
+		t.Fatal("expected a new generation after an outside checkpoint")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := ReadReplicaStream(&stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreReplica(streamed, latest, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(latest); n != 120 {
+		t.Fatalf("expected 120 rows, got %d", n)
+	}
+}
+
+func TestReplicatorJournalMode(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "delete.db"), WithDriver("replicate_delete"), WithJournalMode(JournalDelete))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := NewReplicator(db, DirReplica(t.TempDir())); err == nil {
+		t.Fatal("expected error for a database not in WAL mode")
+	}
+}
+
+func TestReplicatorClosed(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "closed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if _, err := NewReplicator(db, DirReplica(t.TempDir())); err == nil {
+		t.Fatal("expected error for a closed database")
+	}
+}
+
+func TestReplicatorOddName(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "live #1 %41.db"), WithDriver("replicate_odd"), WithJournalMode(JournalWAL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("create table t (n integer)"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReplicator(db, DirReplica(filepath.Join(dir, "replica")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := db.Exec("insert into t values (1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// the replicator's own connections open the same file, not one named by a misread URI
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != "replica" && !strings.HasPrefix(e.Name(), "live #1 %41.db") {
+			t.Errorf("unexpected file: %q", e.Name())
+		}
+	}
+}
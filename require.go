@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Feature is an optional capability of the sqlite library, as named by Has
+type Feature string
+
+// Features that can be required
+const (
+	JSON1             Feature = "JSON1"
+	FTS3              Feature = "FTS3"
+	FTS4              Feature = "FTS4"
+	FTS5              Feature = "FTS5"
+	RTree             Feature = "RTREE"
+	Geopoly           Feature = "GEOPOLY"
+	Sessions          Feature = "SESSION"
+	PreUpdateHook     Feature = "PREUPDATE_HOOK"
+	DBStat            Feature = "DBSTAT_VTAB"
+	ColumnMetadata    Feature = "COLUMN_METADATA"
+	Stat4             Feature = "STAT4"
+	MathFunctions     Feature = "MATH_FUNCTIONS"
+	UpdateDeleteLimit Feature = "UPDATE_DELETE_LIMIT"
+)
+
+// The first versions to support features that vary by the linked library
+const (
+	VersionReturning  = "3.35.0" // INSERT/UPDATE/DELETE ... RETURNING
+	VersionDropColumn = "3.35.0" // ALTER TABLE ... DROP COLUMN
+	VersionStrict     = "3.37.0" // CREATE TABLE ... STRICT
+)
+
+// VersionError is returned when the sqlite library is older than required
+type VersionError struct {
+	Required string
+	Actual   string
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("sqlite version %s is required, but the linked library is %s", e.Required, e.Actual)
+}
+
+// FeatureError is returned when the sqlite library lacks required features
+type FeatureError struct {
+	Missing []Feature
+	Version string
+}
+
+func (e *FeatureError) Error() string {
+	names := make([]string, len(e.Missing))
+	for i, f := range e.Missing {
+		names[i] = string(f)
+	}
+	return fmt.Sprintf("sqlite %s was not compiled with: %s", e.Version, strings.Join(names, ", "))
+}
+
+// parseVersion splits a version such as "3.35.0" into its numbers
+func parseVersion(version string) ([3]int, error) {
+	var parts [3]int
+	fields := strings.Split(strings.TrimSpace(version), ".")
+	if len(fields) > 3 {
+		return parts, fmt.Errorf("invalid version: %q", version)
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, fmt.Errorf("invalid version: %q", version)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
+
+// libVersion returns the version of the library used by db
+func libVersion(db *sql.DB) (string, error) {
+	var version string
+	return version, row(db, []interface{}{&version}, "select sqlite_version()")
+}
+
+// RequireVersion returns a *VersionError if the library used by db
+// is older than version (e.g., "3.35.0" or VersionReturning)
+func RequireVersion(db *sql.DB, version string) error {
+	required, err := parseVersion(version)
+	if err != nil {
+		return err
+	}
+	actual, err := libVersion(db)
+	if err != nil {
+		return err
+	}
+	have, err := parseVersion(actual)
+	if err != nil {
+		return err
+	}
+	for i := range required {
+		if have[i] != required[i] {
+			if have[i] < required[i] {
+				return &VersionError{Required: version, Actual: actual}
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// RequireFeatures returns a *FeatureError listing the features the library used by db lacks
+func RequireFeatures(db *sql.DB, features ...Feature) error {
+	options, err := compileOptions(db)
+	if err != nil {
+		return err
+	}
+	var missing []Feature
+	for _, f := range features {
+		if !hasOption(db, options, featureOption(string(f))) {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	version, err := libVersion(db)
+	if err != nil {
+		return err
+	}
+	return &FeatureError{Missing: missing, Version: version}
+}
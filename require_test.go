@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequireVersion(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	for _, version := range []string{"3", "3.8", "3.8.0", "2.99.99"} {
+		if err := RequireVersion(db, version); err != nil {
+			t.Errorf("%s: %v", version, err)
+		}
+	}
+	var verr *VersionError
+	if err := RequireVersion(db, "99.0.0"); !errors.As(err, &verr) || verr.Actual == "" {
+		t.Fatalf("expected version error but got: %v", err)
+	}
+	if err := RequireVersion(db, "3.x"); err == nil || errors.As(err, &verr) {
+		t.Fatalf("expected invalid version but got: %v", err)
+	}
+}
+
+func TestRequireFeatures(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	if err := RequireFeatures(db, FTS3, FTS4, RTree); err != nil {
+		t.Fatal(err)
+	}
+	err := RequireFeatures(db, RTree, Feature("NO_SUCH_FEATURE"), Geopoly)
+	var ferr *FeatureError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("expected feature error but got: %v", err)
+	}
+	if len(ferr.Missing) != 2 || ferr.Missing[0] != "NO_SUCH_FEATURE" || ferr.Missing[1] != Geopoly {
+		t.Fatalf("unexpected missing features: %v", ferr.Missing)
+	}
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// sqliteHeader starts every sqlite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// checkHeader returns an error if the file isn't a sqlite database
+func checkHeader(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, header); err != nil || !bytes.Equal(header, sqliteHeader) {
+		return redactf("%s is not a sqlite database", file)
+	}
+	return nil
+}
+
+// Restore replaces the contents of the open database with those of the database file src,
+// the inverse of Backup. The copy is made in a single step, so other connections see either
+// the old or the new contents, and statements prepared on them are prepared again as needed.
+// The WAL is checkpointed first so it doesn't hold pages of the replaced contents
+func Restore(db *sql.DB, src string) error {
+	return RestoreContext(context.Background(), db, src)
+}
+
+// RestoreContext is Restore with a context
+func RestoreContext(ctx context.Context, db *sql.DB, src string) error {
+	if err := checkHeader(src); err != nil {
+		return err
+	}
+	from, err := driverConn("file:" + url.PathEscape(src) + "?mode=ro")
+	if err != nil {
+		return redactf("restore %s: %w", src, err)
+	}
+	defer from.Close()
+
+	// an empty source would leave the database empty, so it must be the file checked
+	var pages int64
+	err = connQuery(from, func(columns []string, row int, values []driver.Value) error {
+		pages, _ = values[0].(int64)
+		return nil
+	}, "PRAGMA page_count")
+	if err != nil {
+		return redactf("restore %s: %w", src, err)
+	}
+	if pages == 0 {
+		return redactf("restore %s: source database is empty", src)
+	}
+
+	if _, err := db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("restore checkpoint: %w", err)
+	}
+	err = rawConn(ctx, db, func(dest *sqlite3.SQLiteConn) error {
+		return copyConn(dest, from)
+	})
+	if err != nil {
+		return redactf("restore %s: %w", src, err)
+	}
+	return nil
+}
+
+// RestoreFromReader is Restore from a database image, such as one read from a file or network
+func RestoreFromReader(db *sql.DB, r io.Reader) error {
+	f, err := ioutil.TempFile("", "restore-*.db")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return Restore(db, f.Name())
+}
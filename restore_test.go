@@ -0,0 +1,85 @@
+package sqlite
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "live.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("pragma journal_mode=wal; create table t (n integer); insert into t values(1), (2)"); err != nil {
+		t.Fatal(err)
+	}
+	backupFile := filepath.Join(dir, "backup.db")
+	if err := Backup(db, backupFile); err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := db.Prepare("select count(*) from t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if _, err := db.Exec("insert into t values(3); create table extra (x)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Restore(db, backupFile); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := stmt.QueryRow().Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected: %d but got: %d", 2, count)
+	}
+	if _, err := db.Exec("select * from extra"); err == nil {
+		t.Fatal("expected table to be gone after restore")
+	}
+
+	image, err := ioutil.ReadFile(backupFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("delete from t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreFromReader(db, bytes.NewReader(image)); err != nil {
+		t.Fatal(err)
+	}
+	if err := stmt.QueryRow().Scan(&count); err != nil || count != 2 {
+		t.Fatalf("expected: %d but got: %d (%v)", 2, count, err)
+	}
+
+	if err := RestoreFromReader(db, strings.NewReader("not a database")); err == nil {
+		t.Fatal("expected error restoring garbage")
+	}
+
+	// a name with characters that mean something in a URI
+	odd := filepath.Join(dir, "snap#1.db")
+	if err := ioutil.WriteFile(odd, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("delete from t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(db, odd); err != nil {
+		t.Fatal(err)
+	}
+	if err := stmt.QueryRow().Scan(&count); err != nil || count != 2 {
+		t.Fatalf("expected: %d but got: %d (%v)", 2, count, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "snap")); err == nil {
+		t.Fatal("expected no file named by a misread URI")
+	}
+}
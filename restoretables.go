@@ -0,0 +1,310 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// RestoreOptions select the tables RestoreTables copies from a backup
+type RestoreOptions struct {
+	Tables []string          // tables to restore, or all of them if empty
+	Rename map[string]string // new names for tables, e.g. "orders": "orders_restored"
+	// a table that already exists is an error, unless its rows are added to (Append)
+	// or it is dropped and created again as in the backup (Replace)
+	Append  bool
+	Replace bool
+
+	Reporter Progress // reports on the restore, in tables restored
+}
+
+// RestoreTables copies tables, with their indexes, from the database file src into the open
+// database, leaving the rest of it alone. Tables can be given new names, so a backup can be
+// brought in next to the live data rather than over it, and indexes of a renamed table are
+// named after it. Triggers and views are not restored. It all happens in one transaction
+func RestoreTables(db *sql.DB, src string, opts RestoreOptions) error {
+	return RestoreTablesContext(context.Background(), db, src, opts)
+}
+
+// RestoreTablesContext is RestoreTables with a context
+func RestoreTablesContext(ctx context.Context, db *sql.DB, src string, opts RestoreOptions) (err error) {
+	report := reporting(opts.Reporter)
+	report.OnStart("restore")
+	defer func() { report.OnFinish(err) }()
+
+	if err := checkHeader(src); err != nil {
+		return err
+	}
+	err = rawConn(ctx, db, func(conn *sqlite3.SQLiteConn) error {
+		return restoreTables(ctx, conn, src, opts)
+	})
+	if err != nil {
+		return redactf("restore %s: %w", src, err)
+	}
+	return nil
+}
+
+func restoreTables(ctx context.Context, conn *sqlite3.SQLiteConn, src string, opts RestoreOptions) (err error) {
+	const alias = "restore_src"
+	if _, err := conn.Exec("ATTACH DATABASE ? AS "+alias, []driver.Value{"file:" + url.PathEscape(src) + "?mode=ro"}); err != nil {
+		return err
+	}
+	defer func() {
+		if _, derr := conn.Exec("DETACH DATABASE "+alias, nil); err == nil {
+			err = derr
+		}
+	}()
+
+	type object struct{ kind, name, table, sql string }
+	var objects []object
+	fn := func(cols []string, row int, values []driver.Value) error {
+		var obj object
+		obj.kind, _ = values[0].(string)
+		obj.name, _ = values[1].(string)
+		obj.table, _ = values[2].(string)
+		obj.sql, _ = values[3].(string)
+		objects = append(objects, obj)
+		return nil
+	}
+	q := "SELECT type, name, tbl_name, sql FROM " + alias + ".sqlite_master WHERE type IN ('table', 'index') AND sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY rowid"
+	if err := connQuery(conn, fn, q); err != nil {
+		return err
+	}
+	tables := make(map[string]string) // names in the backup by lower case name
+	for _, obj := range objects {
+		if obj.kind == "table" {
+			tables[strings.ToLower(obj.name)] = obj.name
+		}
+	}
+	var selected []string
+	if len(opts.Tables) == 0 {
+		for _, obj := range objects {
+			if obj.kind == "table" {
+				selected = append(selected, obj.name)
+			}
+		}
+	}
+	for _, name := range opts.Tables {
+		table, ok := tables[strings.ToLower(name)]
+		if !ok {
+			return fmt.Errorf("no such table in backup: %s", name)
+		}
+		selected = append(selected, table)
+	}
+	rename := func(table string) string {
+		for from, to := range opts.Rename {
+			if strings.EqualFold(from, table) {
+				return to
+			}
+		}
+		return table
+	}
+
+	if _, err := conn.Exec("BEGIN IMMEDIATE", nil); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			conn.Exec("ROLLBACK", nil)
+		}
+	}()
+	report := reporting(opts.Reporter)
+	report.OnProgress(0, int64(len(selected)))
+	for i, table := range selected {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if i > 0 {
+			report.OnProgress(int64(i), int64(len(selected)))
+		}
+		target := rename(table)
+		exists, err := connTableExists(conn, target)
+		if err != nil {
+			return err
+		}
+		create := exists && opts.Replace || !exists
+		switch {
+		case exists && opts.Replace:
+			if _, err := conn.Exec("DROP TABLE main."+quoteIdent(target), nil); err != nil {
+				return err
+			}
+		case exists && !opts.Append:
+			return fmt.Errorf("table %s already exists", target)
+		}
+		if create {
+			for _, obj := range objects {
+				if obj.kind != "table" || obj.name != table {
+					continue
+				}
+				stmt, err := renameCreate(obj.sql, target, "")
+				if err != nil {
+					return err
+				}
+				if _, err := conn.Exec(stmt, nil); err != nil {
+					return fmt.Errorf("create %s: %w", target, err)
+				}
+			}
+		}
+		columns, err := connColumns(conn, alias, table)
+		if err != nil {
+			return err
+		}
+		q := fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM %s.%s", quoteIdent(target), columns, columns, alias, quoteIdent(table))
+		if _, err := conn.ExecContext(ctx, q, nil); err != nil {
+			return fmt.Errorf("copy %s: %w", table, err)
+		}
+		if !create {
+			continue
+		}
+		// indexes are created after the rows are in, which is quicker than updating them row by row
+		for _, obj := range objects {
+			if obj.kind != "index" || obj.table != table {
+				continue
+			}
+			name := obj.name
+			if target != table {
+				name = target + "_" + name
+				if strings.Contains(obj.name, table) {
+					name = strings.Replace(obj.name, table, target, 1)
+				}
+			}
+			stmt, err := renameCreate(obj.sql, name, target)
+			if err != nil {
+				return err
+			}
+			if _, err := conn.Exec(stmt, nil); err != nil {
+				return fmt.Errorf("create %s: %w", name, err)
+			}
+		}
+	}
+	if _, err = conn.Exec("COMMIT", nil); err == nil {
+		report.OnProgress(int64(len(selected)), int64(len(selected)))
+	}
+	return err
+}
+
+// connTableExists reports whether the main database has the table
+func connTableExists(conn *sqlite3.SQLiteConn, table string) (bool, error) {
+	var exists bool
+	fn := func(cols []string, row int, values []driver.Value) error {
+		exists = true
+		return nil
+	}
+	q := "SELECT 1 FROM main.sqlite_master WHERE type = 'table' AND name = " + quoteString(table) + " COLLATE NOCASE"
+	return exists, connQuery(conn, fn, q)
+}
+
+// connColumns returns the quoted names of the stored columns of a table, separated by commas
+func connColumns(conn *sqlite3.SQLiteConn, schema, table string) (string, error) {
+	var names []string
+	fn := func(cols []string, row int, values []driver.Value) error {
+		name, _ := values[0].(string)
+		names = append(names, quoteIdent(name))
+		return nil
+	}
+	q := fmt.Sprintf("SELECT name FROM %s.pragma_table_info(%s)", schema, quoteString(table))
+	if err := connQuery(conn, fn, q); err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no columns in %s", table)
+	}
+	return strings.Join(names, ", "), nil
+}
+
+// renameCreate gives a new name to the object created by a CREATE TABLE or CREATE INDEX
+// statement, and for an index the table it is on, if table isn't empty
+func renameCreate(stmt, name, table string) (string, error) {
+	start, end := sqlToken(stmt, 0)
+	var renamed bool
+	for start < len(stmt) {
+		word := strings.ToUpper(stmt[start:end])
+		switch {
+		case !renamed && (word == "TABLE" || word == "INDEX"):
+			start, end = sqlToken(stmt, end)
+			if strings.EqualFold(stmt[start:end], "IF") {
+				_, end = sqlToken(stmt, end) // NOT
+				_, end = sqlToken(stmt, end) // EXISTS
+				start, end = sqlToken(stmt, end)
+			}
+			stmt = stmt[:start] + quoteIdent(name) + stmt[end:]
+			end = start + len(quoteIdent(name))
+			renamed = true
+			if table == "" {
+				return stmt, nil
+			}
+		case renamed && word == "ON":
+			start, end = sqlToken(stmt, end)
+			return stmt[:start] + quoteIdent(table) + stmt[end:], nil
+		}
+		start, end = sqlToken(stmt, end)
+	}
+	return "", fmt.Errorf("cannot rename: %s", stmt)
+}
+
+// sqlToken returns the bounds of the token following offset i, skipping spaces and comments.
+// A quoted identifier or string is one token
+func sqlToken(stmt string, i int) (int, int) {
+	for i < len(stmt) {
+		switch c := stmt[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(stmt[i:], "--"):
+			end := strings.IndexByte(stmt[i:], '\n')
+			if end < 0 {
+				return len(stmt), len(stmt)
+			}
+			i += end
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return len(stmt), len(stmt)
+			}
+			i += end + 4
+		case c == '"' || c == '`' || c == '[' || c == '\'':
+			closer := c
+			if c == '[' {
+				closer = ']'
+			}
+			j := i + 1
+			for j < len(stmt) {
+				if stmt[j] == closer {
+					// a doubled quote is part of the name
+					if closer != ']' && j+1 < len(stmt) && stmt[j+1] == closer {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j < len(stmt) {
+				j++
+			}
+			return i, j
+		case c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80:
+			j := i + 1
+			for j < len(stmt) {
+				d := stmt[j]
+				if !(d == '_' || d == '$' || d >= 'a' && d <= 'z' || d >= 'A' && d <= 'Z' || d >= '0' && d <= '9' || d >= 0x80) {
+					break
+				}
+				j++
+			}
+			// a name qualified by its schema, e.g. main.t, is one token
+			if j < len(stmt) && stmt[j] == '.' {
+				_, end := sqlToken(stmt, j+1)
+				return i, end
+			}
+			return i, j
+		default:
+			return i, i + 1
+		}
+	}
+	return len(stmt), len(stmt)
+}
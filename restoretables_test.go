@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreTables(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(filepath.Join(dir, "live.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	const schema = `
+	create table orders (id integer primary key, item text);
+	create index orders_item on orders (item);
+	create table users (id integer primary key, name text);
+	insert into orders (item) values ('a'), ('b');
+	insert into users (name) values ('ann');
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	backupFile := filepath.Join(dir, "backup.db")
+	if err := Backup(db, backupFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("delete from orders where item = 'a'; insert into users (name) values ('bob')"); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := RestoreOptions{
+		Tables: []string{"orders"},
+		Rename: map[string]string{"orders": "orders_restored"},
+	}
+	if err := RestoreTables(db, backupFile, opts); err != nil {
+		t.Fatal(err)
+	}
+	count := func(q string) int {
+		t.Helper()
+		var n int
+		if err := row(db, []interface{}{&n}, q); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count("select count(*) from orders_restored"); n != 2 {
+		t.Fatalf("expected 2 restored orders, got %d", n)
+	}
+	if n := count("select count(*) from orders"); n != 1 {
+		t.Fatalf("expected live orders untouched, got %d", n)
+	}
+	if n := count("select count(*) from users"); n != 2 {
+		t.Fatalf("expected live users untouched, got %d", n)
+	}
+	if n := count("select count(*) from sqlite_master where name = 'orders_restored_item' and tbl_name = 'orders_restored'"); n != 1 {
+		t.Fatal("expected index of restored table")
+	}
+
+	if err := RestoreTables(db, backupFile, opts); err == nil {
+		t.Fatal("expected error restoring over an existing table")
+	}
+	opts.Replace = true
+	if err := RestoreTables(db, backupFile, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("select count(*) from orders_restored"); n != 2 {
+		t.Fatalf("expected replaced table to have 2 orders, got %d", n)
+	}
+	opts.Replace, opts.Append = false, true
+	opts.Tables, opts.Rename = []string{"users"}, nil
+	if err := RestoreTables(db, backupFile, opts); err == nil {
+		t.Fatal("expected appending duplicate keys to fail")
+	}
+	if n := count("select count(*) from users"); n != 2 {
+		t.Fatalf("expected failed restore to leave users alone, got %d", n)
+	}
+	if err := RestoreTables(db, backupFile, RestoreOptions{Tables: []string{"nonesuch"}}); err == nil {
+		t.Fatal("expected error for unknown table")
+	}
+
+	// a name with characters that mean something in a URI
+	odd := filepath.Join(dir, "backup #1?.db")
+	data, err := os.ReadFile(backupFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(odd, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts = RestoreOptions{Tables: []string{"orders"}, Rename: map[string]string{"orders": "orders_odd"}}
+	if err := RestoreTables(db, odd, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := count("select count(*) from orders_odd"); n != 2 {
+		t.Fatalf("expected restored table to have 2 orders, got %d", n)
+	}
+}
+
+func TestRenameCreate(t *testing.T) {
+	tests := []struct{ stmt, name, table, want string }{
+		{"CREATE TABLE t (n int)", "u", "", `CREATE TABLE "u" (n int)`},
+		{`CREATE TABLE IF NOT EXISTS "a b"(n int)`, "c", "", `CREATE TABLE IF NOT EXISTS "c"(n int)`},
+		{"CREATE UNIQUE INDEX /* x */ t_n ON t(n)", "u_n", "u", `CREATE UNIQUE INDEX /* x */ "u_n" ON "u"(n)`},
+	}
+	for _, tc := range tests {
+		got, err := renameCreate(tc.stmt, tc.name, tc.table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("got %s, want %s", got, tc.want)
+		}
+	}
+}
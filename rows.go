@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+)
+
+// QueryMap returns the rows of a query as maps of column names to values
+func QueryMap(db *sql.DB, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	return QueryMapContext(context.Background(), db, query, args...)
+}
+
+// QueryMapContext is QueryMap with a context
+func QueryMapContext(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	var maps []map[string]interface{}
+	var names []string
+	fn := func(columns []string, row []interface{}) {
+		if columns != nil {
+			names = columns
+		}
+		m := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			m[name] = row[i]
+		}
+		maps = append(maps, m)
+	}
+	return maps, queryContext(ctx, db, fn, query, args...)
+}
+
+// QueryStruct returns the rows of a query, each scanned into a T, which is either
+// a struct with a field for each column (named by its `db` tag, or else its name
+// regardless of case) or a single column value
+func QueryStruct[T any](db *sql.DB, query string, args ...interface{}) ([]T, error) {
+	return QueryStructContext[T](context.Background(), db, query, args...)
+}
+
+// QueryStructContext is QueryStruct with a context
+func QueryStructContext[T any](ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, Args(args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scan, err := rowScanner[T](rows)
+	if err != nil {
+		return nil, err
+	}
+	var list []T
+	for rows.Next() {
+		v, err := scan()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+// QueryJSON writes the rows of a query to w as a JSON array of objects, as they are read,
+// with the members of each object in the order of the columns. Blobs are base64 encoded
+func QueryJSON(db *sql.DB, w io.Writer, query string, args ...interface{}) error {
+	return QueryJSONContext(context.Background(), db, w, query, args...)
+}
+
+// QueryJSONContext is QueryJSON with a context
+func QueryJSONContext(ctx context.Context, db *sql.DB, w io.Writer, query string, args ...interface{}) error {
+	bw := bufio.NewWriter(w)
+	var keys [][]byte
+	var werr error
+	write := func(b []byte) {
+		if werr == nil {
+			_, werr = bw.Write(b)
+		}
+	}
+	fn := func(columns []string, row []interface{}) {
+		if columns != nil {
+			keys = make([][]byte, len(columns))
+			for i, name := range columns {
+				keys[i], _ = json.Marshal(name)
+			}
+			write([]byte("["))
+		} else {
+			write([]byte(","))
+		}
+		write([]byte("{"))
+		for i, v := range row {
+			if i > 0 {
+				write([]byte(","))
+			}
+			write(keys[i])
+			write([]byte(":"))
+			b, err := json.Marshal(v)
+			if err != nil && werr == nil {
+				werr = err
+			}
+			write(b)
+		}
+		write([]byte("}"))
+	}
+	if err := queryContext(ctx, db, fn, query, args...); err != nil {
+		return err
+	}
+	if keys == nil {
+		write([]byte("["))
+	}
+	write([]byte("]\n"))
+	if werr != nil {
+		return werr
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestQueryHelpers(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	const setup = `
+	create table people (id integer primary key, name text, age int, photo blob);
+	insert into people values (1, 'ann', 30, x'0102'), (2, 'bob', null, null);
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	maps, err := QueryMap(db, "select id, name, age from people order by id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(maps) != 2 || maps[0]["name"] != "ann" || maps[0]["age"] != int64(30) || maps[1]["age"] != nil {
+		t.Fatalf("unexpected maps: %v", maps)
+	}
+
+	type person struct {
+		ID       int64
+		FullName string `db:"name"`
+		Age      *int
+		Ignored  string `db:"-"`
+	}
+	people, err := QueryStruct[person](db, "select id, name, age from people where id > ? order by id", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 2 || people[0].FullName != "ann" || *people[0].Age != 30 || people[1].Age != nil {
+		t.Fatalf("unexpected people: %+v", people)
+	}
+	names, err := QueryStruct[string](db, "select name from people order by id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[1] != "bob" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+	if _, err := QueryStruct[person](db, "select photo from people"); err == nil {
+		t.Fatal("expected error for a column without a field")
+	}
+
+	var buf bytes.Buffer
+	if err := QueryJSON(db, &buf, "select id, name, photo from people order by id"); err != nil {
+		t.Fatal(err)
+	}
+	const want = `[{"id":1,"name":"ann","photo":"AQI="},{"id":2,"name":"bob","photo":null}]` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got %s, want %s", buf.String(), want)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := QueryJSON(db, &buf, "select * from people where id < 0"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Fatalf("expected empty array, got %s", buf.String())
+	}
+}
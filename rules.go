@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Expectations are rules about the rows of tables, declared per table, which
+// Evaluate checks, returning the rows that break them:
+//
+//	var e Expectations
+//	e.Table("users").NotNull("email").Unique("email").InRange("age", 0, 150)
+//	e.Table("orders").References("user_id", "users", "id").Check("positive total", "total > 0")
+//	violations, err := e.Evaluate(db)
+type Expectations struct {
+	Samples int // the rows returned with each violation, 5 if not set
+	tables  []*TableExpectations
+}
+
+// TableExpectations are the rules about the rows of a table
+type TableExpectations struct {
+	table string
+	rules []rule
+}
+
+// rule is an expectation, with the condition of the rows that break it
+type rule struct {
+	name  string
+	where string
+	args  []interface{}
+}
+
+// Violation is a rule broken by rows of a table
+type Violation struct {
+	Table   string
+	Rule    string
+	Count   int64           // the rows that break the rule
+	Columns []string        // the columns of the table, for the samples
+	Samples [][]interface{} // some of the rows that break the rule
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %d rows", v.Table, v.Rule, v.Count)
+}
+
+// Table returns the rules for the table, to add rules to
+func (e *Expectations) Table(name string) *TableExpectations {
+	for _, t := range e.tables {
+		if strings.EqualFold(t.table, name) {
+			return t
+		}
+	}
+	t := &TableExpectations{table: name}
+	e.tables = append(e.tables, t)
+	return t
+}
+
+func (t *TableExpectations) add(r rule) *TableExpectations {
+	t.rules = append(t.rules, r)
+	return t
+}
+
+// NotNull expects each of the columns to have a value in every row
+func (t *TableExpectations) NotNull(columns ...string) *TableExpectations {
+	for _, c := range columns {
+		t.add(rule{name: fmt.Sprintf("not null(%s)", c), where: quoteIdent(c) + " IS NULL"})
+	}
+	return t
+}
+
+// Unique expects no two rows to have the same values in the columns, ignoring NULLs
+func (t *TableExpectations) Unique(columns ...string) *TableExpectations {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	list := strings.Join(quoted, ", ")
+	where := fmt.Sprintf("(%s) IN (SELECT %s FROM %s GROUP BY %s HAVING count(*) > 1)", list, list, quoteIdent(t.table), list)
+	return t.add(rule{name: fmt.Sprintf("unique(%s)", strings.Join(columns, ", ")), where: where})
+}
+
+// InRange expects the values of the column to be from min to max, either of which
+// may be nil for no limit. NULLs are in range
+func (t *TableExpectations) InRange(column string, min, max interface{}) *TableExpectations {
+	col := quoteIdent(column)
+	var terms []string
+	var args []interface{}
+	if min != nil {
+		terms = append(terms, col+" < ?")
+		args = append(args, min)
+	}
+	if max != nil {
+		terms = append(terms, col+" > ?")
+		args = append(args, max)
+	}
+	if len(terms) == 0 {
+		return t
+	}
+	return t.add(rule{
+		name:  fmt.Sprintf("range(%s, %v, %v)", column, min, max),
+		where: strings.Join(terms, " OR "),
+		args:  args,
+	})
+}
+
+// References expects each value of the column other than NULL to be in the
+// column of the parent table, whether or not a foreign key enforces it
+func (t *TableExpectations) References(column, parent, parentColumn string) *TableExpectations {
+	col := quoteIdent(column)
+	where := fmt.Sprintf("%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s AS parent WHERE parent.%s = t.%s)", col, quoteIdent(parent), quoteIdent(parentColumn), col)
+	return t.add(rule{name: fmt.Sprintf("references(%s, %s.%s)", column, parent, parentColumn), where: where})
+}
+
+// Check expects the predicate, an SQL expression of the columns of the table, to be
+// true for every row; rows for which it's false or NULL break it. The table is named t
+func (t *TableExpectations) Check(name, predicate string, args ...interface{}) *TableExpectations {
+	return t.add(rule{name: name, where: fmt.Sprintf("NOT ifnull((%s), 0)", predicate), args: args})
+}
+
+// Evaluate checks the rules, returning a violation for each that's broken,
+// in the order they were declared
+func (e *Expectations) Evaluate(db *sql.DB) ([]Violation, error) {
+	return e.EvaluateContext(context.Background(), db)
+}
+
+// EvaluateContext is Evaluate, stopping if ctx is done
+func (e *Expectations) EvaluateContext(ctx context.Context, db *sql.DB) ([]Violation, error) {
+	samples := e.Samples
+	if samples <= 0 {
+		samples = 5
+	}
+	var violations []Violation
+	for _, t := range e.tables {
+		from := fmt.Sprintf("FROM %s AS t WHERE ", quoteIdent(t.table))
+		for _, r := range t.rules {
+			v := Violation{Table: t.table, Rule: r.name}
+			if err := db.QueryRowContext(ctx, "SELECT count(*) "+from+r.where, Args(r.args...)...).Scan(&v.Count); err != nil {
+				return nil, fmt.Errorf("rule %s of %s: %w", r.name, t.table, ContextError(ctx, err))
+			}
+			if v.Count == 0 {
+				continue
+			}
+			err := queryContext(ctx, db, func(columns []string, row []interface{}) {
+				if columns != nil {
+					v.Columns = columns
+				}
+				v.Samples = append(v.Samples, append([]interface{}(nil), row...))
+			}, fmt.Sprintf("SELECT * %s%s LIMIT %d", from, r.where, samples), r.args...)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s of %s: %w", r.name, t.table, ContextError(ctx, err))
+			}
+			violations = append(violations, v)
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestExpectations(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	const schema = `
+	create table users (id integer primary key, email text, age int);
+	insert into users values (1, 'a@x.com', 30), (2, 'a@x.com', 200), (3, null, 40), (4, 'd@x.com', null);
+	create table orders (id integer primary key, user_id int, total real);
+	insert into orders values (1, 1, 10), (2, 9, 5), (3, null, -1);
+	`
+	if _, err := ExecScript(db, schema); err != nil {
+		t.Fatal(err)
+	}
+
+	e := Expectations{Samples: 1}
+	e.Table("users").NotNull("email", "id").Unique("email").InRange("age", 0, 150).InRange("id", 1, nil)
+	e.Table("orders").References("user_id", "users", "id").Check("positive total", "total > ?", 0)
+	violations, err := e.Evaluate(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		table, rule string
+		count       int64
+		id          int64
+	}{
+		{"users", "not null(email)", 1, 3},
+		{"users", "unique(email)", 2, 1},
+		{"users", "range(age, 0, 150)", 1, 2},
+		{"orders", "references(user_id, users.id)", 1, 2},
+		{"orders", "positive total", 1, 3},
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("got %d violations, want %d: %v", len(violations), len(want), violations)
+	}
+	for i, w := range want {
+		v := violations[i]
+		if v.Table != w.table || v.Rule != w.rule || v.Count != w.count {
+			t.Errorf("violation %d: got %v, want %s: %s: %d rows", i, v, w.table, w.rule, w.count)
+			continue
+		}
+		if len(v.Samples) != 1 || len(v.Columns) != 3 || v.Columns[0] != "id" || v.Samples[0][0] != w.id {
+			t.Errorf("violation %d: got samples %v of %v", i, v.Samples, v.Columns)
+		}
+	}
+
+	var ok Expectations
+	ok.Table("users").NotNull("id")
+	if violations, err := ok.Evaluate(db); err != nil || len(violations) != 0 {
+		t.Errorf("got %v, %v", violations, err)
+	}
+	var bad Expectations
+	bad.Table("users").Check("broken", "nosuch > 1")
+	if _, err := bad.Evaluate(db); err == nil {
+		t.Error("expected an error for a bad predicate")
+	}
+}
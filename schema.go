@@ -0,0 +1,230 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaObject is a table, index, view, or trigger, with the statement that creates it
+type SchemaObject struct {
+	Kind         string // table, index, view, or trigger
+	Name         string
+	Table        string   // the table an index or trigger belongs to, or the name of a table or view
+	SQL          string   // the CREATE statement, as stored in sqlite_master
+	Virtual      bool     // a virtual table, e.g. one for full text search
+	Dependencies []string // objects that must exist before this one is created
+}
+
+// kindOrder is the order objects are created in, other things being equal
+var kindOrder = map[string]int{"table": 0, "view": 1, "index": 2, "trigger": 3}
+
+// shadowSuffixes are the suffixes of the tables that virtual table modules create to store their data
+var shadowSuffixes = []string{
+	"_content", "_segments", "_segdir", "_docsize", "_stat", // fts3/4
+	"_data", "_idx", "_config", // fts5
+	"_node", "_rowid", "_parent", // rtree
+}
+
+// Schema returns the objects of the main database in an order they can be created in:
+// each after the objects it depends on (foreign key references, the table of an index or
+// trigger, and the tables and views named by a view or trigger), otherwise in the order
+// tables, views, indexes, and triggers. Internal objects and the shadow tables of virtual tables,
+// which the virtual tables create themselves, are left out
+func Schema(db *sql.DB) ([]SchemaObject, error) {
+	rows, err := db.Query("SELECT type, name, tbl_name, sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var objects []SchemaObject
+	for rows.Next() {
+		var obj SchemaObject
+		if err := rows.Scan(&obj.Kind, &obj.Name, &obj.Table, &obj.SQL); err != nil {
+			return nil, err
+		}
+		obj.Virtual = obj.Kind == "table" && strings.HasPrefix(strings.ToUpper(obj.SQL), "CREATE VIRTUAL")
+		objects = append(objects, obj)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	objects = withoutShadows(objects)
+	names := make(map[string]string) // tables and views by lower case name
+	for _, obj := range objects {
+		if obj.Kind == "table" || obj.Kind == "view" {
+			names[strings.ToLower(obj.Name)] = obj.Name
+		}
+	}
+	for i := range objects {
+		obj := &objects[i]
+		deps := make(map[string]bool)
+		switch obj.Kind {
+		case "table":
+			if obj.Virtual {
+				break
+			}
+			fks, err := ForeignKeyList(db, obj.Name)
+			if err != nil {
+				return nil, fmt.Errorf("schema %s: %w", obj.Name, err)
+			}
+			for _, fk := range fks {
+				if name, ok := names[strings.ToLower(fk.Table)]; ok {
+					deps[name] = true
+				}
+			}
+		case "index":
+			deps[obj.Table] = true
+		case "view", "trigger":
+			if obj.Kind == "trigger" {
+				deps[obj.Table] = true
+			}
+			for _, ident := range sqlIdentifiers(obj.SQL) {
+				if name, ok := names[ident]; ok {
+					deps[name] = true
+				}
+			}
+		}
+		delete(deps, obj.Name)
+		for dep := range deps {
+			obj.Dependencies = append(obj.Dependencies, dep)
+		}
+		sort.Strings(obj.Dependencies)
+	}
+	return creationOrder(objects), nil
+}
+
+// withoutShadows removes the shadow tables of virtual tables
+func withoutShadows(objects []SchemaObject) []SchemaObject {
+	var virtual []string
+	for _, obj := range objects {
+		if obj.Virtual {
+			virtual = append(virtual, strings.ToLower(obj.Name))
+		}
+	}
+	if len(virtual) == 0 {
+		return objects
+	}
+	shadow := func(obj SchemaObject) bool {
+		name := strings.ToLower(obj.Name)
+		for _, v := range virtual {
+			for _, suffix := range shadowSuffixes {
+				if obj.Kind == "table" && name == v+suffix {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	kept := objects[:0]
+	for _, obj := range objects {
+		if !shadow(obj) {
+			kept = append(kept, obj)
+		}
+	}
+	return kept
+}
+
+// creationOrder sorts objects so each comes after its dependencies, preferring
+// kindOrder and then the original order among those that are ready. Objects
+// caught in a dependency cycle are placed in their original order at the end
+func creationOrder(objects []SchemaObject) []SchemaObject {
+	pending := append([]SchemaObject(nil), objects...)
+	created := make(map[string]bool)
+	sorted := make([]SchemaObject, 0, len(objects))
+	ready := func(obj SchemaObject) bool {
+		for _, dep := range obj.Dependencies {
+			if !created[dep] {
+				return false
+			}
+		}
+		return true
+	}
+	for len(pending) > 0 {
+		next := -1
+		for i, obj := range pending {
+			if ready(obj) && (next < 0 || kindOrder[obj.Kind] < kindOrder[pending[next].Kind]) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return append(sorted, pending...)
+		}
+		created[pending[next].Name] = true
+		sorted = append(sorted, pending[next])
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+	return sorted
+}
+
+// sqlIdentifiers returns the identifiers and keywords of a statement in lower case,
+// unquoted, skipping string literals and comments
+func sqlIdentifiers(stmt string) []string {
+	var idents []string
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == '\'':
+			// a quote inside a literal is doubled
+			j := i + 1
+			for j < len(stmt) && (stmt[j] != '\'' || j+1 < len(stmt) && stmt[j+1] == '\'') {
+				if stmt[j] == '\'' {
+					j++
+				}
+				j++
+			}
+			i = j + 1
+		case c == '"' || c == '`' || c == '[':
+			closer := c
+			if c == '[' {
+				closer = ']'
+			}
+			end := strings.IndexByte(stmt[i+1:], closer)
+			if end < 0 {
+				return idents
+			}
+			idents = append(idents, strings.ToLower(stmt[i+1:i+1+end]))
+			i += end + 2
+		case c == '-' && strings.HasPrefix(stmt[i:], "--"):
+			end := strings.IndexByte(stmt[i:], '\n')
+			if end < 0 {
+				return idents
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return idents
+			}
+			i += end + 4
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i + 1
+			for j < len(stmt) && (stmt[j] == '_' || stmt[j] == '$' || stmt[j] >= 'a' && stmt[j] <= 'z' || stmt[j] >= 'A' && stmt[j] <= 'Z' || stmt[j] >= '0' && stmt[j] <= '9') {
+				j++
+			}
+			idents = append(idents, strings.ToLower(stmt[i:j]))
+			i = j
+		default:
+			i++
+		}
+	}
+	return idents
+}
+
+// schemaTables returns the names of the tables in the order they can be created in
+func schemaTables(db *sql.DB) ([]string, error) {
+	objects, err := Schema(db)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for _, obj := range objects {
+		if obj.Kind == "table" {
+			tables = append(tables, obj.Name)
+		}
+	}
+	return tables, nil
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// created out of order, as a series of migrations might leave them
+	const schema = `
+	create table orders (id integer primary key, customer int, note text);
+	create view big_orders as select o.id, c.name from orders o join "customers" c on c.id = o.customer where o.note != 'customers';
+	create table customers (id integer primary key, name text);
+	create table audit (msg text);
+	create table lines (id integer primary key, order_id int references orders(id), item text);
+	create virtual table notes using fts4(body);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	// a foreign key added by rebuilding the table after the tables it refers to
+	const rebuild = `
+	pragma legacy_alter_table = on;
+	create table orders2 (id integer primary key, customer int references customers(id), note text);
+	insert into orders2 select * from orders;
+	drop table orders;
+	alter table orders2 rename to orders;
+	create trigger orders_audit after insert on orders begin
+		insert into audit values ('order ' || new.id);
+	end;
+	create index orders_customer on orders (customer);
+	`
+	if _, err := db.Exec(rebuild); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := Schema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	position := make(map[string]int)
+	for i, obj := range objects {
+		position[obj.Name] = i
+	}
+	for _, obj := range objects {
+		for _, dep := range obj.Dependencies {
+			if position[dep] > position[obj.Name] {
+				t.Fatalf("%s comes before its dependency %s: %v", obj.Name, dep, position)
+			}
+		}
+	}
+	if _, ok := position["notes_content"]; ok {
+		t.Fatal("expected shadow tables to be left out")
+	}
+	deps := map[string][]string{
+		"big_orders":      {"customers", "orders"},
+		"orders_audit":    {"audit", "orders"},
+		"lines":           {"orders"},
+		"orders":          {"customers"},
+		"orders_customer": {"orders"},
+	}
+	if len(objects) != 8 {
+		t.Fatalf("expected: %d objects but got: %d", 8, len(objects))
+	}
+	for _, obj := range objects {
+		if want, ok := deps[obj.Name]; ok && !reflect.DeepEqual(obj.Dependencies, want) {
+			t.Fatalf("%s expected dependencies: %v but got: %v", obj.Name, want, obj.Dependencies)
+		}
+		if obj.Name == "notes" && !obj.Virtual {
+			t.Fatal("expected notes to be virtual")
+		}
+	}
+
+	// recreating the objects in order succeeds
+	copied := memDB(t)
+	defer copied.Close()
+	copied.SetMaxOpenConns(1)
+	for _, obj := range objects {
+		if _, err := copied.Exec(obj.SQL); err != nil {
+			t.Fatalf("%s: %v", obj.Name, err)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DiffSchemas returns the statements that change the current schema into the desired one.
+// Columns added at the end of a table are added with ALTER TABLE when SQLite allows it,
+// otherwise the table is rebuilt: created anew under another name, its rows copied by
+// column name, the old table dropped and the new one renamed, with its indexes created
+// again. Views and triggers that use a rebuilt or dropped table are dropped first and
+// created again afterwards. The statements are meant to run in one transaction with
+// foreign key enforcement off, as ApplySchema runs them
+func DiffSchemas(current, desired *SchemaInfo) ([]Statement, error) {
+	var stmts []string
+	add := func(format string, args ...interface{}) {
+		stmts = append(stmts, fmt.Sprintf(format, args...))
+	}
+
+	// the tables that are dropped or rebuilt, by lower case name
+	affected := make(map[string]bool)
+	rebuilt := make(map[string]bool)
+	var alters []string
+	for _, cur := range current.Tables {
+		des := desired.Table(cur.Name)
+		if des == nil {
+			affected[strings.ToLower(cur.Name)] = true
+			continue
+		}
+		added, same, err := addedColumns(cur, *des)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case same:
+		case added != nil:
+			for _, def := range added {
+				alters = append(alters, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", quoteIdent(des.Name), def))
+			}
+		default:
+			affected[strings.ToLower(cur.Name)] = true
+			rebuilt[strings.ToLower(cur.Name)] = true
+		}
+	}
+
+	// views and triggers go first, as they may use what is changed
+	var dropViews []ViewInfo
+	droppedViews := make(map[string]bool)
+	for _, v := range current.Views {
+		des := desired.View(v.Name)
+		drop := des == nil || sqlKey(des.SQL) != sqlKey(v.SQL) || usesAny(v.SQL, affected)
+		if drop {
+			// the views that use this one are dropped too
+			affected[strings.ToLower(v.Name)] = true
+			droppedViews[strings.ToLower(v.Name)] = true
+			dropViews = append(dropViews, v)
+		}
+	}
+	droppedTriggers := make(map[string]bool)
+	for _, tr := range current.Triggers {
+		des := desired.trigger(tr.Name)
+		if des == nil || sqlKey(des.SQL) != sqlKey(tr.SQL) || affected[strings.ToLower(tr.Table)] || usesAny(tr.SQL, affected) {
+			droppedTriggers[strings.ToLower(tr.Name)] = true
+			add("DROP TRIGGER IF EXISTS %s", quoteIdent(tr.Name))
+		}
+	}
+	for i := len(dropViews) - 1; i >= 0; i-- {
+		add("DROP VIEW %s", quoteIdent(dropViews[i].Name))
+	}
+
+	// indexes changed or dropped on the tables that are kept
+	for _, cur := range current.Tables {
+		des := desired.Table(cur.Name)
+		if des == nil || rebuilt[strings.ToLower(cur.Name)] {
+			continue
+		}
+		for _, idx := range cur.Indexes {
+			if idx.SQL == "" {
+				continue
+			}
+			if d := des.index(idx.Name); d == nil || sqlKey(d.SQL) != sqlKey(idx.SQL) {
+				add("DROP INDEX %s", quoteIdent(idx.Name))
+			}
+		}
+	}
+	for _, cur := range current.Tables {
+		if desired.Table(cur.Name) == nil {
+			add("DROP TABLE %s", quoteIdent(cur.Name))
+		}
+	}
+
+	stmts = append(stmts, alters...)
+	for _, des := range desired.Tables {
+		cur := current.Table(des.Name)
+		switch {
+		case cur == nil:
+			add("%s", des.SQL)
+		case rebuilt[strings.ToLower(des.Name)]:
+			temp := "_new_" + des.Name
+			create, err := renameCreate(des.SQL, temp, "")
+			if err != nil {
+				return nil, err
+			}
+			columns := copiedColumns(*cur, des)
+			add("%s", create)
+			if columns != "" {
+				add("INSERT INTO %s (%s) SELECT %s FROM %s", quoteIdent(temp), columns, columns, quoteIdent(cur.Name))
+			}
+			add("DROP TABLE %s", quoteIdent(cur.Name))
+			add("ALTER TABLE %s RENAME TO %s", quoteIdent(temp), quoteIdent(des.Name))
+		}
+		// indexes are created after any rows are copied
+		for i := len(des.Indexes) - 1; i >= 0; i-- {
+			idx := des.Indexes[i]
+			if idx.SQL == "" {
+				continue
+			}
+			if cur != nil && !rebuilt[strings.ToLower(des.Name)] {
+				if c := cur.index(idx.Name); c != nil && sqlKey(c.SQL) == sqlKey(idx.SQL) {
+					continue
+				}
+			}
+			add("%s", idx.SQL)
+		}
+	}
+
+	for _, v := range desired.Views {
+		if current.View(v.Name) == nil || droppedViews[strings.ToLower(v.Name)] {
+			add("%s", v.SQL)
+		}
+	}
+	for _, tr := range desired.Triggers {
+		if current.trigger(tr.Name) == nil || droppedTriggers[strings.ToLower(tr.Name)] {
+			add("%s", tr.SQL)
+		}
+	}
+
+	list := make([]Statement, len(stmts))
+	for i, s := range stmts {
+		list[i] = Statement{Text: s, Line: i + 1}
+	}
+	return list, nil
+}
+
+// ApplySchema changes the schema of db to the one created by the desiredDDL script,
+// keeping the rows of the tables that remain, and returns the statements it ran.
+// The script is run in a scratch database to learn the desired schema, which DiffSchemas
+// compares with the current one. The changes are made in one transaction with foreign key
+// enforcement off, and are rolled back if any foreign key is broken by them
+func ApplySchema(db *sql.DB, desiredDDL string) ([]Statement, error) {
+	return ApplySchemaContext(context.Background(), db, desiredDDL)
+}
+
+// ApplySchemaContext is ApplySchema with a context
+func ApplySchemaContext(ctx context.Context, db *sql.DB, desiredDDL string) ([]Statement, error) {
+	scratch, err := Open(":memory:")
+	if err != nil {
+		return nil, err
+	}
+	defer Close(scratch)
+	// each connection to :memory: is a database of its own
+	scratch.SetMaxOpenConns(1)
+	if _, err := ExecScriptContext(ctx, scratch, desiredDDL); err != nil {
+		return nil, fmt.Errorf("desired schema: %w", err)
+	}
+	desired, err := InspectSchema(scratch)
+	if err != nil {
+		return nil, err
+	}
+	current, err := InspectSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := DiffSchemas(current, desired)
+	if err != nil || len(stmts) == 0 {
+		return stmts, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// foreign keys can only be turned off outside of a transaction
+	var enforced bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enforced); err != nil {
+		return nil, err
+	}
+	if enforced {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys=OFF"); err != nil {
+			return nil, err
+		}
+		defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys=ON")
+	}
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return nil, err
+	}
+	for _, stmt := range stmts {
+		if _, err := conn.ExecContext(ctx, stmt.Text); err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+			return stmts, fmt.Errorf("apply schema: %s: %w", stmt.Text, err)
+		}
+	}
+	var table string
+	switch err := conn.QueryRowContext(ctx, "SELECT \"table\" FROM pragma_foreign_key_check").Scan(&table); err {
+	case sql.ErrNoRows:
+	case nil:
+		conn.ExecContext(context.Background(), "ROLLBACK")
+		return stmts, fmt.Errorf("apply schema: foreign key violated in %s", table)
+	default:
+		conn.ExecContext(context.Background(), "ROLLBACK")
+		return stmts, err
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		conn.ExecContext(context.Background(), "ROLLBACK")
+		return stmts, err
+	}
+	return stmts, nil
+}
+
+// trigger returns the named trigger, or nil if there is none
+func (s *SchemaInfo) trigger(name string) *TriggerInfo {
+	for i := range s.Triggers {
+		if strings.EqualFold(s.Triggers[i].Name, name) {
+			return &s.Triggers[i]
+		}
+	}
+	return nil
+}
+
+// index returns the named index, or nil if there is none
+func (t *TableInfo) index(name string) *IndexInfo {
+	for i := range t.Indexes {
+		if strings.EqualFold(t.Indexes[i].Name, name) {
+			return &t.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// usesAny reports whether a statement names any of the objects
+func usesAny(stmt string, names map[string]bool) bool {
+	for _, ident := range sqlIdentifiers(stmt) {
+		if names[ident] {
+			return true
+		}
+	}
+	return false
+}
+
+// copiedColumns returns the quoted names of the stored columns a rebuilt table keeps
+func copiedColumns(cur, des TableInfo) string {
+	var names []string
+	for _, c := range des.Columns {
+		if c.Hidden != 0 {
+			continue
+		}
+		if old := cur.Column(c.Name); old != nil && old.Hidden == 0 {
+			names = append(names, quoteIdent(c.Name))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// tableDefinition splits a CREATE TABLE statement into the keys of its column definitions,
+// of its table constraints, and of what follows them, along with the column definitions
+func tableDefinition(stmt string) (columns, constraints []string, tail string, defs [][]dumpToken, err error) {
+	s := &dumpScanner{src: stmt, dialect: DialectSQLite}
+	st, _, err := s.statement()
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	open := -1
+	for i, t := range st.tokens {
+		if t.punct("(") {
+			open = i
+			break
+		}
+	}
+	if open < 0 {
+		return nil, nil, "", nil, fmt.Errorf("no column definitions: %s", stmt)
+	}
+	body, end := group(st.tokens, open)
+	for _, item := range splitList(body) {
+		if len(item) > 0 && item[0].is("CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN") {
+			constraints = append(constraints, tokenKey(item))
+			continue
+		}
+		columns = append(columns, tokenKey(item))
+		defs = append(defs, item)
+	}
+	return columns, constraints, tokenKey(st.tokens[end:]), defs, nil
+}
+
+// addedColumns compares the definitions of a table, reporting whether they are the same,
+// or else the definitions of the columns that can be added to make them the same, which
+// is nil if the table must be rebuilt
+func addedColumns(cur, des TableInfo) ([]string, bool, error) {
+	curCols, curCons, curTail, _, err := tableDefinition(cur.SQL)
+	if err != nil {
+		return nil, false, fmt.Errorf("table %s: %w", cur.Name, err)
+	}
+	desCols, desCons, desTail, defs, err := tableDefinition(des.SQL)
+	if err != nil {
+		return nil, false, fmt.Errorf("table %s: %w", des.Name, err)
+	}
+	if cur.Virtual != des.Virtual || curTail != desTail || strings.Join(curCons, ",") != strings.Join(desCons, ",") || len(desCols) < len(curCols) {
+		return nil, false, nil
+	}
+	for i := range curCols {
+		if curCols[i] != desCols[i] {
+			return nil, false, nil
+		}
+	}
+	if len(desCols) == len(curCols) {
+		return nil, true, nil
+	}
+	if cur.Virtual {
+		return nil, false, nil
+	}
+	var added []string
+	for _, def := range defs[len(curCols):] {
+		if !addable(des, def) {
+			return nil, false, nil
+		}
+		added = append(added, renderTokens(def))
+	}
+	return added, false, nil
+}
+
+// addable reports whether ALTER TABLE ADD COLUMN can add a column: it can't be in a key,
+// be stored if generated, or have a default that isn't constant, and if it is NOT NULL
+// it must have a default that isn't NULL
+func addable(table TableInfo, def []dumpToken) bool {
+	if len(def) == 0 || !def[0].isName() {
+		return false
+	}
+	for _, t := range def {
+		if t.is("PRIMARY", "UNIQUE", "STORED") {
+			return false
+		}
+	}
+	c := table.Column(def[0].text)
+	if c == nil {
+		return false
+	}
+	d := strings.ToUpper(c.Default)
+	if strings.HasPrefix(d, "(") || strings.HasPrefix(d, "CURRENT_") {
+		return false
+	}
+	return !c.NotNull || (d != "" && d != "NULL")
+}
+
+// sqlKey returns a statement in a form that is the same for statements that differ
+// only in spacing, comments, the case of keywords and names, or how names are quoted
+func sqlKey(stmt string) string {
+	s := &dumpScanner{src: stmt, dialect: DialectSQLite}
+	st, _, err := s.statement()
+	if err != nil {
+		return Normalize(stmt)
+	}
+	return tokenKey(st.tokens)
+}
+
+// tokenKey joins tokens as sqlKey does
+func tokenKey(tokens []dumpToken) string {
+	keys := make([]string, len(tokens))
+	for i, t := range tokens {
+		switch t.kind {
+		case tkWord, tkIdent:
+			keys[i] = strings.ToLower(t.text)
+		default:
+			keys[i] = t.sql()
+		}
+	}
+	return strings.Join(keys, " ")
+}
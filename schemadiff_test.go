@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	const before = `
+create table author (id integer primary key, name text);
+create table book (id integer primary key, author_id integer references author(id), title text, pages int);
+create index book_title on book(title);
+create index book_pages on book(pages);
+create table scratch (x);
+create view titles as select title from book;
+create view authors as select name from author;
+create trigger author_gone after delete on author begin delete from book where author_id = old.id; end;
+`
+	const after = `
+CREATE TABLE author (
+	id   INTEGER PRIMARY KEY,
+	name TEXT,
+	born TEXT DEFAULT '1900-01-01',
+	country TEXT
+);
+create table book (id integer primary key, author_id integer not null references author(id), title text not null);
+create index book_title on book(title collate nocase);
+create view titles as select title from book;
+create view authors as select name, born from author;
+create trigger author_gone after delete on author begin delete from book where author_id = old.id; end;
+create table tag (book_id integer references book(id), tag text);
+`
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(before); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into author values(1, 'Le Guin'); insert into book values(1, 1, 'Lathe', 184), (2, 1, 'Tehanu', 252)"); err != nil {
+		t.Fatal(err)
+	}
+
+	stmts, err := ApplySchema(db, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var script []string
+	for _, s := range stmts {
+		script = append(script, s.Text)
+	}
+	text := strings.Join(script, ";\n")
+	for _, want := range []string{
+		`DROP TABLE "scratch"`,
+		`ALTER TABLE "author" ADD COLUMN born TEXT DEFAULT '1900-01-01'`,
+		`ALTER TABLE "author" ADD COLUMN country TEXT`,
+		`INSERT INTO "_new_book" ("id", "author_id", "title") SELECT "id", "author_id", "title" FROM "book"`,
+		`ALTER TABLE "_new_book" RENAME TO "book"`,
+		`DROP VIEW "authors"`,
+		`DROP VIEW "titles"`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s in:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "_new_author") {
+		t.Errorf("author should not be rebuilt:\n%s", text)
+	}
+
+	var title string
+	var born string
+	if err := db.QueryRow("select title, born from book join author on author.id = author_id where book.id = 2").Scan(&title, &born); err != nil {
+		t.Fatal(err)
+	}
+	if title != "Tehanu" || born != "1900-01-01" {
+		t.Fatalf("got %q %q", title, born)
+	}
+
+	// the schema now matches, so there is nothing left to do
+	stmts, err = ApplySchema(db, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 0 {
+		t.Fatalf("expected no changes, got %v", stmts)
+	}
+	info, err := InspectSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := info.Table("book")
+	if book == nil || book.index("book_pages") != nil || !strings.Contains(strings.ToLower(book.index("book_title").SQL), "nocase") {
+		t.Fatalf("unexpected book table: %+v", book)
+	}
+	if info.trigger("author_gone") == nil || info.View("titles") == nil {
+		t.Fatalf("expected the trigger and view to be created again: %+v", info)
+	}
+}
+
+func TestApplySchemaErrors(t *testing.T) {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("create table parent (id integer primary key); create table child (parent_id int, n int); insert into child values (7, 1)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ApplySchema(db, "create table parent (id integer primary key"); err == nil {
+		t.Fatal("expected error for an invalid schema")
+	}
+	// the new foreign key is broken by the existing row, so nothing changes
+	_, err := ApplySchema(db, "create table parent (id integer primary key); create table child (parent_id int references parent(id), n int);")
+	if err == nil || !strings.Contains(err.Error(), "foreign key") {
+		t.Fatalf("expected a foreign key violation, got %v", err)
+	}
+	fks, err := ForeignKeyList(db, "child")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fks) != 0 {
+		t.Fatalf("expected the change to be rolled back: %+v", fks)
+	}
+	// a new NOT NULL column without a default can't hold the existing rows
+	if _, err := ApplySchema(db, "create table parent (id integer primary key); create table child (parent_id int, n int, m int not null);"); err == nil {
+		t.Fatal("expected error adding a NOT NULL column to a table with rows")
+	}
+}
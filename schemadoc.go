@@ -0,0 +1,310 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// CommentsTable holds the comments on tables and columns that DocumentSchema
+// includes, a row for each, with the column empty for a comment on the table
+const CommentsTable = "schema_comments"
+
+// DocFormat is the format of the documentation written by DocumentSchema
+type DocFormat int
+
+const (
+	DocMarkdown DocFormat = iota
+	DocHTML
+)
+
+// SetComment records a comment on a table, or on its column if column isn't empty,
+// in CommentsTable, which is created if need be. An empty comment removes it
+func SetComment(db *sql.DB, table, column, comment string) error {
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (tbl TEXT NOT NULL, col TEXT NOT NULL DEFAULT '', comment TEXT NOT NULL, PRIMARY KEY (tbl, col))", CommentsTable)
+	if _, err := db.Exec(create); err != nil {
+		return err
+	}
+	if comment == "" {
+		_, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE tbl = ? AND col = ?", CommentsTable), table, column)
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("INSERT OR REPLACE INTO %s (tbl, col, comment) VALUES (?, ?, ?)", CommentsTable), table, column, comment)
+	return err
+}
+
+// schemaComments returns the comments by lower case table and column names
+func schemaComments(db *sql.DB) (map[string]string, error) {
+	comments := make(map[string]string)
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", CommentsTable).Scan(&n); err != nil || n == 0 {
+		return comments, err
+	}
+	rows, err := db.Query(fmt.Sprintf("SELECT tbl, col, comment FROM %s", CommentsTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column, comment string
+		if err := rows.Scan(&table, &column, &comment); err != nil {
+			return nil, err
+		}
+		comments[commentKey(table, column)] = comment
+	}
+	return comments, rows.Err()
+}
+
+func commentKey(table, column string) string {
+	return strings.ToLower(table) + "\x00" + strings.ToLower(column)
+}
+
+// docTable is a table or view as the documentation templates see it
+type docTable struct {
+	Name         string
+	Comment      string
+	SQL          string
+	Columns      []docColumn
+	Indexes      []docIndex
+	References   []docReference
+	ReferencedBy []docReference
+}
+
+type docColumn struct {
+	Name, Type, Default, Key, Comment string
+	NotNull                           bool
+}
+
+type docIndex struct {
+	Name, Columns, Where string
+	Unique               bool
+}
+
+// docReference is a foreign key, from the columns of a table to those of another
+type docReference struct {
+	Table, From, To, Actions string
+}
+
+// DocumentSchema writes documentation of the tables and views of the database,
+// with their columns, indexes, and the foreign keys between them, and the comments
+// recorded with SetComment
+func DocumentSchema(db *sql.DB, w io.Writer, format DocFormat) error {
+	info, err := InspectSchema(db)
+	if err != nil {
+		return err
+	}
+	comments, err := schemaComments(db)
+	if err != nil {
+		return err
+	}
+
+	var tables, views []docTable
+	referencedBy := make(map[string][]docReference)
+	for _, t := range info.Tables {
+		if strings.EqualFold(t.Name, CommentsTable) {
+			continue
+		}
+		table := docTable{Name: t.Name, Comment: comments[commentKey(t.Name, "")], SQL: t.SQL}
+		table.Columns = docColumns(t.Name, t.Columns, comments)
+		for _, idx := range t.Indexes {
+			if idx.Origin == "pk" {
+				continue
+			}
+			terms := idx.Terms
+			if len(terms) == 0 {
+				terms = idx.Columns
+			}
+			table.Indexes = append(table.Indexes, docIndex{Name: idx.Name, Columns: strings.Join(terms, ", "), Where: idx.Where, Unique: idx.Unique})
+		}
+		for _, ref := range docReferences(info, t) {
+			table.References = append(table.References, ref)
+			key := strings.ToLower(ref.Table)
+			referencedBy[key] = append(referencedBy[key], docReference{Table: t.Name, From: ref.From, To: ref.To, Actions: ref.Actions})
+		}
+		tables = append(tables, table)
+	}
+	for i := range tables {
+		tables[i].ReferencedBy = referencedBy[strings.ToLower(tables[i].Name)]
+	}
+	for _, v := range info.Views {
+		views = append(views, docTable{Name: v.Name, Comment: comments[commentKey(v.Name, "")], SQL: v.SQL, Columns: docColumns(v.Name, v.Columns, comments)})
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+
+	data := struct {
+		Tables, Views []docTable
+	}{tables, views}
+	switch format {
+	case DocMarkdown:
+		return markdownDoc.Execute(w, data)
+	case DocHTML:
+		return htmlDoc.Execute(w, data)
+	}
+	return fmt.Errorf("unknown documentation format: %d", format)
+}
+
+func docColumns(table string, columns []ColumnInfo, comments map[string]string) []docColumn {
+	var list []docColumn
+	for _, c := range columns {
+		if c.Hidden == 1 {
+			continue
+		}
+		col := docColumn{Name: c.Name, Type: c.Type, Default: c.Default, NotNull: c.NotNull, Comment: comments[commentKey(table, c.Name)]}
+		if c.PrimaryKey > 0 {
+			col.Key = "PK"
+		}
+		list = append(list, col)
+	}
+	return list
+}
+
+// docReferences returns the foreign keys of a table, with the columns of each together
+func docReferences(info *SchemaInfo, t TableInfo) []docReference {
+	var refs []docReference
+	var from, to []string
+	for i, fk := range t.ForeignKeys {
+		from = append(from, fk.From)
+		to = append(to, fk.To)
+		if i+1 < len(t.ForeignKeys) && t.ForeignKeys[i+1].ID == fk.ID {
+			continue
+		}
+		// an empty column refers to the primary key of the parent
+		if parent := info.Table(fk.Table); parent != nil && to[0] == "" && len(parent.PrimaryKey) == len(to) {
+			to = parent.PrimaryKey
+		}
+		var actions []string
+		if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+			actions = append(actions, "ON DELETE "+fk.OnDelete)
+		}
+		if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+			actions = append(actions, "ON UPDATE "+fk.OnUpdate)
+		}
+		refs = append(refs, docReference{Table: fk.Table, From: strings.Join(from, ", "), To: strings.Join(to, ", "), Actions: strings.Join(actions, " ")})
+		from, to = nil, nil
+	}
+	return refs
+}
+
+// cell escapes text for a Markdown table cell
+func cell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+var markdownDoc = template.Must(template.New("markdown").Funcs(template.FuncMap{
+	"cell":  cell,
+	"yesNo": yesNo,
+}).Parse(`# Schema
+{{if .Tables}}
+## Tables
+{{range .Tables}}
+### {{.Name}}
+{{if .Comment}}
+{{.Comment}}
+{{end}}
+| Column | Type | Not null | Default | Key | Comment |
+|--------|------|----------|---------|-----|---------|
+{{range .Columns}}| {{cell .Name}} | {{cell .Type}} | {{yesNo .NotNull}} | {{cell .Default}} | {{.Key}} | {{cell .Comment}} |
+{{end}}{{if .Indexes}}
+**Indexes**
+
+{{range .Indexes}}- {{.Name}}{{if .Unique}} (unique){{end}}: {{.Columns}}{{if .Where}} where {{.Where}}{{end}}
+{{end}}{{end}}{{if .References}}
+**References**
+
+{{range .References}}- {{.From}} → {{.Table}} ({{.To}}){{if .Actions}} {{.Actions}}{{end}}
+{{end}}{{end}}{{if .ReferencedBy}}
+**Referenced by**
+
+{{range .ReferencedBy}}- {{.Table}} ({{.From}}){{if .Actions}} {{.Actions}}{{end}}
+{{end}}{{end}}{{end}}{{end}}{{if .Views}}
+## Views
+{{range .Views}}
+### {{.Name}}
+{{if .Comment}}
+{{.Comment}}
+{{end}}
+| Column | Type | Comment |
+|--------|------|---------|
+{{range .Columns}}| {{cell .Name}} | {{cell .Type}} | {{cell .Comment}} |
+{{end}}
+` + "```sql\n{{.SQL}}\n```" + `
+{{end}}{{end}}`))
+
+var htmlDoc = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{
+	"yesNo": yesNo,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Schema</title></head>
+<body>
+<h1>Schema</h1>
+{{- if .Tables}}
+<h2>Tables</h2>
+{{- range .Tables}}
+<h3 id="{{.Name}}">{{.Name}}</h3>
+{{- if .Comment}}
+<p>{{.Comment}}</p>
+{{- end}}
+<table>
+<tr><th>Column</th><th>Type</th><th>Not null</th><th>Default</th><th>Key</th><th>Comment</th></tr>
+{{- range .Columns}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{yesNo .NotNull}}</td><td>{{.Default}}</td><td>{{.Key}}</td><td>{{.Comment}}</td></tr>
+{{- end}}
+</table>
+{{- if .Indexes}}
+<h4>Indexes</h4>
+<ul>
+{{- range .Indexes}}
+<li>{{.Name}}{{if .Unique}} (unique){{end}}: {{.Columns}}{{if .Where}} where {{.Where}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .References}}
+<h4>References</h4>
+<ul>
+{{- range .References}}
+<li>{{.From}} → <a href="#{{.Table}}">{{.Table}}</a> ({{.To}}){{if .Actions}} {{.Actions}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .ReferencedBy}}
+<h4>Referenced by</h4>
+<ul>
+{{- range .ReferencedBy}}
+<li><a href="#{{.Table}}">{{.Table}}</a> ({{.From}}){{if .Actions}} {{.Actions}}{{end}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Views}}
+<h2>Views</h2>
+{{- range .Views}}
+<h3 id="{{.Name}}">{{.Name}}</h3>
+{{- if .Comment}}
+<p>{{.Comment}}</p>
+{{- end}}
+<table>
+<tr><th>Column</th><th>Type</th><th>Comment</th></tr>
+{{- range .Columns}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td>{{.Comment}}</td></tr>
+{{- end}}
+</table>
+<pre><code>{{.SQL}}</code></pre>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
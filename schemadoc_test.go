@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocumentSchema(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	const schema = `
+	create table users (id integer primary key, email text not null unique, status text default 'active');
+	create table posts (id integer primary key, author_id int references users on delete cascade, title text);
+	create index posts_title on posts(title) where title is not null;
+	create view authors as select distinct u.email from users u join posts p on p.author_id = u.id;
+	`
+	if _, err := ExecScript(db, schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetComment(db, "users", "", "People who can sign in"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetComment(db, "users", "email", "Unique | lower case"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := DocumentSchema(db, &buf, DocMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, want := range []string{
+		"### users\n\nPeople who can sign in\n",
+		"| email | text | yes |  |  | Unique \\| lower case |",
+		"| status | text | no | 'active' |  |  |",
+		"| id | integer | no |  | PK |  |",
+		"- posts_title: title where title is not null",
+		"- author_id → users (id) ON DELETE CASCADE",
+		"**Referenced by**\n\n- posts (author_id) ON DELETE CASCADE",
+		"### authors",
+		"```sql\nCREATE VIEW authors",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("expected documentation to contain %q:\n%s", want, doc)
+		}
+	}
+	if strings.Contains(doc, CommentsTable) {
+		t.Fatalf("expected no documentation of the comments table:\n%s", doc)
+	}
+
+	buf.Reset()
+	if err := DocumentSchema(db, &buf, DocHTML); err != nil {
+		t.Fatal(err)
+	}
+	doc = buf.String()
+	for _, want := range []string{
+		`<h3 id="users">users</h3>`,
+		"<p>People who can sign in</p>",
+		"<td>&#39;active&#39;</td>",
+		`<li>author_id → <a href="#users">users</a> (id) ON DELETE CASCADE</li>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Fatalf("expected documentation to contain %q:\n%s", want, doc)
+		}
+	}
+
+	if err := SetComment(db, "users", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := DocumentSchema(db, &buf, DocMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "sign in") {
+		t.Fatal("expected the removed comment to be gone")
+	}
+}
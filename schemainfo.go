@@ -0,0 +1,211 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SchemaInfo describes the tables, views, and triggers of a database
+type SchemaInfo struct {
+	Tables   []TableInfo
+	Views    []ViewInfo
+	Triggers []TriggerInfo
+}
+
+// TableInfo describes a table, with its columns, indexes, and foreign keys
+type TableInfo struct {
+	Name         string
+	SQL          string // the CREATE TABLE statement
+	Virtual      bool
+	WithoutRowID bool
+	Columns      []ColumnInfo
+	PrimaryKey   []string // the primary key columns in key order, empty for a rowid table without one
+	Indexes      []IndexInfo
+	ForeignKeys  []ForeignKey
+}
+
+// ColumnInfo is a row of PRAGMA table_xinfo
+type ColumnInfo struct {
+	Name       string
+	Type       string // the declared type, as written
+	NotNull    bool
+	Default    string // the default value as an SQL expression, empty if there is none
+	PrimaryKey int    // the position of the column in the primary key, 0 if not in it
+	Hidden     int    // 1 for a hidden column of a virtual table, 2 or 3 for a generated column
+}
+
+// IndexInfo describes an index of a table
+type IndexInfo struct {
+	Name    string
+	Table   string
+	SQL     string // the CREATE INDEX statement, empty for an index made by a constraint
+	Unique  bool
+	Origin  string // "c" for CREATE INDEX, "u" for a UNIQUE constraint, "pk" for a PRIMARY KEY
+	Partial bool
+	Columns []string // the indexed columns, with "" for an expression
+	Terms   []string // the indexed columns and expressions as written, with any COLLATE or DESC
+	Where   string   // the condition of a partial index, without the WHERE
+}
+
+// ViewInfo describes a view and the columns of its result
+type ViewInfo struct {
+	Name    string
+	SQL     string
+	Columns []ColumnInfo
+}
+
+// TriggerInfo describes a trigger
+type TriggerInfo struct {
+	Name  string
+	Table string // the table or view the trigger is on
+	SQL   string
+}
+
+// InspectSchema returns the schema of the main database, read from sqlite_master and
+// the table_xinfo, index_list, index_info, and foreign_key_list pragmas. The objects are
+// listed in the order Schema returns them, which they can be created in
+func InspectSchema(db *sql.DB) (*SchemaInfo, error) {
+	objects, err := Schema(db)
+	if err != nil {
+		return nil, err
+	}
+	info := &SchemaInfo{}
+	for _, obj := range objects {
+		switch obj.Kind {
+		case "table":
+			table, err := inspectTable(db, obj)
+			if err != nil {
+				return nil, fmt.Errorf("schema %s: %w", obj.Name, err)
+			}
+			info.Tables = append(info.Tables, table)
+		case "view":
+			columns, err := tableColumns(db, obj.Name)
+			if err != nil {
+				return nil, fmt.Errorf("schema %s: %w", obj.Name, err)
+			}
+			info.Views = append(info.Views, ViewInfo{Name: obj.Name, SQL: obj.SQL, Columns: columns})
+		case "trigger":
+			info.Triggers = append(info.Triggers, TriggerInfo{Name: obj.Name, Table: obj.Table, SQL: obj.SQL})
+		}
+	}
+	return info, nil
+}
+
+// Table returns the named table, or nil if there is none
+func (s *SchemaInfo) Table(name string) *TableInfo {
+	for i := range s.Tables {
+		if strings.EqualFold(s.Tables[i].Name, name) {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// View returns the named view, or nil if there is none
+func (s *SchemaInfo) View(name string) *ViewInfo {
+	for i := range s.Views {
+		if strings.EqualFold(s.Views[i].Name, name) {
+			return &s.Views[i]
+		}
+	}
+	return nil
+}
+
+// Column returns the named column, or nil if there is none
+func (t *TableInfo) Column(name string) *ColumnInfo {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// inspectTable reads the columns, indexes, and foreign keys of a table
+func inspectTable(db *sql.DB, obj SchemaObject) (TableInfo, error) {
+	table := TableInfo{Name: obj.Name, SQL: obj.SQL, Virtual: obj.Virtual, WithoutRowID: withoutRowID(obj.SQL)}
+	var err error
+	if table.Columns, err = tableColumns(db, obj.Name); err != nil {
+		return table, err
+	}
+	keys := make([]string, len(table.Columns))
+	n := 0
+	for _, c := range table.Columns {
+		if c.PrimaryKey > 0 && c.PrimaryKey <= len(keys) {
+			keys[c.PrimaryKey-1] = c.Name
+			n++
+		}
+	}
+	table.PrimaryKey = keys[:n]
+	if obj.Virtual {
+		// virtual tables have no indexes or foreign keys of their own
+		return table, nil
+	}
+	if table.Indexes, err = tableIndexes(db, obj.Name); err != nil {
+		return table, err
+	}
+	if table.ForeignKeys, err = ForeignKeyList(db, obj.Name); err != nil {
+		return table, err
+	}
+	return table, nil
+}
+
+// withoutRowID reports whether a CREATE TABLE statement ends with WITHOUT ROWID
+func withoutRowID(stmt string) bool {
+	idents := sqlIdentifiers(stmt)
+	n := len(idents)
+	return n >= 2 && idents[n-2] == "without" && idents[n-1] == "rowid"
+}
+
+// tableColumns returns the columns of a table or view, including hidden and generated ones
+func tableColumns(db *sql.DB, table string) ([]ColumnInfo, error) {
+	rows, err := PragmaRows(db, fmt.Sprintf("table_xinfo(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]ColumnInfo, len(rows))
+	for i, row := range rows {
+		c := &columns[i]
+		var notNull int
+		if err := pragmaInts("table_xinfo", row, nil, nil, nil, &notNull, nil, &c.PrimaryKey, &c.Hidden); err != nil {
+			return nil, err
+		}
+		c.Name, c.Type, c.NotNull, c.Default = row[1], row[2], notNull != 0, row[4]
+	}
+	return columns, nil
+}
+
+// tableIndexes returns the indexes of a table, in the order of index_list
+func tableIndexes(db *sql.DB, table string) ([]IndexInfo, error) {
+	rows, err := PragmaRows(db, fmt.Sprintf("index_list(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]IndexInfo, len(rows))
+	for i, row := range rows {
+		idx := &indexes[i]
+		var unique, partial int
+		if err := pragmaInts("index_list", row, nil, nil, &unique, nil, &partial); err != nil {
+			return nil, err
+		}
+		idx.Name, idx.Table, idx.Unique, idx.Origin, idx.Partial = row[1], table, unique != 0, row[3], partial != 0
+		columns, err := PragmaRows(db, fmt.Sprintf("index_info(%s)", quoteIdent(idx.Name)))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range columns {
+			idx.Columns = append(idx.Columns, c[2])
+		}
+		var stmt sql.NullString
+		err = db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'index' AND name = ?", idx.Name).Scan(&stmt)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		idx.SQL = stmt.String
+		if idx.SQL != "" {
+			idx.Terms, idx.Where = indexDefinition(idx.SQL)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspectSchema(t *testing.T) {
+	db := memDB(t)
+	const schema = `
+create table owner (id integer primary key, name text not null default 'nobody' unique);
+create table pet (
+	owner_id integer references owner(id) on delete cascade,
+	name text collate nocase,
+	born date default (date('now')),
+	tag text generated always as (upper(name)) virtual,
+	primary key (owner_id, name)
+) without rowid;
+create index pet_born on pet(born, lower(name)) where born is not null;
+create view named as select o.name, p.name as pet from owner o join pet p on p.owner_id = o.id;
+create trigger owner_gone after delete on owner begin select 1; end;
+`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	info, err := InspectSchema(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Tables) != 2 || len(info.Views) != 1 || len(info.Triggers) != 1 {
+		t.Fatalf("unexpected schema: %+v", info)
+	}
+
+	owner := info.Table("OWNER")
+	if owner == nil || owner.WithoutRowID || strings.Join(owner.PrimaryKey, ",") != "id" {
+		t.Fatalf("unexpected owner table: %+v", owner)
+	}
+	name := owner.Column("name")
+	if name == nil || !name.NotNull || name.Default != "'nobody'" || name.Type != "text" {
+		t.Fatalf("unexpected name column: %+v", name)
+	}
+	if len(owner.Indexes) != 1 || owner.Indexes[0].Origin != "u" || !owner.Indexes[0].Unique || owner.Indexes[0].SQL != "" {
+		t.Fatalf("unexpected owner indexes: %+v", owner.Indexes)
+	}
+
+	pet := info.Table("pet")
+	if pet == nil || !pet.WithoutRowID || strings.Join(pet.PrimaryKey, ",") != "owner_id,name" {
+		t.Fatalf("unexpected pet table: %+v", pet)
+	}
+	if tag := pet.Column("tag"); tag == nil || tag.Hidden != 2 {
+		t.Fatalf("expected a generated column: %+v", tag)
+	}
+	if born := pet.Column("born"); born == nil || born.Default != "date('now')" {
+		t.Fatalf("unexpected born column: %+v", born)
+	}
+	if len(pet.ForeignKeys) != 1 || pet.ForeignKeys[0].Table != "owner" || pet.ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Fatalf("unexpected foreign keys: %+v", pet.ForeignKeys)
+	}
+	var born *IndexInfo
+	for i := range pet.Indexes {
+		if pet.Indexes[i].Name == "pet_born" {
+			born = &pet.Indexes[i]
+		}
+	}
+	if born == nil || !born.Partial || born.Unique || born.Origin != "c" || !strings.HasPrefix(born.SQL, "CREATE INDEX") {
+		t.Fatalf("unexpected index: %+v", born)
+	}
+	if strings.Join(born.Columns, ",") != "born," {
+		t.Fatalf("expected an expression column, got %q", born.Columns)
+	}
+
+	view := info.View("named")
+	if view == nil || len(view.Columns) != 2 || view.Columns[1].Name != "pet" {
+		t.Fatalf("unexpected view: %+v", view)
+	}
+	if info.Triggers[0].Table != "owner" || info.Table("missing") != nil {
+		t.Fatalf("unexpected trigger: %+v", info.Triggers[0])
+	}
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// WithScope sets up the functions, aggregates, collations, hooks, and other
+// connection settings for this database handle alone, rather than for its driver
+// name. They never appear on other handles, even those opened with the same driver
+// name, and the handle doesn't take on the settings already registered for it.
+// The handle should be closed with Close, which releases its settings
+func WithScope() Optional {
+	return func(c *Config) {
+		c.scoped = true
+	}
+}
+
+// scopedConnector opens the connections of a scoped handle with its own driver
+type scopedConnector struct {
+	dsn    string
+	driver *sqlite3.SQLiteDriver
+}
+
+func (c *scopedConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *scopedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// openScoped returns a handle whose connections are set up by a driver of its own,
+// which is never registered by name
+func openScoped(file string, config *Config) *sql.DB {
+	if Debug {
+		logf("opening scoped database: %s", file)
+	}
+	drvr := newDriver(config)
+	imu.Lock()
+	configs[drvr] = config
+	imu.Unlock()
+	return sql.OpenDB(&scopedConnector{dsn: file, driver: drvr})
+}
+
+// unscope releases the settings of a scoped handle
+func unscope(db *sql.DB) {
+	imu.Lock()
+	defer imu.Unlock()
+	if config := configs[db.Driver()]; config != nil && config.scoped {
+		delete(configs, db.Driver())
+	}
+}
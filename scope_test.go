@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScope(t *testing.T) {
+	double := FuncReg{"double", func(i int64) int64 { return i * 2 }, true}
+	scoped, err := Open(":memory:", WithDriver("scoped"), WithScope(), WithFunctions(double))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(scoped)
+
+	var n int64
+	if err := scoped.QueryRow("SELECT double(21)").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 42 {
+		t.Errorf("got %d, want 42", n)
+	}
+	if configFor(scoped) == nil {
+		t.Error("scoped database has no config")
+	}
+
+	// the same driver name, without the function
+	other, err := Open(":memory:", WithDriver("scoped"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(other)
+	err = other.QueryRow("SELECT double(21)").Scan(&n)
+	if err == nil || !strings.Contains(err.Error(), "no such function") {
+		t.Errorf("function leaked to another handle: %v", err)
+	}
+
+	// nor another scoped handle
+	again, err := Open(":memory:", WithDriver("scoped"), WithScope())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := again.QueryRow("SELECT double(21)").Scan(&n); err == nil {
+		t.Error("function leaked to another scoped handle")
+	}
+	Close(again)
+	if configFor(again) != nil {
+		t.Error("closed scoped database still has its config")
+	}
+}
+
+func TestScopeSharedDriver(t *testing.T) {
+	dir := t.TempDir()
+	plain, err := Open(filepath.Join(dir, "a.db"), WithDriver("scope_shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(plain)
+
+	// the settings of a later Open of the driver name aren't lost to the first
+	var updates int
+	double := FuncReg{"double", func(i int64) int64 { return i * 2 }, true}
+	db, err := Open(filepath.Join(dir, "b.db"), WithDriver("scope_shared"),
+		WithUpdateHook(func(UpdateEvent) { updates++ }),
+		WithBusyHandler(func(int, time.Duration) bool { return false }),
+		WithChangeCapture(),
+		WithFunctions(double),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	db.SetMaxOpenConns(1)
+
+	events, stop := ChangeStream(db)
+	defer stop()
+	if _, err := db.Exec("create table t (n int); insert into t values (double(2))"); err != nil {
+		t.Fatal(err)
+	}
+	if updates != 1 {
+		t.Fatalf("expected 1 update but got: %d", updates)
+	}
+	select {
+	case e := <-events:
+		if e.Table != "t" || e.Op != OpInsert {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a change event")
+	}
+
+	// an Open with no settings of its own takes on those of the first
+	again, err := Open(filepath.Join(dir, "c.db"), WithDriver("scope_shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(again)
+	if configFor(again) != configFor(plain) {
+		t.Fatal("expected a handle without settings to share the registered driver")
+	}
+	if _, err := again.Exec("select double(1)"); err == nil {
+		t.Fatal("expected the function to be missing from the registered driver")
+	}
+
+	// the same functions share the driver too
+	first, err := Open(filepath.Join(dir, "d.db"), WithDriver("scope_funcs"), WithFunctions(FuzzyFuncs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(first)
+	second, err := Open(filepath.Join(dir, "e.db"), WithDriver("scope_funcs"), WithFunctions(FuzzyFuncs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(second)
+	if configFor(first) != configFor(second) {
+		t.Fatal("expected the same functions to share the registered driver")
+	}
+}
@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// ScriptError reports the statement of a script that failed
+type ScriptError struct {
+	Line      int    // the line of the script where the statement starts
+	Index     int    // the index of the statement in the script, from 0
+	Statement string // the statement, without comments
+	Err       error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("line %d: %s -- %v", e.Line, e.Statement, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ExecScript executes each statement of a script in turn on a single connection, the
+// way sqlite3_exec does, and returns the total rows changed (including by triggers). Execution stops at the first
+// failing statement, which is returned as a *ScriptError; the statements before it are not undone,
+// but a transaction the script left open is rolled back so the connection can return to the pool
+func ExecScript(db *sql.DB, script string) (int64, error) {
+	return ExecScriptContext(context.Background(), db, script)
+}
+
+// ExecScriptContext is ExecScript with a context
+func ExecScriptContext(ctx context.Context, db *sql.DB, script string) (int64, error) {
+	defer observe(MetricScript, time.Now())
+	stmts, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = rawConn(ctx, db, func(conn *sqlite3.SQLiteConn) error {
+		before, err := totalChanges(conn)
+		if err != nil {
+			return err
+		}
+		err = execStatements(ctx, conn, stmts)
+		if err != nil && !conn.AutoCommit() {
+			if _, rerr := conn.Exec("ROLLBACK", nil); rerr != nil {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+			}
+		}
+		if after, cerr := totalChanges(conn); cerr == nil {
+			total = after - before
+		}
+		return err
+	})
+	return total, err
+}
+
+func execStatements(ctx context.Context, conn *sqlite3.SQLiteConn, stmts []Statement) error {
+	for i, stmt := range stmts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if stmt.Command {
+			return &ScriptError{Line: stmt.Line, Index: i, Statement: stmt.Text, Err: fmt.Errorf("dot commands are not supported")}
+		}
+		if _, err := conn.ExecContext(ctx, stmt.Text, nil); err != nil {
+			return &ScriptError{Line: stmt.Line, Index: i, Statement: stmt.Text, Err: err}
+		}
+	}
+	return nil
+}
+
+// totalChanges returns the rows changed since the connection was opened
+func totalChanges(conn *sqlite3.SQLiteConn) (int64, error) {
+	var total int64
+	fn := func(cols []string, row int, values []driver.Value) error {
+		total = values[0].(int64)
+		return nil
+	}
+	return total, connQuery(conn, fn, "select total_changes()")
+}
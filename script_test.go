@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecScript(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	const script = `
+	create table t (id integer primary key, name text); -- a comment
+	insert into t (name) values ('a;b'), ('c');
+	create trigger t_upper after insert on t begin
+		update t set name = upper(new.name) where id = new.id;
+	end;
+	insert into t (name) values ('d');
+	`
+	n, err := ExecScript(db, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Fatalf("expected: %d but got: %d", 4, n)
+	}
+	var name string
+	if err := row(db, []interface{}{&name}, "select name from t where id=3"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "D" {
+		t.Fatalf("expected: %q but got: %q", "D", name)
+	}
+
+	const broken = `
+	begin;
+	insert into t (name) values ('e');
+
+	insert into nosuch (name) values ('f');
+	insert into t (name) values ('g');
+	commit;
+	`
+	_, err = ExecScript(db, broken)
+	var serr *ScriptError
+	if !errors.As(err, &serr) {
+		t.Fatalf("expected script error but got: %v", err)
+	}
+	if serr.Line != 5 || serr.Index != 2 {
+		t.Fatalf("unexpected position: %+v", serr)
+	}
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from t"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected open transaction to be rolled back, got %d rows", count)
+	}
+
+	if _, err := ExecScript(db, ".tables"); !errors.As(err, &serr) {
+		t.Fatalf("expected script error but got: %v", err)
+	}
+}
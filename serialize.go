@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// The driver doesn't expose sqlite3_serialize or sqlite3_deserialize, so the
+// database image passes through a temporary file, which is removed at once
+
+// Serialize returns the contents of the database as a database image,
+// the same bytes as its file would have after a checkpoint
+func Serialize(db *sql.DB) ([]byte, error) {
+	f, err := ioutil.TempFile("", "serialize-*.db")
+	if err != nil {
+		return nil, err
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+
+	dest, err := driverConn(name)
+	if err != nil {
+		return nil, err
+	}
+	err = rawConn(context.Background(), db, func(src *sqlite3.SQLiteConn) error {
+		return copyConn(dest, src)
+	})
+	if cerr := dest.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("serialize: %w", err)
+	}
+	return ioutil.ReadFile(name)
+}
+
+// OpenFromBytes opens an in-memory database with the contents of a database image,
+// such as one returned by Serialize. The database is shared by the connections of the
+// pool, and should be closed with Close to release it
+func OpenFromBytes(data []byte, opts ...Optional) (*sql.DB, error) {
+	if !bytes.HasPrefix(data, sqliteHeader) {
+		return nil, fmt.Errorf("not a sqlite database image")
+	}
+	db, err := OpenMemory("", opts...)
+	if err != nil {
+		return nil, fmt.Errorf("open from bytes: %w", err)
+	}
+	if err := RestoreFromReader(db, bytes.NewReader(data)); err != nil {
+		Close(db)
+		return nil, fmt.Errorf("open from bytes: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSerialize(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("insert into structs (name, kind) values('serialized', 7)"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Serialize(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 512 || string(data[:15]) != "SQLite format 3" {
+		t.Fatalf("unexpected image of %d bytes", len(data))
+	}
+
+	copied, err := OpenFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(copied)
+	copied.SetMaxOpenConns(4)
+
+	// every connection of the pool sees the same database
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		conn, err := copied.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		var kind int
+		if err := conn.QueryRowContext(ctx, "select kind from structs where name='serialized'").Scan(&kind); err != nil {
+			t.Fatal(err)
+		}
+		if kind != 7 {
+			t.Fatalf("expected: %d but got: %d", 7, kind)
+		}
+	}
+
+	if _, err := OpenFromBytes([]byte("nope")); err == nil {
+		t.Fatal("expected error for invalid image")
+	}
+}
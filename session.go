@@ -0,0 +1,599 @@
+package sqlite
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// The sqlite session extension isn't part of the driver's build, so sessions are
+// recorded by triggers into a log table, and changesets have their own encoding
+
+// ErrConflict is returned by ApplyChangeset when a conflict aborts it
+var ErrConflict = errors.New("changeset conflict")
+
+// changesetMagic starts every changeset
+var changesetMagic = []byte("SQLCS\x01")
+
+// Session records the changes made to a set of tables, by any connection,
+// until it is closed. Tables must have a primary key, which identifies their
+// rows when the changes are applied elsewhere, and must not be altered while
+// the session records them
+type Session struct {
+	db     *sql.DB
+	prefix string
+	tables []sessionTable
+	width  int // the number of value columns of the log
+}
+
+// sessionTable is a table recorded by a session
+type sessionTable struct {
+	name    string
+	columns []string
+	pk      []bool
+}
+
+// Change is a row inserted, updated, or deleted
+type Change struct {
+	Table   string
+	Op      int // OpInsert, OpUpdate, or OpDelete
+	Columns []string
+	Old     []interface{} // the values before an update or delete
+	New     []interface{} // the values after an insert or update
+}
+
+// OpName returns the name of the operation, e.g. "INSERT"
+func (c Change) OpName() string {
+	return UpdateEvent{Op: c.Op}.OpName()
+}
+
+// ConflictKind is the reason a change cannot be applied as it is
+type ConflictKind int
+
+const (
+	// ConflictData is a row that exists to be inserted, or has other values than those updated or deleted
+	ConflictData ConflictKind = iota + 1
+	// ConflictNotFound is a row to update or delete that doesn't exist
+	ConflictNotFound
+	// ConflictConstraint is a change that would break a constraint
+	ConflictConstraint
+)
+
+// ConflictAction is what ApplyChangeset does about a conflict
+type ConflictAction int
+
+const (
+	// ConflictAbort rolls back the changes applied so far and returns ErrConflict
+	ConflictAbort ConflictAction = iota
+	// ConflictOmit skips the change
+	ConflictOmit
+	// ConflictReplace applies the change anyway: a row is overwritten, an update of a
+	// missing row inserts it, and a delete removes the row whatever its values
+	ConflictReplace
+)
+
+// ChangeConflict is a change that cannot be applied as it is, with the current values of its row
+type ChangeConflict struct {
+	Change
+	Kind    ConflictKind
+	Current []interface{}
+}
+
+// CreateSession starts recording the changes to the given tables, or to all of them if none are given
+func CreateSession(db *sql.DB, tables ...string) (*Session, error) {
+	if len(tables) == 0 {
+		var err error
+		if tables, err = userTables(db); err != nil {
+			return nil, err
+		}
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	s := &Session{db: db, prefix: "_session_" + hex.EncodeToString(id)}
+	for _, name := range tables {
+		columns, err := exportColumns(db, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("no such table: %s", name)
+		}
+		table := sessionTable{name: name}
+		var keyed bool
+		for _, c := range columns {
+			table.columns = append(table.columns, c.name)
+			table.pk = append(table.pk, c.pk > 0)
+			keyed = keyed || c.pk > 0
+		}
+		if !keyed {
+			return nil, fmt.Errorf("table %s has no primary key", name)
+		}
+		if len(columns) > s.width {
+			s.width = len(columns)
+		}
+		s.tables = append(s.tables, table)
+	}
+
+	defs := []string{"seq INTEGER PRIMARY KEY", "tbl INTEGER", "op INTEGER"}
+	for i := 0; i < s.width; i++ {
+		defs = append(defs, fmt.Sprintf("o%d, n%d", i, i))
+	}
+	stmts := []string{fmt.Sprintf("CREATE TABLE %s (%s)", quoteIdent(s.prefix+"_log"), strings.Join(defs, ", "))}
+	for i, table := range s.tables {
+		stmts = append(stmts, s.triggers(i, table)...)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+	return s, tx.Commit()
+}
+
+// triggers returns the statements creating the triggers that log changes to a table
+func (s *Session) triggers(index int, table sessionTable) []string {
+	var stmts []string
+	for _, op := range []int{OpInsert, OpUpdate, OpDelete} {
+		targets := []string{"tbl", "op"}
+		values := []string{fmt.Sprint(index), fmt.Sprint(op)}
+		for i, column := range table.columns {
+			if op != OpInsert {
+				targets = append(targets, fmt.Sprintf("o%d", i))
+				values = append(values, "OLD."+quoteIdent(column))
+			}
+			if op != OpDelete {
+				targets = append(targets, fmt.Sprintf("n%d", i))
+				values = append(values, "NEW."+quoteIdent(column))
+			}
+		}
+		event := UpdateEvent{Op: op}.OpName()
+		name := fmt.Sprintf("%s_%d_%s", s.prefix, index, strings.ToLower(event))
+		stmts = append(stmts, fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s BEGIN INSERT INTO %s (%s) VALUES (%s); END",
+			quoteIdent(name), event, quoteIdent(table.name), quoteIdent(s.prefix+"_log"),
+			strings.Join(targets, ", "), strings.Join(values, ", ")))
+	}
+	return stmts
+}
+
+// Changes returns the changes recorded so far, in the order they were made
+func (s *Session) Changes() ([]Change, error) {
+	columns := []string{"tbl", "op"}
+	for i := 0; i < s.width; i++ {
+		columns = append(columns, fmt.Sprintf("o%d", i), fmt.Sprintf("n%d", i))
+	}
+	rows, err := s.db.Query(fmt.Sprintf("SELECT %s FROM %s ORDER BY seq", strings.Join(columns, ", "), quoteIdent(s.prefix+"_log")))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var changes []Change
+	for rows.Next() {
+		var index, op int
+		ptrs[0], ptrs[1] = &index, &op
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(s.tables) {
+			return nil, fmt.Errorf("session log has unknown table %d", index)
+		}
+		table := s.tables[index]
+		change := Change{Table: table.name, Op: op, Columns: table.columns}
+		for i := range table.columns {
+			if op != OpInsert {
+				change.Old = append(change.Old, values[2+2*i])
+			}
+			if op != OpDelete {
+				change.New = append(change.New, values[3+2*i])
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// Changeset returns the changes recorded so far, encoded for ApplyChangeset
+func (s *Session) Changeset() ([]byte, error) {
+	changes, err := s.Changes()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(changesetMagic)
+	putUvarint(&buf, uint64(len(s.tables)))
+	for _, table := range s.tables {
+		putString(&buf, table.name)
+		putUvarint(&buf, uint64(len(table.columns)))
+		for i, column := range table.columns {
+			putString(&buf, column)
+			if table.pk[i] {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		}
+	}
+	index := make(map[string]int)
+	for i, table := range s.tables {
+		index[table.name] = i
+	}
+	for _, change := range changes {
+		putUvarint(&buf, uint64(index[change.Table]))
+		buf.WriteByte(byte(change.Op))
+		for _, values := range [][]interface{}{change.Old, change.New} {
+			for _, v := range values {
+				if err := putValue(&buf, v); err != nil {
+					return nil, fmt.Errorf("changeset %s: %w", change.Table, err)
+				}
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Close stops recording and removes the session's triggers and log
+func (s *Session) Close() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i := range s.tables {
+		for _, event := range []string{"insert", "update", "delete"} {
+			name := fmt.Sprintf("%s_%d_%s", s.prefix, i, event)
+			if _, err := tx.Exec("DROP TRIGGER IF EXISTS " + quoteIdent(name)); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := tx.Exec("DROP TABLE IF EXISTS " + quoteIdent(s.prefix+"_log")); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Value tags of the changeset encoding
+const (
+	tagNull = iota
+	tagInt
+	tagFloat
+	tagText
+	tagBlob
+)
+
+func putUvarint(buf *bytes.Buffer, n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func putString(buf *bytes.Buffer, s string) {
+	putUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func putValue(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(tagNull)
+	case int64:
+		buf.WriteByte(tagInt)
+		var b [binary.MaxVarintLen64]byte
+		buf.Write(b[:binary.PutVarint(b[:], v)])
+	case float64:
+		buf.WriteByte(tagFloat)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(v))
+		buf.Write(b[:])
+	case string:
+		buf.WriteByte(tagText)
+		putString(buf, v)
+	case []byte:
+		buf.WriteByte(tagBlob)
+		putString(buf, string(v))
+	default:
+		return fmt.Errorf("unexpected value type %T", v)
+	}
+	return nil
+}
+
+// changesetReader decodes a changeset
+type changesetReader struct {
+	*bytes.Reader
+}
+
+func (r changesetReader) uvarint() (uint64, error) {
+	return binary.ReadUvarint(r)
+}
+
+func (r changesetReader) string() (string, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", fmt.Errorf("string of %d bytes is past the end", n)
+	}
+	b := make([]byte, n)
+	_, err = r.Read(b)
+	return string(b), err
+}
+
+func (r changesetReader) value() (interface{}, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagNull:
+		return nil, nil
+	case tagInt:
+		return binary.ReadVarint(r)
+	case tagFloat:
+		var b [8]byte
+		if _, err := r.Read(b[:]); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b[:])), nil
+	case tagText:
+		return r.string()
+	case tagBlob:
+		s, err := r.string()
+		return []byte(s), err
+	}
+	return nil, fmt.Errorf("unknown value tag %d", tag)
+}
+
+// ParseChangeset decodes the changes of a changeset
+func ParseChangeset(data []byte) ([]Change, error) {
+	_, changes, err := parseChangeset(data)
+	return changes, err
+}
+
+// parseChangeset decodes the tables and changes of a changeset
+func parseChangeset(data []byte) ([]sessionTable, []Change, error) {
+	if !bytes.HasPrefix(data, changesetMagic) {
+		return nil, nil, errors.New("not a changeset")
+	}
+	r := changesetReader{bytes.NewReader(data[len(changesetMagic):])}
+	fail := func(err error) ([]sessionTable, []Change, error) {
+		return nil, nil, fmt.Errorf("invalid changeset: %w", err)
+	}
+	count, err := r.uvarint()
+	if err != nil {
+		return fail(err)
+	}
+	var tables []sessionTable
+	for i := uint64(0); i < count; i++ {
+		var table sessionTable
+		if table.name, err = r.string(); err != nil {
+			return fail(err)
+		}
+		n, err := r.uvarint()
+		if err != nil {
+			return fail(err)
+		}
+		for j := uint64(0); j < n; j++ {
+			column, err := r.string()
+			if err != nil {
+				return fail(err)
+			}
+			pk, err := r.ReadByte()
+			if err != nil {
+				return fail(err)
+			}
+			table.columns = append(table.columns, column)
+			table.pk = append(table.pk, pk != 0)
+		}
+		tables = append(tables, table)
+	}
+	var changes []Change
+	for r.Len() > 0 {
+		index, err := r.uvarint()
+		if err != nil {
+			return fail(err)
+		}
+		if index >= uint64(len(tables)) {
+			return fail(fmt.Errorf("unknown table %d", index))
+		}
+		op, err := r.ReadByte()
+		if err != nil {
+			return fail(err)
+		}
+		table := tables[index]
+		change := Change{Table: table.name, Op: int(op), Columns: table.columns}
+		switch change.Op {
+		case OpInsert, OpUpdate, OpDelete:
+		default:
+			return fail(fmt.Errorf("unknown operation %d", op))
+		}
+		for _, values := range []*[]interface{}{&change.Old, &change.New} {
+			if values == &change.Old && change.Op == OpInsert || values == &change.New && change.Op == OpDelete {
+				continue
+			}
+			for range table.columns {
+				v, err := r.value()
+				if err != nil {
+					return fail(err)
+				}
+				*values = append(*values, v)
+			}
+		}
+		changes = append(changes, change)
+	}
+	return tables, changes, nil
+}
+
+// ApplyChangeset applies the changes of a changeset to db in one transaction, matching
+// rows by the primary key they had when recorded. The conflict function decides what
+// to do about each change that cannot be applied as it is; without one, any conflict
+// aborts. The primary key columns are taken from the changeset, so db must have the same
+// keys, but columns are matched by name and it may have others
+func ApplyChangeset(db *sql.DB, data []byte, conflict func(ChangeConflict) ConflictAction) error {
+	tables, changes, err := parseChangeset(data)
+	if err != nil {
+		return err
+	}
+	if conflict == nil {
+		conflict = func(ChangeConflict) ConflictAction { return ConflictAbort }
+	}
+	keys := make(map[string][]bool)
+	for _, table := range tables {
+		keys[table.name] = table.pk
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, change := range changes {
+		if err := applyChange(tx, change, keys[change.Table], conflict); err != nil {
+			return fmt.Errorf("apply %s to %s: %w", change.OpName(), change.Table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// applyChange applies one change, consulting conflict if it can't be applied as it is
+func applyChange(tx *sql.Tx, change Change, pk []bool, conflict func(ChangeConflict) ConflictAction) error {
+	table := quoteIdent(change.Table)
+	key := change.Old
+	if change.Op == OpInsert {
+		key = change.New
+	}
+	var where []string
+	var whereArgs []interface{}
+	selects := make([]string, len(change.Columns))
+	for i, column := range change.Columns {
+		// the unary plus keeps the driver from converting values by declared type
+		selects[i] = "+" + quoteIdent(column)
+		if pk[i] {
+			where = append(where, quoteIdent(column)+" IS ?")
+			whereArgs = append(whereArgs, key[i])
+		}
+	}
+	whereClause := strings.Join(where, " AND ")
+
+	current := make([]interface{}, len(change.Columns))
+	ptrs := make([]interface{}, len(current))
+	for i := range current {
+		ptrs[i] = &current[i]
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(selects, ", "), table, whereClause)
+	switch err := tx.QueryRow(q, whereArgs...).Scan(ptrs...); {
+	case err == sql.ErrNoRows:
+		current = nil
+	case err != nil:
+		return err
+	}
+
+	var kind ConflictKind
+	switch {
+	case change.Op == OpInsert && current != nil:
+		kind = ConflictData
+	case change.Op != OpInsert && current == nil:
+		kind = ConflictNotFound
+	case change.Op != OpInsert && !sameValues(current, change.Old):
+		kind = ConflictData
+	}
+	replace := false
+	if kind != 0 {
+		switch conflict(ChangeConflict{Change: change, Kind: kind, Current: current}) {
+		case ConflictOmit:
+			return nil
+		case ConflictReplace:
+			replace = true
+		default:
+			return fmt.Errorf("%w: %s", ErrConflict, kind)
+		}
+	}
+
+	columns := make([]string, len(change.Columns))
+	marks := make([]string, len(change.Columns))
+	for i, column := range change.Columns {
+		columns[i] = quoteIdent(column)
+		marks[i] = "?"
+	}
+	var stmt string
+	var args []interface{}
+	switch {
+	case change.Op == OpDelete:
+		stmt, args = fmt.Sprintf("DELETE FROM %s WHERE %s", table, whereClause), whereArgs
+	case change.Op == OpInsert || current == nil:
+		verb := "INSERT"
+		if replace {
+			verb = "INSERT OR REPLACE"
+		}
+		stmt = fmt.Sprintf("%s INTO %s (%s) VALUES (%s)", verb, table, strings.Join(columns, ", "), strings.Join(marks, ", "))
+		args = change.New
+	default:
+		sets := make([]string, len(columns))
+		for i, column := range columns {
+			sets[i] = column + " = ?"
+		}
+		stmt = fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(sets, ", "), whereClause)
+		args = append(append([]interface{}(nil), change.New...), whereArgs...)
+	}
+	_, err := tx.Exec(stmt, args...)
+	var serr sqlite3.Error
+	if errors.As(err, &serr) && serr.Code == sqlite3.ErrConstraint {
+		switch conflict(ChangeConflict{Change: change, Kind: ConflictConstraint, Current: current}) {
+		case ConflictOmit:
+			return nil
+		default:
+			return fmt.Errorf("%w: %s: %v", ErrConflict, ConflictConstraint, err)
+		}
+	}
+	return err
+}
+
+// sameValues reports whether two rows of values are equal
+func sameValues(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, xok := a[i].([]byte)
+		y, yok := b[i].([]byte)
+		if xok || yok {
+			if xok != yok || !bytes.Equal(x, y) {
+				return false
+			}
+		} else if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String names the kind of conflict
+func (k ConflictKind) String() string {
+	switch k {
+	case ConflictData:
+		return "data"
+	case ConflictNotFound:
+		return "not found"
+	case ConflictConstraint:
+		return "constraint"
+	}
+	return fmt.Sprintf("ConflictKind(%d)", int(k))
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+	open := func(name string) *sql.DB {
+		db, err := Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		const schema = `
+		create table items (id integer primary key, name text, price real, data blob, added datetime);
+		insert into items values (1, 'one', 1.5, x'01', '2020-01-01 00:00:00'), (2, 'two', 2.5, null, null);
+		`
+		if _, err := db.Exec(schema); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	src, dst := open("src.db"), open("dst.db")
+
+	session, err := CreateSession(src, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const changes = `
+	insert into items values (3, 'three', 3.5, x'0304', '2021-02-03 04:05:06');
+	update items set price = 10 where id = 1;
+	delete from items where id = 2;
+	`
+	if _, err := src.Exec(changes); err != nil {
+		t.Fatal(err)
+	}
+	data, err := session.Changeset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseChangeset(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != 3 || parsed[0].OpName() != "INSERT" || parsed[1].OpName() != "UPDATE" || parsed[2].OpName() != "DELETE" {
+		t.Fatalf("unexpected changes: %+v", parsed)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Exec("insert into items (id) values (4)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyChangeset(dst, data, nil); err != nil {
+		t.Fatal(err)
+	}
+	same := func() bool {
+		const q = "select group_concat(id || quote(name) || quote(price) || quote(data) || quote(added)) from (select * from items where id < 4 order by id)"
+		var a, b string
+		if err := row(src, []interface{}{&a}, q); err != nil {
+			t.Fatal(err)
+		}
+		if err := row(dst, []interface{}{&b}, q); err != nil {
+			t.Fatal(err)
+		}
+		return a == b
+	}
+	if !same() {
+		t.Fatal("expected databases to match after applying changeset")
+	}
+
+	// applying it again conflicts on every change
+	var kinds []ConflictKind
+	err = ApplyChangeset(dst, data, func(c ChangeConflict) ConflictAction {
+		kinds = append(kinds, c.Kind)
+		return ConflictOmit
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kinds) != 3 || kinds[0] != ConflictData || kinds[1] != ConflictData || kinds[2] != ConflictNotFound {
+		t.Fatalf("unexpected conflicts: %v", kinds)
+	}
+	if err := ApplyChangeset(dst, data, nil); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+
+	if _, err := dst.Exec("update items set price = 99 where id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	err = ApplyChangeset(dst, data, func(c ChangeConflict) ConflictAction { return ConflictReplace })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same() {
+		t.Fatal("expected replaced rows to match")
+	}
+
+	var triggers int
+	if err := row(src, []interface{}{&triggers}, "select count(*) from sqlite_master where name like '\\_session\\_%' escape '\\'"); err != nil {
+		t.Fatal(err)
+	}
+	if triggers != 0 {
+		t.Fatalf("expected session objects to be removed, found %d", triggers)
+	}
+	if _, err := ParseChangeset([]byte("nonsense")); err == nil {
+		t.Fatal("expected error for invalid changeset")
+	}
+}
+
+func TestSessionNoPrimaryKey(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("create table t (n int)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateSession(db, "t"); err == nil {
+		t.Fatal("expected error for table without primary key")
+	}
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// sharedDB is a database opened WithShared, and the number of its holders
+type sharedDB struct {
+	key    string
+	config *Config
+	db     *sql.DB
+	err    error
+	refs   int
+	ready  chan struct{} // closed once the database is open, or failed to open
+}
+
+var (
+	sharedMu sync.Mutex
+	// shared holds the databases opened WithShared, by driver and file
+	shared = make(map[string]*sharedDB)
+	// sharing holds the same databases by their handle, for Close
+	sharing = make(map[*sql.DB]*sharedDB)
+)
+
+// WithShared returns the database already opened with the same file and driver,
+// if there is one, rather than opening another pool on it. Each holder should close
+// it with Close, which closes the pool once the last of them has. As options apply
+// when the database is first opened, a later Open must give the same ones or fails.
+// It may leave out the connection settings, such as functions and hooks, and share
+// those of the first, which it must do for hooks and handlers as they can't be compared
+func WithShared(share bool) Optional {
+	return func(c *Config) {
+		c.shared = share
+	}
+}
+
+// sharedKey identifies the file of a database and the driver opening it
+func sharedKey(file, driver string) string {
+	if dsn, err := ParseDSN(file); err == nil && !dsn.Memory {
+		if abs, err := filepath.Abs(dsn.Filename); err == nil {
+			file = abs
+			if len(dsn.Params) > 0 {
+				file += "?" + dsn.Params.Encode()
+			}
+		}
+	}
+	return driver + "\x00" + file
+}
+
+// sameOptions reports whether a later Open of a shared database, with config b,
+// asks for what the first, with config a, was opened with
+func sameOptions(a, b *Config) bool {
+	if a.exclusive != b.exclusive || a.recover != b.recover || a.fail != b.fail ||
+		a.durable != b.durable || a.fileMode != b.fileMode || a.schema != b.schema || !sameFS(a.schemaFS, b.schemaFS) {
+		return false
+	}
+	if (a.pool == nil) != (b.pool == nil) || (a.pool != nil && *a.pool != *b.pool) {
+		return false
+	}
+	return !b.connects() || sameConnections(a, b)
+}
+
+// sameFS reports whether two file systems are the same one
+func sameFS(a, b fs.FS) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	switch {
+	case ta != tb:
+		return false
+	case ta.Comparable():
+		return a == b
+	case ta.Kind() == reflect.Map:
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return false
+}
+
+// openShared returns the shared database for the file, opening it if need be
+func openShared(ctx context.Context, file string, config *Config) (*sql.DB, error) {
+	if config.err != nil {
+		return nil, config.err
+	}
+	key := sharedKey(file, config.driver)
+	sharedMu.Lock()
+	if s, ok := shared[key]; ok {
+		if !sameOptions(s.config, config) {
+			sharedMu.Unlock()
+			return nil, redactf("%s is already shared with other options", file)
+		}
+		s.refs++
+		sharedMu.Unlock()
+		<-s.ready
+		return s.db, s.err
+	}
+	s := &sharedDB{key: key, config: config, refs: 1, ready: make(chan struct{})}
+	shared[key] = s
+	sharedMu.Unlock()
+
+	s.db, s.err = open(ctx, file, config)
+	sharedMu.Lock()
+	if s.err != nil {
+		delete(shared, key)
+	} else {
+		sharing[s.db] = s
+	}
+	sharedMu.Unlock()
+	close(s.ready)
+	return s.db, s.err
+}
+
+// releaseShared drops a holder of a shared database, and reports whether others still hold it
+func releaseShared(db *sql.DB) bool {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	s, ok := sharing[db]
+	if !ok {
+		return false
+	}
+	if s.refs--; s.refs > 0 {
+		return true
+	}
+	delete(sharing, db)
+	delete(shared, s.key)
+	return false
+}
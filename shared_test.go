@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWithShared(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "shared.db")
+
+	const n = 8
+	dbs := make([]*sql.DB, n)
+	var wg sync.WaitGroup
+	for i := range dbs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db, err := Open(file, WithShared(true))
+			if err != nil {
+				t.Error(err)
+			}
+			dbs[i] = db
+		}(i)
+	}
+	wg.Wait()
+	for _, db := range dbs {
+		if db != dbs[0] {
+			t.Fatal("expected every open to share one database")
+		}
+	}
+	other, err := Open(filepath.Join(dir, "other.db"), WithShared(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(other)
+	if other == dbs[0] {
+		t.Fatal("expected another file to be opened apart")
+	}
+	if db, err := Open(file); err != nil || db == dbs[0] {
+		t.Fatalf("expected an open without sharing to have its own database (%v)", err)
+	} else {
+		db.Close()
+	}
+
+	// the database stays open until the last holder closes it
+	for i := 0; i < n-1; i++ {
+		Close(dbs[i])
+	}
+	if err := dbs[0].Ping(); err != nil {
+		t.Fatal(err)
+	}
+	Close(dbs[n-1])
+	if err := dbs[0].Ping(); err == nil {
+		t.Fatal("expected the database to be closed")
+	}
+
+	db, err := Open(file, WithShared(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	if db == dbs[0] {
+		t.Fatal("expected a new database once the shared one was closed")
+	}
+}
+
+func TestWithSharedOptions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "shared.db")
+	open := Opener(WithShared(true), WithPool(PoolSettings{MaxOpen: 2}), WithFunctions(MoneyFuncs...))
+	db, err := open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	again, err := open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(again)
+	if again != db {
+		t.Fatal("expected the opener to share the database")
+	}
+
+	// the connection settings may be left out, but not the others
+	plain, err := Open(file, WithShared(true), WithPool(PoolSettings{MaxOpen: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(plain)
+	if plain != db {
+		t.Fatal("expected an open without functions to share the database")
+	}
+	for name, opts := range map[string][]Optional{
+		"pool":      {WithPool(PoolSettings{MaxOpen: 4})},
+		"schema":    {WithPool(PoolSettings{MaxOpen: 2}), WithSchema("create table t (n int)")},
+		"exclusive": {WithPool(PoolSettings{MaxOpen: 2}), WithExclusiveProcess()},
+		"hooks":     {WithPool(PoolSettings{MaxOpen: 2}), WithUpdateHook(func(UpdateEvent) {})},
+	} {
+		if other, err := Open(file, append(opts, WithShared(true))...); err == nil {
+			Close(other)
+			t.Errorf("%s: expected a mismatch error", name)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// SketchAggregates are approximate aggregates backed by HyperLogLog and Bloom filter sketches
+//
+// hll_add(x) returns a sketch of the distinct values of x, which hll_count(sketch) estimates the
+// number of, and bloom_add(x) returns a Bloom filter that bloom_contains(filter, x) can test
+var SketchAggregates = []AggregateReg{
+	{"hll_add", newHLLAgg, true},
+	{"bloom_add", newBloomAgg, true},
+}
+
+// SketchFuncs are the scalar functions that read the sketches created by SketchAggregates
+var SketchFuncs = []FuncReg{
+	{"hll_count", hllCount, true},
+	{"bloom_contains", bloomContains, true},
+}
+
+// hashValue hashes an SQL value, distinguishing between types
+func hashValue(v interface{}) (uint64, bool) {
+	h := fnv.New64a()
+	var buf [8]byte
+	switch v := v.(type) {
+	case nil:
+		return 0, false
+	case int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		h.Write([]byte{'i'})
+		h.Write(buf[:])
+	case float64:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		h.Write([]byte{'f'})
+		h.Write(buf[:])
+	case string:
+		h.Write([]byte{'s'})
+		h.Write([]byte(v))
+	case []byte:
+		if v == nil {
+			return 0, false // the driver passes NULL as a nil slice
+		}
+		h.Write([]byte{'b'})
+		h.Write(v)
+	default:
+		fmt.Fprintf(h, "%T%v", v, v)
+	}
+	// finish with a splitmix64 mix, as FNV's high bits are poorly distributed
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x, true
+}
+
+// hllPrecision is the number of bits used to select a register
+const hllPrecision = 12
+
+// HLL is a HyperLogLog sketch for estimating the number of distinct values
+type HLL []byte
+
+// NewHLL returns an empty sketch
+func NewHLL() HLL {
+	return make(HLL, 1<<hllPrecision)
+}
+
+// Add adds a value to the sketch
+func (h HLL) Add(v interface{}) {
+	x, ok := hashValue(v)
+	if !ok {
+		return
+	}
+	idx := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > h[idx] {
+		h[idx] = rank
+	}
+}
+
+// Merge adds the values of other to the sketch
+func (h HLL) Merge(other HLL) error {
+	if len(other) != len(h) {
+		return fmt.Errorf("invalid sketch size: %d", len(other))
+	}
+	for i, r := range other {
+		if r > h[i] {
+			h[i] = r
+		}
+	}
+	return nil
+}
+
+// Count estimates the number of distinct values added to the sketch
+func (h HLL) Count() int64 {
+	m := float64(len(h))
+	var sum float64
+	zeros := 0
+	for _, r := range h {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// linear counting is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(estimate + 0.5)
+}
+
+type hllAgg struct {
+	sketch HLL
+}
+
+func newHLLAgg() *hllAgg {
+	return &hllAgg{sketch: NewHLL()}
+}
+
+func (a *hllAgg) Step(v interface{}) {
+	a.sketch.Add(v)
+}
+
+func (a *hllAgg) Done() []byte {
+	return a.sketch
+}
+
+func hllCount(sketch []byte) (int64, error) {
+	if len(sketch) == 0 {
+		return 0, nil
+	}
+	if len(sketch) != 1<<hllPrecision {
+		return 0, fmt.Errorf("invalid sketch size: %d", len(sketch))
+	}
+	return HLL(sketch).Count(), nil
+}
+
+// Bloom is a Bloom filter, stored as the number of hashes followed by the bit array
+type Bloom []byte
+
+// NewBloom returns a Bloom filter sized for n values with the given false positive
+// rate, which must be between 0 and 1
+func NewBloom(n int, rate float64) (Bloom, error) {
+	if !(rate > 0 && rate < 1) {
+		return nil, fmt.Errorf("invalid false positive rate: %v", rate)
+	}
+	if n < 1 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(n) * math.Ln2))
+	// the number of hashes is stored in a byte
+	if k < 1 {
+		k = 1
+	} else if k > math.MaxUint8 {
+		k = math.MaxUint8
+	}
+	b := make(Bloom, 1+(int(m)+7)/8)
+	b[0] = byte(k)
+	return b, nil
+}
+
+func (b Bloom) positions(v interface{}, fn func(byteIdx int, mask byte) bool) bool {
+	x, ok := hashValue(v)
+	if !ok {
+		return false
+	}
+	nbits := uint64(len(b)-1) * 8
+	h1, h2 := x, x>>33|x<<31
+	for i := uint64(0); i < uint64(b[0]); i++ {
+		bit := (h1 + i*h2) % nbits
+		if !fn(1+int(bit/8), 1<<(bit%8)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Add adds a value to the filter
+func (b Bloom) Add(v interface{}) {
+	b.positions(v, func(i int, mask byte) bool {
+		b[i] |= mask
+		return true
+	})
+}
+
+// Contains reports whether the value may have been added to the filter
+func (b Bloom) Contains(v interface{}) bool {
+	if len(b) < 2 {
+		return false
+	}
+	return b.positions(v, func(i int, mask byte) bool {
+		return b[i]&mask != 0
+	})
+}
+
+// BloomCapacity and BloomRate size the filters created by bloom_add
+var (
+	BloomCapacity = 10000
+	BloomRate     = 0.01
+)
+
+type bloomAgg struct {
+	filter Bloom
+	err    error
+}
+
+func newBloomAgg() *bloomAgg {
+	filter, err := NewBloom(BloomCapacity, BloomRate)
+	return &bloomAgg{filter: filter, err: err}
+}
+
+func (a *bloomAgg) Step(v interface{}) {
+	if a.err == nil {
+		a.filter.Add(v)
+	}
+}
+
+func (a *bloomAgg) Done() ([]byte, error) {
+	return a.filter, a.err
+}
+
+func bloomContains(filter []byte, v interface{}) bool {
+	return Bloom(filter).Contains(v)
+}
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestHLL(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		h := NewHLL()
+		for i := 0; i < n; i++ {
+			h.Add(fmt.Sprintf("value-%d", i))
+			h.Add(fmt.Sprintf("value-%d", i)) // duplicates don't count
+		}
+		count := h.Count()
+		if e := math.Abs(float64(count-int64(n))) / float64(n); e > 0.05 {
+			t.Errorf("expected about %d but got: %d", n, count)
+		}
+	}
+}
+
+func TestBloom(t *testing.T) {
+	b, err := NewBloom(1000, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 1000; i++ {
+		b.Add(i)
+	}
+	for i := int64(0); i < 1000; i++ {
+		if !b.Contains(i) {
+			t.Fatalf("expected filter to contain: %d", i)
+		}
+	}
+	falsePositives := 0
+	for i := int64(1000); i < 11000; i++ {
+		if b.Contains(i) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 200 {
+		t.Fatalf("too many false positives: %d", falsePositives)
+	}
+	if b.Contains("0") {
+		t.Fatal("expected text and integer values to differ")
+	}
+
+	for _, rate := range []float64{0, 1, 2, -0.5, math.NaN()} {
+		if _, err := NewBloom(10, rate); err == nil {
+			t.Errorf("expected rate %v to be rejected", rate)
+		}
+	}
+	if b, err := NewBloom(10, 1e-300); err != nil || b[0] != math.MaxUint8 {
+		t.Fatalf("expected the hashes to be clamped to %d but got: %v (%v)", math.MaxUint8, b[:1], err)
+	}
+}
+
+func TestSketchAggregates(t *testing.T) {
+	db, err := Open(":memory:", WithAggregates(SketchAggregates...), WithFunctions(SketchFuncs...), WithDriver("sketches"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const setup = `
+	create table visits (user integer);
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 5000)
+	insert into visits select n % 500 from seq;
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	if err := row(db, []interface{}{&count}, "select hll_count(hll_add(user)) from visits"); err != nil {
+		t.Fatal(err)
+	}
+	if count < 475 || count > 525 {
+		t.Fatalf("expected about 500 distinct users but got: %d", count)
+	}
+
+	var seen, unseen bool
+	const q = "with f(filter) as (select bloom_add(user) from visits) select bloom_contains(filter, 42), bloom_contains(filter, 4242) from f"
+	if err := row(db, []interface{}{&seen, &unseen}, q); err != nil {
+		t.Fatal(err)
+	}
+	if !seen || unseen {
+		t.Fatalf("unexpected results: %t %t", seen, unseen)
+	}
+
+	defer func(rate float64) { BloomRate = rate }(BloomRate)
+	BloomRate = 1
+	if _, err := db.Exec("select bloom_add(user) from visits"); err == nil {
+		t.Fatal("expected an invalid rate to fail")
+	}
+}
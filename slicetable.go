@@ -0,0 +1,323 @@
+//go:build sqlite_vtable || vtable
+// +build sqlite_vtable vtable
+
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// RegisterSliceTable makes a Go slice, array, or map a read-only table of the
+// database named name, which can be queried and joined like any other. The data
+// may be given by a pointer, to see the changes made to it, but it mustn't change
+// while a query reads it.
+//
+// A slice of structs, or of pointers to them, has a column for each exported field,
+// named by its `db` tag or else the field name, as InsertJSON does; any other slice
+// has one column, "value". A map has a "key" column before those of its values,
+// and its rows are in the order of their keys. Columns are typed by their fields:
+// integers as INTEGER, floats as REAL, strings as TEXT, []byte as BLOB, bools as
+// BOOLEAN, time.Time as TIMESTAMP, and anything else as JSON text; types registered
+// with RegisterType are encoded as they are for queries. Nil pointers are NULL.
+//
+// The table is on every connection of the pool, including those opened later, but
+// as with Attach, connections in use while it's called get it when they're next
+// opened. Registering a name again replaces the data.
+// Tables must be enabled by using the build tag "vtable" or "sqlite_vtable"
+func RegisterSliceTable(db *sql.DB, name string, data interface{}) error {
+	module, err := newSliceModule(data)
+	if err != nil {
+		return fmt.Errorf("slice table %s: %w", name, err)
+	}
+	return addModule(db, moduleReg{
+		name: name,
+		register: func(conn *sqlite3.SQLiteConn) error {
+			return conn.CreateModule(name, module)
+		},
+	})
+}
+
+// sliceModule is an eponymous-only module, a table of its own name on each connection
+type sliceModule struct {
+	data    reflect.Value // the slice, array, or map, or a pointer to one
+	columns []sliceColumn
+}
+
+// sliceColumn is a column of a slice table
+type sliceColumn struct {
+	name  string
+	ctype string
+	key   bool  // the key of a map
+	field []int // the index of the field in a struct, nil for the whole value
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func newSliceModule(data interface{}) (*sliceModule, error) {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("no data")
+	}
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	m := &sliceModule{data: v}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+	case reflect.Map:
+		m.columns = append(m.columns, sliceColumn{name: "key", ctype: sliceType(t.Key()), key: true})
+	default:
+		return nil, fmt.Errorf("%s is not a slice, array, or map", t)
+	}
+
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if _, ok := lookupType(elem); ok || elem.Kind() != reflect.Struct || elem == timeType {
+		m.columns = append(m.columns, sliceColumn{name: "value", ctype: sliceType(elem)})
+		return m, nil
+	}
+	for i := 0; i < elem.NumField(); i++ {
+		f := elem.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		name := f.Tag.Get("db")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		m.columns = append(m.columns, sliceColumn{name: name, ctype: sliceType(f.Type), field: f.Index})
+	}
+	if len(m.columns) == 0 {
+		return nil, fmt.Errorf("%s has no exported fields", elem)
+	}
+	return m, nil
+}
+
+// sliceType returns the declared type of a column for values of the type
+func sliceType(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if _, ok := lookupType(t); ok {
+		return ""
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	case reflect.String:
+		return "TEXT"
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB"
+		}
+	case reflect.Struct:
+		if t == timeType {
+			return "TIMESTAMP"
+		}
+	}
+	return "TEXT"
+}
+
+func (m *sliceModule) EponymousOnlyModule() {}
+
+func (m *sliceModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Connect(c, args)
+}
+
+func (m *sliceModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	defs := make([]string, len(m.columns))
+	for i, col := range m.columns {
+		defs[i] = strings.TrimSpace(quoteIdent(col.name) + " " + col.ctype)
+	}
+	if err := c.DeclareVTab(fmt.Sprintf("CREATE TABLE x(%s)", strings.Join(defs, ", "))); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *sliceModule) DestroyModule() {}
+
+func (m *sliceModule) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	// every query scans the rows
+	n := float64(m.len())
+	return &sqlite3.IndexResult{Used: make([]bool, len(cst)), EstimatedCost: n, EstimatedRows: n}, nil
+}
+
+func (m *sliceModule) Disconnect() error { return nil }
+func (m *sliceModule) Destroy() error    { return nil }
+
+func (m *sliceModule) Open() (sqlite3.VTabCursor, error) {
+	return &sliceCursor{module: m}, nil
+}
+
+// len returns the number of rows
+func (m *sliceModule) len() int {
+	v := reflect.Indirect(m.data)
+	if !v.IsValid() {
+		return 0
+	}
+	return v.Len()
+}
+
+// rows returns the keys, for a map, and the values of the rows
+func (m *sliceModule) rows() (keys, values []reflect.Value) {
+	v := reflect.Indirect(m.data)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Map {
+		values = make([]reflect.Value, v.Len())
+		for i := range values {
+			values[i] = v.Index(i)
+		}
+		return nil, values
+	}
+	keys = v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return lessKey(keys[i], keys[j]) })
+	values = make([]reflect.Value, len(keys))
+	for i, k := range keys {
+		values[i] = v.MapIndex(k)
+	}
+	return keys, values
+}
+
+// lessKey orders map keys by value where they're numbers or strings
+func lessKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// sliceCursor reads the rows as they were when the query started
+type sliceCursor struct {
+	module       *sliceModule
+	keys, values []reflect.Value
+	i            int
+}
+
+func (c *sliceCursor) Close() error { return nil }
+
+func (c *sliceCursor) Filter(int, string, []interface{}) error {
+	c.keys, c.values = c.module.rows()
+	c.i = 0
+	return nil
+}
+
+func (c *sliceCursor) Next() error {
+	c.i++
+	return nil
+}
+
+func (c *sliceCursor) EOF() bool { return c.i >= len(c.values) }
+
+func (c *sliceCursor) Rowid() (int64, error) { return int64(c.i + 1), nil }
+
+func (c *sliceCursor) Column(ctx *sqlite3.SQLiteContext, col int) error {
+	column := c.module.columns[col]
+	var v reflect.Value
+	if column.key {
+		v = c.keys[c.i]
+	} else {
+		v = c.values[c.i]
+		if column.field != nil {
+			if v = reflect.Indirect(v); !v.IsValid() {
+				ctx.ResultNull()
+				return nil
+			}
+			v = v.FieldByIndex(column.field)
+		}
+	}
+	value, err := sliceValue(v)
+	if err != nil {
+		return fmt.Errorf("column %s: %w", column.name, err)
+	}
+	switch value := value.(type) {
+	case nil:
+		ctx.ResultNull()
+	case int64:
+		ctx.ResultInt64(value)
+	case float64:
+		ctx.ResultDouble(value)
+	case bool:
+		ctx.ResultBool(value)
+	case []byte:
+		ctx.ResultBlob(value)
+	case string:
+		ctx.ResultText(value)
+	case time.Time:
+		ctx.ResultText(value.Format(sqlite3.SQLiteTimestampFormats[0]))
+	default:
+		return fmt.Errorf("column %s: unsupported value %T", column.name, value)
+	}
+	return nil
+}
+
+// sliceValue converts a Go value to one SQLite can store
+func sliceValue(v reflect.Value) (interface{}, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	x := v.Interface()
+	if valuer, ok := encoded(x); ok {
+		return valuer.(driver.Valuer).Value()
+	}
+	if valuer, ok := x.(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+	case reflect.Struct:
+		if t, ok := x.(time.Time); ok {
+			return t, nil
+		}
+	}
+	text, err := json.Marshal(x)
+	return string(text), err
+}
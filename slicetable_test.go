@@ -0,0 +1,90 @@
+//go:build sqlite_vtable || vtable
+// +build sqlite_vtable vtable
+
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegisterSliceTable(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "slices.db"), WithDriver("slice_tables"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	if _, err := db.Exec("create table orders (id integer primary key, sku text, qty int)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into orders (sku, qty) values ('a1', 2), ('b2', 1), ('a1', 5), ('zz', 1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	type product struct {
+		SKU     string `db:"sku"`
+		Price   float64
+		Added   time.Time
+		Tags    []string
+		Note    *string
+		private int
+	}
+	note := "fragile"
+	added := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	products := []product{
+		{SKU: "a1", Price: 1.5, Added: added, Tags: []string{"x"}, Note: &note},
+		{SKU: "b2", Price: 10},
+	}
+	if err := RegisterSliceTable(db, "products", &products); err != nil {
+		t.Fatal(err)
+	}
+
+	var total float64
+	if err := db.QueryRow("select sum(o.qty * p.price) from orders o join products p using (sku)").Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != 20.5 {
+		t.Errorf("got total %v, want 20.5", total)
+	}
+	var when time.Time
+	var tags string
+	var got *string
+	if err := db.QueryRow("select added, tags, note from products where sku = 'a1'").Scan(&when, &tags, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !when.Equal(added) || tags != `["x"]` || got == nil || *got != note {
+		t.Errorf("got %v, %s, %v", when, tags, got)
+	}
+
+	// the slice is read as it is, through the pointer
+	products = append(products, product{SKU: "zz", Price: 100})
+	var n int
+	if err := db.QueryRow("select count(*) from orders join products using (sku)").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("got %d orders with products, want 4", n)
+	}
+	if _, err := db.Exec("insert into products (sku) values ('new')"); err == nil {
+		t.Error("expected a read-only table")
+	}
+
+	stock := map[string]int{"b2": 7, "a1": 3}
+	if err := RegisterSliceTable(db, "stock", stock); err != nil {
+		t.Fatal(err)
+	}
+	// a connection opened afterwards has the tables too
+	db.SetMaxIdleConns(0)
+	var keys string
+	if err := db.QueryRow("select group_concat(key || '=' || value) from stock").Scan(&keys); err != nil {
+		t.Fatal(err)
+	}
+	if keys != "a1=3,b2=7" {
+		t.Errorf("got %s", keys)
+	}
+
+	if err := RegisterSliceTable(db, "bad", 42); err == nil {
+		t.Error("expected an error for data that isn't a collection")
+	}
+}
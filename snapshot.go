@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// Snapshot is an in-memory copy of a database's state, as captured by SnapshotState
+type Snapshot struct {
+	conn *sqlite3.SQLiteConn
+}
+
+// memConn returns a private in-memory connection not tied to any pool
+func memConn() (*sqlite3.SQLiteConn, error) {
+	return driverConn(":memory:")
+}
+
+// driverConn returns a private connection not tied to any pool
+func driverConn(dsn string) (*sqlite3.SQLiteConn, error) {
+	dc, err := (&sqlite3.SQLiteDriver{}).Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	conn, ok := dc.(*sqlite3.SQLiteConn)
+	if !ok {
+		dc.Close()
+		return nil, fmt.Errorf("unexpected driver connection: %T", dc)
+	}
+	return conn, nil
+}
+
+// copyConn copies the entire main database of src into dest
+func copyConn(dest, src *sqlite3.SQLiteConn) error {
+	bk, err := dest.Backup("main", src, "main")
+	if err != nil {
+		return err
+	}
+	for {
+		var done bool
+		if done, err = bk.Step(-1); done || err != nil {
+			break
+		}
+	}
+	if ferr := bk.Finish(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// SnapshotState captures the current contents of the database in memory,
+// so that it can later be restored with ResetTo
+//
+// This is intended for test suites, which can load their schema and fixtures
+// once and then reset to that state between cases
+func SnapshotState(db *sql.DB) (*Snapshot, error) {
+	conn, err := memConn()
+	if err != nil {
+		return nil, err
+	}
+	err = rawConn(context.Background(), db, func(src *sqlite3.SQLiteConn) error {
+		return copyConn(conn, src)
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("snapshot failed: %w", err)
+	}
+	return &Snapshot{conn: conn}, nil
+}
+
+// ResetTo replaces the contents of the database with the snapshot
+//
+// Note that each connection to a ":memory:" database is a separate database,
+// so only the connection used for the reset is restored
+func ResetTo(db *sql.DB, snap *Snapshot) error {
+	if snap == nil || snap.conn == nil {
+		return fmt.Errorf("invalid snapshot")
+	}
+	err := rawConn(context.Background(), db, func(dest *sqlite3.SQLiteConn) error {
+		return copyConn(dest, snap.conn)
+	})
+	if err != nil {
+		return fmt.Errorf("reset failed: %w", err)
+	}
+	return nil
+}
+
+// Close releases the memory held by the snapshot
+func (s *Snapshot) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,53 @@
+package sqlite
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func structCount(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from structs"); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestSnapshot(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+
+	snap, err := SnapshotState(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Close()
+
+	if _, err := db.Exec("delete from structs"); err != nil {
+		t.Fatal(err)
+	}
+	if count := structCount(t, db); count != 0 {
+		t.Fatalf("expected no rows but got: %d", count)
+	}
+	if err := ResetTo(db, snap); err != nil {
+		t.Fatal(err)
+	}
+	if count := structCount(t, db); count != 4 {
+		t.Fatalf("expected 4 rows but got: %d", count)
+	}
+}
+
+func TestSnapshotClosed(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+
+	snap, err := SnapshotState(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap.Close()
+	if err := ResetTo(db, snap); err == nil {
+		t.Fatal("expected error for closed snapshot")
+	}
+}
@@ -0,0 +1,100 @@
+--
+-- PostgreSQL database dump
+--
+
+\restrict abc123
+
+SET statement_timeout = 0;
+SET client_encoding = 'UTF8';
+SET standard_conforming_strings = on;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE SCHEMA app;
+ALTER SCHEMA app OWNER TO postgres;
+
+CREATE FUNCTION public.touch() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+  NEW.updated := now();
+  RETURN NEW;
+END;
+$$;
+
+SET default_tablespace = '';
+
+CREATE TABLE public.users (
+    id integer NOT NULL,
+    name character varying(40) NOT NULL,
+    email text,
+    active boolean DEFAULT true NOT NULL,
+    balance numeric(10,2) DEFAULT 0.00,
+    avatar bytea,
+    tags text[],
+    created timestamp without time zone DEFAULT now() NOT NULL,
+    kind character varying DEFAULT 'member'::character varying
+);
+
+ALTER TABLE public.users OWNER TO postgres;
+
+CREATE SEQUENCE public.users_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+ALTER SEQUENCE public.users_id_seq OWNED BY public.users.id;
+
+CREATE TABLE app.orders (
+    id bigint NOT NULL,
+    user_id integer,
+    total double precision,
+    note text
+);
+
+CREATE VIEW public.active_users AS
+ SELECT users.id,
+    users.name
+   FROM public.users
+  WHERE (users.active = true);
+
+ALTER TABLE ONLY public.users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+
+COPY public.users (id, name, email, active, balance, avatar, tags, created, kind) FROM stdin;
+1	alice	alice@example.com	t	10.50	\\x0102ff	{a,b}	2020-01-02 03:04:05	member
+2	bob	\N	f	0.00	\N	\N	2021-06-07 08:09:10	admin
+3	o'neil	tab\there	t	1.00	\N	\N	2022-01-01 00:00:00	line\nbreak
+\.
+
+COPY app.orders (id, user_id, total, note) FROM stdin;
+10	1	99.5	first
+11	2	5	\N
+\.
+
+SELECT pg_catalog.setval('public.users_id_seq', 3, true);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE ONLY app.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY (id);
+
+CREATE INDEX users_name_idx ON public.users USING btree (lower((name)::text) text_pattern_ops);
+
+CREATE INDEX users_tags_idx ON public.users USING gin (tags);
+
+CREATE TRIGGER users_touch BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION public.touch();
+
+ALTER TABLE ONLY app.orders
+    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+
+GRANT ALL ON TABLE public.users TO reader;
+
+--
+-- PostgreSQL database dump complete
+--
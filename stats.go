@@ -0,0 +1,157 @@
+package sqlite
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// StatAggregates are statistical aggregate functions missing from SQLite:
+// median(x), percentile(x, p) for p from 0 to 100, variance(x), stddev(x), and mode(x)
+//
+// Variance and standard deviation are of a sample. NULLs are ignored, and because the driver
+// can't return NULL from a numeric function, an empty group returns 0 rather than NULL
+var StatAggregates = []AggregateReg{
+	{"median", newMedian, true},
+	{"percentile", newPercentile, true},
+	{"variance", newVariance, true},
+	{"stddev", newStddev, true},
+	{"mode", newMode, true},
+}
+
+// number converts a numeric SQL value to a float, ignoring everything else
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// Percentile returns the p-th percentile (0 to 100) of values, interpolating between
+// the closest ranks. The values are sorted in place
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	rank := p / 100 * float64(len(values)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return values[lo] + (values[hi]-values[lo])*(rank-float64(lo))
+}
+
+type percentileAgg struct {
+	values []float64
+	p      float64
+}
+
+func newMedian() *medianAgg {
+	return &medianAgg{}
+}
+
+type medianAgg struct {
+	percentileAgg
+}
+
+func (a *medianAgg) Step(v interface{}) {
+	if f, ok := number(v); ok {
+		a.values = append(a.values, f)
+	}
+}
+
+func (a *medianAgg) Done() float64 {
+	return Percentile(a.values, 50)
+}
+
+func newPercentile() *percentileAgg {
+	return &percentileAgg{p: -1}
+}
+
+func (a *percentileAgg) Step(v, percentile interface{}) error {
+	p, ok := number(percentile)
+	if !ok || p < 0 || p > 100 {
+		return fmt.Errorf("percentile must be between 0 and 100: %v", percentile)
+	}
+	if a.p >= 0 && p != a.p {
+		return fmt.Errorf("percentile must be the same for all rows")
+	}
+	a.p = p
+	if f, ok := number(v); ok {
+		a.values = append(a.values, f)
+	}
+	return nil
+}
+
+func (a *percentileAgg) Done() float64 {
+	return Percentile(a.values, a.p)
+}
+
+// varianceAgg uses Welford's algorithm for numerical stability
+type varianceAgg struct {
+	n    int
+	mean float64
+	m2   float64
+}
+
+func newVariance() *varianceAgg {
+	return &varianceAgg{}
+}
+
+func (a *varianceAgg) Step(v interface{}) {
+	f, ok := number(v)
+	if !ok {
+		return
+	}
+	a.n++
+	delta := f - a.mean
+	a.mean += delta / float64(a.n)
+	a.m2 += delta * (f - a.mean)
+}
+
+func (a *varianceAgg) Done() float64 {
+	if a.n < 2 {
+		return 0
+	}
+	return a.m2 / float64(a.n-1)
+}
+
+type stddevAgg struct {
+	varianceAgg
+}
+
+func newStddev() *stddevAgg {
+	return &stddevAgg{}
+}
+
+func (a *stddevAgg) Done() float64 {
+	return math.Sqrt(a.varianceAgg.Done())
+}
+
+// modeAgg returns the most frequent value as text, with ties going to the lowest value
+type modeAgg struct {
+	counts map[string]int
+}
+
+func newMode() *modeAgg {
+	return &modeAgg{counts: make(map[string]int)}
+}
+
+func (a *modeAgg) Step(v interface{}) {
+	if s, ok := sqlText(v); ok {
+		a.counts[s]++
+	}
+}
+
+func (a *modeAgg) Done() string {
+	var mode string
+	best := 0
+	for s, n := range a.counts {
+		if n > best || (n == best && s < mode) {
+			mode, best = s, n
+		}
+	}
+	return mode
+}
@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{15, 20, 35, 40, 50}
+	tests := map[float64]float64{0: 15, 25: 20, 50: 35, 90: 46, 100: 50}
+	for p, expected := range tests {
+		if v := Percentile(values, p); v != expected {
+			t.Errorf("percentile %v expected: %v but got: %v", p, expected, v)
+		}
+	}
+	if v := Percentile(nil, 50); v != 0 {
+		t.Fatalf("expected zero for no values but got: %v", v)
+	}
+}
+
+func TestStatAggregates(t *testing.T) {
+	db, err := Open(":memory:", WithAggregates(StatAggregates...), WithDriver("stats"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const setup = `
+	create table scores (score);
+	insert into scores values(2), (4), (4), (4), (5), (5), (7), (9), (null);
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	var median, p90, variance, stddev float64
+	var mode string
+	const q = "select median(score), percentile(score, 90), variance(score), stddev(score), mode(score) from scores"
+	if err := row(db, []interface{}{&median, &p90, &variance, &stddev, &mode}, q); err != nil {
+		t.Fatal(err)
+	}
+	if median != 4.5 || mode != "4" {
+		t.Errorf("unexpected median: %v mode: %s", median, mode)
+	}
+	if math.Abs(p90-7.6) > 1e-9 {
+		t.Errorf("unexpected 90th percentile: %v", p90)
+	}
+	if math.Abs(variance-32.0/7) > 1e-9 || math.Abs(stddev-math.Sqrt(32.0/7)) > 1e-9 {
+		t.Errorf("unexpected variance: %v stddev: %v", variance, stddev)
+	}
+
+	if err := row(db, []interface{}{&p90}, "select percentile(score, 101) from scores"); err == nil {
+		t.Fatal("expected error for invalid percentile")
+	}
+}
@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// StdFuncs are commonly wanted functions that SQLite doesn't have:
+//
+//	x REGEXP pattern                          whether x matches the Go regular expression
+//	regexp_replace(x, pattern, replacement)   x with the matches replaced, $1 for a group
+//	concat_ws(sep, x, ...)                    the values joined by sep, skipping NULLs
+//	sha256(x)                                 the SHA-256 of text or a blob, in hex
+//	uuid4()                                   a random UUID
+//	unixepoch(time)                           a date and time as seconds since 1970
+//	date_trunc(unit, time)                    the time at the start of its year, month,
+//	                                          week, day, hour, or minute, as datetime() gives
+//
+// Times are text in the formats SQLite's date functions take, or unix seconds.
+// Numbers are taken as the text SQLite would give them, and because the driver can't
+// return NULL from a text function, NULL arguments never match in REGEXP and give
+// empty text from the others, as in UnicodeFuncs
+var StdFuncs = []FuncReg{
+	{"regexp", regexpMatch, true},
+	{"regexp_replace", regexpReplace, true},
+	{"concat_ws", concatWS, true},
+	{"sha256", sha256Hex, true},
+	{"uuid4", uuid4, false},
+	{"unixepoch", unixEpoch, true},
+	{"date_trunc", dateTrunc, true},
+}
+
+// WithStdFuncs registers StdFuncs
+func WithStdFuncs() Optional {
+	return WithFunctions(StdFuncs...)
+}
+
+var (
+	regexpMu sync.Mutex
+	// regexps are the compiled patterns, as a query uses the same one for each row
+	regexps = make(map[string]*regexp.Regexp)
+)
+
+// compileRegexp returns the pattern compiled, from the cache if it's been seen
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexpMu.Lock()
+	defer regexpMu.Unlock()
+	if re, ok := regexps[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(regexps) >= 256 {
+		regexps = make(map[string]*regexp.Regexp)
+	}
+	regexps[pattern] = re
+	return re, nil
+}
+
+// regexpMatch implements the REGEXP operator, for which SQLite calls regexp(pattern, x)
+func regexpMatch(pattern, v interface{}) (bool, error) {
+	p, ok := sqlText(pattern)
+	if !ok {
+		return false, nil
+	}
+	s, ok := sqlText(v)
+	if !ok {
+		return false, nil
+	}
+	re, err := compileRegexp(p)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+func regexpReplace(v, pattern, replacement interface{}) (string, error) {
+	s, ok := sqlText(v)
+	if !ok {
+		return "", nil
+	}
+	p, ok := sqlText(pattern)
+	if !ok {
+		return "", nil
+	}
+	re, err := compileRegexp(p)
+	if err != nil {
+		return "", err
+	}
+	repl, _ := sqlText(replacement)
+	return re.ReplaceAllString(s, repl), nil
+}
+
+func concatWS(sep interface{}, values ...interface{}) string {
+	separator, ok := sqlText(sep)
+	if !ok {
+		return ""
+	}
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := sqlText(v); ok {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+func sha256Hex(v interface{}) string {
+	data, ok := sqlText(v)
+	if !ok {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func uuid4() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+// parseTime reads a time as SQLite's date functions do, in UTC
+func parseTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case float64:
+		return time.Unix(0, int64(v*float64(time.Second))).UTC(), nil
+	case string:
+		s := strings.TrimSuffix(strings.TrimSpace(v), "Z")
+		for _, layout := range sqlite3.SQLiteTimestampFormats {
+			if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid time: %q", v)
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %v", v)
+}
+
+func unixEpoch(v interface{}) (int64, error) {
+	t, err := parseTime(v)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+func dateTrunc(unit string, v interface{}) (string, error) {
+	t, err := parseTime(v)
+	if err != nil {
+		return "", err
+	}
+	y, m, d := t.Date()
+	switch strings.ToLower(unit) {
+	case "year":
+		t = time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "month":
+		t = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case "week":
+		// weeks start on Monday
+		t = time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case "day":
+		t = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case "hour":
+		t = t.Truncate(time.Hour)
+	case "minute":
+		t = t.Truncate(time.Minute)
+	case "second":
+		t = t.Truncate(time.Second)
+	default:
+		return "", fmt.Errorf("date_trunc: unknown unit %q", unit)
+	}
+	return t.Format("2006-01-02 15:04:05"), nil
+}
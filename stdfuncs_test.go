@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestStdFuncs(t *testing.T) {
+	db, err := Open(":memory:", WithDriver("std_funcs"), WithStdFuncs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, test := range []struct {
+		query, want string
+	}{
+		{"select 'abc123' regexp '^[a-z]+[0-9]+$'", "1"},
+		{"select 'abc' regexp '[0-9]'", "0"},
+		{"select regexp_replace('2021-03-04', '(\\d+)-(\\d+)-(\\d+)', '$3/$2/$1')", "04/03/2021"},
+		{"select concat_ws('-', 'a', null, 2, 3.5)", "a-2-3.5"},
+		{"select sha256('abc')", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"select unixepoch('1970-01-02 00:00:00')", "86400"},
+		{"select unixepoch('2021-03-04T05:06:07Z')", "1614834367"},
+		{"select date_trunc('month', '2021-03-04 05:06:07')", "2021-03-01 00:00:00"},
+		{"select date_trunc('week', '2021-03-04 05:06:07')", "2021-03-01 00:00:00"},
+		{"select date_trunc('hour', 1614834367)", "2021-03-04 05:00:00"},
+		{"select strftime('%Y', date_trunc('year', '2021-03-04'))", "2021"},
+		{"select 12345 regexp '^1[0-9]+5$'", "1"},
+		{"select null regexp 'x'", "0"},
+		{"select regexp_replace(2021, '0', 'o')", "2o21"},
+		{"select regexp_replace(null, 'a', 'b')", ""},
+		{"select concat_ws(null, 'a', 'b')", ""},
+		{"select sha256(x'616263')", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"select sha256(null)", ""},
+	} {
+		var got string
+		if err := db.QueryRow(test.query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", test.query, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.query, got, test.want)
+		}
+	}
+
+	var a, b string
+	if err := db.QueryRow("select uuid4(), uuid4()").Scan(&a, &b); err != nil {
+		t.Fatal(err)
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(a) || a == b {
+		t.Errorf("got uuids %s and %s", a, b)
+	}
+	if err := db.QueryRow("select 'x' regexp '('").Scan(&a); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if err := db.QueryRow("select date_trunc('fortnight', '2021-03-04')").Scan(&a); err == nil {
+		t.Error("expected an error for an unknown unit")
+	}
+}
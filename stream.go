@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fieldIndex maps column names to struct fields, using the `db` tag
+// or else a case insensitive match on the field name
+func fieldIndex(t reflect.Type, columns []string) ([][]int, error) {
+	names := make(map[string][]int)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		name := f.Tag.Get("db")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[strings.ToLower(name)] = f.Index
+	}
+	index := make([][]int, len(columns))
+	for i, col := range columns {
+		idx, ok := names[strings.ToLower(col)]
+		if !ok {
+			return nil, fmt.Errorf("no field in %s for column %q", t, col)
+		}
+		index[i] = idx
+	}
+	return index, nil
+}
+
+// rowScanner returns a function that scans the current row into a new T,
+// which is either a struct with fields for each column or a single column value
+func rowScanner[T any](rows *sql.Rows) (func() (T, error), error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	_, isScanner := reflect.New(t).Interface().(sql.Scanner)
+	if t.Kind() != reflect.Struct || isScanner {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("can't scan %d columns into %s", len(columns), t)
+		}
+		return func() (T, error) {
+			var v T
+			err := rows.Scan(Dests(&v)...)
+			return v, err
+		}, nil
+	}
+
+	index, err := fieldIndex(t, columns)
+	if err != nil {
+		return nil, err
+	}
+	return func() (T, error) {
+		var v T
+		rv := reflect.ValueOf(&v).Elem()
+		dest := make([]interface{}, len(index))
+		for i, idx := range index {
+			dest[i] = rv.FieldByIndex(idx).Addr().Interface()
+		}
+		err := rows.Scan(Dests(dest...)...)
+		return v, err
+	}, nil
+}
+
+// QueryChan streams the results of a query over a channel, scanning each row into a T,
+// which is either a struct with a field for each column or a single column value
+//
+// Rows are read only as fast as they are received, and reading stops when ctx is done.
+// The error channel receives at most one error and is closed once the rows channel is closed
+func QueryChan[T any](ctx context.Context, db *sql.DB, query string, args ...interface{}) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		err := ContextError(ctx, streamRows(ctx, db, out, query, args...))
+		close(out)
+		if err != nil {
+			errc <- err
+		}
+	}()
+	return out, errc
+}
+
+func streamRows[T any](ctx context.Context, db *sql.DB, out chan<- T, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, Args(args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	scan, err := rowScanner[T](rows)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		v, err := scan()
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- v:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return rows.Err()
+}
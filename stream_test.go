@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestQueryChan(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+
+	type item struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+		Kind int
+	}
+	rows, errc := QueryChan[item](context.Background(), db, "select id, name, kind from structs where kind > ? order by id", 10)
+	var list []item
+	for r := range rows {
+		list = append(list, r)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].Name != "abc" || list[0].Kind != 23 {
+		t.Fatalf("unexpected rows: %+v", list)
+	}
+
+	names, errc := QueryChan[string](context.Background(), db, "select name from structs order by name")
+	count := 0
+	for range names {
+		count++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 names but got: %d", count)
+	}
+}
+
+func TestQueryChanCancel(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	names, errc := QueryChan[string](ctx, db, "select name from structs")
+	<-names
+	cancel()
+	for range names {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error but got: %v", err)
+	}
+}
+
+func TestQueryChanBadColumns(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+
+	names, errc := QueryChan[string](context.Background(), db, "select id, name from structs")
+	for range names {
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("expected error scanning two columns into a string")
+	}
+}
@@ -0,0 +1,490 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IndexSuggestion is an index SuggestIndexes expects to make a query cheaper
+type IndexSuggestion struct {
+	Table      string
+	SQL        string // the CREATE INDEX statement
+	Covering   bool   // the index holds every column the query reads from the table
+	Before     string // the step of the plan reading the table without the index
+	After      string // and with it
+	RowsBefore int64  // the estimated rows read from the table without the index
+	RowsAfter  int64  // and with it
+	AvoidsSort bool   // the index gives the order wanted, so the rows aren't sorted
+}
+
+// Benefit is the estimated factor by which the index cuts the rows read
+func (s IndexSuggestion) Benefit() float64 {
+	if s.RowsAfter <= 0 {
+		return float64(s.RowsBefore)
+	}
+	return float64(s.RowsBefore) / float64(s.RowsAfter)
+}
+
+var (
+	// planTable matches a step reading a table, with its alias, e.g. "SCAN TABLE t AS x"
+	planTable = regexp.MustCompile(`^(SCAN|SEARCH) (?:TABLE )?(\S+)(?: AS (\S+))?`)
+
+	// planIndex matches the index a step uses
+	planIndex = regexp.MustCompile(`USING (?:COVERING )?INDEX (\S+)`)
+)
+
+// maxCovering is the most columns a suggested index holds to cover a query
+const maxCovering = 8
+
+// columnUse is how a query uses the columns of a table
+type columnUse struct {
+	table, alias string
+	step         string
+	eq, rng      []string // columns compared for equality with a value, or with a range
+	join         []string // columns compared for equality with a column of another table
+	order        []string // columns the rows are ordered or grouped by, with DESC if descending
+	read         []string // every column named
+}
+
+// SuggestIndexes proposes indexes for the tables a query scans in full or sorts, with
+// an estimate of what each would save. Candidate indexes, made of the columns the query
+// compares for equality, then one compared with a range or those it orders by, and
+// then, to cover the query, the others it reads, are created in a scratch copy of the
+// schema and its statistics, and those the planner then chooses are suggested. Rows are
+// estimated from sqlite_stat1, if ANALYZE has been run, otherwise by counting
+func SuggestIndexes(db *sql.DB, query string, args ...interface{}) ([]IndexSuggestion, error) {
+	before, err := QueryPlan(db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := Schema(db)
+	if err != nil {
+		return nil, err
+	}
+	virtual := make(map[string]bool)
+	for _, obj := range objects {
+		if obj.Virtual {
+			virtual[strings.ToLower(obj.Name)] = true
+		}
+	}
+	sorted := false
+	var uses []*columnUse
+	for _, step := range before {
+		if strings.Contains(step.Detail, "TEMP B-TREE FOR ORDER BY") || strings.Contains(step.Detail, "TEMP B-TREE FOR GROUP BY") {
+			sorted = true
+		}
+		m := planTable.FindStringSubmatch(step.Detail)
+		if m == nil || virtual[strings.ToLower(m[2])] {
+			continue
+		}
+		use := &columnUse{table: m[2], alias: m[3], step: step.Detail}
+		if use.alias == "" {
+			use.alias = use.table
+		}
+		uses = append(uses, use)
+	}
+	if err := queryColumns(db, query, uses); err != nil {
+		return nil, err
+	}
+
+	// candidates by name
+	type candidate struct {
+		use      *columnUse
+		key      []string
+		columns  []string
+		covering bool
+		sql      string
+	}
+	candidates := make(map[string]*candidate)
+	var names []string
+	for _, use := range uses {
+		if strings.HasPrefix(use.step, "SEARCH") && !(sorted && len(use.order) > 0) {
+			continue
+		}
+		// the columns compared with values, and then those compared with the columns of
+		// other tables, which help if the table is read in the inner loop of a join
+		prefixes := [][]string{use.eq}
+		if len(use.join) > 0 {
+			prefixes = append(prefixes, append(append([]string(nil), use.eq...), use.join...))
+		}
+		for _, prefix := range prefixes {
+			key := append([]string(nil), prefix...)
+			switch {
+			case len(use.rng) > 0:
+				key = append(key, use.rng[0])
+			case sorted && len(uses) == 1:
+				key = append(key, use.order...)
+			}
+			if len(key) == 0 {
+				continue
+			}
+			covering := append([]string(nil), key...)
+			for _, c := range use.read {
+				if !containsFold(covering, c) && !containsFold(covering, c+" DESC") {
+					covering = append(covering, c)
+				}
+			}
+			variants := [][]string{key}
+			if len(covering) > len(key) && len(covering) <= maxCovering {
+				variants = append(variants, covering)
+			}
+			for i, cols := range variants {
+				name := indexName(use.table, cols, i > 0)
+				if candidates[name] != nil {
+					continue
+				}
+				terms := make([]string, len(cols))
+				plain := make([]string, len(cols))
+				for j, c := range cols {
+					plain[j] = strings.TrimSuffix(c, " DESC")
+					terms[j] = quoteIdent(plain[j])
+					if plain[j] != c {
+						terms[j] += " DESC"
+					}
+				}
+				candidates[name] = &candidate{
+					use: use, key: prefix, columns: plain, covering: i > 0 || len(covering) == len(key),
+					sql: fmt.Sprintf("CREATE INDEX %s ON %s (%s)", quoteIdent(name), quoteIdent(use.table), strings.Join(terms, ", ")),
+				}
+				names = append(names, name)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	scratch, err := scratchSchema(db, objects)
+	if err != nil {
+		return nil, err
+	}
+	defer Close(scratch)
+	for _, name := range names {
+		if _, err := scratch.Exec(candidates[name].sql); err != nil {
+			return nil, fmt.Errorf("index %s: %w", name, err)
+		}
+	}
+	after, err := QueryPlan(scratch, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	sortedAfter := false
+	for _, step := range after {
+		if strings.Contains(step.Detail, "TEMP B-TREE FOR ORDER BY") || strings.Contains(step.Detail, "TEMP B-TREE FOR GROUP BY") {
+			sortedAfter = true
+		}
+	}
+
+	var suggestions []IndexSuggestion
+	for _, step := range after {
+		m := planIndex.FindStringSubmatch(step.Detail)
+		if m == nil || candidates[m[1]] == nil {
+			continue
+		}
+		c := candidates[m[1]]
+		rows, err := tableRows(db, c.use.table)
+		if err != nil {
+			return nil, err
+		}
+		s := IndexSuggestion{
+			Table:      c.use.table,
+			SQL:        c.sql,
+			Covering:   c.covering,
+			Before:     c.use.step,
+			After:      step.Detail,
+			RowsBefore: rows,
+			RowsAfter:  rows,
+			AvoidsSort: sorted && !sortedAfter,
+		}
+		if len(c.key) > 0 {
+			distinct, err := distinctRows(db, c.use.table, c.key)
+			if err != nil {
+				return nil, err
+			}
+			if distinct > 0 {
+				s.RowsAfter = (rows + distinct - 1) / distinct
+			}
+		}
+		if len(c.use.rng) > 0 && len(c.columns) > len(c.key) && strings.EqualFold(c.columns[len(c.key)], c.use.rng[0]) {
+			// the planner's guess for a range
+			s.RowsAfter = (s.RowsAfter + 3) / 4
+		}
+		if strings.HasPrefix(s.Before, "SEARCH") {
+			s.RowsBefore = s.RowsAfter
+		}
+		if s.RowsAfter < s.RowsBefore || s.AvoidsSort {
+			suggestions = append(suggestions, s)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Benefit() > suggestions[j].Benefit()
+	})
+	return suggestions, nil
+}
+
+// indexName returns a name for a suggested index
+func indexName(table string, columns []string, covering bool) string {
+	var b strings.Builder
+	b.WriteString(table)
+	for _, c := range columns {
+		b.WriteByte('_')
+		b.WriteString(strings.TrimSuffix(c, " DESC"))
+	}
+	if covering {
+		b.WriteString("_covering")
+	}
+	name := []byte(b.String())
+	for i, c := range name {
+		if !isWordByte(c) {
+			name[i] = '_'
+		}
+	}
+	return string(name) + "_idx"
+}
+
+// containsFold reports whether list holds s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, x := range list {
+		if strings.EqualFold(x, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryColumns finds how the query uses the columns of the tables
+func queryColumns(db *sql.DB, query string, uses []*columnUse) error {
+	s := &dumpScanner{src: query, dialect: DialectSQLite}
+	st, _, err := s.statement()
+	if err != nil {
+		return err
+	}
+	tokens := st.tokens
+	columns := make(map[*columnUse][]exportColumn)
+	for _, use := range uses {
+		if columns[use], err = exportColumns(db, use.table); err != nil {
+			return err
+		}
+	}
+	// resolve returns the uses, and the names, of the tables with a column
+	resolve := func(i int) ([]*columnUse, string) {
+		t := tokens[i]
+		if !t.isName() || (i+1 < len(tokens) && (tokens[i+1].punct(".") || tokens[i+1].punct("("))) {
+			return nil, ""
+		}
+		qualifier := ""
+		if i >= 2 && tokens[i-1].punct(".") {
+			qualifier = tokens[i-2].text
+		}
+		var found []*columnUse
+		name := ""
+		for _, use := range uses {
+			if qualifier != "" && !strings.EqualFold(qualifier, use.alias) && !strings.EqualFold(qualifier, use.table) {
+				continue
+			}
+			for _, c := range columns[use] {
+				if strings.EqualFold(c.name, t.text) {
+					found = append(found, use)
+					name = c.name
+				}
+			}
+		}
+		return found, name
+	}
+	// isColumn reports whether a column, which may be qualified, starts at tokens[i]
+	isColumn := func(i int) bool {
+		if i+2 < len(tokens) && tokens[i+1].punct(".") {
+			i += 2
+		}
+		found, _ := resolve(i)
+		return i < len(tokens) && found != nil
+	}
+	add := func(list []string, name string) []string {
+		if containsFold(list, name) {
+			return list
+		}
+		return append(list, name)
+	}
+
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.punct("("):
+			depth++
+		case t.punct(")"):
+			depth--
+		}
+		if (t.is("ORDER") || t.is("GROUP")) && depth == 0 && i+1 < len(tokens) && tokens[i+1].is("BY") {
+			i = orderColumns(tokens, i+2, resolve) - 1
+			continue
+		}
+		found, name := resolve(i)
+		if found == nil {
+			continue
+		}
+		start := i
+		if i >= 2 && tokens[i-1].punct(".") {
+			start = i - 2
+		}
+		eq, rng, join := false, false, false
+		if i+1 < len(tokens) {
+			switch next := tokens[i+1]; {
+			case next.punct("="), next.is("IS", "IN"):
+				eq = true
+				join = next.punct("=") && isColumn(i+2)
+			case next.punct("<"), next.punct(">"), next.punct("<="), next.punct(">="), next.is("BETWEEN", "LIKE", "GLOB"):
+				rng = true
+			}
+		}
+		if start > 1 && tokens[start-1].punct("=") {
+			eq = true
+			join = isColumn(start - 2)
+			if start > 3 && tokens[start-3].punct(".") {
+				join = isColumn(start - 4)
+			}
+		}
+		for _, use := range found {
+			use.read = add(use.read, name)
+			switch {
+			case join:
+				use.join = add(use.join, name)
+			case eq:
+				use.eq = add(use.eq, name)
+			case rng:
+				use.rng = add(use.rng, name)
+			}
+		}
+	}
+	return nil
+}
+
+// orderColumns reads the terms of an ORDER BY or GROUP BY clause starting at tokens[i],
+// adding them to the order of the table they are all columns of, and returns the index
+// of the token after the clause
+func orderColumns(tokens []dumpToken, i int, resolve func(int) ([]*columnUse, string)) int {
+	var order []string
+	var owner *columnUse
+	simple := true
+	depth := 0
+clause:
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.punct("("):
+			depth++
+			continue
+		case t.punct(")"):
+			if depth == 0 {
+				break clause
+			}
+			depth--
+			continue
+		case depth == 0 && t.is("LIMIT", "HAVING", "WINDOW", "UNION", "EXCEPT", "INTERSECT", "ORDER"):
+			break clause
+		case t.is("DESC"):
+			if len(order) > 0 {
+				order[len(order)-1] += " DESC"
+			}
+			continue
+		}
+		found, name := resolve(i)
+		if found == nil {
+			continue
+		}
+		if len(found) != 1 || (owner != nil && owner != found[0]) || depth > 0 {
+			// an expression, or columns of more than one table
+			simple = false
+			continue
+		}
+		owner = found[0]
+		order = append(order, name)
+	}
+	if simple && owner != nil {
+		owner.order = order
+		for _, c := range order {
+			if name := strings.TrimSuffix(c, " DESC"); !containsFold(owner.read, name) {
+				owner.read = append(owner.read, name)
+			}
+		}
+	}
+	return i
+}
+
+// scratchSchema returns an in-memory database with the tables, indexes, and views of
+// the objects, and the statistics of db
+func scratchSchema(db *sql.DB, objects []SchemaObject) (*sql.DB, error) {
+	scratch, err := Open(":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// each connection to :memory: is a database of its own
+	scratch.SetMaxOpenConns(1)
+	for _, obj := range objects {
+		if obj.Kind == "trigger" {
+			continue
+		}
+		if _, err := scratch.Exec(obj.SQL); err != nil {
+			Close(scratch)
+			return nil, fmt.Errorf("%s %s: %w", obj.Kind, obj.Name, err)
+		}
+	}
+	var stats []string
+	rows, err := db.Query("SELECT tbl, idx, stat FROM sqlite_stat1")
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var tbl string
+			var idx sql.NullString
+			var stat string
+			if err := rows.Scan(&tbl, &idx, &stat); err != nil {
+				Close(scratch)
+				return nil, err
+			}
+			v := "NULL"
+			if idx.Valid {
+				v = quoteString(idx.String)
+			}
+			stats = append(stats, fmt.Sprintf("(%s, %s, %s)", quoteString(tbl), v, quoteString(stat)))
+		}
+	}
+	if len(stats) > 0 {
+		// ANALYZE of sqlite_master creates sqlite_stat1, and then loads what is put in it
+		script := "ANALYZE sqlite_master; INSERT INTO sqlite_stat1 VALUES " + strings.Join(stats, ", ") + "; ANALYZE sqlite_master;"
+		if _, err := scratch.Exec(script); err != nil {
+			Close(scratch)
+			return nil, err
+		}
+	}
+	return scratch, nil
+}
+
+// tableRows estimates the rows of a table from its statistics, or counts them
+func tableRows(db *sql.DB, table string) (int64, error) {
+	var stat string
+	err := db.QueryRow("SELECT stat FROM sqlite_stat1 WHERE tbl = ? LIMIT 1", table).Scan(&stat)
+	if fields := strings.Fields(stat); err == nil && len(fields) > 0 {
+		if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	var n int64
+	err = db.QueryRow(fmt.Sprintf("SELECT count(*) FROM %s", quoteIdent(table))).Scan(&n)
+	return n, err
+}
+
+// distinctRows counts the distinct values of the columns of a table
+func distinctRows(db *sql.DB, table string, columns []string) (int64, error) {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	var n int64
+	q := fmt.Sprintf("SELECT count(*) FROM (SELECT DISTINCT %s FROM %s)", strings.Join(quoted, ", "), quoteIdent(table))
+	err := db.QueryRow(q).Scan(&n)
+	return n, err
+}
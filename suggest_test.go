@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuggestIndexes(t *testing.T) {
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	const schema = `
+create table customer (id integer primary key, region text, name text);
+create table orders (id integer primary key, customer_id int, status text, total real, placed text);
+with recursive n(i) as (select 1 union all select i + 1 from n where i < 400)
+insert into customer select i, 'r' || (i % 8), 'customer ' || i from n;
+with recursive n(i) as (select 1 union all select i + 1 from n where i < 4000)
+insert into orders select i, i % 400 + 1, case i % 4 when 0 then 'open' else 'closed' end, i * 1.5, date('2020-01-01', '+' || (i % 365) || ' days') from n;
+`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions, err := SuggestIndexes(db, "select id, total from orders where customer_id = ? and placed > '2020-06-01'", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 {
+		t.Fatalf("expected one suggestion, got %+v", suggestions)
+	}
+	s := suggestions[0]
+	if s.Table != "orders" || !strings.Contains(s.SQL, `("customer_id", "placed"`) || !s.Covering {
+		t.Fatalf("unexpected suggestion: %+v", s)
+	}
+	if s.RowsBefore != 4000 || s.RowsAfter != 3 || s.Benefit() < 1000 {
+		t.Fatalf("unexpected estimate: %+v", s)
+	}
+	if _, err := db.Exec(s.SQL); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := SuggestIndexes(db, "select id, total from orders where customer_id = ? and placed > '2020-06-01'", 7); err != nil || len(again) != 0 {
+		t.Fatalf("expected no suggestions once the index exists, got %+v (%v)", again, err)
+	}
+
+	// a join scans the customers, and looks up their orders by the new index
+	suggestions, err = SuggestIndexes(db, "select c.name, o.total from customer c join orders o on o.customer_id = c.id where c.region = 'r3' order by c.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) == 0 || suggestions[0].Table != "customer" || !strings.Contains(suggestions[0].SQL, `("region"`) {
+		t.Fatalf("unexpected suggestions: %+v", suggestions)
+	}
+	if suggestions[0].RowsAfter != 50 {
+		t.Fatalf("expected 50 rows per region, got %+v", suggestions[0])
+	}
+
+	suggestions, err = SuggestIndexes(db, "select * from orders order by status desc limit 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 || !suggestions[0].AvoidsSort || !strings.Contains(suggestions[0].SQL, `"status" DESC`) {
+		t.Fatalf("expected an index for the order, got %+v", suggestions)
+	}
+
+	if suggestions, err := SuggestIndexes(db, "select * from customer where id = 3"); err != nil || suggestions != nil {
+		t.Fatalf("expected no suggestions for a rowid lookup, got %+v (%v)", suggestions, err)
+	}
+}
@@ -109,11 +109,11 @@ func traceCallback(logger *log.Logger) sqlite3.TraceUserCallback {
 		} else {
 			modeText = "+Tx+"
 		}
-		logger.Printf("Trace: ev %d %s conn 0x%x, stmt 0x%x {%q}%s%s%s\n",
+		logger.Print(Redact(fmt.Sprintf("Trace: ev %d %s conn 0x%x, stmt 0x%x {%q}%s%s%s\n",
 			info.EventCode, modeText, info.ConnHandle, info.StmtHandle,
 			info.StmtOrTrigger, expandedText,
 			runTimeText,
-			dbErrText)
+			dbErrText)))
 		return 0
 	}
 }
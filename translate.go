@@ -0,0 +1,1361 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Untranslated is a statement of a dump that TranslateDump left out
+type Untranslated struct {
+	Line      int    // the line of the dump where the statement starts
+	Statement string // the statement as written in the dump
+	Reason    string
+}
+
+func (u Untranslated) String() string {
+	return fmt.Sprintf("line %d: %s: %s", u.Line, u.Reason, u.Statement)
+}
+
+// TranslateDump translates a script written by pg_dump or mysqldump into one for SQLite,
+// returning it with the statements that couldn't be translated. It is a best effort for the
+// common subset of a dump: tables, with their column types mapped to SQLite affinities and
+// constraints added later by ALTER TABLE folded in; data, as INSERT statements or COPY blocks;
+// indexes and views. An integer primary key that a sequence or AUTO_INCREMENT fills becomes
+// an INTEGER PRIMARY KEY. Session settings, ownership, grants, comments and sequences are
+// dropped as they have no meaning for SQLite, while functions, triggers, types, and the like
+// are reported as untranslated
+func TranslateDump(d Dialect, dump string) (string, []Untranslated, error) {
+	if d != DialectPostgres && d != DialectMySQL {
+		return "", nil, fmt.Errorf("cannot translate a dump from %s", d)
+	}
+	tr := &translator{
+		dialect: d,
+		schemas: map[string]bool{"public": true, "pg_catalog": true},
+		tables:  make(map[string]*transTable),
+		indexes: make(map[string]bool),
+	}
+	s := &dumpScanner{src: dump, line: 1, dialect: d}
+	for {
+		stmt, ok, err := s.statement()
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			break
+		}
+		if len(stmt.tokens) == 0 {
+			continue
+		}
+		if stmt.tokens[0].is("COPY") {
+			tr.copy(stmt, s)
+			continue
+		}
+		tr.statement(stmt)
+	}
+	skipped := append(s.notes, tr.skipped...)
+	sort.SliceStable(skipped, func(i, j int) bool { return skipped[i].Line < skipped[j].Line })
+
+	var b strings.Builder
+	for _, item := range tr.items {
+		if item.table != nil {
+			b.WriteString(item.table.sql())
+		} else {
+			b.WriteString(item.sql)
+		}
+		b.WriteString(";\n")
+	}
+	return b.String(), skipped, nil
+}
+
+// ImportDump translates a pg_dump or mysqldump script with TranslateDump and runs it on db
+// with ExecScript, in one transaction, returning the statements that weren't translated
+func ImportDump(db *sql.DB, d Dialect, r io.Reader) ([]Untranslated, error) {
+	return ImportDumpContext(context.Background(), db, d, r)
+}
+
+// ImportDumpContext is ImportDump with a context
+func ImportDumpContext(ctx context.Context, db *sql.DB, d Dialect, r io.Reader) ([]Untranslated, error) {
+	dump, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	script, skipped, err := TranslateDump(d, string(dump))
+	if err != nil {
+		return skipped, err
+	}
+	// foreign keys are checked once everything is in place
+	script = "BEGIN;\nPRAGMA defer_foreign_keys=ON;\n" + script + "COMMIT;\n"
+	_, err = ExecScriptContext(ctx, db, script)
+	return skipped, err
+}
+
+// tokenKind is the kind of a token of a dump
+type tokenKind int
+
+const (
+	tkWord   tokenKind = iota // a keyword, unquoted name, or number
+	tkIdent                   // a quoted identifier, without its quotes
+	tkString                  // a string literal, with its escapes decoded
+	tkBlob                    // a hex or binary literal, as its bytes
+	tkPunct                   // an operator or punctuation
+)
+
+// dumpToken is a token of a dump
+type dumpToken struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// is reports whether the token is one of the keywords
+func (t dumpToken) is(words ...string) bool {
+	if t.kind != tkWord {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// punct reports whether the token is the punctuation p
+func (t dumpToken) punct(p string) bool {
+	return t.kind == tkPunct && t.text == p
+}
+
+// isName reports whether the token can name something
+func (t dumpToken) isName() bool {
+	return t.kind == tkIdent || (t.kind == tkWord && t.text != "" && !isDigit(rune(t.text[0])))
+}
+
+// sql returns the token as SQLite reads it
+func (t dumpToken) sql() string {
+	switch t.kind {
+	case tkIdent:
+		return quoteIdent(t.text)
+	case tkString:
+		return sqlLiteral(t.text)
+	case tkBlob:
+		return "X'" + hex.EncodeToString([]byte(t.text)) + "'"
+	}
+	return t.text
+}
+
+// renderTokens joins tokens into SQL
+func renderTokens(tokens []dumpToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && spaced(tokens[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.sql())
+	}
+	return b.String()
+}
+
+// spaced reports whether a space goes between two tokens
+func spaced(prev, t dumpToken) bool {
+	if t.kind == tkPunct && (t.text == "," || t.text == ")" || t.text == "." || t.text == "]") {
+		return false
+	}
+	if prev.kind == tkPunct && (prev.text == "(" || prev.text == "." || prev.text == "[") {
+		return false
+	}
+	return !(t.punct("(") && prev.isName() && !prev.is(parenKeywords...))
+}
+
+// parenKeywords are the keywords followed by a space before a parenthesis
+var parenKeywords = []string{
+	"AND", "AS", "BY", "CHECK", "ELSE", "EXISTS", "FROM", "IN", "IS", "JOIN", "KEY", "NOT", "ON",
+	"OR", "SELECT", "THEN", "USING", "WHEN", "WHERE",
+}
+
+// dumpStatement is a statement of a dump
+type dumpStatement struct {
+	tokens []dumpToken
+	text   string
+	line   int
+}
+
+// dumpScanner splits a dump into statements of tokens
+type dumpScanner struct {
+	src     string
+	pos     int
+	line    int
+	dialect Dialect
+	notes   []Untranslated // statements hidden in mysql's conditional comments
+}
+
+// dollarTag matches the delimiter of a postgres dollar-quoted string
+var dollarTag = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || isDigit(rune(c)) || (c|0x20 >= 'a' && c|0x20 <= 'z')
+}
+
+// skipLine moves to the end of the line
+func (s *dumpScanner) skipLine() {
+	if i := strings.IndexByte(s.src[s.pos:], '\n'); i >= 0 {
+		s.pos += i
+	} else {
+		s.pos = len(s.src)
+	}
+}
+
+// skipSpace moves past white space and comments
+func (s *dumpScanner) skipSpace() {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\n':
+			s.line++
+			s.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			s.pos++
+		case c == '-' && strings.HasPrefix(s.src[s.pos:], "--"), c == '#' && s.dialect == DialectMySQL:
+			s.skipLine()
+		case c == '/' && strings.HasPrefix(s.src[s.pos:], "/*"):
+			end := strings.Index(s.src[s.pos+2:], "*/")
+			if end < 0 {
+				end = len(s.src) - s.pos - 4
+			}
+			body := s.src[s.pos : s.pos+end+4]
+			// mysqldump hides triggers and views in comments run only by mysql, on one line
+			if strings.HasPrefix(body, "/*!") && strings.Contains(strings.ToUpper(body), "CREATE") {
+				text := body
+				if eol := strings.IndexByte(s.src[s.pos:], '\n'); eol > len(body) {
+					text = s.src[s.pos : s.pos+eol]
+				}
+				s.notes = append(s.notes, Untranslated{Line: s.line, Statement: text, Reason: "conditional comment"})
+			}
+			s.line += strings.Count(body, "\n")
+			s.pos += len(body)
+		default:
+			return
+		}
+	}
+}
+
+// statement reads the tokens up to the next semicolon
+func (s *dumpScanner) statement() (dumpStatement, bool, error) {
+	var stmt dumpStatement
+	for {
+		s.skipSpace()
+		if s.pos >= len(s.src) {
+			return stmt, false, nil
+		}
+		// psql meta-commands such as \connect, and mysql's DELIMITER, take the rest of the line
+		if s.src[s.pos] == '\\' || (len(s.src)-s.pos > 10 && strings.EqualFold(s.src[s.pos:s.pos+10], "DELIMITER ")) {
+			s.skipLine()
+			continue
+		}
+		break
+	}
+	start := s.pos
+	stmt.line = s.line
+	for {
+		t, ok, err := s.next()
+		if err != nil {
+			return stmt, false, err
+		}
+		if !ok || t.punct(";") {
+			break
+		}
+		stmt.tokens = append(stmt.tokens, t)
+	}
+	stmt.text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s.src[start:s.pos]), ";"))
+	return stmt, true, nil
+}
+
+// operators are the punctuation of more than one character, longest first
+var operators = []string{"->>", "::", "<=", ">=", "<>", "!=", "||", "->"}
+
+// next returns the next token
+func (s *dumpScanner) next() (dumpToken, bool, error) {
+	s.skipSpace()
+	if s.pos >= len(s.src) {
+		return dumpToken{}, false, nil
+	}
+	t := dumpToken{line: s.line}
+	c := s.src[s.pos]
+	var err error
+	switch {
+	case c == '\'':
+		t.kind = tkString
+		t.text, err = s.quoted(s.dialect == DialectMySQL)
+		return t, true, err
+	case c == '"' && s.dialect == DialectMySQL:
+		t.kind = tkString
+		t.text, err = s.quoted(true)
+		return t, true, err
+	case c == '"' || c == '`':
+		t.kind = tkIdent
+		t.text, err = s.quoted(false)
+		return t, true, err
+	case c == '$' && s.dialect == DialectPostgres && dollarTag.MatchString(s.src[s.pos:]):
+		tag := dollarTag.FindString(s.src[s.pos:])
+		end := strings.Index(s.src[s.pos+len(tag):], tag)
+		if end < 0 {
+			return t, false, fmt.Errorf("line %d: unterminated %s string", s.line, tag)
+		}
+		t.kind = tkString
+		t.text = s.src[s.pos+len(tag) : s.pos+len(tag)+end]
+		s.line += strings.Count(t.text, "\n")
+		s.pos += 2*len(tag) + end
+		return t, true, nil
+	case c == '0' && s.dialect == DialectMySQL && strings.HasPrefix(s.src[s.pos:], "0x"):
+		end := s.pos + 2
+		for end < len(s.src) && isWordByte(s.src[end]) {
+			end++
+		}
+		b, err := hex.DecodeString(s.src[s.pos+2 : end])
+		if err != nil {
+			return t, false, fmt.Errorf("line %d: invalid hex literal: %w", s.line, err)
+		}
+		s.pos = end
+		return dumpToken{kind: tkBlob, text: string(b), line: t.line}, true, nil
+	case isDigit(rune(c)) || (c == '.' && s.pos+1 < len(s.src) && isDigit(rune(s.src[s.pos+1]))):
+		end := s.pos
+		for end < len(s.src) {
+			b := s.src[end]
+			if isDigit(rune(b)) || b == '.' {
+				end++
+			} else if (b == 'e' || b == 'E') && end+1 < len(s.src) {
+				end++
+				if s.src[end] == '+' || s.src[end] == '-' {
+					end++
+				}
+			} else {
+				break
+			}
+		}
+		t.kind, t.text = tkWord, s.src[s.pos:end]
+		s.pos = end
+		return t, true, nil
+	case isWordByte(c):
+		end := s.pos
+		for end < len(s.src) && isWordByte(s.src[end]) {
+			end++
+		}
+		word := s.src[s.pos:end]
+		s.pos = end
+		if s.pos < len(s.src) && s.src[s.pos] == '\'' {
+			switch strings.ToUpper(word) {
+			case "X":
+				text, err := s.quoted(false)
+				if err != nil {
+					return t, false, err
+				}
+				b, err := hex.DecodeString(text)
+				if err != nil {
+					return t, false, fmt.Errorf("line %d: invalid hex literal: %w", t.line, err)
+				}
+				return dumpToken{kind: tkBlob, text: string(b), line: t.line}, true, nil
+			case "E", "N":
+				t.kind = tkString
+				t.text, err = s.quoted(true)
+				return t, true, err
+			}
+		}
+		t.kind, t.text = tkWord, word
+		return t, true, nil
+	}
+	t.kind = tkPunct
+	for _, op := range operators {
+		if strings.HasPrefix(s.src[s.pos:], op) {
+			t.text = op
+			s.pos += len(op)
+			return t, true, nil
+		}
+	}
+	t.text = s.src[s.pos : s.pos+1]
+	s.pos++
+	return t, true, nil
+}
+
+// quoted reads a string or identifier from its opening quote, in which the quote is
+// escaped by doubling it, and with backslash escapes if they are allowed
+func (s *dumpScanner) quoted(backslash bool) (string, error) {
+	q := s.src[s.pos]
+	line := s.line
+	var b strings.Builder
+	for i := s.pos + 1; i < len(s.src); i++ {
+		c := s.src[i]
+		switch {
+		case c == q:
+			if i+1 < len(s.src) && s.src[i+1] == q {
+				b.WriteByte(q)
+				i++
+				continue
+			}
+			s.pos = i + 1
+			return b.String(), nil
+		case c == '\\' && backslash && i+1 < len(s.src):
+			i++
+			n := unescapeByte(&b, s.src[i:], s.dialect)
+			i += n - 1
+			continue
+		case c == '\n':
+			s.line++
+		}
+		b.WriteByte(c)
+	}
+	return "", fmt.Errorf("line %d: unterminated %c", line, q)
+}
+
+// unescapeByte writes the character of the backslash escape at the start of s, returning
+// the length of the escape without its backslash
+func unescapeByte(b *strings.Builder, s string, d Dialect) int {
+	switch c := s[0]; c {
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'v':
+		b.WriteByte('\v')
+	case 'Z':
+		b.WriteByte(0x1a)
+	case '%', '_':
+		// mysql keeps the backslash, as these are only escaped for LIKE
+		if d == DialectMySQL {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	case 'x':
+		n := 1
+		var v byte
+		for ; n < 3 && n < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[n]) >= 0; n++ {
+			x := s[n] | 0x20
+			if isDigit(rune(s[n])) {
+				x = s[n] - '0'
+			} else {
+				x = x - 'a' + 10
+			}
+			v = v<<4 | x
+		}
+		if n == 1 {
+			b.WriteByte('x')
+		} else {
+			b.WriteByte(v)
+		}
+		return n
+	default:
+		if c >= '0' && c <= '7' && d == DialectPostgres {
+			n := 0
+			var v byte
+			for ; n < 3 && n < len(s) && s[n] >= '0' && s[n] <= '7'; n++ {
+				v = v<<3 | (s[n] - '0')
+			}
+			b.WriteByte(v)
+			return n
+		}
+		if c == '0' {
+			b.WriteByte(0)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return 1
+}
+
+// copyUnescape decodes a field of a postgres COPY block
+func copyUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i += unescapeByte(&b, s[i+1:], DialectPostgres)
+	}
+	return b.String()
+}
+
+// copyRows reads the rows of a COPY ... FROM stdin statement, up to the line "\."
+func (s *dumpScanner) copyRows() [][]string {
+	s.skipLine()
+	if s.pos < len(s.src) {
+		s.pos++
+		s.line++
+	}
+	var rows [][]string
+	for s.pos < len(s.src) {
+		var line string
+		if end := strings.IndexByte(s.src[s.pos:], '\n'); end >= 0 {
+			line = s.src[s.pos : s.pos+end]
+			s.pos += end + 1
+		} else {
+			line = s.src[s.pos:]
+			s.pos = len(s.src)
+		}
+		s.line++
+		line = strings.TrimSuffix(line, "\r")
+		if line == `\.` {
+			break
+		}
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	return rows
+}
+
+// group returns the tokens inside the parentheses starting at tokens[i], and the index after them
+func group(tokens []dumpToken, i int) ([]dumpToken, int) {
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		switch {
+		case tokens[j].punct("("):
+			depth++
+		case tokens[j].punct(")"):
+			if depth--; depth == 0 {
+				return tokens[i+1 : j], j + 1
+			}
+		}
+	}
+	return tokens[i+1:], len(tokens)
+}
+
+// splitList splits tokens at the commas outside of parentheses
+func splitList(tokens []dumpToken) [][]dumpToken {
+	var items [][]dumpToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.punct("("):
+			depth++
+		case t.punct(")"):
+			depth--
+		case t.punct(",") && depth == 0:
+			items = append(items, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, tokens[start:])
+}
+
+// accept advances i past the words if they come next
+func accept(tokens []dumpToken, i *int, words ...string) bool {
+	if *i+len(words) > len(tokens) {
+		return false
+	}
+	for j, w := range words {
+		if !tokens[*i+j].is(w) {
+			return false
+		}
+	}
+	*i += len(words)
+	return true
+}
+
+// qualifiedName reads a name that may have a schema, returning it without the schema
+func qualifiedName(tokens []dumpToken, i int) (string, int) {
+	if i >= len(tokens) || !tokens[i].isName() {
+		return "", i
+	}
+	name := tokens[i].text
+	for i++; i+1 < len(tokens) && tokens[i].punct(".") && tokens[i+1].isName(); i += 2 {
+		name = tokens[i+1].text
+	}
+	return name, i
+}
+
+// castEnd returns the index after the type of a postgres cast starting at tokens[i]
+func castEnd(tokens []dumpToken, i int) int {
+	if _, j := qualifiedName(tokens, i); j > i {
+		i = j
+	} else {
+		return i
+	}
+	for i < len(tokens) && tokens[i].is("varying", "without", "with", "time", "zone", "precision") {
+		i++
+	}
+	if i < len(tokens) && tokens[i].punct("(") {
+		_, i = group(tokens, i)
+	}
+	for i+1 < len(tokens) && tokens[i].punct("[") && tokens[i+1].punct("]") {
+		i += 2
+	}
+	return i
+}
+
+// transTable is a table being translated, which is written once the whole dump is read
+type transTable struct {
+	name        string
+	columns     []*transColumn
+	primaryKey  []string
+	constraints []string
+}
+
+// transColumn is a column of a table being translated
+type transColumn struct {
+	name   string
+	ctype  string
+	serial bool     // filled by a sequence or AUTO_INCREMENT
+	parts  []string // its constraints
+}
+
+// column returns the named column
+func (t *transTable) column(name string) *transColumn {
+	for _, c := range t.columns {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// sql returns the statement creating the table. A single integer primary key
+// becomes INTEGER PRIMARY KEY, so it takes the rowid as it would a sequence
+func (t *transTable) sql() string {
+	var alias *transColumn
+	if len(t.primaryKey) == 1 {
+		if c := t.column(t.primaryKey[0]); c != nil && c.ctype == "INTEGER" {
+			alias = c
+		}
+	}
+	defs := make([]string, 0, len(t.columns)+len(t.constraints)+1)
+	for _, c := range t.columns {
+		def := quoteIdent(c.name) + " " + c.ctype
+		if c == alias {
+			def += " PRIMARY KEY"
+		}
+		for _, p := range c.parts {
+			def += " " + p
+		}
+		defs = append(defs, def)
+	}
+	if len(t.primaryKey) > 0 && alias == nil {
+		keys := make([]string, len(t.primaryKey))
+		for i, k := range t.primaryKey {
+			keys[i] = quoteIdent(k)
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(keys, ", ")+")")
+	}
+	defs = append(defs, t.constraints...)
+	return fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", quoteIdent(t.name), strings.Join(defs, ",\n\t"))
+}
+
+// transItem is a translated statement, or a table to write when the dump has been read
+type transItem struct {
+	sql   string
+	table *transTable
+}
+
+// translator translates the statements of a dump
+type translator struct {
+	dialect Dialect
+	schemas map[string]bool // schema names left out of qualified names
+	tables  map[string]*transTable
+	indexes map[string]bool
+	items   []transItem
+	skipped []Untranslated
+}
+
+func (tr *translator) add(sql string) {
+	tr.items = append(tr.items, transItem{sql: sql})
+}
+
+func (tr *translator) skip(stmt dumpStatement, reason string) {
+	tr.skipped = append(tr.skipped, Untranslated{Line: stmt.line, Statement: stmt.text, Reason: reason})
+}
+
+// ignored are the statements that have no meaning for SQLite, which are dropped without a report
+var ignored = map[string]bool{
+	"SET": true, "RESET": true, "LOCK": true, "UNLOCK": true, "USE": true, "BEGIN": true, "START": true,
+	"COMMIT": true, "END": true, "GRANT": true, "REVOKE": true, "COMMENT": true, "ANALYZE": true, "VACUUM": true,
+}
+
+// statement translates a statement other than COPY
+func (tr *translator) statement(stmt dumpStatement) {
+	tokens := stmt.tokens
+	first := strings.ToUpper(tokens[0].text)
+	switch {
+	case tokens[0].kind != tkWord:
+		tr.skip(stmt, "unsupported statement")
+	case ignored[first]:
+	case first == "SELECT":
+		// pg_dump sets the search path and sequence values with functions
+		if len(tokens) > 1 && tokens[1].is("pg_catalog", "set_config", "setval") {
+			return
+		}
+		tr.skip(stmt, "queries are not imported")
+	case first == "CREATE":
+		tr.create(stmt)
+	case first == "ALTER":
+		tr.alter(stmt)
+	case first == "DROP":
+		tr.drop(stmt)
+	case first == "INSERT" || first == "REPLACE":
+		tr.insert(stmt)
+	default:
+		tr.skip(stmt, "unsupported statement")
+	}
+}
+
+// create translates a CREATE statement
+func (tr *translator) create(stmt dumpStatement) {
+	tokens := stmt.tokens
+	i := 1
+	accept(tokens, &i, "OR", "REPLACE")
+	for i < len(tokens) && tokens[i].is("UNLOGGED", "TEMP", "TEMPORARY", "GLOBAL", "LOCAL") {
+		i++
+	}
+	if i >= len(tokens) {
+		tr.skip(stmt, "unsupported statement")
+		return
+	}
+	switch kind := tokens[i]; {
+	case kind.is("TABLE"):
+		tr.createTable(stmt, i+1)
+	case kind.is("INDEX"):
+		tr.createIndex(stmt, i+1, false)
+	case kind.is("UNIQUE") && i+1 < len(tokens) && tokens[i+1].is("INDEX"):
+		tr.createIndex(stmt, i+2, true)
+	case kind.is("VIEW"):
+		tr.add("CREATE VIEW " + renderTokens(tr.expr(tokens[i+1:])))
+	case kind.is("SCHEMA"):
+		j := i + 1
+		accept(tokens, &j, "IF", "NOT", "EXISTS")
+		if name, _ := qualifiedName(tokens, j); name != "" {
+			tr.schemas[strings.ToLower(name)] = true
+		}
+	case kind.is("SEQUENCE"):
+	default:
+		tr.skip(stmt, "unsupported CREATE "+strings.ToUpper(kind.text))
+	}
+}
+
+// tableConstraints are the words that start a table constraint rather than a column
+var tableConstraints = map[string]bool{
+	"CONSTRAINT": true, "PRIMARY": true, "UNIQUE": true, "KEY": true, "INDEX": true, "FOREIGN": true,
+	"CHECK": true, "FULLTEXT": true, "SPATIAL": true, "EXCLUDE": true,
+}
+
+// createTable translates CREATE TABLE, from the tokens after TABLE
+func (tr *translator) createTable(stmt dumpStatement, i int) {
+	tokens := stmt.tokens
+	accept(tokens, &i, "IF", "NOT", "EXISTS")
+	name, i := qualifiedName(tokens, i)
+	if name == "" || i >= len(tokens) || !tokens[i].punct("(") {
+		tr.skip(stmt, "unsupported CREATE TABLE")
+		return
+	}
+	inner, _ := group(tokens, i)
+	t := &transTable{name: name}
+	tr.tables[strings.ToLower(name)] = t
+	// added first, so that indexes for mysql's keys come after it
+	tr.items = append(tr.items, transItem{table: t})
+	for _, item := range splitList(inner) {
+		if len(item) == 0 {
+			continue
+		}
+		if item[0].kind == tkWord && tableConstraints[strings.ToUpper(item[0].text)] {
+			tr.tableConstraint(stmt, t, item)
+		} else {
+			tr.column(t, item)
+		}
+	}
+}
+
+// sqliteType returns the SQLite type for a column type of the dump, keeping the names
+// of dates, times and booleans the driver converts, and whether it's a sequence
+func sqliteType(dumpType string) (string, bool) {
+	u := strings.ToUpper(dumpType)
+	base := u
+	if i := strings.IndexAny(base, " ("); i > 0 {
+		base = base[:i]
+	}
+	switch {
+	case base == "SERIAL" || base == "BIGSERIAL" || base == "SMALLSERIAL" || base == "SERIAL4" || base == "SERIAL8":
+		return "INTEGER", true
+	case strings.HasSuffix(u, "]"):
+		return "TEXT", false // arrays are dumped as text
+	case base == "BOOLEAN" || base == "BOOL" || strings.HasPrefix(u, "TINYINT(1)"):
+		return "BOOLEAN", false
+	case base == "DATE" || base == "DATETIME" || base == "TIMESTAMP":
+		return base, false
+	case base == "TIMESTAMPTZ":
+		return "TIMESTAMP", false
+	}
+	if aff := affinity(dumpType); aff != "" {
+		return aff, false
+	}
+	return "TEXT", false
+}
+
+// columnStops are the words that end a column's type or a constraint's expression
+var columnStops = map[string]bool{
+	"NOT": true, "NULL": true, "DEFAULT": true, "PRIMARY": true, "UNIQUE": true, "KEY": true, "REFERENCES": true,
+	"CHECK": true, "COLLATE": true, "CONSTRAINT": true, "AUTO_INCREMENT": true, "GENERATED": true, "COMMENT": true,
+	"CHARACTER": true, "CHARSET": true, "ON": true, "AS": true,
+}
+
+// stops reports whether the token ends a column's type or expression
+func stops(t dumpToken) bool {
+	return t.kind == tkWord && columnStops[strings.ToUpper(t.text)]
+}
+
+// exprEnd returns the index after an expression of a column definition
+func exprEnd(tokens []dumpToken, i int) int {
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		switch {
+		case tokens[j].punct("("):
+			depth++
+		case tokens[j].punct(")"):
+			depth--
+		case depth == 0 && j > i && stops(tokens[j]):
+			return j
+		}
+	}
+	return len(tokens)
+}
+
+// column translates a column definition
+func (tr *translator) column(t *transTable, item []dumpToken) {
+	c := &transColumn{name: item[0].text}
+	t.columns = append(t.columns, c)
+	var typ strings.Builder
+	i := 1
+	for i < len(item) {
+		tk := item[i]
+		switch {
+		case tk.punct("("):
+			var inner []dumpToken
+			inner, i = group(item, i)
+			typ.WriteString("(" + renderTokens(inner) + ")")
+			continue
+		case tk.punct("[") || tk.punct("]"):
+			typ.WriteString(tk.text)
+		case tk.kind == tkWord && (i == 1 || !stops(tk)):
+			if typ.Len() > 0 {
+				typ.WriteByte(' ')
+			}
+			typ.WriteString(tk.text)
+		default:
+			c.ctype, c.serial = sqliteType(typ.String())
+			tr.columnConstraints(c, item[i:])
+			return
+		}
+		i++
+	}
+	c.ctype, c.serial = sqliteType(typ.String())
+}
+
+// sqliteCollations are the collations SQLite has built in
+var sqliteCollations = map[string]bool{"BINARY": true, "NOCASE": true, "RTRIM": true}
+
+// columnConstraints translates the constraints of a column
+func (tr *translator) columnConstraints(c *transColumn, tokens []dumpToken) {
+	for i := 0; i < len(tokens); {
+		tk := tokens[i]
+		switch {
+		case tk.is("CONSTRAINT"):
+			i += 2
+		case accept(tokens, &i, "NOT", "NULL"):
+			c.parts = append(c.parts, "NOT NULL")
+		case tk.is("DEFAULT"):
+			j := exprEnd(tokens, i+1)
+			tr.columnDefault(c, tokens[i+1:j])
+			i = j
+		case accept(tokens, &i, "PRIMARY", "KEY"):
+			c.parts = append(c.parts, "PRIMARY KEY")
+		case tk.is("UNIQUE"):
+			i++
+			accept(tokens, &i, "KEY")
+			c.parts = append(c.parts, "UNIQUE")
+		case tk.is("REFERENCES"):
+			j := referencesEnd(tokens, i+1)
+			c.parts = append(c.parts, renderTokens(tr.expr(tokens[i:j])))
+			i = j
+		case tk.is("CHECK") && i+1 < len(tokens) && tokens[i+1].punct("("):
+			inner, j := group(tokens, i+1)
+			c.parts = append(c.parts, "CHECK ("+renderTokens(tr.expr(inner))+")")
+			i = j
+		case tk.is("COLLATE"):
+			name, j := qualifiedName(tokens, i+1)
+			if sqliteCollations[strings.ToUpper(name)] {
+				c.parts = append(c.parts, "COLLATE "+strings.ToUpper(name))
+			}
+			i = j
+		case tk.is("AUTO_INCREMENT"):
+			c.serial = true
+			i++
+		case tk.is("GENERATED", "AS"):
+			i = tr.generated(c, tokens, i)
+		case tk.is("COMMENT", "CHARSET"):
+			i += 2
+		case accept(tokens, &i, "CHARACTER", "SET"):
+			i++
+		case accept(tokens, &i, "ON", "UPDATE"):
+			i = exprEnd(tokens, i)
+		default:
+			i++
+		}
+	}
+}
+
+// generated translates GENERATED ... AS IDENTITY, which is a sequence, or a generated
+// column, from tokens[i], and returns the index after it
+func (tr *translator) generated(c *transColumn, tokens []dumpToken, i int) int {
+	accept(tokens, &i, "GENERATED")
+	if !accept(tokens, &i, "ALWAYS") {
+		accept(tokens, &i, "BY", "DEFAULT")
+	}
+	accept(tokens, &i, "AS")
+	if accept(tokens, &i, "IDENTITY") {
+		c.serial = true
+		if i < len(tokens) && tokens[i].punct("(") {
+			_, i = group(tokens, i)
+		}
+		return i
+	}
+	if i < len(tokens) && tokens[i].punct("(") {
+		var inner []dumpToken
+		inner, i = group(tokens, i)
+		part := "GENERATED ALWAYS AS (" + renderTokens(tr.expr(inner)) + ")"
+		if i < len(tokens) && tokens[i].is("STORED", "VIRTUAL") {
+			part += " " + strings.ToUpper(tokens[i].text)
+			i++
+		}
+		c.parts = append(c.parts, part)
+	}
+	return i
+}
+
+// referencesEnd returns the index after a foreign key's REFERENCES clause, starting after REFERENCES
+func referencesEnd(tokens []dumpToken, i int) int {
+	_, i = qualifiedName(tokens, i)
+	if i < len(tokens) && tokens[i].punct("(") {
+		_, i = group(tokens, i)
+	}
+	for i < len(tokens) {
+		switch {
+		case accept(tokens, &i, "ON", "DELETE"), accept(tokens, &i, "ON", "UPDATE"):
+			if !accept(tokens, &i, "NO", "ACTION") && !accept(tokens, &i, "SET", "NULL") && !accept(tokens, &i, "SET", "DEFAULT") {
+				i++
+			}
+		case tokens[i].is("MATCH"):
+			i += 2
+		case accept(tokens, &i, "NOT", "DEFERRABLE"), accept(tokens, &i, "DEFERRABLE"),
+			accept(tokens, &i, "INITIALLY", "DEFERRED"), accept(tokens, &i, "INITIALLY", "IMMEDIATE"):
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// timestamps are the functions for the current time, which SQLite has as CURRENT_TIMESTAMP
+var timestamps = map[string]bool{
+	"NOW": true, "CURRENT_TIMESTAMP": true, "LOCALTIMESTAMP": true, "TRANSACTION_TIMESTAMP": true,
+	"STATEMENT_TIMESTAMP": true, "CLOCK_TIMESTAMP": true,
+}
+
+// columnDefault translates a column's default, which is dropped if it takes a value from a sequence
+func (tr *translator) columnDefault(c *transColumn, tokens []dumpToken) {
+	expr := tr.expr(tokens)
+	for _, t := range expr {
+		if t.is("nextval") {
+			c.serial = true
+			return
+		}
+	}
+	switch {
+	case len(expr) == 0:
+	case expr[0].kind == tkWord && timestamps[strings.ToUpper(expr[0].text)]:
+		c.parts = append(c.parts, "DEFAULT CURRENT_TIMESTAMP")
+	case len(expr) == 1, len(expr) == 2 && (expr[0].punct("-") || expr[0].punct("+")):
+		c.parts = append(c.parts, "DEFAULT "+renderTokens(expr))
+	default:
+		c.parts = append(c.parts, "DEFAULT ("+renderTokens(expr)+")")
+	}
+}
+
+// tableConstraint translates a constraint of a table, or one added by ALTER TABLE
+func (tr *translator) tableConstraint(stmt dumpStatement, t *transTable, item []dumpToken) {
+	i := 0
+	name := ""
+	if item[0].is("CONSTRAINT") && len(item) > 2 {
+		name, i = item[1].text, 2
+	}
+	switch tk := item[i]; {
+	case accept(item, &i, "PRIMARY", "KEY"):
+		if i < len(item) && item[i].is("USING") {
+			i += 2
+		}
+		_, t.primaryKey, _ = tr.indexColumns(item, i)
+	case tk.is("UNIQUE"):
+		i++
+		if i < len(item) && item[i].is("KEY", "INDEX") {
+			i++
+		}
+		if i < len(item) && item[i].isName() && !item[i].is("USING") {
+			i++
+		}
+		if i < len(item) && item[i].is("USING") {
+			i += 2
+		}
+		cols, _, _ := tr.indexColumns(item, i)
+		t.constraints = append(t.constraints, constraintName(name)+"UNIQUE ("+cols+")")
+	case tk.is("KEY", "INDEX"):
+		i++
+		if i < len(item) && item[i].isName() && !item[i].is("USING") {
+			name = item[i].text
+			i++
+		}
+		if i < len(item) && item[i].is("USING") {
+			i += 2
+		}
+		cols, names, _ := tr.indexColumns(item, i)
+		if name == "" {
+			name = t.name + "_" + strings.Join(names, "_")
+		}
+		tr.addIndex(false, false, name, t.name, cols, "")
+	case tk.is("FOREIGN", "CHECK"):
+		rest := tr.expr(item[i:])
+		for j := 0; j+1 < len(rest); j++ {
+			if rest[j].is("NOT") && rest[j+1].is("VALID") {
+				rest = rest[:j]
+			}
+		}
+		t.constraints = append(t.constraints, constraintName(name)+renderTokens(rest))
+	default:
+		tr.skip(stmt, "unsupported constraint "+renderTokens(item))
+	}
+}
+
+// constraintName returns the CONSTRAINT clause naming a constraint, if it has a name
+func constraintName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "CONSTRAINT " + quoteIdent(name) + " "
+}
+
+// indexColumns translates the parenthesized columns of an index or key at tokens[i],
+// returning them and the names of the plain columns among them
+func (tr *translator) indexColumns(tokens []dumpToken, i int) (string, []string, int) {
+	if i >= len(tokens) || !tokens[i].punct("(") {
+		return "", nil, i
+	}
+	inner, end := group(tokens, i)
+	var cols, names []string
+	for _, item := range splitList(inner) {
+		var kept []dumpToken
+		for k := 0; k < len(item); k++ {
+			tk := item[k]
+			switch {
+			case tk.punct("(") && k > 0 && item[k-1].isName() && k+2 < len(item) &&
+				item[k+2].punct(")") && item[k+1].kind == tkWord && isDigit(rune(item[k+1].text[0])):
+				k += 2 // a mysql prefix length
+			case tk.kind == tkWord && strings.HasSuffix(strings.ToLower(tk.text), "_ops"):
+				// a postgres operator class
+			case tk.is("COLLATE"):
+				_, next := qualifiedName(item, k+1)
+				k = next - 1
+			default:
+				kept = append(kept, tk)
+			}
+		}
+		if len(kept) == 0 {
+			continue
+		}
+		if kept[0].isName() {
+			names = append(names, kept[0].text)
+		}
+		cols = append(cols, renderTokens(tr.expr(kept)))
+	}
+	return strings.Join(cols, ", "), names, end
+}
+
+// addIndex adds an index, naming it after its table if the name is taken, as mysql's
+// key names only need to be unique within a table
+func (tr *translator) addIndex(unique, ifNotExists bool, name, table, cols, where string) {
+	if tr.indexes[strings.ToLower(name)] {
+		name = table + "_" + name
+	}
+	tr.indexes[strings.ToLower(name)] = true
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if unique {
+		b.WriteString("UNIQUE ")
+	}
+	b.WriteString("INDEX ")
+	if ifNotExists {
+		b.WriteString("IF NOT EXISTS ")
+	}
+	fmt.Fprintf(&b, "%s ON %s (%s)%s", quoteIdent(name), quoteIdent(table), cols, where)
+	tr.add(b.String())
+}
+
+// createIndex translates CREATE INDEX, from the tokens after INDEX
+func (tr *translator) createIndex(stmt dumpStatement, i int, unique bool) {
+	tokens := stmt.tokens
+	accept(tokens, &i, "CONCURRENTLY")
+	ifNotExists := accept(tokens, &i, "IF", "NOT", "EXISTS")
+	var name string
+	if i < len(tokens) && !tokens[i].is("ON") {
+		name, i = qualifiedName(tokens, i)
+	}
+	if !accept(tokens, &i, "ON") {
+		tr.skip(stmt, "unsupported CREATE INDEX")
+		return
+	}
+	accept(tokens, &i, "ONLY")
+	table, i := qualifiedName(tokens, i)
+	if i+1 < len(tokens) && tokens[i].is("USING") {
+		if method := tokens[i+1]; !method.is("btree", "hash") {
+			tr.skip(stmt, "unsupported index method "+method.text)
+			return
+		}
+		i += 2
+	}
+	cols, names, i := tr.indexColumns(tokens, i)
+	if cols == "" {
+		tr.skip(stmt, "unsupported CREATE INDEX")
+		return
+	}
+	var where string
+	for ; i < len(tokens); i++ {
+		if tokens[i].is("WHERE") {
+			where = " WHERE " + renderTokens(tr.expr(tokens[i+1:]))
+			break
+		}
+	}
+	if name == "" {
+		name = table + "_" + strings.Join(names, "_") + "_idx"
+	}
+	tr.addIndex(unique, ifNotExists, name, table, cols, where)
+}
+
+// alter translates ALTER TABLE, folding constraints and defaults into the tables
+func (tr *translator) alter(stmt dumpStatement) {
+	tokens := stmt.tokens
+	i := 1
+	if !accept(tokens, &i, "TABLE") {
+		// sequences, schemas, functions and so on, or their owners
+		for _, t := range tokens {
+			if t.is("OWNER") {
+				return
+			}
+		}
+		if len(tokens) > 1 && tokens[1].is("SEQUENCE", "SCHEMA") {
+			return
+		}
+		tr.skip(stmt, "unsupported statement")
+		return
+	}
+	accept(tokens, &i, "IF", "EXISTS")
+	accept(tokens, &i, "ONLY")
+	name, i := qualifiedName(tokens, i)
+	t := tr.tables[strings.ToLower(name)]
+	for _, action := range splitList(tokens[i:]) {
+		switch {
+		case len(action) == 0:
+		case action[0].is("OWNER"):
+		case len(action) > 1 && action[0].is("DISABLE", "ENABLE") && action[1].is("KEYS"):
+		case t == nil:
+			tr.skip(stmt, "table "+name+" is not created by the dump")
+			return
+		case action[0].is("ADD") && len(action) > 1 && action[1].kind == tkWord && tableConstraints[strings.ToUpper(action[1].text)]:
+			tr.tableConstraint(stmt, t, action[1:])
+		case action[0].is("ALTER"):
+			j := 1
+			accept(action, &j, "COLUMN")
+			if j >= len(action) {
+				tr.skip(stmt, "unsupported ALTER TABLE")
+				continue
+			}
+			c := t.column(action[j].text)
+			j++
+			switch {
+			case c == nil:
+				tr.skip(stmt, "no such column: "+action[j-1].text)
+			case accept(action, &j, "SET", "DEFAULT"):
+				tr.columnDefault(c, action[j:])
+			case accept(action, &j, "SET", "NOT", "NULL"):
+				c.parts = append(c.parts, "NOT NULL")
+			case accept(action, &j, "ADD"):
+				tr.generated(c, action, j)
+			default:
+				tr.skip(stmt, "unsupported ALTER TABLE")
+			}
+		default:
+			tr.skip(stmt, "unsupported ALTER TABLE")
+		}
+	}
+}
+
+// drop translates DROP TABLE, VIEW, or INDEX
+func (tr *translator) drop(stmt dumpStatement) {
+	tokens := tr.expr(stmt.tokens)
+	if len(tokens) < 2 || !tokens[1].is("TABLE", "VIEW", "INDEX") {
+		return
+	}
+	if last := tokens[len(tokens)-1]; last.is("CASCADE", "RESTRICT") {
+		tokens = tokens[:len(tokens)-1]
+	}
+	tr.add(renderTokens(tokens))
+}
+
+// insert translates INSERT, or mysql's REPLACE
+func (tr *translator) insert(stmt dumpStatement) {
+	tokens := tr.expr(stmt.tokens)
+	verb := "INSERT"
+	if tokens[0].is("REPLACE") {
+		verb = "INSERT OR REPLACE"
+	}
+	i := 1
+	for i < len(tokens) && tokens[i].is("IGNORE", "LOW_PRIORITY", "DELAYED", "HIGH_PRIORITY") {
+		if tokens[i].is("IGNORE") {
+			verb = "INSERT OR IGNORE"
+		}
+		i++
+	}
+	rest := make([]dumpToken, 0, len(tokens)-i)
+	for ; i < len(tokens); i++ {
+		switch {
+		case accept(tokens, &i, "ON", "DUPLICATE"):
+			tr.skip(stmt, "unsupported ON DUPLICATE KEY UPDATE")
+			return
+		case tokens[i].is("OVERRIDING") && i+2 < len(tokens):
+			i += 2
+		default:
+			rest = append(rest, tokens[i])
+		}
+	}
+	tr.add(verb + " " + renderTokens(rest))
+}
+
+// copy translates a postgres COPY ... FROM stdin statement and its rows into INSERT statements
+func (tr *translator) copy(stmt dumpStatement, s *dumpScanner) {
+	tokens := stmt.tokens
+	i := 1
+	accept(tokens, &i, "ONLY")
+	name, i := qualifiedName(tokens, i)
+	var columns []string
+	if i < len(tokens) && tokens[i].punct("(") {
+		var inner []dumpToken
+		inner, i = group(tokens, i)
+		for _, item := range splitList(inner) {
+			if len(item) > 0 {
+				columns = append(columns, item[0].text)
+			}
+		}
+	}
+	if name == "" || !accept(tokens, &i, "FROM", "stdin") {
+		tr.skip(stmt, "COPY is only translated from stdin")
+		return
+	}
+	rows := s.copyRows()
+	t := tr.tables[strings.ToLower(name)]
+	if columns == nil && t != nil {
+		for _, c := range t.columns {
+			columns = append(columns, c.name)
+		}
+	}
+	types := make([]string, len(columns))
+	quoted := make([]string, len(columns))
+	for j, col := range columns {
+		quoted[j] = quoteIdent(col)
+		if t != nil {
+			if c := t.column(col); c != nil {
+				types[j] = c.ctype
+			}
+		}
+	}
+	prefix := "INSERT INTO " + quoteIdent(name)
+	if len(columns) > 0 {
+		prefix += " (" + strings.Join(quoted, ", ") + ")"
+	}
+	for n, fields := range rows {
+		if columns != nil && len(fields) != len(columns) {
+			tr.skipped = append(tr.skipped, Untranslated{
+				Line:      stmt.line + 1 + n,
+				Statement: strings.Join(fields, "\t"),
+				Reason:    fmt.Sprintf("%d fields for %d columns", len(fields), len(columns)),
+			})
+			continue
+		}
+		values := make([]string, len(fields))
+		for j, f := range fields {
+			values[j] = copyValue(f, types, j)
+		}
+		tr.add(prefix + " VALUES(" + strings.Join(values, ",") + ")")
+	}
+}
+
+// copyValue returns a field of a COPY block as an SQL literal for its column
+func copyValue(field string, types []string, j int) string {
+	if field == `\N` {
+		return "NULL"
+	}
+	v := copyUnescape(field)
+	var ctype string
+	if j < len(types) {
+		ctype = types[j]
+	}
+	switch {
+	case ctype == "BOOLEAN" && (v == "t" || v == "f"):
+		if v == "t" {
+			return "1"
+		}
+		return "0"
+	case ctype == "BLOB" && strings.HasPrefix(v, `\x`):
+		if b, err := hex.DecodeString(v[2:]); err == nil {
+			return sqlLiteral(b)
+		}
+	}
+	return sqlLiteral(v)
+}
+
+// expr translates the tokens of an expression or statement: names lose their schema,
+// postgres casts are dropped, and mysql's character set introducers are removed
+func (tr *translator) expr(tokens []dumpToken) []dumpToken {
+	out := make([]dumpToken, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.isName() && i+2 < len(tokens) && tokens[i+1].punct(".") && tokens[i+2].isName() &&
+			tr.dialect == DialectPostgres && tr.schemas[strings.ToLower(t.text)]:
+			i++
+		case t.punct("::"):
+			end := castEnd(tokens, i+1)
+			// a bytea literal is text in hex
+			if end > i+1 && tokens[i+1].is("bytea") && len(out) > 0 {
+				if last := out[len(out)-1]; last.kind == tkString && strings.HasPrefix(last.text, `\x`) {
+					if b, err := hex.DecodeString(last.text[2:]); err == nil {
+						out[len(out)-1] = dumpToken{kind: tkBlob, text: string(b), line: last.line}
+					}
+				}
+			}
+			i = end - 1
+		case t.kind == tkWord && strings.HasPrefix(t.text, "_") && tr.dialect == DialectMySQL &&
+			i+1 < len(tokens) && (tokens[i+1].kind == tkString || tokens[i+1].kind == tkBlob):
+			if strings.EqualFold(t.text, "_binary") {
+				out = append(out, dumpToken{kind: tkBlob, text: tokens[i+1].text, line: t.line})
+				i++
+			}
+		default:
+			out = append(out, t)
+		}
+	}
+	return out
+}
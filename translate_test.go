@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtures is where the dumps are, found before TestFile changes the directory
+var fixtures, _ = filepath.Abs("sql")
+
+func TestImportPostgresDump(t *testing.T) {
+	dump, err := os.ReadFile(filepath.Join(fixtures, "pg_dump.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	skipped, err := ImportDump(db, DialectPostgres, bytes.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := make([]string, len(skipped))
+	for i, u := range skipped {
+		reasons[i] = u.Reason
+	}
+	expect := []string{"unsupported CREATE FUNCTION", "unsupported index method gin", "unsupported CREATE TRIGGER"}
+	if strings.Join(reasons, "|") != strings.Join(expect, "|") {
+		t.Fatalf("unexpected statements skipped: %v", skipped)
+	}
+	if skipped[0].Line != 15 {
+		t.Fatalf("expected the function at line 15, got %d", skipped[0].Line)
+	}
+
+	var name, email, note string
+	var active bool
+	var avatar []byte
+	if err := db.QueryRow("select name, email, active, avatar from users where id = 1").Scan(&name, &email, &active, &avatar); err != nil {
+		t.Fatal(err)
+	}
+	if name != "alice" || email != "alice@example.com" || !active || !bytes.Equal(avatar, []byte{1, 2, 0xff}) {
+		t.Fatalf("got %q %q %v %x", name, email, active, avatar)
+	}
+	if err := db.QueryRow("select kind from users where id = 3").Scan(&note); err != nil {
+		t.Fatal(err)
+	}
+	if note != "line\nbreak" {
+		t.Fatalf("got %q", note)
+	}
+	// the primary key was folded in, and the sequence became the rowid
+	if _, err := db.Exec("insert into users (name) values('dave')"); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	if err := db.QueryRow("select id from users where name = 'dave'").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 {
+		t.Fatalf("expected id 4, got %d", id)
+	}
+	if _, err := db.Exec("insert into users (name, email) values('eve', 'alice@example.com')"); err == nil {
+		t.Fatal("expected the unique constraint to be folded in")
+	}
+	var count int
+	if err := db.QueryRow("select count(*) from active_users").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 active users, got %d", count)
+	}
+	fks, err := ForeignKeyList(db, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fks) != 1 || fks[0].Table != "users" {
+		t.Fatalf("unexpected foreign keys: %+v", fks)
+	}
+}
+
+func TestImportMySQLDump(t *testing.T) {
+	dump, err := os.ReadFile(filepath.Join(fixtures, "mysqldump.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := memDB(t)
+	db.SetMaxOpenConns(1)
+	skipped, err := ImportDump(db, DialectMySQL, bytes.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 2 || !strings.Contains(skipped[0].Reason, "FULLTEXT") || !strings.Contains(skipped[1].Statement, "TRIGGER `orders_check`") {
+		t.Fatalf("unexpected statements skipped: %v", skipped)
+	}
+
+	var name string
+	var photo []byte
+	if err := db.QueryRow("select name, photo from customers where id = 1").Scan(&name, &photo); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Ann O'Hara" || !bytes.Equal(photo, []byte{0, 0x1a}) {
+		t.Fatalf("got %q %x", name, photo)
+	}
+	if err := db.QueryRow("select name from customers where id = 2").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Bo \"Bear\"\nSmith" {
+		t.Fatalf("got %q", name)
+	}
+	var indexes int
+	if err := db.QueryRow("select count(*) from sqlite_master where type = 'index' and name in ('idx_name', 'orders_idx_name')").Scan(&indexes); err != nil {
+		t.Fatal(err)
+	}
+	if indexes != 2 {
+		t.Fatalf("expected both idx_name keys as indexes, got %d", indexes)
+	}
+	var amount float64
+	if err := db.QueryRow("select sum(amount) from orders").Scan(&amount); err != nil {
+		t.Fatal(err)
+	}
+	if amount != 15.5 {
+		t.Fatalf("got %v", amount)
+	}
+}
+
+func TestTranslateDump(t *testing.T) {
+	const dump = `
+CREATE TABLE public.t (a integer, b text);
+INSERT INTO public.t VALUES (1, E'it\'s'), (2, '\x41'::bytea);
+CREATE TYPE public.mood AS ENUM ('sad', 'ok');
+COPY public.t (a, b) FROM stdin;
+3	three	extra
+\.
+`
+	script, skipped, err := TranslateDump(DialectPostgres, dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(script, `INSERT INTO t VALUES(1, 'it''s'), (2, X'41');`) {
+		t.Fatalf("unexpected script:\n%s", script)
+	}
+	if len(skipped) != 2 || skipped[0].Line != 4 || skipped[1].Line != 6 || skipped[1].Reason != "3 fields for 2 columns" {
+		t.Fatalf("unexpected statements skipped: %v", skipped)
+	}
+	if _, _, err := TranslateDump(DialectPostgres, "INSERT INTO t VALUES('unterminated);"); err == nil {
+		t.Fatal("expected error for an unterminated string")
+	}
+	if _, _, err := TranslateDump(DialectSQLite, ""); err == nil {
+		t.Fatal("expected error for an unsupported dialect")
+	}
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TxInfo describes a transaction tracked by a TxMonitor
+type TxInfo struct {
+	ID       uint64
+	Started  time.Time
+	Duration time.Duration // how long the transaction has been, or was, open
+	Caller   string        // where the transaction was started
+}
+
+// TxStats are the totals collected by a TxMonitor
+type TxStats struct {
+	Started    uint64
+	Committed  uint64
+	RolledBack uint64
+	Long       uint64 // transactions that exceeded the threshold
+	Open       int
+	Total      time.Duration // the total time of finished transactions
+	Max        time.Duration // the longest finished transaction
+}
+
+// TxMonitor tracks transactions started through it and reports any that stay
+// open longer than its threshold, as long write transactions are the usual cause
+// of WAL growth and busy errors
+type TxMonitor struct {
+	threshold time.Duration
+	onLong    func(TxInfo)
+
+	mu    sync.Mutex
+	next  uint64
+	open  map[uint64]*Tx
+	stats TxStats
+}
+
+// NewTxMonitor returns a monitor that calls onLong for each transaction still open after threshold.
+// If onLong is nil the transaction is logged
+func NewTxMonitor(threshold time.Duration, onLong func(TxInfo)) *TxMonitor {
+	if onLong == nil {
+		onLong = func(info TxInfo) {
+			logf("transaction %d open for %s, started at %s\n", info.ID, info.Duration, info.Caller)
+		}
+	}
+	return &TxMonitor{
+		threshold: threshold,
+		onLong:    onLong,
+		open:      make(map[uint64]*Tx),
+	}
+}
+
+// Tx is a transaction tracked by a TxMonitor
+type Tx struct {
+	*sql.Tx
+	info    TxInfo
+	monitor *TxMonitor
+	timer   *time.Timer
+	once    sync.Once
+}
+
+// Begin starts a tracked transaction
+func (m *TxMonitor) Begin(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	t := &Tx{Tx: tx, monitor: m}
+	t.info.Started = time.Now()
+	if _, file, line, ok := runtime.Caller(1); ok {
+		t.info.Caller = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	m.mu.Lock()
+	m.next++
+	t.info.ID = m.next
+	m.open[t.info.ID] = t
+	m.stats.Started++
+	m.mu.Unlock()
+
+	if m.threshold > 0 {
+		t.timer = time.AfterFunc(m.threshold, func() {
+			m.mu.Lock()
+			_, open := m.open[t.info.ID]
+			if open {
+				m.stats.Long++
+			}
+			m.mu.Unlock()
+			if open {
+				info := t.info
+				info.Duration = time.Since(info.Started)
+				m.onLong(info)
+			}
+		})
+	}
+	return t, nil
+}
+
+// finish records the end of the transaction
+func (t *Tx) finish(committed bool) {
+	t.once.Do(func() {
+		if t.timer != nil {
+			t.timer.Stop()
+		}
+		d := time.Since(t.info.Started)
+		m := t.monitor
+		m.mu.Lock()
+		delete(m.open, t.info.ID)
+		if committed {
+			m.stats.Committed++
+		} else {
+			m.stats.RolledBack++
+		}
+		m.stats.Total += d
+		if d > m.stats.Max {
+			m.stats.Max = d
+		}
+		m.mu.Unlock()
+	})
+}
+
+// Commit commits the transaction. If it fails, including with sql.ErrTxDone as
+// when its context was canceled, the transaction was rolled back
+func (t *Tx) Commit() error {
+	err := t.Tx.Commit()
+	t.finish(err == nil)
+	return err
+}
+
+// Rollback aborts the transaction. A transaction already ended, as by canceling
+// its context, is counted as rolled back unless it was committed
+func (t *Tx) Rollback() error {
+	err := t.Tx.Rollback()
+	t.finish(false)
+	return err
+}
+
+// Stats returns the totals for the monitor's transactions
+func (m *TxMonitor) Stats() TxStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats
+	stats.Open = len(m.open)
+	return stats
+}
+
+// Open returns the transactions that are currently open, oldest first
+func (m *TxMonitor) Open() []TxInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]TxInfo, 0, len(m.open))
+	for _, t := range m.open {
+		info := t.info
+		info.Duration = time.Since(info.Started)
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
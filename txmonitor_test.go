@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTxMonitor(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+
+	long := make(chan TxInfo, 1)
+	m := NewTxMonitor(20*time.Millisecond, func(info TxInfo) {
+		long <- info
+	})
+
+	tx, err := m.Begin(context.Background(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("insert into structs (name) values('tx')"); err != nil {
+		t.Fatal(err)
+	}
+	if open := m.Open(); len(open) != 1 || open[0].Caller == "" {
+		t.Fatalf("unexpected open transactions: %+v", open)
+	}
+
+	select {
+	case info := <-long:
+		if info.ID != 1 || info.Duration < 20*time.Millisecond {
+			t.Fatalf("unexpected long transaction: %+v", info)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("long transaction not reported")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err = m.Begin(context.Background(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	tx.Rollback()
+
+	stats := m.Stats()
+	if stats.Started != 2 || stats.Committed != 1 || stats.RolledBack != 1 || stats.Long != 1 || stats.Open != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	select {
+	case info := <-long:
+		t.Fatalf("unexpected long transaction: %+v", info)
+	case <-time.After(40 * time.Millisecond):
+	}
+}
+
+func TestTxMonitorCanceled(t *testing.T) {
+	db := structDb(t)
+	defer db.Close()
+
+	m := NewTxMonitor(time.Hour, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := m.Begin(ctx, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	// database/sql rolls the transaction back itself when its context is done,
+	// so Rollback has nothing left to do
+	time.Sleep(10 * time.Millisecond)
+	tx.Rollback()
+	if stats := m.Stats(); stats.Open != 0 || stats.RolledBack != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	if tx, err = m.Begin(ctx, db, nil); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected commit of a canceled transaction to fail")
+	}
+	if stats := m.Stats(); stats.Open != 0 || stats.RolledBack != 2 || stats.Committed != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
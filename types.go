@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Encoder converts a registered type into a value the driver can store
+type Encoder func(interface{}) (driver.Value, error)
+
+// Decoder converts a value read from the database into a registered type
+type Decoder func(interface{}) (interface{}, error)
+
+type codec struct {
+	encode Encoder
+	decode Decoder
+}
+
+var (
+	tmu   sync.RWMutex
+	types = make(map[reflect.Type]codec)
+)
+
+// RegisterType registers the functions used to encode and decode values of the same type as sample,
+// so that they can be used directly as query arguments and scan destinations by the package helpers
+//
+// The decoder must return a value of the registered type
+func RegisterType(sample interface{}, encode Encoder, decode Decoder) {
+	tmu.Lock()
+	types[reflect.TypeOf(sample)] = codec{encode: encode, decode: decode}
+	tmu.Unlock()
+}
+
+func lookupType(t reflect.Type) (codec, bool) {
+	tmu.RLock()
+	c, ok := types[t]
+	tmu.RUnlock()
+	return c, ok
+}
+
+// typeValuer encodes a registered type as a query argument
+type typeValuer struct {
+	value  interface{}
+	encode Encoder
+}
+
+func (v typeValuer) Value() (driver.Value, error) {
+	return v.encode(v.value)
+}
+
+// typeScanner decodes into a pointer to a registered type
+type typeScanner struct {
+	dest   reflect.Value
+	decode Decoder
+}
+
+func (s typeScanner) Scan(src interface{}) error {
+	v, err := s.decode(src)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		s.dest.Set(reflect.Zero(s.dest.Type()))
+		return nil
+	}
+	if rv.Type() != s.dest.Type() {
+		return fmt.Errorf("decoder returned %T instead of %s", v, s.dest.Type())
+	}
+	s.dest.Set(rv)
+	return nil
+}
+
+// Args returns args with any values of registered types replaced by driver.Valuers
+func Args(args ...interface{}) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		out[i] = arg
+		if named, ok := arg.(sql.NamedArg); ok {
+			if v, ok := encoded(named.Value); ok {
+				named.Value = v
+				out[i] = named
+			}
+		} else if v, ok := encoded(arg); ok {
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// encoded wraps arg in a driver.Valuer if its type is registered
+func encoded(arg interface{}) (interface{}, bool) {
+	if arg == nil {
+		return nil, false
+	}
+	c, ok := lookupType(reflect.TypeOf(arg))
+	if !ok {
+		return nil, false
+	}
+	return typeValuer{value: arg, encode: c.encode}, true
+}
+
+// Dests returns dest with any pointers to registered types replaced by sql.Scanners
+func Dests(dest ...interface{}) []interface{} {
+	out := make([]interface{}, len(dest))
+	for i, d := range dest {
+		out[i] = d
+		rv := reflect.ValueOf(d)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			continue
+		}
+		if c, ok := lookupType(rv.Type().Elem()); ok {
+			out[i] = typeScanner{dest: rv.Elem(), decode: c.decode}
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func init() {
+	RegisterType(net.IP{},
+		func(v interface{}) (driver.Value, error) {
+			return v.(net.IP).String(), nil
+		},
+		func(src interface{}) (interface{}, error) {
+			var s string
+			switch src := src.(type) {
+			case nil:
+				return net.IP(nil), nil
+			case string:
+				s = src
+			case []byte:
+				s = string(src)
+			default:
+				return nil, fmt.Errorf("can't decode %T as an IP", src)
+			}
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP: %q", s)
+			}
+			return ip, nil
+		},
+	)
+}
+
+func TestRegisteredType(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec("create table hosts (name text, ip text)"); err != nil {
+		t.Fatal(err)
+	}
+	addr := net.ParseIP("10.1.2.3")
+	if _, err := db.Exec("insert into hosts values(?, ?)", Args("gateway", addr)...); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored string
+	if err := row(db, []interface{}{&stored}, "select ip from hosts"); err != nil {
+		t.Fatal(err)
+	}
+	if stored != "10.1.2.3" {
+		t.Fatalf("expected text encoding but got: %q", stored)
+	}
+
+	var ip net.IP
+	if err := row(db, []interface{}{&ip}, "select ip from hosts where ip = ?", addr); err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(addr) {
+		t.Fatalf("expected: %s but got: %s", addr, ip)
+	}
+}
@@ -0,0 +1,135 @@
+package sqlite
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// UnicodeFuncs replace the built-in lower(), upper(), and like() functions with versions
+// that handle all of Unicode rather than just ASCII, without requiring the ICU extension
+//
+// Note that overriding like() disables SQLite's LIKE optimization for indexed columns,
+// and because the driver can't return NULL from a text function, NULL arguments
+// are treated as empty strings by lower() and upper() and never match in like()
+var UnicodeFuncs = []FuncReg{
+	{"lower", unicodeLower, true},
+	{"upper", unicodeUpper, true},
+	{"like", unicodeLike, true},
+}
+
+// sqlText converts a function argument to text the way SQLite would, with nil for NULL
+func sqlText(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), v != nil
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+func unicodeLower(v interface{}) string {
+	s, _ := sqlText(v)
+	return strings.ToLower(s)
+}
+
+func unicodeUpper(v interface{}) string {
+	s, _ := sqlText(v)
+	return strings.ToUpper(s)
+}
+
+// unicodeLike implements "str LIKE pattern [ESCAPE esc]", which SQLite calls as like(pattern, str, esc)
+func unicodeLike(pattern, str interface{}, escape ...interface{}) (bool, error) {
+	p, ok := sqlText(pattern)
+	if !ok {
+		return false, nil
+	}
+	s, ok := sqlText(str)
+	if !ok {
+		return false, nil
+	}
+	esc := rune(-1)
+	if len(escape) > 0 {
+		e, ok := sqlText(escape[0])
+		if !ok {
+			return false, nil
+		}
+		if utf8.RuneCountInString(e) != 1 {
+			return false, errors.New("ESCAPE expression must be a single character")
+		}
+		esc, _ = utf8.DecodeRuneInString(e)
+	}
+	return Like(p, s, esc), nil
+}
+
+// likeToken is a single element of a LIKE pattern
+type likeToken struct {
+	r    rune
+	kind byte // 0 for a literal, '%' or '_' for wildcards
+}
+
+// Like reports whether s matches the SQL LIKE pattern, ignoring case for all Unicode letters
+//
+// Use -1 for esc if the pattern has no escape character
+func Like(pattern, s string, esc rune) bool {
+	var tokens []likeToken
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			tokens = append(tokens, likeToken{r: r})
+			escaped = false
+		case r == esc:
+			escaped = true
+		case r == '%' || r == '_':
+			tokens = append(tokens, likeToken{kind: byte(r)})
+		default:
+			tokens = append(tokens, likeToken{r: r})
+		}
+	}
+	text := []rune(s)
+
+	// iterative wildcard matching, backtracking to the most recent '%'
+	ti, si := 0, 0
+	star, mark := -1, 0
+	for si < len(text) {
+		switch {
+		case ti < len(tokens) && tokens[ti].kind == '%':
+			star, mark = ti, si
+			ti++
+		case ti < len(tokens) && (tokens[ti].kind == '_' || foldEqual(tokens[ti].r, text[si])):
+			ti++
+			si++
+		case star >= 0:
+			ti = star + 1
+			mark++
+			si = mark
+		default:
+			return false
+		}
+	}
+	for ti < len(tokens) && tokens[ti].kind == '%' {
+		ti++
+	}
+	return ti == len(tokens)
+}
+
+// foldEqual reports whether a and b are equal under Unicode case folding
+func foldEqual(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	for f := unicode.SimpleFold(a); f != a; f = unicode.SimpleFold(f) {
+		if f == b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestLike(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		esc        rune
+		match      bool
+	}{
+		{"ÄPFEL%", "äpfel und birnen", -1, true},
+		{"%straße", "GROSSE STRASSE", -1, false},
+		{"%STRASSE", "große strasse", -1, true},
+		{"_ñ_", "AÑO", -1, true},
+		{"%σ%", "ΟΔΥΣΣΕΥΣ", -1, true},
+		{"100\\%", "100%", '\\', true},
+		{"100\\%", "1000", '\\', false},
+		{"a%b%c", "aXXbYYc", -1, true},
+		{"a%b%c", "aXXbYY", -1, false},
+		{"", "", -1, true},
+		{"%", "", -1, true},
+	}
+	for _, test := range tests {
+		if match := Like(test.pattern, test.s, test.esc); match != test.match {
+			t.Errorf("%q LIKE %q expected: %t", test.s, test.pattern, test.match)
+		}
+	}
+}
+
+func TestUnicodeFuncs(t *testing.T) {
+	db, err := Open(":memory:", WithFunctions(UnicodeFuncs...), WithDriver("unicode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var lower, upper string
+	var match, escaped bool
+	const q = `select lower('ÉCOLE'), upper('über'), 'Ärger' like 'ä%', '5%' like '5!%' escape '!'`
+	if err := row(db, []interface{}{&lower, &upper, &match, &escaped}, q); err != nil {
+		t.Fatal(err)
+	}
+	if lower != "école" || upper != "ÜBER" || !match || !escaped {
+		t.Fatalf("unexpected results: %q %q %t %t", lower, upper, match, escaped)
+	}
+
+	if err := row(db, []interface{}{&match}, "select null like 'a'"); err != nil {
+		t.Fatal(err)
+	}
+	if match {
+		t.Fatal("expected NULL not to match")
+	}
+}
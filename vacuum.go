@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// VacuumInto writes a compacted copy of the open database to dest, which must not exist.
+// Unlike Backup, the copy is made in a single read transaction, so it's consistent
+// without holding up writers, and it leaves out free pages. It suits databases that
+// are quiet enough for one pass over the whole file
+func VacuumInto(db *sql.DB, dest string) error {
+	return VacuumIntoContext(context.Background(), db, dest)
+}
+
+// VacuumIntoContext is VacuumInto, stopping if ctx is done
+func VacuumIntoContext(ctx context.Context, db *sql.DB, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("vacuum into %s: file already exists", dest)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("vacuum into %s: %w", dest, err)
+	}
+	return nil
+}
+
+// SetAutoVacuum sets the auto_vacuum mode of the database, one of "NONE", "FULL",
+// or "INCREMENTAL", vacuuming it if need be for the change to take effect
+func SetAutoVacuum(db *sql.DB, mode string) error {
+	modes := map[string]int{"NONE": 0, "FULL": 1, "INCREMENTAL": 2}
+	want, ok := modes[strings.ToUpper(mode)]
+	if !ok {
+		return fmt.Errorf("invalid auto_vacuum mode: %q", mode)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var current int
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&current); err != nil {
+		return err
+	}
+	if current == want {
+		return nil
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA auto_vacuum = %d", want)); err != nil {
+		return err
+	}
+	// switching between FULL and INCREMENTAL is immediate, but not to or from NONE
+	if current == 0 || want == 0 {
+		if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+			return fmt.Errorf("vacuum for auto_vacuum %s: %w", mode, err)
+		}
+	}
+	return nil
+}
+
+// IncrementalVacuum frees up to pages unused pages from the end of a database in
+// INCREMENTAL auto_vacuum mode, or all of them if pages isn't positive, and returns
+// how many were freed
+func IncrementalVacuum(db *sql.DB, pages int) (int, error) {
+	return incrementalVacuum(context.Background(), db, pages)
+}
+
+func incrementalVacuum(ctx context.Context, db *sql.DB, pages int) (int, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	var before, after int
+	if err := conn.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&before); err != nil {
+		return 0, err
+	}
+	// the pragma frees a page each time it's stepped, so every row must be read
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("PRAGMA incremental_vacuum(%d)", pages))
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := conn.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&after); err != nil {
+		return 0, err
+	}
+	return before - after, nil
+}
+
+// MaintenanceScheduler runs routine maintenance on a database at set intervals.
+// A task with no interval isn't run
+type MaintenanceScheduler struct {
+	IncrementalVacuum time.Duration // PRAGMA incremental_vacuum
+	VacuumPages       int           // pages freed by each incremental vacuum, all if not set
+	Optimize          time.Duration // PRAGMA optimize
+	Checkpoint        time.Duration // PRAGMA wal_checkpoint
+	CheckpointMode    string        // the mode of each checkpoint, PASSIVE if not set
+	FTSOptimize       time.Duration // FTSOptimize of each full-text table
+	FTSCheck          time.Duration // FTSIntegrityCheck of each full-text table, with FTSRebuild of any that fail
+	FTSTables         []string      // the full-text tables maintained, all of them if not set
+
+	// OnError is called when a task fails. If nil the error is logged
+	OnError func(task string, err error)
+}
+
+// Run performs maintenance until ctx is done, or the database is closed
+func (m *MaintenanceScheduler) Run(ctx context.Context, db *sql.DB) error {
+	var tickers []*time.Ticker
+	defer func() {
+		for _, t := range tickers {
+			t.Stop()
+		}
+	}()
+	tick := func(d time.Duration) <-chan time.Time {
+		if d <= 0 {
+			return nil
+		}
+		tickers = append(tickers, time.NewTicker(d))
+		return tickers[len(tickers)-1].C
+	}
+	vacuum, optimize, checkpoint := tick(m.IncrementalVacuum), tick(m.Optimize), tick(m.Checkpoint)
+	ftsOptimize, ftsCheck := tick(m.FTSOptimize), tick(m.FTSCheck)
+	if len(tickers) == 0 {
+		return nil
+	}
+	mode := m.CheckpointMode
+	if mode == "" {
+		mode = "PASSIVE"
+	}
+	for {
+		var task string
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-vacuum:
+			task = "incremental_vacuum"
+			_, err = incrementalVacuum(ctx, db, m.VacuumPages)
+		case <-optimize:
+			task = "optimize"
+			_, err = db.ExecContext(ctx, "PRAGMA optimize")
+		case <-checkpoint:
+			task = "wal_checkpoint"
+			_, err = WALCheckpoint(db, mode)
+		case <-ftsOptimize:
+			task = "fts_optimize"
+			err = m.eachFTS(db, FTSOptimize)
+		case <-ftsCheck:
+			task = "fts_integrity_check"
+			err = m.eachFTS(db, checkFTS)
+		}
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		if err == sql.ErrConnDone || db.PingContext(ctx) != nil {
+			return err
+		}
+		if m.OnError != nil {
+			m.OnError(task, err)
+		} else {
+			logf("maintenance: %s -- %v\n", task, err)
+		}
+	}
+}
+
+// eachFTS runs fn on each full-text table maintained, returning the first error
+func (m *MaintenanceScheduler) eachFTS(db *sql.DB, fn func(db *sql.DB, table string) error) error {
+	tables := m.FTSTables
+	if len(tables) == 0 {
+		var err error
+		if tables, err = ftsTables(db); err != nil {
+			return err
+		}
+	}
+	var first error
+	for _, table := range tables {
+		if err := fn(db, table); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// checkFTS rebuilds a full-text index that fails its integrity check.
+// The failure is still returned, so that it's reported
+func checkFTS(db *sql.DB, table string) error {
+	err := FTSIntegrityCheck(db, table)
+	if err == nil {
+		return nil
+	}
+	if rerr := FTSRebuild(db, table); rerr != nil {
+		return fmt.Errorf("%w, and rebuilding it failed: %v", err, rerr)
+	}
+	return fmt.Errorf("%w, so it was rebuilt", err)
+}
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVacuum(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "vacuum.db")
+	db, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := SetAutoVacuum(db, "incremental"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAutoVacuum(db, "sometimes"); err == nil {
+		t.Fatal("expected error for an invalid mode")
+	}
+	const setup = `
+create table blob (id integer primary key, body text);
+with recursive n(i) as (select 1 union all select i + 1 from n where i < 1000)
+insert into blob select i, printf('%.1000c', 'x') from n;
+delete from blob where id > 100;
+`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	var free int
+	if err := db.QueryRow("PRAGMA freelist_count").Scan(&free); err != nil {
+		t.Fatal(err)
+	}
+	if free == 0 {
+		t.Fatal("expected free pages after the delete")
+	}
+
+	dest := filepath.Join(dir, "copy.db")
+	if err := VacuumInto(db, dest); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Size() >= original.Size() {
+		t.Fatalf("expected the copy to be compacted, got %d bytes from %d", copied.Size(), original.Size())
+	}
+	if err := VacuumInto(db, dest); err == nil {
+		t.Fatal("expected error vacuuming into an existing file")
+	}
+
+	freed, err := IncrementalVacuum(db, 10)
+	if err != nil || freed != 10 {
+		t.Fatalf("expected 10 pages freed, got %d (%v)", freed, err)
+	}
+
+	// the scheduler frees the rest
+	var failed int32
+	m := &MaintenanceScheduler{
+		IncrementalVacuum: 5 * time.Millisecond,
+		Optimize:          5 * time.Millisecond,
+		OnError:           func(string, error) { atomic.AddInt32(&failed, 1) },
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := m.Run(ctx, db); err != context.DeadlineExceeded {
+		t.Fatalf("expected the scheduler to run until the deadline, got %v", err)
+	}
+	if err := db.QueryRow("PRAGMA freelist_count").Scan(&free); err != nil {
+		t.Fatal(err)
+	}
+	if free != 0 || failed != 0 {
+		t.Fatalf("expected no free pages and no errors, got %d and %d", free, failed)
+	}
+}
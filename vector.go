@@ -0,0 +1,362 @@
+package sqlite
+
+import (
+	"container/heap"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Vector is an embedding, stored as a blob of little endian float32 values
+type Vector []float32
+
+// Value implements driver.Valuer
+func (v Vector) Value() (driver.Value, error) {
+	return EncodeVector(v), nil
+}
+
+// Scan implements sql.Scanner
+func (v *Vector) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok && src != nil {
+		return fmt.Errorf("can't scan %T as a vector", src)
+	}
+	vec, err := DecodeVector(b)
+	*v = vec
+	return err
+}
+
+// EncodeVector encodes v as a blob
+func EncodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+// DecodeVector decodes a blob created by EncodeVector
+func DecodeVector(b []byte) (Vector, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector length: %d bytes", len(b))
+	}
+	v := make(Vector, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v, nil
+}
+
+// Metric measures the distance between vectors, where smaller is closer
+type Metric int
+
+const (
+	// L2 is the euclidean distance
+	L2 Metric = iota
+	// Cosine is the cosine distance (1 - cosine similarity)
+	Cosine
+)
+
+func (m Metric) distance(a, b Vector) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("vector dimensions differ: %d != %d", len(a), len(b))
+	}
+	if m == Cosine {
+		s, err := cosineSimilarity(a, b)
+		return 1 - s, err
+	}
+	return l2Distance(a, b)
+}
+
+func l2Distance(a, b Vector) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("vector dimensions differ: %d != %d", len(a), len(b))
+	}
+	var sum float64
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum), nil
+}
+
+func cosineSimilarity(a, b Vector) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("vector dimensions differ: %d != %d", len(a), len(b))
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0, nil
+	}
+	return dot / math.Sqrt(na*nb), nil
+}
+
+// vectorFunc adapts a vector function to take blobs from SQL
+func vectorFunc(fn func(a, b Vector) (float64, error)) func([]byte, []byte) (float64, error) {
+	return func(x, y []byte) (float64, error) {
+		a, err := DecodeVector(x)
+		if err != nil {
+			return 0, err
+		}
+		b, err := DecodeVector(y)
+		if err != nil {
+			return 0, err
+		}
+		return fn(a, b)
+	}
+}
+
+// VectorFuncs are SQL functions comparing vectors stored as blobs
+var VectorFuncs = []FuncReg{
+	{"cosine_similarity", vectorFunc(cosineSimilarity), true},
+	{"l2_distance", vectorFunc(l2Distance), true},
+}
+
+// Neighbor is a row found by KNN
+type Neighbor struct {
+	RowID    int64
+	Distance float64
+}
+
+// neighbors is a max heap, so the farthest of the current k nearest is on top
+type neighbors []Neighbor
+
+func (n neighbors) Len() int            { return len(n) }
+func (n neighbors) Less(i, j int) bool  { return n[i].Distance > n[j].Distance }
+func (n neighbors) Swap(i, j int)       { n[i], n[j] = n[j], n[i] }
+func (n *neighbors) Push(x interface{}) { *n = append(*n, x.(Neighbor)) }
+func (n *neighbors) Pop() interface{} {
+	old := *n
+	x := old[len(old)-1]
+	*n = old[:len(old)-1]
+	return x
+}
+
+// KNNOptions are the options for KNN
+type KNNOptions struct {
+	Metric Metric
+	Probes int // if non-zero, search only this many of the nearest buckets built by BuildVectorIndex
+}
+
+// vectorTables returns the names of the tables used by the vector index of the column
+func vectorTables(table, column string) (centroids, buckets string) {
+	prefix := table + "_" + column
+	return prefix + "_centroids", prefix + "_buckets"
+}
+
+// KNN returns the k rows of table whose vector column is nearest to query, nearest first
+func KNN(db *sql.DB, table, column string, query Vector, k int, opts KNNOptions) ([]Neighbor, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("knn: k must be positive: %d", k)
+	}
+	q := fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %[1]s IS NOT NULL", quoteIdent(column), quoteIdent(table))
+	if opts.Probes > 0 {
+		centroids, buckets := vectorTables(table, column)
+		near, err := nearestCentroids(db, centroids, query, opts.Probes, opts.Metric)
+		if err != nil {
+			return nil, err
+		}
+		q += fmt.Sprintf(" AND rowid IN (SELECT rowid FROM %s WHERE bucket IN (%s))", quoteIdent(buckets), near)
+	}
+
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	h := make(neighbors, 0, k+1)
+	for rows.Next() {
+		var id int64
+		var v Vector
+		if err := rows.Scan(&id, &v); err != nil {
+			return nil, err
+		}
+		d, err := opts.Metric.distance(query, v)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", id, err)
+		}
+		if len(h) < k {
+			heap.Push(&h, Neighbor{RowID: id, Distance: d})
+		} else if len(h) > 0 && d < h[0].Distance {
+			h[0] = Neighbor{RowID: id, Distance: d}
+			heap.Fix(&h, 0)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	list := make([]Neighbor, len(h))
+	for i := len(h) - 1; i >= 0; i-- {
+		list[i] = heap.Pop(&h).(Neighbor)
+	}
+	return list, nil
+}
+
+// nearestCentroids returns a comma separated list of the buckets nearest to query
+func nearestCentroids(db *sql.DB, table string, query Vector, probes int, metric Metric) (string, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT bucket, centroid FROM %s", quoteIdent(table)))
+	if err != nil {
+		return "", fmt.Errorf("vector index: %w", err)
+	}
+	defer rows.Close()
+
+	h := make(neighbors, 0, probes+1)
+	for rows.Next() {
+		var bucket int64
+		var v Vector
+		if err := rows.Scan(&bucket, &v); err != nil {
+			return "", err
+		}
+		d, err := metric.distance(query, v)
+		if err != nil {
+			return "", err
+		}
+		heap.Push(&h, Neighbor{RowID: bucket, Distance: d})
+		if len(h) > probes {
+			heap.Pop(&h)
+		}
+	}
+	ids := make([]string, len(h))
+	for i, n := range h {
+		ids[i] = fmt.Sprint(n.RowID)
+	}
+	return strings.Join(ids, ","), rows.Err()
+}
+
+// BuildVectorIndex partitions the vectors of a column into buckets using k-means clustering,
+// so that KNN can search only the buckets nearest to the query (an IVF index)
+//
+// The index is stored in the tables <table>_<column>_centroids and <table>_<column>_buckets,
+// and must be rebuilt after rows are added or changed
+func BuildVectorIndex(db *sql.DB, table, column string, buckets int, metric Metric) error {
+	if buckets < 1 {
+		return fmt.Errorf("invalid bucket count: %d", buckets)
+	}
+	rows, err := db.Query(fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %[1]s IS NOT NULL", quoteIdent(column), quoteIdent(table)))
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	var vecs []Vector
+	for rows.Next() {
+		var id int64
+		var v Vector
+		if err := rows.Scan(&id, &v); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+		vecs = append(vecs, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(vecs) < buckets {
+		buckets = len(vecs)
+	}
+
+	centroids, assigned, err := kmeans(vecs, buckets, metric, 10)
+	if err != nil {
+		return err
+	}
+
+	ctable, btable := vectorTables(table, column)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ddl := fmt.Sprintf(`
+DROP TABLE IF EXISTS %[1]s;
+DROP TABLE IF EXISTS %[2]s;
+CREATE TABLE %[1]s (bucket INTEGER PRIMARY KEY, centroid BLOB NOT NULL);
+CREATE TABLE %[2]s (rowid INTEGER PRIMARY KEY, bucket INTEGER NOT NULL);
+CREATE INDEX %[3]s ON %[2]s (bucket);
+`, quoteIdent(ctable), quoteIdent(btable), quoteIdent(btable+"_bucket"))
+	if _, err := tx.Exec(ddl); err != nil {
+		return err
+	}
+	for i, c := range centroids {
+		if _, err := tx.Exec(fmt.Sprintf("INSERT INTO %s VALUES(?, ?)", quoteIdent(ctable)), i, c); err != nil {
+			return err
+		}
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES(?, ?)", quoteIdent(btable)))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, id := range ids {
+		if _, err := stmt.Exec(id, assigned[i]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// kmeans clusters vecs into k groups, returning the centroids and the group of each vector
+func kmeans(vecs []Vector, k int, metric Metric, rounds int) ([]Vector, []int, error) {
+	if k == 0 {
+		return nil, nil, nil
+	}
+	dims := len(vecs[0])
+	centroids := make([]Vector, k)
+	for i := range centroids {
+		// spread the initial centroids evenly through the data
+		centroids[i] = append(Vector(nil), vecs[i*len(vecs)/k]...)
+	}
+	assigned := make([]int, len(vecs))
+	for round := 0; round < rounds; round++ {
+		changed := false
+		for i, v := range vecs {
+			best, bestDist := 0, math.Inf(1)
+			for j, c := range centroids {
+				d, err := metric.distance(v, c)
+				if err != nil {
+					return nil, nil, err
+				}
+				if d < bestDist {
+					best, bestDist = j, d
+				}
+			}
+			if assigned[i] != best || round == 0 {
+				changed = true
+			}
+			assigned[i] = best
+		}
+		if !changed {
+			break
+		}
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for i := range sums {
+			sums[i] = make([]float64, dims)
+		}
+		for i, v := range vecs {
+			for d, f := range v {
+				sums[assigned[i]][d] += float64(f)
+			}
+			counts[assigned[i]]++
+		}
+		for j := range centroids {
+			if counts[j] == 0 {
+				continue // keep empty clusters where they are
+			}
+			for d := range centroids[j] {
+				centroids[j][d] = float32(sums[j][d] / float64(counts[j]))
+			}
+		}
+	}
+	return centroids, assigned, nil
+}
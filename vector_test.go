@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVectorEncoding(t *testing.T) {
+	v := Vector{1.5, -2, 0, math.MaxFloat32}
+	out, err := DecodeVector(EncodeVector(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range v {
+		if v[i] != out[i] {
+			t.Fatalf("expected: %v but got: %v", v, out)
+		}
+	}
+	if _, err := DecodeVector([]byte{1, 2, 3}); err == nil {
+		t.Fatal("expected error for invalid length")
+	}
+}
+
+func TestVectorSearch(t *testing.T) {
+	db, err := Open(":memory:", WithFunctions(VectorFuncs...), WithDriver("vectors"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("create table items (id integer primary key, embedding blob)"); err != nil {
+		t.Fatal(err)
+	}
+	// two clusters, around (0,0) and (10,10)
+	for i := 0; i < 20; i++ {
+		f := float32(i % 10)
+		v := Vector{f / 10, f / 10}
+		if i >= 10 {
+			v = Vector{10 + f/10, 10 + f/10}
+		}
+		if _, err := db.Exec("insert into items (id, embedding) values(?, ?)", i+1, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var similarity, distance float64
+	const q = "select cosine_similarity(?1, ?2), l2_distance(?1, ?2)"
+	if err := row(db, []interface{}{&similarity, &distance}, q, Vector{1, 0}, Vector{0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if similarity != 0 || math.Abs(distance-math.Sqrt2) > 1e-9 {
+		t.Fatalf("unexpected similarity: %f distance: %f", similarity, distance)
+	}
+
+	query := Vector{10, 10}
+	exact, err := KNN(db, "items", "embedding", query, 3, KNNOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exact) != 3 || exact[0].RowID != 11 || exact[0].Distance != 0 {
+		t.Fatalf("unexpected neighbors: %+v", exact)
+	}
+
+	if err := BuildVectorIndex(db, "items", "embedding", 2, L2); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []int{0, -5} {
+		if _, err := KNN(db, "items", "embedding", query, k, KNNOptions{}); err == nil {
+			t.Errorf("expected an error for k = %d", k)
+		}
+	}
+	approx, err := KNN(db, "items", "embedding", query, 3, KNNOptions{Probes: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range exact {
+		if approx[i] != exact[i] {
+			t.Fatalf("expected: %+v but got: %+v", exact, approx)
+		}
+	}
+}
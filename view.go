@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ListViewDependencies returns the tables and views a view selects from
+func ListViewDependencies(db *sql.DB, view string) ([]string, error) {
+	objects, err := Schema(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		if obj.Kind == "view" && strings.EqualFold(obj.Name, view) {
+			return obj.Dependencies, nil
+		}
+	}
+	return nil, fmt.Errorf("no such view: %s", view)
+}
+
+// DependentViews returns the views that select from a table or view, directly or through
+// other views, along with the triggers on them, in the order they can be created in
+func DependentViews(db *sql.DB, name string) ([]SchemaObject, error) {
+	objects, err := Schema(db)
+	if err != nil {
+		return nil, err
+	}
+	return dependents(objects, name), nil
+}
+
+// dependents returns the views that depend on name, and their triggers, in creation order
+func dependents(objects []SchemaObject, name string) []SchemaObject {
+	affected := map[string]bool{strings.ToLower(name): true}
+	var found []SchemaObject
+	// objects are in creation order, so a view comes after those it depends on
+	for _, obj := range objects {
+		if obj.Kind != "view" && obj.Kind != "trigger" {
+			continue
+		}
+		if obj.Kind == "trigger" && !affected[strings.ToLower(obj.Table)] {
+			continue
+		}
+		for _, dep := range obj.Dependencies {
+			if affected[strings.ToLower(dep)] {
+				if obj.Kind == "view" {
+					affected[strings.ToLower(obj.Name)] = true
+				}
+				found = append(found, obj)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// ReplaceView creates a view, or replaces it if it exists, as the given SELECT statement.
+// The views that depend on it, and the triggers on them, are dropped and created again
+// so they are checked against the new definition, all in one transaction:
+// if any of them no longer works with it, nothing is changed
+func ReplaceView(db *sql.DB, name, query string) (err error) {
+	objects, err := Schema(db)
+	if err != nil {
+		return err
+	}
+	var exists bool
+	for _, obj := range objects {
+		if strings.EqualFold(obj.Name, name) {
+			if obj.Kind != "view" {
+				return fmt.Errorf("%s is a %s, not a view", name, obj.Kind)
+			}
+			exists = true
+		}
+	}
+	rebuild := dependents(objects, name)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("replace view %s: %w", name, err)
+		}
+	}()
+	for i := len(rebuild) - 1; i >= 0; i-- {
+		if rebuild[i].Kind != "view" {
+			// dropped along with its view
+			continue
+		}
+		if _, err = tx.Exec("DROP VIEW " + quoteIdent(rebuild[i].Name)); err != nil {
+			return err
+		}
+	}
+	if exists {
+		if _, err = tx.Exec("DROP VIEW " + quoteIdent(name)); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.Exec(fmt.Sprintf("CREATE VIEW %s AS %s", quoteIdent(name), query)); err != nil {
+		return err
+	}
+	for _, obj := range rebuild {
+		if _, err = tx.Exec(obj.SQL); err != nil {
+			return fmt.Errorf("%s %s: %w", obj.Kind, obj.Name, err)
+		}
+		// a view is only checked when it is used
+		if obj.Kind == "view" {
+			var rows *sql.Rows
+			if rows, err = tx.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 0", quoteIdent(obj.Name))); err != nil {
+				return fmt.Errorf("%s %s: %w", obj.Kind, obj.Name, err)
+			}
+			rows.Close()
+		}
+	}
+	return tx.Commit()
+}
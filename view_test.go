@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplaceView(t *testing.T) {
+	db := memDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	const schema = `
+	create table people (id integer primary key, name text, age int);
+	create table log (msg text);
+	insert into people (name, age) values ('ann', 30), ('bob', 17);
+	create view adults as select id, name from people where age >= 18;
+	create view adult_names as select name from adults;
+	create view shouting as select upper(name) as name from adult_names;
+	create trigger shouting_insert instead of insert on shouting begin
+		insert into log values (new.name);
+	end;
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	deps, err := ListViewDependencies(db, "adult_names")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(deps, []string{"adults"}) {
+		t.Fatalf("unexpected dependencies: %v", deps)
+	}
+	views, err := DependentViews(db, "adults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range views {
+		names = append(names, v.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"adult_names", "shouting", "shouting_insert"}) {
+		t.Fatalf("unexpected dependents: %v", names)
+	}
+
+	if err := ReplaceView(db, "adults", "select id, name from people where age >= 16"); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := row(db, []interface{}{&count}, "select count(*) from shouting"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected: %d but got: %d", 2, count)
+	}
+	if err := row(db, []interface{}{&count}, "select count(*) from sqlite_master where name = 'shouting_insert'"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatal("expected trigger to be recreated")
+	}
+
+	// dropping the name column breaks adult_names, so nothing changes
+	if err := ReplaceView(db, "adults", "select id from people"); err == nil {
+		t.Fatal("expected error for a definition that breaks dependent views")
+	}
+	if err := row(db, []interface{}{&count}, "select count(*) from adult_names"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected: %d but got: %d", 2, count)
+	}
+
+	if err := ReplaceView(db, "teens", "select * from people where age < 20"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceView(db, "people", "select 1"); err == nil {
+		t.Fatal("expected error replacing a table")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build sqlite_vtable || vtable
+// +build sqlite_vtable vtable
+
+package sqlite
+
+import (
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// WithModule registers a virtual table module on each connection, so that tables
+// can be created with it, e.g. CREATE VIRTUAL TABLE t USING name(args).
+// An eponymous-only module is a table of its own name on each connection.
+// The same module is registered on every connection of the pool, and its
+// DestroyModule is called as each of them closes.
+// Modules must be enabled by using the build tag "vtable" or "sqlite_vtable"
+func WithModule(name string, module sqlite3.Module) Optional {
+	return func(c *Config) {
+		c.modules = append(c.modules, moduleReg{
+			name: name,
+			register: func(conn *sqlite3.SQLiteConn) error {
+				return conn.CreateModule(name, module)
+			},
+		})
+	}
+}
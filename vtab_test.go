@@ -0,0 +1,100 @@
+//go:build sqlite_vtable || vtable
+// +build sqlite_vtable vtable
+
+package sqlite
+
+import (
+	"strconv"
+	"testing"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// seriesModule is a table of the numbers from 1 to the argument it's created with
+type seriesModule struct{}
+
+func (m seriesModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Connect(c, args)
+}
+
+func (seriesModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	n := 10
+	if len(args) > 3 {
+		var err error
+		if n, err = strconv.Atoi(args[3]); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.DeclareVTab("CREATE TABLE x(value INTEGER)"); err != nil {
+		return nil, err
+	}
+	return &seriesTable{n: n}, nil
+}
+
+func (seriesModule) DestroyModule() {}
+
+type seriesTable struct{ n int }
+
+func (t *seriesTable) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	return &sqlite3.IndexResult{Used: make([]bool, len(cst))}, nil
+}
+
+func (t *seriesTable) Disconnect() error { return nil }
+func (t *seriesTable) Destroy() error    { return nil }
+
+func (t *seriesTable) Open() (sqlite3.VTabCursor, error) {
+	return &seriesCursor{n: t.n}, nil
+}
+
+type seriesCursor struct{ i, n int }
+
+func (c *seriesCursor) Close() error { return nil }
+
+func (c *seriesCursor) Filter(int, string, []interface{}) error {
+	c.i = 1
+	return nil
+}
+
+func (c *seriesCursor) Next() error {
+	c.i++
+	return nil
+}
+
+func (c *seriesCursor) EOF() bool { return c.i > c.n }
+
+func (c *seriesCursor) Column(ctx *sqlite3.SQLiteContext, col int) error {
+	ctx.ResultInt(c.i)
+	return nil
+}
+
+func (c *seriesCursor) Rowid() (int64, error) { return int64(c.i), nil }
+
+func TestWithModule(t *testing.T) {
+	db, err := Open(":memory:", WithDriver("vtab_series"), WithModule("series", seriesModule{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec("CREATE VIRTUAL TABLE numbers USING series(5)"); err != nil {
+		t.Fatal(err)
+	}
+	var count, sum int
+	if err := db.QueryRow("SELECT count(*), sum(value) FROM numbers").Scan(&count, &sum); err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 || sum != 15 {
+		t.Errorf("got %d rows summing to %d, want 5 and 15", count, sum)
+	}
+
+	// another pool with the same module
+	other, err := Open(":memory:", WithDriver("vtab_series"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, err := other.Exec("CREATE VIRTUAL TABLE numbers USING series(3)"); err != nil {
+		t.Fatal(err)
+	}
+}
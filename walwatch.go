@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"time"
+)
+
+// checkpointModes are used in order of escalation
+var checkpointModes = []string{"PASSIVE", "RESTART", "TRUNCATE"}
+
+// WALStatus is the state of the write-ahead log after a watchdog check
+type WALStatus struct {
+	Size         int64  // size of the -wal file in bytes
+	Frames       int    // frames in the log
+	Checkpointed int    // frames checkpointed into the database
+	Mode         string // the checkpoint mode used, if any
+	Busy         bool   // the checkpoint could not complete
+}
+
+// WALWatchdog checkpoints a database when its write-ahead log grows past a threshold,
+// escalating from PASSIVE to RESTART to TRUNCATE checkpoints while it stays too large
+type WALWatchdog struct {
+	MaxSize   int64         // the -wal file size in bytes that triggers a checkpoint
+	MaxFrames int           // the number of log frames that triggers a checkpoint
+	Interval  time.Duration // how often Run checks the log
+	Failures  int           // consecutive incomplete checkpoints before reporting the log as stuck
+
+	// Monitor, if set, provides the open transactions reported with a stuck log,
+	// as a long-lived reader is what prevents a checkpoint from completing
+	Monitor *TxMonitor
+
+	// OnStuck is called when the log is stuck. If nil the status is logged
+	OnStuck func(WALStatus, []TxInfo)
+
+	level  int
+	failed int
+}
+
+// walSize returns the size of the database's -wal file
+func walSize(db *sql.DB) (int64, error) {
+	file, err := filename(db)
+	if file == "" || err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(file + "-wal")
+	if err != nil {
+		return 0, nil
+	}
+	return fi.Size(), nil
+}
+
+// Check inspects the log once, checkpointing if it's over a threshold
+func (w *WALWatchdog) Check(db *sql.DB) (WALStatus, error) {
+	var status WALStatus
+	size, err := walSize(db)
+	if err != nil {
+		return status, err
+	}
+	status.Size = size
+
+	// a passive checkpoint with nothing to do is cheap and reports the frame count
+	result, err := WALCheckpoint(db, "PASSIVE")
+	if err != nil {
+		return status, err
+	}
+	status.Frames, status.Checkpointed, status.Busy = result.Frames, result.Checkpointed, result.Busy
+
+	over := (w.MaxSize > 0 && status.Size > w.MaxSize) || (w.MaxFrames > 0 && status.Frames > w.MaxFrames)
+	if !over {
+		w.level, w.failed = 0, 0
+		return status, nil
+	}
+
+	status.Mode = checkpointModes[w.level]
+	if w.level > 0 {
+		if result, err = WALCheckpoint(db, status.Mode); err != nil {
+			return status, err
+		}
+		status.Frames, status.Checkpointed, status.Busy = result.Frames, result.Checkpointed, result.Busy
+	}
+	if w.level < len(checkpointModes)-1 {
+		w.level++
+	}
+	if status.Size, err = walSize(db); err != nil {
+		return status, err
+	}
+
+	if status.Busy || status.Checkpointed < status.Frames {
+		w.failed++
+		if w.Failures > 0 && w.failed >= w.Failures {
+			w.failed = 0
+			w.stuck(status)
+		}
+	} else {
+		w.failed = 0
+	}
+	return status, nil
+}
+
+func (w *WALWatchdog) stuck(status WALStatus) {
+	var open []TxInfo
+	if w.Monitor != nil {
+		open = w.Monitor.Open()
+	}
+	if w.OnStuck != nil {
+		w.OnStuck(status, open)
+		return
+	}
+	logf("WAL checkpoint stuck: %d bytes, %d of %d frames checkpointed, %d open transactions\n",
+		status.Size, status.Checkpointed, status.Frames, len(open))
+	for _, tx := range open {
+		logf("transaction %d open for %s, started at %s\n", tx.ID, tx.Duration, tx.Caller)
+	}
+}
+
+// Run checks the log every Interval until ctx is done
+func (w *WALWatchdog) Run(ctx context.Context, db *sql.DB) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := w.Check(db); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestWALWatchdog(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "wal.db")
+	db, err := Open(file + "?_busy_timeout=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const setup = `
+	pragma journal_mode=wal;
+	pragma wal_autocheckpoint=0;
+	create table numbers (n integer);
+	with recursive seq(n) as (select 1 union all select n+1 from seq where n < 5000)
+	insert into numbers select n from seq;
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &WALWatchdog{MaxSize: 1024, Failures: 2}
+	modes := []string{"PASSIVE", "RESTART", "TRUNCATE"}
+	for i, mode := range modes {
+		status, err := w.Check(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Mode != mode {
+			t.Fatalf("check %d expected: %s but got: %+v", i, mode, status)
+		}
+		if _, err := db.Exec("insert into numbers select n from numbers limit 500"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		t.Fatal(err)
+	}
+	status, err := w.Check(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Mode != "" || status.Size != 0 {
+		t.Fatalf("expected no checkpoint but got: %+v", status)
+	}
+
+	// a reader holding a snapshot keeps the log from being reset
+	m := NewTxMonitor(0, nil)
+	tx, err := m.Begin(context.Background(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	var count int
+	if err := tx.QueryRow("select count(*) from numbers").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("insert into numbers select n from numbers limit 500"); err != nil {
+		t.Fatal(err)
+	}
+
+	var stuck []TxInfo
+	w.Monitor = m
+	w.OnStuck = func(status WALStatus, open []TxInfo) {
+		stuck = open
+	}
+	for i := 0; i < 3 && stuck == nil; i++ {
+		if _, err := w.Check(db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(stuck) != 1 || stuck[0].Caller == "" {
+		t.Fatalf("expected stuck reader but got: %+v", stuck)
+	}
+}
@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+	"unsafe"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// txConfig is how WithTx runs a transaction
+type txConfig struct {
+	readOnly   bool
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// TxOption configures WithTx
+type TxOption func(*txConfig)
+
+// TxReadOnly runs the transaction as a deferred one that only reads, and fails any
+// statement that writes. It takes no lock until its first read, and doesn't keep
+// writers out
+func TxReadOnly() TxOption {
+	return func(c *txConfig) {
+		c.readOnly = true
+	}
+}
+
+// TxAttempts sets how many times the transaction is tried before the busy error
+// is returned, 10 if not set
+func TxAttempts(n int) TxOption {
+	return func(c *txConfig) {
+		c.attempts = n
+	}
+}
+
+// TxBackoff sets the wait before the first retry, which doubles with each retry up
+// to max. The defaults are 10ms and 1s
+func TxBackoff(initial, max time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.backoff, c.maxBackoff = initial, max
+	}
+}
+
+// IsBusy reports whether err is SQLITE_BUSY or SQLITE_LOCKED, which a retry can resolve
+func IsBusy(err error) bool {
+	var serr sqlite3.Error
+	return errors.As(err, &serr) && (serr.Code == sqlite3.ErrBusy || serr.Code == sqlite3.ErrLocked)
+}
+
+// WithTx runs fn in a transaction, committing it if fn returns nil and rolling it
+// back otherwise. The transaction begins IMMEDIATE, taking the write lock at once,
+// so it can't fail part way through for want of it. If the database is busy or
+// locked at any point the transaction is rolled back and run again after a backoff,
+// so fn may be called more than once and shouldn't have effects outside of tx
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error, opts ...TxOption) error {
+	config := txConfig{attempts: 10, backoff: 10 * time.Millisecond, maxBackoff: time.Second}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	wait := config.backoff
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, db, fn, config.readOnly)
+		if err == nil || !IsBusy(err) || attempt >= config.attempts {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if wait *= 2; wait > config.maxBackoff {
+			wait = config.maxBackoff
+		}
+	}
+}
+
+// runTx runs fn once in a transaction on a connection of its own
+func runTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error, readOnly bool) (err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer conn.Close()
+
+	// the driver begins transactions with the statement set by the DSN, so it's
+	// swapped for the one wanted while the connection is held
+	begin := "BEGIN IMMEDIATE"
+	if readOnly {
+		begin = "BEGIN"
+		// the connection may already be read only, as the DSN or a hook can make it
+		var queryOnly int
+		if err := conn.QueryRowContext(ctx, "PRAGMA query_only").Scan(&queryOnly); err != nil {
+			return ContextError(ctx, err)
+		}
+		if queryOnly == 0 {
+			if _, err := conn.ExecContext(ctx, "PRAGMA query_only = 1"); err != nil {
+				return err
+			}
+			defer conn.ExecContext(context.Background(), "PRAGMA query_only = 0")
+		}
+	}
+	var previous string
+	err = conn.Raw(func(dc interface{}) error {
+		sc, ok := dc.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection: %T", dc)
+		}
+		lock, err := txLock(sc)
+		if err != nil {
+			return err
+		}
+		previous, *lock = *lock, begin
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Raw(func(dc interface{}) error {
+		lock, err := txLock(dc.(*sqlite3.SQLiteConn))
+		if err == nil {
+			*lock = previous
+		}
+		return err
+	})
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// txLock returns the statement the connection begins transactions with, which
+// the driver keeps in an unexported field. TestDriverFields fails if a new version
+// of the driver moves it
+func txLock(conn *sqlite3.SQLiteConn) (*string, error) {
+	field := reflect.ValueOf(conn).Elem().FieldByName("txlock")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return nil, errors.New("this version of the driver can't choose how transactions begin")
+	}
+	return (*string)(unsafe.Pointer(field.UnsafeAddr())), nil
+}
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+func TestWithTx(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "withtx.db")
+	holder, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	if _, err := holder.Exec("create table counter (n int)"); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(file + "?_busy_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	conn, err := holder.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func(tx *sql.Tx) error {
+		_, err := tx.Exec("insert into counter values (1)")
+		return err
+	}
+	err = WithTx(ctx, db, insert, TxAttempts(2), TxBackoff(time.Millisecond, time.Millisecond))
+	if !IsBusy(err) {
+		t.Fatalf("expected a busy error, got %v", err)
+	}
+
+	// retries until the lock is released
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		conn.ExecContext(ctx, "COMMIT")
+	}()
+	if err := WithTx(ctx, db, insert, TxBackoff(5*time.Millisecond, 20*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err = WithTx(ctx, db, func(tx *sql.Tx) error {
+		if err := insert(tx); err != nil {
+			return err
+		}
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("expected the error of the function, got %v", err)
+	}
+
+	var count int
+	err = WithTx(ctx, db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("select count(*) from counter").Scan(&count); err != nil {
+			return err
+		}
+		return insert(tx)
+	}, TxReadOnly())
+	if err == nil || count != 1 {
+		t.Fatalf("expected one row and a read-only error, got %d (%v)", count, err)
+	}
+
+	// the connection can write again afterwards
+	if _, err := db.Exec("insert into counter values (2)"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithTxReadOnlyConnection(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "readonly.db"), WithDriver("withtx_query_only"), WithQuery("PRAGMA query_only = 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	err = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("select 1")
+		return err
+	}, TxReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the connection is left read only, as it was
+	if _, err := db.Exec("create table t (n int)"); err == nil {
+		t.Fatal("expected the connection to still be read only")
+	}
+}
+
+// TestDriverFields checks the unexported fields of the driver's connection that
+// WithTx and WithBusyHandler rely on. It's written against go-sqlite3 v1.14.6, and
+// failing here means a new version of the driver has moved them
+func TestDriverFields(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(dc interface{}) error {
+		sc := dc.(*sqlite3.SQLiteConn)
+		lock, err := txLock(sc)
+		if err != nil {
+			return fmt.Errorf("txlock: %w", err)
+		}
+		if *lock != "BEGIN" {
+			return fmt.Errorf("txlock: expected %q, got %q", "BEGIN", *lock)
+		}
+		if _, err := connHandle(sc); err != nil {
+			return fmt.Errorf("db: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("the driver's connection has changed: %v", err)
+	}
+}